@@ -40,7 +40,7 @@ var BackupCmd = &cobra.Command{
 		err := pkg.Backup(cmd)
 		if err != nil {
 			slog.Error("Backup error", "error", err)
-			os.Exit(1)
+			os.Exit(pkg.ExitCode(err))
 		}
 	},
 }
@@ -49,7 +49,49 @@ func init() {
 	// Backup
 	BackupCmd.PersistentFlags().BoolP("compress", "c", false, "Enable backup compression")
 	BackupCmd.PersistentFlags().BoolP("timestamp", "t", false, "Enable timestamp in backup file name, only for compression")
+	BackupCmd.PersistentFlags().BoolP("compress-files", "", false, "Gzip each file individually, objects get the .gz suffix and are decompressed on restore")
+	BackupCmd.PersistentFlags().BoolP("no-recompress", "", false, "Store files that are already compressed (jpg, mp4, zip, gz, ...) without compressing them again")
 	BackupCmd.PersistentFlags().StringP("path", "p", "", "Storage path`")
 	BackupCmd.PersistentFlags().StringP("dest", "d", "", "S3 destination path`")
 	BackupCmd.PersistentFlags().StringP("file", "f", "", "Backup a single file`")
+	BackupCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Skip unreadable files and directories instead of aborting the backup")
+	BackupCmd.PersistentFlags().StringP("on-unreadable", "", "", "Unreadable files and directories: skip, warn (skip and report them, exit code 4) or fail (default fail, warn with --ignore-errors)")
+	BackupCmd.PersistentFlags().StringP("failure-report", "", "", "Write the skipped files to a local path or s3://bucket/key, retry them with \"s3safe retry\"")
+	BackupCmd.PersistentFlags().StringP("format", "", "text", "Output format of the run summary printed on stdout: text (logs only), json or csv")
+	BackupCmd.PersistentFlags().StringP("report-html", "", "", "Write a self-contained HTML report of the run (files, sizes, errors, throughput) to a local path or s3://bucket/key")
+	BackupCmd.PersistentFlags().StringP("max-errors", "", "", "Abort once more files were skipped by --ignore-errors, a number such as 10 or a percentage such as 5%")
+	BackupCmd.PersistentFlags().StringP("checkpoint", "", "", "Write the progress of a folder backup to a local file, an interrupted backup resumes from it")
+	BackupCmd.PersistentFlags().BoolP("restart", "", false, "Discard the checkpoint of an interrupted backup and upload all files again")
+	BackupCmd.PersistentFlags().BoolP("verify", "", false, "Verify the size and checksum of uploaded objects")
+	BackupCmd.PersistentFlags().BoolP("skip-identical", "", false, "Skip files whose content is already stored under the same key, compared by SHA-256 or ETag")
+	BackupCmd.PersistentFlags().BoolP("no-clobber-remote", "", false, "Fail instead of overwriting an existing object, uploads are conditional writes (If-None-Match: *)")
+	BackupCmd.PersistentFlags().StringP("verify-sample", "", "", "Download a random sample of the uploaded files after the backup and compare them, a number such as 20 or a percentage such as 5%")
+	BackupCmd.PersistentFlags().BoolP("delete-source", "", false, "Delete local files once uploaded and verified, requires --verify")
+	BackupCmd.PersistentFlags().StringP("timestamp-format", "", "", "Go time layout of the archive timestamp (default \"2006-01-02_15-04-05\")")
+	BackupCmd.PersistentFlags().StringP("name-template", "", "", "Compressed archive name template, e.g. \"{{ .Base }}-{{ .Host }}-{{ .Timestamp }}.tar.gz\"")
+	BackupCmd.PersistentFlags().StringP("timezone", "", "", "Time zone of the archive timestamp and path templates, e.g. UTC or Europe/Paris (default local time)")
+	BackupCmd.PersistentFlags().StringP("storage-class", "", "", "S3 storage class for uploaded objects (STANDARD, STANDARD_IA, GLACIER, DEEP_ARCHIVE, ...)")
+	BackupCmd.PersistentFlags().StringP("acl", "", "", "Canned ACL for uploaded objects (private, bucket-owner-full-control, ...)")
+	BackupCmd.PersistentFlags().StringArrayP("mirror", "m", nil, "Additional destination, s3://bucket/prefix[?endpoint=...&region=...&credentials=NAME] or file:///path, can be repeated")
+	BackupCmd.PersistentFlags().BoolP("parallel", "", false, "Upload to all destinations in parallel")
+	BackupCmd.PersistentFlags().StringP("object-lock-mode", "", "", "Object Lock retention mode for uploaded objects (GOVERNANCE or COMPLIANCE)")
+	BackupCmd.PersistentFlags().IntP("object-lock-days", "", 0, "Object Lock retention period in days")
+	BackupCmd.PersistentFlags().StringP("object-lock-policy", "", "", "Object Lock retention per run, e.g. daily=35d,weekly=90d,monthly=400d,yearly=2555d (weekly runs on Sunday, monthly on the 1st, yearly on January 1st)")
+	BackupCmd.PersistentFlags().BoolP("legal-hold", "", false, "Enable Object Lock legal hold on uploaded objects")
+	BackupCmd.PersistentFlags().StringP("checksum", "", "", "S3 additional checksum algorithm of uploaded objects (SHA256, CRC32, CRC32C, SHA1, CRC64NVME or NONE), default SHA256 on AWS and none elsewhere")
+	BackupCmd.PersistentFlags().StringP("unsafe-keys", "", "", "Keys with control characters, '#' or '?': keep, encode (percent-encoded, decoded on restore) or reject (default keep)")
+	BackupCmd.PersistentFlags().StringP("content-type", "", "", "Content type for uploaded objects, detected from extension and content by default")
+	BackupCmd.PersistentFlags().StringP("storage-class-rules", "", "", "Per-file storage class rules, first match wins (e.g. \"size>1GB:GLACIER,*.json:STANDARD,age>30d:STANDARD_IA\")")
+	BackupCmd.PersistentFlags().StringP("plugins-dir", "", "", "Directory of executables invoked with a JSON event on stdin before the scan, after each uploaded file and after the backup")
+	BackupCmd.PersistentFlags().IntP("keep-local", "", 0, "Number of compressed archives kept in the backed up directory, older archives are deleted once the backup is uploaded (default 0, keep all)")
+	BackupCmd.PersistentFlags().BoolP("one-file-system", "", false, "Don't descend into directories on other file systems, such as /proc, NFS shares or bind-mounted volumes (ignored on Windows)")
+	BackupCmd.PersistentFlags().BoolP("exclude-caches", "", false, "Skip directories containing a CACHEDIR.TAG file, as tar, borg and restic do")
+	BackupCmd.PersistentFlags().BoolP("exclude-common-caches", "", false, "Skip directories named .cache, node_modules or __pycache__")
+	BackupCmd.PersistentFlags().BoolP("respect-gitignore", "", false, "Skip the files and directories matched by the .gitignore files of the backed up directories")
+	BackupCmd.PersistentFlags().StringP("require", "", "", "Fail unless the bucket has these settings, comma-separated: versioning, object-lock, encryption")
+	BackupCmd.PersistentFlags().IntP("ensure-mpu-cleanup-rule", "", 0, "Add a lifecycle rule aborting incomplete multipart uploads under --dest after N days, unless the bucket has one")
+	BackupCmd.PersistentFlags().BoolP("require-warn", "", false, "Warn instead of failing when the bucket doesn't meet --require")
+	BackupCmd.PersistentFlags().StringP("snapshot", "", "", "Back up from a snapshot of the volume holding --path, removed afterwards: lvm:vg/lv, zfs:pool/dataset, btrfs:/subvolume or vss[:C:] (Windows)")
+	BackupCmd.PersistentFlags().BoolP("include-special", "", false, "Archive FIFOs and device files as tar entries with --compress, they are skipped by default and sockets are always skipped")
+	BackupCmd.PersistentFlags().StringP("layout", "", "", "Layout of the uploads under the destination: flat, or date to upload under YYYY/MM/DD directories (default flat)")
 }
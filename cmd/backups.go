@@ -0,0 +1,53 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var BackupsCmd = &cobra.Command{
+	Use:     "backups ",
+	Short:   "List the restore points of every destination under a prefix with their time and size",
+	Example: utils.BackupsExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Backups(cmd)
+		if err != nil {
+			slog.Error("Backups error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Backups
+	BackupsCmd.PersistentFlags().StringP("dest", "d", "", "S3 destination path of the backups`")
+	BackupsCmd.PersistentFlags().StringP("timezone", "", "", "Time zone of the archive timestamps, e.g. Europe/Paris (default UTC)")
+	BackupsCmd.PersistentFlags().StringP("timestamp-format", "", "", "Go time layout of the archive timestamps, as given to backup (default \"2006-01-02_15-04-05\")")
+	BackupsCmd.PersistentFlags().StringP("format", "", "text", "Output format: text, json or csv")
+}
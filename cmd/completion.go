@@ -0,0 +1,84 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var CompletionCmd = &cobra.Command{
+	Use:                   "completion [bash|zsh|fish|powershell]",
+	Short:                 "Generate the shell completion script",
+	Long:                  utils.CompletionDescription,
+	Example:               utils.CompletionExample,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		if err != nil {
+			slog.Error("Completion error", "error", err)
+			os.Exit(pkg.ExitFailure)
+		}
+	},
+}
+
+// completedFlags are the flags completed with a fixed set of values
+var completedFlags = []string{"provider", "storage-class", "class", "tier", "checksum", "object-lock-mode", "normalize-unicode", "unsafe-keys", "sanitize-names", "format", "layout", "on-unreadable"}
+
+// registerCompletions registers the completion of flag values on every command defining them,
+// once all the commands and their flags are created
+func registerCompletions() {
+	commands := append([]*cobra.Command{rootCmd}, rootCmd.Commands()...)
+	for _, c := range commands {
+		for _, name := range completedFlags {
+			if c.PersistentFlags().Lookup(name) == nil {
+				continue
+			}
+			values := pkg.CompletionValues(name)
+			_ = c.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+		}
+	}
+	_ = rootCmd.RegisterFlagCompletionFunc("job", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		envFile, _ := cmd.Flags().GetString("env-file")
+		return pkg.JobNames(".", envFile), cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = rootCmd.MarkPersistentFlagFilename("env-file")
+	_ = BackupCmd.MarkPersistentFlagDirname("plugins-dir")
+}
@@ -0,0 +1,59 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var LifecycleCmd = &cobra.Command{
+	Use:   "lifecycle ",
+	Short: "Manage the bucket lifecycle rules of backup prefixes",
+}
+
+var LifecycleApplyCmd = &cobra.Command{
+	Use:     "apply ",
+	Short:   "Create or update the lifecycle rule of a backup prefix",
+	Example: utils.LifecycleExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.LifecycleApply(cmd)
+		if err != nil {
+			slog.Error("Lifecycle error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	LifecycleApplyCmd.PersistentFlags().StringP("dest", "d", "", "S3 backup prefix the rule applies to`")
+	LifecycleApplyCmd.PersistentFlags().StringP("transition-glacier", "", "", "Move objects to GLACIER after this age (e.g. 30d, 4w)")
+	LifecycleApplyCmd.PersistentFlags().StringP("transition-deep-archive", "", "", "Move objects to DEEP_ARCHIVE after this age (e.g. 180d)")
+	LifecycleApplyCmd.PersistentFlags().StringP("expire", "", "", "Delete objects after this age (e.g. 365d)")
+	LifecycleApplyCmd.PersistentFlags().BoolP("dry-run", "", false, "Print the rule without changing the bucket")
+	LifecycleCmd.AddCommand(LifecycleApplyCmd)
+}
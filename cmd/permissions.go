@@ -0,0 +1,50 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var CheckPermissionsCmd = &cobra.Command{
+	Use:     "check-permissions ",
+	Short:   "Probe the IAM permissions of the credentials on the bucket with harmless operations",
+	Example: " s3safe check-permissions --path /s3path/backups",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.CheckPermissions(cmd)
+		if err != nil {
+			slog.Error("Check permissions error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Check permissions
+	CheckPermissionsCmd.PersistentFlags().StringP("path", "p", "", "S3 prefix the sentinel object is written to`")
+}
@@ -0,0 +1,54 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var ReplicateCmd = &cobra.Command{
+	Use:     "replicate ",
+	Short:   "Incrementally copy new and changed objects between buckets",
+	Example: utils.ReplicateExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Replicate(cmd)
+		if err != nil {
+			slog.Error("Replicate error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Replicate
+	ReplicateCmd.PersistentFlags().StringP("from", "", "", "Source location, s3://bucket/prefix")
+	ReplicateCmd.PersistentFlags().StringP("to", "", "", "Destination location, s3://bucket/prefix[?endpoint=...&region=...&credentials=NAME]")
+	ReplicateCmd.PersistentFlags().BoolP("dry-run", "", false, "Print the objects that would be copied without copying them")
+	ReplicateCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Ignore errors when copying objects")
+}
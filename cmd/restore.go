@@ -40,7 +40,7 @@ var RestoreCmd = &cobra.Command{
 		err := pkg.Restore(cmd)
 		if err != nil {
 			slog.Error("Restore error", "error", err)
-			os.Exit(1)
+			os.Exit(pkg.ExitCode(err))
 		}
 	},
 }
@@ -50,8 +50,26 @@ func init() {
 	RestoreCmd.PersistentFlags().StringP("path", "p", "", "S3 Storage path`")
 	RestoreCmd.PersistentFlags().StringP("dest", "d", "", "Destination path`")
 	RestoreCmd.PersistentFlags().StringP("file", "f", "", "File to restore`")
-	RestoreCmd.PersistentFlags().BoolP("decompress", "D", false, "Enable decompression, only for compressed file, when using --file flag")
+	RestoreCmd.PersistentFlags().BoolP("decompress", "D", false, "Extract downloaded tar.gz, tar.zst, tar.xz and zip archives")
 	RestoreCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Ignore errors when restoring files")
+	RestoreCmd.PersistentFlags().StringP("failure-report", "", "", "Write the failed files to a local path or s3://bucket/key, retry them with \"s3safe retry\"")
+	RestoreCmd.PersistentFlags().StringP("report-html", "", "", "Write a self-contained HTML report of the run (files, sizes, errors, throughput) to a local path or s3://bucket/key")
+	RestoreCmd.PersistentFlags().StringP("max-errors", "", "", "Abort once more files failed with --ignore-errors, a number such as 10 or a percentage such as 5%")
 	RestoreCmd.PersistentFlags().BoolP("force", "", false, "Force restore to destination path, overwrite existing files")
+	RestoreCmd.PersistentFlags().BoolP("latest", "", false, "Restore the newest compressed backup referenced by latest.json in --path")
+	RestoreCmd.PersistentFlags().StringP("match", "", "", "Restore only files whose name matches the pattern, e.g. \"db-*.tar.gz\"")
+	RestoreCmd.PersistentFlags().BoolP("newest", "", false, "Restore only the most recent file, combined with --match")
+	RestoreCmd.PersistentFlags().BoolP("flatten", "", false, "Restore every file into --dest by its name, without the directories of its key")
+	RestoreCmd.PersistentFlags().StringP("as-of", "", "", "Restore only the newest backup made at or before a time, e.g. \"2025-06-01 03:00\", from the timestamp of archive names or the modification time")
+	RestoreCmd.PersistentFlags().StringP("timezone", "", "", "Time zone of --as-of and of the archive timestamps, e.g. Europe/Paris (default UTC)")
+	RestoreCmd.PersistentFlags().BoolP("list", "l", false, "Print the files that would be restored and their local paths without downloading")
+	RestoreCmd.PersistentFlags().StringP("format", "", "text", "Output format of the --list listing and the run summary: text, json or csv")
+	RestoreCmd.PersistentFlags().BoolP("restart", "", false, "Discard the journal of an interrupted restore and restore all files again")
+	RestoreCmd.PersistentFlags().BoolP("preserve-permissions", "", false, "Restore the file mode, and the owner when running as root, stored by non-archive backups")
+	RestoreCmd.PersistentFlags().StringP("inventory", "", "", "S3 Inventory manifest (s3://bucket/path/manifest.json) listing the objects to restore instead of the bucket, CSV format")
+	RestoreCmd.PersistentFlags().StringP("sanitize-names", "", "", "Keys that are not valid Windows file names: replace, skip or fail (default replace)")
+	RestoreCmd.PersistentFlags().BoolP("skip-verify", "", false, "Skip the size and checksum verification of downloaded files")
+	RestoreCmd.PersistentFlags().BoolP("skip-space-check", "", false, "Skip the free disk space check before downloading")
+	RestoreCmd.PersistentFlags().StringP("version-id", "", "", "Restore a specific object version, only with --file flag on versioned buckets")
 
 }
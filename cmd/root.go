@@ -25,6 +25,7 @@
 package cmd
 
 import (
+	"github.com/jkaninda/s3safe/pkg"
 	"github.com/jkaninda/s3safe/utils"
 	"github.com/spf13/cobra"
 	"os"
@@ -37,23 +38,68 @@ var rootCmd = &cobra.Command{
 	Long:    utils.AppDescription,
 	Example: utils.AppExample,
 	Version: utils.Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return pkg.SetupKubernetes(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	registerCompletions()
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		// Command handlers exit on their own, errors left are invalid commands or flags
+		os.Exit(pkg.ExitConfig)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringP("exclude", "e", "", "Exclude files/directories (comma-separated patterns)")
 	rootCmd.PersistentFlags().BoolP("recursive", "r", false, "Recursively backup or restore files")
+	rootCmd.PersistentFlags().IntP("max-depth", "", 0, "Backup or restore only the files at most N directory levels below the path, with --recursive (default 0, unlimited)")
 	rootCmd.PersistentFlags().StringP("env-file", "", "", "Custom environment file")
+	rootCmd.PersistentFlags().BoolP("k8s", "", false, "Kubernetes mode: load /etc/s3safe ConfigMap files, read flags from S3SAFE_<FLAG> variables and s3safe/<flag> pod annotations, log JSON")
 	rootCmd.PersistentFlags().StringP("bucket", "b", "", "S3 bucket name")
+	rootCmd.PersistentFlags().StringP("proxy", "", "", "Proxy URL for S3 requests (http, https or socks5), defaults to HTTP_PROXY/HTTPS_PROXY")
+	rootCmd.PersistentFlags().BoolP("accelerate", "", false, "Use the S3 Transfer Acceleration endpoint, the bucket must have acceleration enabled")
+	rootCmd.PersistentFlags().StringP("provider", "", "", "S3-compatible provider preset: r2, minio, ceph, wasabi, digitalocean, scaleway")
+	rootCmd.PersistentFlags().StringP("job", "", "", "Job name, available as {{ .Job }} in path templates")
+	rootCmd.PersistentFlags().StringP("normalize-unicode", "", "", "Unicode normalization of keys and restored file names: nfc, nfd or none (default none)")
+	rootCmd.PersistentFlags().StringP("part-size", "", "", "S3 multipart part size, e.g. 16MiB (default 5MiB or the provider preset)")
+	rootCmd.PersistentFlags().IntP("list-shards", "", 0, "List the prefixes of recursive S3 listings with up to N requests at once, for prefixes with millions of keys (default 0, sequential)")
+	rootCmd.PersistentFlags().IntP("concurrency", "", 0, "Number of parts transferred at once per file (default 5)")
+	rootCmd.PersistentFlags().StringP("max-memory", "", "", "Bound the part buffers of each S3 transfer, e.g. 256MiB, the concurrency and part size are lowered to fit")
+	rootCmd.PersistentFlags().DurationP("connect-timeout", "", 0, "Timeout of the connection and TLS handshake with the endpoint, e.g. 5s (default 30s)")
+	rootCmd.PersistentFlags().DurationP("response-header-timeout", "", 0, "Timeout waiting for the response headers of a request, e.g. 30s (default unbounded)")
+	rootCmd.PersistentFlags().IntP("max-idle-conns", "", 0, "Idle connections kept open per host, raise it for many small objects (default 2)")
+	rootCmd.PersistentFlags().BoolP("disable-keep-alives", "", false, "Open a new connection for every request")
+	rootCmd.PersistentFlags().BoolP("disable-http2", "", false, "Use HTTP/1.1 only, for gateways that misbehave with HTTP/2")
+	rootCmd.PersistentFlags().BoolP("debug-aws", "", false, "Log AWS SDK requests and responses, credentials are redacted")
+	rootCmd.PersistentFlags().StringP("bandwidth-schedule", "", "", "Transfer rates by time of day, e.g. \"00:00-06:00=unlimited,*=5MB\", a rate is a size per second, unlimited or pause")
+	rootCmd.PersistentFlags().BoolP("fips", "", false, "Use the AWS S3 FIPS endpoints and FIPS-approved crypto only, requires GODEBUG=fips140=on")
+	rootCmd.PersistentFlags().BoolP("debug-requests", "", false, "Log every S3 API call at debug level: operation, key, duration, status and request ID, without credentials")
+	rootCmd.PersistentFlags().StringP("audit-log", "", "", "Append backup, restore, undelete and purge-versions runs to a monthly NDJSON audit log under a prefix of the bucket or an s3://bucket/prefix URL")
+	rootCmd.PersistentFlags().BoolP("selinux", "", false, "Store the SELinux context of backed up files in the archive or the object metadata, and restore it (Linux)")
+	rootCmd.PersistentFlags().BoolP("obfuscate-keys", "", false, "Upload objects under a keyed hash of their key and restore them from the key encrypted in their metadata, the secret is read from S3SAFE_KEY_SECRET")
 	rootCmd.AddCommand(BackupCmd)
 	rootCmd.AddCommand(RestoreCmd)
 	rootCmd.AddCommand(ValidateCmd)
+	rootCmd.AddCommand(DoctorCmd)
+	rootCmd.AddCommand(CheckPermissionsCmd)
+	rootCmd.AddCommand(ThawCmd)
+	rootCmd.AddCommand(ThawStatusCmd)
+	rootCmd.AddCommand(VersionsCmd)
+	rootCmd.AddCommand(UndeleteCmd)
+	rootCmd.AddCommand(PurgeVersionsCmd)
+	rootCmd.AddCommand(ReplicateCmd)
+	rootCmd.AddCommand(RetryCmd)
+	rootCmd.AddCommand(CheckCmd)
+	rootCmd.AddCommand(CostCmd)
+	rootCmd.AddCommand(UsageCmd)
+	rootCmd.AddCommand(BackupsCmd)
+	rootCmd.AddCommand(SearchCmd)
+	rootCmd.AddCommand(LifecycleCmd)
+	rootCmd.AddCommand(CompletionCmd)
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 }
@@ -0,0 +1,56 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var SearchCmd = &cobra.Command{
+	Use:     "search PATTERN",
+	Short:   "Find the backups holding a copy of a file, the newest first",
+	Example: utils.SearchExample,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Search(cmd, args[0])
+		if err != nil {
+			slog.Error("Search error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Search
+	SearchCmd.PersistentFlags().StringP("path", "p", "", "S3 Storage path of the backups`")
+	SearchCmd.PersistentFlags().StringP("before", "", "", "Search only the backups made before a time, e.g. \"2025-05-01\" or RFC 3339")
+	SearchCmd.PersistentFlags().BoolP("archives", "", false, "Also read the entries of compressed backups, every archive is downloaded")
+	SearchCmd.PersistentFlags().StringP("timezone", "", "", "Time zone of the archive timestamps and of --before, e.g. Europe/Paris (default UTC)")
+	SearchCmd.PersistentFlags().StringP("timestamp-format", "", "", "Go time layout of the archive timestamps, as given to backup (default \"2006-01-02_15-04-05\")")
+	SearchCmd.PersistentFlags().StringP("format", "", "text", "Output format: text, json or csv")
+}
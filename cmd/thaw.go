@@ -0,0 +1,76 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+	"time"
+)
+
+var ThawCmd = &cobra.Command{
+	Use:     "thaw ",
+	Short:   "Request retrieval of objects archived in Glacier or Deep Archive",
+	Example: utils.ThawExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Thaw(cmd)
+		if err != nil {
+			slog.Error("Thaw error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+var ThawStatusCmd = &cobra.Command{
+	Use:     "thaw-status ",
+	Short:   "Show the retrieval status of archived objects",
+	Example: utils.ThawExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.ThawStatus(cmd)
+		if err != nil {
+			slog.Error("Thaw status error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Thaw
+	ThawCmd.PersistentFlags().StringP("path", "p", "", "S3 Storage path`")
+	ThawCmd.PersistentFlags().StringP("file", "f", "", "Archived file to thaw`")
+	ThawCmd.PersistentFlags().StringP("tier", "", "Standard", "Retrieval tier (Standard, Bulk, Expedited)")
+	ThawCmd.PersistentFlags().IntP("days", "", 7, "Number of days the restored copy stays available")
+	ThawCmd.PersistentFlags().BoolP("wait", "w", false, "Wait until all objects are retrievable")
+	ThawCmd.PersistentFlags().DurationP("poll-interval", "", 15*time.Minute, "Interval between status checks when waiting")
+	ThawCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Ignore errors when requesting restores")
+	// Thaw status
+	ThawStatusCmd.PersistentFlags().StringP("path", "p", "", "S3 Storage path`")
+	ThawStatusCmd.PersistentFlags().StringP("file", "f", "", "Archived file`")
+	ThawStatusCmd.PersistentFlags().BoolP("wait", "w", false, "Wait until all objects are retrievable")
+	ThawStatusCmd.PersistentFlags().DurationP("poll-interval", "", 15*time.Minute, "Interval between status checks when waiting")
+}
@@ -39,7 +39,7 @@ var ValidateCmd = &cobra.Command{
 		err := pkg.Validate(cmd)
 		if err != nil {
 			slog.Error("Validate error", "error", err)
-			os.Exit(1)
+			os.Exit(pkg.ExitCode(err))
 		}
 	},
 }
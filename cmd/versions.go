@@ -0,0 +1,94 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package cmd
+
+import (
+	"github.com/jkaninda/s3safe/pkg"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+)
+
+var VersionsCmd = &cobra.Command{
+	Use:     "versions ",
+	Short:   "List object versions and delete markers of a versioned bucket",
+	Example: " s3safe versions --path /s3path/backups/backup.tar.gz",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Versions(cmd)
+		if err != nil {
+			slog.Error("Versions error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+var UndeleteCmd = &cobra.Command{
+	Use:     "undelete ",
+	Short:   "Remove delete markers to recover deleted objects of a versioned bucket",
+	Example: " s3safe undelete --path /s3path/backups --since 24h",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.Undelete(cmd)
+		if err != nil {
+			slog.Error("Undelete error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+var PurgeVersionsCmd = &cobra.Command{
+	Use:     "purge-versions ",
+	Short:   "Permanently delete old noncurrent object versions of a versioned bucket",
+	Example: " s3safe purge-versions --path /s3path/backups --older-than 90d",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pkg.PurgeVersions(cmd)
+		if err != nil {
+			slog.Error("Purge versions error", "error", err)
+			os.Exit(pkg.ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	// Versions
+	VersionsCmd.PersistentFlags().StringP("path", "p", "", "S3 key or prefix`")
+	VersionsCmd.PersistentFlags().StringP("format", "", "text", "Output format: text, json or csv")
+}
+
+func init() {
+	// Undelete
+	UndeleteCmd.PersistentFlags().StringP("path", "p", "", "S3 prefix`")
+	UndeleteCmd.PersistentFlags().StringP("since", "", "", "Only recover objects deleted after this time (RFC3339, \"2006-01-02 15:04\" or a duration such as 24h, 7d)")
+	UndeleteCmd.PersistentFlags().BoolP("dry-run", "", false, "Print the objects that would be recovered without changing anything")
+	UndeleteCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Ignore errors when removing delete markers")
+}
+
+func init() {
+	// Purge versions
+	PurgeVersionsCmd.PersistentFlags().StringP("path", "p", "", "S3 prefix`")
+	PurgeVersionsCmd.PersistentFlags().StringP("older-than", "", "", "Delete versions noncurrent for longer than this duration (e.g. 90d, 12w, 720h)")
+	PurgeVersionsCmd.PersistentFlags().BoolP("dry-run", "", false, "Print the versions that would be deleted without deleting them")
+	PurgeVersionsCmd.PersistentFlags().StringP("prune-report", "", "", "Write the deleted and retained versions to a local path, an s3://bucket/key URL or a file name under --path, {{ .Timestamp }} is replaced by the run time")
+	PurgeVersionsCmd.PersistentFlags().BoolP("ignore-errors", "i", false, "Ignore errors when deleting versions")
+}
@@ -0,0 +1,185 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compression formats of the archives restored with --decompress
+const (
+	formatGzip = "gzip"
+	formatZstd = "zstd"
+	formatXz   = "xz"
+	formatZip  = "zip"
+)
+
+// archiveMagics are the leading bytes of the compression formats
+var archiveMagics = []struct {
+	format string
+	magic  []byte
+}{
+	{formatGzip, []byte{0x1f, 0x8b}},
+	{formatZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{formatXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{formatZip, []byte("PK\x03\x04")},
+	// Empty zip archive
+	{formatZip, []byte("PK\x05\x06")},
+}
+
+// archiveFormat returns the compression format of a file from its first bytes, empty when it is not compressed
+func archiveFormat(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			slog.Error("error closing file", "error", err)
+		}
+	}(file)
+
+	buf := make([]byte, 8)
+	n, _ := io.ReadFull(file, buf)
+	for _, m := range archiveMagics {
+		if bytes.HasPrefix(buf[:n], m.magic) {
+			return m.format
+		}
+	}
+	return ""
+}
+
+// isArchive reports whether a restored file is extracted by --decompress. Zip files are only
+// extracted with the .zip extension, documents such as .docx or .jar files are zip files too.
+func isArchive(filePath string) bool {
+	switch archiveFormat(filePath) {
+	case "":
+		return false
+	case formatZip:
+		return strings.EqualFold(filepath.Ext(filePath), ".zip")
+	}
+	return true
+}
+
+// tarStream returns the decompressed stream of a compressed tar archive, closing it
+// returns the error of the decompressor
+func tarStream(format string, r io.Reader) (io.Reader, func() error, error) {
+	switch format {
+	case formatGzip:
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gzr, gzr.Close, nil
+	case formatZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() error {
+			zr.Close()
+			return nil
+		}, nil
+	case formatXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xr, func() error { return nil }, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported compression format %q", format)
+}
+
+// extractZip extracts a zip archive into destDir, entries outside of destDir are rejected
+func extractZip(sourceFile, destDir string) error {
+	zr, err := zip.OpenReader(sourceFile)
+	if err != nil {
+		return fmt.Errorf("could not open zip archive: %w", err)
+	}
+	defer func(zr *zip.ReadCloser) {
+		err := zr.Close()
+		if err != nil {
+			slog.Error("error closing zip archive", "error", err)
+		}
+	}(zr)
+
+	for _, f := range zr.File {
+		name := f.Name
+		if windowsPaths {
+			name, _ = sanitizeWindowsPath(name)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(strings.TrimSuffix(name, "/"))) {
+			return fmt.Errorf("invalid zip entry %q: outside of the destination", f.Name)
+		}
+		target := longPath(keyToLocal(destDir, name))
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("could not create directory: %w", err)
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return fmt.Errorf("unsupported type: %s in %s", f.Mode().Type(), f.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("could not create directory: %w", err)
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile writes a zip entry to target
+func extractZipFile(f *zip.File, target string) error {
+	in, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", f.Name, err)
+	}
+	defer func(in io.ReadCloser) {
+		_ = in.Close()
+	}(in)
+	outFile, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	if _, err := io.Copy(outFile, in); err != nil {
+		_ = outFile.Close()
+		return fmt.Errorf("could not write to file: %w", err)
+	}
+	return outFile.Close()
+}
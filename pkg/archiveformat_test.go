@@ -0,0 +1,202 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testTar returns a tar archive of dir/a.txt
+func testTar(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("hello")
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/a.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testZip returns a zip archive of the entries
+func testZip(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(name, "/") {
+			_, _ = w.Write([]byte("hello"))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// compressWith compresses data with the writer of a format such as xz
+func compressWith(t *testing.T, format string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch format {
+	case formatZstd:
+		w, err = zstd.NewWriter(&buf)
+	case formatXz:
+		w, err = xz.NewWriter(&buf)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFormat(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content []byte
+		format  string
+		archive bool
+	}{
+		{"a.tar.gz", []byte{0x1f, 0x8b, 0x08, 0}, formatGzip, true},
+		{"a.tar.zst", []byte{0x28, 0xb5, 0x2f, 0xfd, 0}, formatZstd, true},
+		{"a.tar.xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0, 0}, formatXz, true},
+		{"a.zip", []byte("PK\x03\x04\x14\x00"), formatZip, true},
+		{"a.docx", []byte("PK\x03\x04\x14\x00"), formatZip, false},
+		{"a.txt", []byte("hello"), "", false},
+		{"empty", nil, "", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := archiveFormat(path); got != tt.format {
+			t.Errorf("archiveFormat(%s) = %q, want %q", tt.name, got, tt.format)
+		}
+		if got := isArchive(path); got != tt.archive {
+			t.Errorf("isArchive(%s) = %v, want %v", tt.name, got, tt.archive)
+		}
+	}
+}
+
+func TestDecompressFormats(t *testing.T) {
+	archive := testTar(t)
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write(archive)
+	_ = gw.Close()
+
+	tests := []struct {
+		name    string
+		content func(t *testing.T) []byte
+	}{
+		{"backup.tar.gz", func(*testing.T) []byte { return gz.Bytes() }},
+		{"backup.tar.zst", func(t *testing.T) []byte { return compressWith(t, formatZstd, archive) }},
+		{"backup.tar.xz", func(t *testing.T) []byte { return compressWith(t, formatXz, archive) }},
+		{"backup.zip", func(t *testing.T) []byte { return testZip(t, "dir/", "dir/a.txt") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(source, tt.content(t), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			dest := t.TempDir()
+			if err := decompressDirectory(source, dest, false); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(dest, "dir", "a.txt"))
+			if err != nil || string(got) != "hello" {
+				t.Errorf("Expected dir/a.txt to be extracted, got %q: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestDecompressCorruptedXz(t *testing.T) {
+	compressed := compressWith(t, formatXz, testTar(t))
+	source := filepath.Join(t.TempDir(), "backup.tar.xz")
+	if err := os.WriteFile(source, compressed[:len(compressed)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := decompressDirectory(source, t.TempDir(), false); err == nil {
+		t.Error("Expected a truncated archive to fail")
+	}
+
+	// The archive is decoded without the xz command
+	t.Setenv("PATH", "")
+	if err := os.WriteFile(source, compressed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := decompressDirectory(source, t.TempDir(), false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestExtractZipOutsideDestination(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "evil.zip")
+	if err := os.WriteFile(source, testZip(t, "../evil.txt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	if err := decompressDirectory(source, dest, false); err == nil {
+		t.Error("Expected an entry outside of the destination to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no file outside of the destination")
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAuditKeys bounds the keys recorded per audit entry, the entry is marked truncated beyond
+	maxAuditKeys = 1000
+	// maxAuditAttempts is the number of conditional writes tried when other runs append concurrently
+	maxAuditAttempts = 10
+)
+
+// Operations recorded in the audit log only
+const (
+	operationUndelete      = "undelete"
+	operationPurgeVersions = "purge-versions"
+)
+
+// Results of audit entries
+const (
+	auditSuccess = "success"
+	auditPartial = "partial"
+	auditFailure = "failure"
+)
+
+// AuditEntry is a line of the audit log
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// User and Hostname identify who ran the operation, AccessKeyID the credentials it used
+	User        string `json:"user,omitempty"`
+	Hostname    string `json:"hostname,omitempty"`
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Dest        string `json:"dest,omitempty"`
+	Files       int    `json:"files"`
+	Bytes       int64  `json:"bytes"`
+	// Keys holds the uploaded, downloaded or deleted object keys, at most maxAuditKeys
+	Keys          []string `json:"keys"`
+	KeysTruncated bool     `json:"keys_truncated,omitempty"`
+	Result        string   `json:"result"`
+	Error         string   `json:"error,omitempty"`
+	ExitCode      int      `json:"exit_code"`
+	DurationMs    int64    `json:"duration_ms"`
+}
+
+// auditLog appends the runs of a manager to a monthly NDJSON object.
+// A nil auditLog records nothing, it is nil unless --audit-log is set.
+type auditLog struct {
+	storage *S3Storage
+	prefix  string
+	keys    []string
+	total   int
+}
+
+// newAuditLog returns the audit log of --audit-log, a prefix in the configured bucket or an s3:// URL
+func newAuditLog(ctx context.Context, config *Config) (*auditLog, error) {
+	if config.AuditLog == "" {
+		return nil, nil
+	}
+	storageConfig, prefix := config, config.AuditLog
+	if strings.HasPrefix(config.AuditLog, "s3://") {
+		remote, err := config.ParseRemote(config.AuditLog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit log: %w", err)
+		}
+		storageConfig, prefix = remote.Config, remote.Prefix
+	}
+	storage, err := storageConfig.NewS3Storage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log storage: %w", err)
+	}
+	return &auditLog{storage: storage, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// reset clears the keys of a previous run
+func (a *auditLog) reset() {
+	if a == nil {
+		return
+	}
+	a.keys = nil
+	a.total = 0
+}
+
+// add records an object key of the current run
+func (a *auditLog) add(key string) {
+	if a == nil {
+		return
+	}
+	a.total++
+	if len(a.keys) < maxAuditKeys {
+		a.keys = append(a.keys, key)
+	}
+}
+
+// key returns the object key of the month of t
+func (a *auditLog) key(t time.Time) string {
+	return objectKey(a.prefix, t.UTC().Format("2006-01")+".ndjson")
+}
+
+// write appends the entry of a run ending with err, the error of the append is returned
+func (a *auditLog) write(ctx context.Context, config *Config, entry AuditEntry, err error) error {
+	if a == nil {
+		return nil
+	}
+	entry.Time = time.Now().UTC()
+	if u, uerr := user.Current(); uerr == nil {
+		entry.User = u.Username
+	}
+	entry.Hostname, _ = os.Hostname()
+	entry.AccessKeyID = config.KeyID
+	entry.Bucket = config.Bucket
+	entry.Keys = a.keys
+	if entry.Keys == nil {
+		entry.Keys = []string{}
+	}
+	entry.KeysTruncated = a.total > len(a.keys)
+	entry.ExitCode = ExitCode(err)
+	switch entry.ExitCode {
+	case ExitOK:
+		entry.Result = auditSuccess
+	case ExitPartial:
+		entry.Result = auditPartial
+	default:
+		entry.Result = auditFailure
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	line, mErr := json.Marshal(entry)
+	if mErr != nil {
+		return fmt.Errorf("failed to write audit log: %w", mErr)
+	}
+	key := a.key(entry.Time)
+	if err := a.storage.appendObject(ctx, key, append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", key, err)
+	}
+	loggerOrDefault(config.logger).Debug("Audit log written", "key", key, "operation", entry.Operation)
+	return nil
+}
+
+// appendObject appends data to an object. The object is rewritten with a conditional write,
+// so a concurrent append makes the write fail and it is retried with the new content.
+func (s S3Storage) appendObject(ctx context.Context, key string, data []byte) error {
+	for attempt := 1; ; attempt++ {
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(key),
+			ContentType: aws.String("application/x-ndjson"),
+			IfNoneMatch: aws.String("*"),
+		}
+		content := data
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		switch {
+		case err == nil:
+			existing, readErr := io.ReadAll(out.Body)
+			_ = out.Body.Close()
+			if readErr != nil {
+				return readErr
+			}
+			content = append(existing, data...)
+			input.IfNoneMatch = nil
+			input.IfMatch = out.ETag
+		case !isStatus(err, http.StatusNotFound):
+			return err
+		}
+		input.Body = bytes.NewReader(content)
+		input.ContentLength = aws.Int64(int64(len(content)))
+
+		_, err = s.client.PutObject(ctx, input)
+		if err == nil || attempt == maxAuditAttempts {
+			return err
+		}
+		if !isStatus(err, http.StatusPreconditionFailed) && !isStatus(err, http.StatusConflict) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		}
+	}
+}
+
+// isStatus reports whether err is an S3 response with the HTTP status code
+func isStatus(err error, code int) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == code
+}
+
+// writeAudit appends the backup to the audit log, the error of the audit log is joined with err
+func (bm *BackupManager) writeAudit(ctx context.Context, operation string, start time.Time, err error) error {
+	entry := AuditEntry{
+		Operation:  operation,
+		Path:       bm.config.Path,
+		Dest:       bm.config.Dest,
+		Files:      bm.result.Files,
+		Bytes:      bm.result.Bytes,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if auditErr := bm.audit.write(ctx, bm.config, entry, err); auditErr != nil {
+		return errors.Join(err, auditErr)
+	}
+	return err
+}
+
+// writeAudit appends the restore to the audit log, the error of the audit log is joined with err
+func (rm *RestoreManager) writeAudit(ctx context.Context, operation string, start time.Time, err error) error {
+	entry := AuditEntry{
+		Operation:  operation,
+		Path:       rm.location,
+		Dest:       rm.config.Dest,
+		Files:      rm.result.Files,
+		Bytes:      rm.result.Bytes,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if auditErr := rm.audit.write(ctx, rm.config, entry, err); auditErr != nil {
+		return errors.Join(err, auditErr)
+	}
+	return err
+}
+
+// writeAudit appends the deletions of undelete and purge-versions to the audit log, dry runs are not recorded
+func (vm *VersionManager) writeAudit(ctx context.Context, operation string, start time.Time, files int, size int64, err error) error {
+	if vm.config.DryRun {
+		return err
+	}
+	entry := AuditEntry{
+		Operation:  operation,
+		Path:       vm.config.Path,
+		Files:      files,
+		Bytes:      size,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if auditErr := vm.audit.write(ctx, vm.config, entry, err); auditErr != nil {
+		return errors.Join(err, auditErr)
+	}
+	return err
+}
@@ -0,0 +1,225 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// conditionalServer is a fake S3 endpoint honoring If-Match and If-None-Match on PUT,
+// before is called with the key of each conditional PUT
+func conditionalServer(t *testing.T, before func(key string, objects map[string][]byte)) (*httptest.Server, func(key string) []byte) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	etag := func(body []byte) string {
+		return fmt.Sprintf(`"%x"`, md5.Sum(body))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			conditional := r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != ""
+			if conditional && before != nil {
+				before(key, objects)
+			}
+			current, exists := objects[key]
+			if match := r.Header.Get("If-Match"); match != "" && (!exists || match != etag(current)) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			if r.Header.Get("If-None-Match") == "*" && exists {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			objects[key] = body
+			w.Header().Set("ETag", etag(body))
+			return
+		}
+		body, ok := objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag(body))
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	return server, func(key string) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return objects[key]
+	}
+}
+
+// auditEntries decodes the lines of an audit log object
+func auditEntries(t *testing.T, data []byte) []AuditEntry {
+	t.Helper()
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLogAppend(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	conflicts := 0
+	server, object := conditionalServer(t, func(key string, objects map[string][]byte) {
+		// Another run appends before the second write
+		if len(objects[key]) > 0 && conflicts == 0 {
+			conflicts++
+			objects[key] = append(objects[key], []byte(`{"operation":"restore"}`+"\n")...)
+		}
+	})
+	defer server.Close()
+
+	cfg := testConfig(t.TempDir(), server.URL)
+	cfg.AuditLog = "/audit/"
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := &auditLog{storage: storage, prefix: "audit"}
+	audit.add("backups/a.txt")
+	if err := audit.write(context.Background(), &cfg, AuditEntry{Operation: operationBackup, Files: 1}, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	audit.reset()
+	if err := audit.write(context.Background(), &cfg, AuditEntry{Operation: operationBackup}, withExitCode(ExitPartial, fmt.Errorf("1 file skipped"))); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	key := audit.key(time.Now())
+	if want := "audit/" + time.Now().UTC().Format("2006-01") + ".ndjson"; key != want {
+		t.Errorf("key = %q, want %q", key, want)
+	}
+	entries := auditEntries(t, object(key))
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3 including the concurrent append", len(entries))
+	}
+	first, last := entries[0], entries[2]
+	if first.Result != auditSuccess || !slices.Equal(first.Keys, []string{"backups/a.txt"}) || first.Bucket != "bucket" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if entries[1].Operation != operationRestore {
+		t.Errorf("concurrent entry = %+v", entries[1])
+	}
+	if last.Result != auditPartial || last.ExitCode != ExitPartial || last.Error != "1 file skipped" || len(last.Keys) != 0 {
+		t.Errorf("last entry = %+v", last)
+	}
+}
+
+func TestAuditLogKeysTruncated(t *testing.T) {
+	audit := &auditLog{}
+	for i := range maxAuditKeys + 5 {
+		audit.add(fmt.Sprintf("key-%d", i))
+	}
+	if len(audit.keys) != maxAuditKeys || audit.total != maxAuditKeys+5 {
+		t.Errorf("keys = %d, total = %d", len(audit.keys), audit.total)
+	}
+
+	var disabled *auditLog
+	disabled.add("key")
+	disabled.reset()
+	if err := disabled.write(context.Background(), &Config{}, AuditEntry{}, nil); err != nil {
+		t.Errorf("a nil audit log must not write: %v", err)
+	}
+}
+
+func TestBackupAuditLog(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, object := conditionalServer(t, nil)
+	defer server.Close()
+
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("content of "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.AuditLog = "audit"
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	entries := auditEntries(t, object(bm.audit.key(time.Now())))
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Operation != operationBackup || entry.Result != auditSuccess || entry.Files != 2 || entry.Path != src {
+		t.Errorf("entry = %+v", entry)
+	}
+	if want := []string{"backups/a.txt", "backups/b.txt"}; !slices.Equal(entry.Keys, want) {
+		t.Errorf("keys = %v, want %v", entry.Keys, want)
+	}
+	if entry.Hostname == "" || entry.Time.IsZero() {
+		t.Errorf("entry does not identify the run: %+v", entry)
+	}
+}
+
+func TestValidateAuditLog(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	cfg := testConfig(t.TempDir(), "http://127.0.0.1:1")
+	cfg.AuditLog = "s3://"
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); err == nil || !strings.Contains(err.Error(), "audit log") {
+		t.Errorf("expected an invalid audit log error, got %v", err)
+	}
+	cfg.AuditLog = "s3://audit-bucket/s3safe"
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.audit.storage.bucket != "audit-bucket" || bm.audit.prefix != "s3safe" {
+		t.Errorf("audit log = %s/%s, want audit-bucket/s3safe", bm.audit.storage.bucket, bm.audit.prefix)
+	}
+}
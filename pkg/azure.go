@@ -0,0 +1,429 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/jkaninda/s3safe/utils"
+	"io"
+	"iter"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AzureRemote is an Azure Blob Storage location given as azblob://account/container/prefix.
+// Authentication uses a SAS token, then an account key, then the default Azure credential chain
+// (environment, workload identity, managed identity, Azure CLI). Query parameters:
+// endpoint to override the service URL, and credentials=NAME to read NAME_SAS_TOKEN or NAME_ACCOUNT_KEY.
+type AzureRemote struct {
+	Account    string
+	Container  string
+	Prefix     string
+	Endpoint   string
+	SASToken   string
+	AccountKey string
+}
+
+// AzureStorage stores backups in an Azure Blob Storage container
+type AzureStorage struct {
+	container string
+	client    *azblob.Client
+	logger    *slog.Logger
+}
+
+// isAzureRemote reports whether a location is an azblob:// URL
+func isAzureRemote(location string) bool {
+	return strings.HasPrefix(location, "azblob://")
+}
+
+// parseAzureRemote parses an azblob:// URL
+func parseAzureRemote(raw string) (*AzureRemote, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote %q: %w", raw, err)
+	}
+	containerName, prefix, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if u.Scheme != "azblob" || u.Host == "" || containerName == "" {
+		return nil, fmt.Errorf("invalid remote %q, expected azblob://account/container/prefix", raw)
+	}
+
+	remote := &AzureRemote{
+		Account:    u.Host,
+		Container:  containerName,
+		Prefix:     prefix,
+		Endpoint:   fmt.Sprintf("https://%s.blob.core.windows.net/", u.Host),
+		SASToken:   utils.Env(utils.AzureSASTokenEnv),
+		AccountKey: utils.Env(utils.AzureAccountKeyEnv),
+	}
+	query := u.Query()
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		remote.Endpoint = strings.TrimSuffix(endpoint, "/") + "/"
+	}
+	if name := query.Get("credentials"); name != "" {
+		name = strings.ToUpper(name)
+		remote.SASToken = utils.Env(name + "_SAS_TOKEN")
+		remote.AccountKey = utils.Env(name + "_ACCOUNT_KEY")
+		if remote.SASToken == "" && remote.AccountKey == "" {
+			return nil, fmt.Errorf("credentials %s not found, set %s_SAS_TOKEN or %s_ACCOUNT_KEY env variable", name, name, name)
+		}
+	}
+	remote.SASToken = strings.TrimPrefix(remote.SASToken, "?")
+	return remote, nil
+}
+
+// String returns the remote as an azblob:// URL without credentials
+func (r *AzureRemote) String() string {
+	return fmt.Sprintf("azblob://%s/%s/%s", r.Account, r.Container, r.Prefix)
+}
+
+// NewAzureStorage creates an AzureStorage for the remote container
+func (c *Config) NewAzureStorage(remote *AzureRemote) (*AzureStorage, error) {
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := c.transferLimiter()
+	if err != nil {
+		return nil, err
+	}
+	options := &azblob.ClientOptions{ClientOptions: azcore.ClientOptions{Transport: httpClient}}
+	if limiter != nil {
+		options.PerCallPolicies = []policy.Policy{bandwidthPausePolicy{limiter: limiter}}
+	}
+
+	var client *azblob.Client
+	switch {
+	case remote.SASToken != "":
+		client, err = azblob.NewClientWithNoCredential(remote.Endpoint+"?"+remote.SASToken, options)
+	case remote.AccountKey != "":
+		var cred *azblob.SharedKeyCredential
+		cred, err = azblob.NewSharedKeyCredential(remote.Account, remote.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Azure account key: %w", err)
+		}
+		client, err = azblob.NewClientWithSharedKeyCredential(remote.Endpoint, cred, options)
+	default:
+		var cred *azidentity.DefaultAzureCredential
+		cred, err = azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: azcore.ClientOptions{Transport: httpClient},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Azure credentials: %w", err)
+		}
+		client, err = azblob.NewClient(remote.Endpoint, cred, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	return &AzureStorage{
+		container: remote.Container,
+		client:    client,
+		logger:    c.logger,
+	}, nil
+}
+
+// Upload uploads a file as a block blob, the storage class is mapped to the closest access tier
+func (a AzureStorage) Upload(ctx context.Context, path string, target string, opts UploadOptions) error {
+	if opts.ObjectLockMode != "" || opts.LegalHold {
+		return errors.New("object lock is not supported by Azure Blob Storage, use an immutability policy on the container")
+	}
+	a.log().Info("Uploading file", "file", path, "target", target)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("upload error: %w", err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			a.log().Error("error closing file", "error", err)
+		}
+	}(file)
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		if contentType, err = detectContentType(file); err != nil {
+			return fmt.Errorf("upload error: %w", err)
+		}
+	}
+
+	uploadOptions := &azblob.UploadFileOptions{
+		AccessTier:  azureAccessTier(opts.StorageClass),
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	}
+	if len(opts.Metadata) > 0 {
+		uploadOptions.Metadata = make(map[string]*string, len(opts.Metadata))
+		for key, value := range opts.Metadata {
+			uploadOptions.Metadata[key] = &value
+		}
+	}
+	if opts.NoClobber {
+		etag := azcore.ETagAny
+		uploadOptions.AccessConditions = &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etag},
+		}
+	}
+	_, err = a.client.UploadFile(ctx, a.container, target, file, uploadOptions)
+	if err != nil && opts.NoClobber && isConditionFailed(err) {
+		return fmt.Errorf("unable to upload %q to %q: %w", path, target, errObjectExists)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to upload %q to %q: %w", path, a.container, err)
+	}
+
+	a.log().Info("Upload completed successfully", "file", path, "target", target)
+	return nil
+}
+
+// Download downloads a blob to dest and verifies it against the size and the Content-MD5 of the blob
+func (a AzureStorage) Download(ctx context.Context, path string, dest string, opts DownloadOptions) error {
+	if opts.VersionID != "" {
+		return errors.New("version-id is not supported by Azure Blob Storage")
+	}
+	file, err := createDownloadFile(dest, opts.Force)
+	if err != nil {
+		return err
+	}
+	// An incomplete download never replaces dest
+	defer file.discard()
+
+	props, err := a.client.ServiceClient().NewContainerClient(a.container).NewBlobClient(path).GetProperties(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to download %q from %q: %w", path, a.container, err)
+	}
+	var digest objectDigest
+	if props.ContentLength != nil {
+		digest.Size = *props.ContentLength
+	}
+	if len(props.ContentMD5) == md5.Size && md5Allowed() {
+		digest.MD5 = hex.EncodeToString(props.ContentMD5)
+	}
+	metadata := blobMetadata(props.Metadata)
+	// Download the version that was inspected
+	downloadOptions := &azblob.DownloadFileOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: props.ETag},
+		},
+	}
+
+	// A download that does not match the blob digest is retried once
+	for attempt := 1; ; attempt++ {
+		if _, err := a.client.DownloadFile(ctx, a.container, path, file.File, downloadOptions); err != nil {
+			if bloberror.HasCode(err, bloberror.BlobArchived) {
+				return fmt.Errorf("blob %q is in the Archive tier, rehydrate it to Hot or Cool before restoring: %w", path, err)
+			}
+			return fmt.Errorf("unable to download %q from %q: %w", path, a.container, err)
+		}
+		if !opts.SkipVerify {
+			err = verifyFile(file.File, digest)
+		}
+		if opts.SkipVerify || err == nil {
+			if err := file.commit(); err != nil {
+				return fmt.Errorf("download error: %w", err)
+			}
+			applyMetadata(dest, metadata, opts)
+			return nil
+		}
+		if attempt == 2 {
+			return fmt.Errorf("downloaded file %q is corrupted: %w", dest, err)
+		}
+		a.log().Warn("Downloaded file does not match the blob, downloading again", "file", path, "error", err)
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("download error: %w", err)
+		}
+	}
+}
+
+// Open streams the content of a blob with the digest it is verified against
+func (a AzureStorage) Open(ctx context.Context, key string) (io.ReadCloser, objectDigest, error) {
+	resp, err := a.client.DownloadStream(ctx, a.container, key, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobArchived) {
+			return nil, objectDigest{}, fmt.Errorf("blob %q is in the Archive tier, rehydrate it to Hot or Cool before reading it: %w", key, err)
+		}
+		return nil, objectDigest{}, fmt.Errorf("unable to read %q from %q: %w", key, a.container, err)
+	}
+	var digest objectDigest
+	if resp.ContentLength != nil {
+		digest.Size = *resp.ContentLength
+	}
+	if len(resp.ContentMD5) == md5.Size && md5Allowed() {
+		digest.MD5 = hex.EncodeToString(resp.ContentMD5)
+	}
+	return resp.Body, digest, nil
+}
+
+// VerifyUpload compares the size and the Content-MD5 of the blob, when set, with the local file
+func (a AzureStorage) VerifyUpload(ctx context.Context, path string, target string) error {
+	props, err := a.client.ServiceClient().NewContainerClient(a.container).NewBlobClient(target).GetProperties(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to verify %q: %w", target, err)
+	}
+	var digest objectDigest
+	if props.ContentLength != nil {
+		digest.Size = *props.ContentLength
+	}
+	if len(props.ContentMD5) == md5.Size && md5Allowed() {
+		digest.MD5 = hex.EncodeToString(props.ContentMD5)
+	}
+	if err := verifyLocalFile(path, digest); err != nil {
+		return fmt.Errorf("verification of %q failed: %w", target, err)
+	}
+	return nil
+}
+
+// Size returns the size of a blob
+func (a AzureStorage) Size(ctx context.Context, path string) (int64, error) {
+	props, err := a.client.ServiceClient().NewContainerClient(a.container).NewBlobClient(path).GetProperties(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if props.ContentLength == nil {
+		return 0, nil
+	}
+	return *props.ContentLength, nil
+}
+
+// Metadata returns the user metadata of a blob
+func (a AzureStorage) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	props, err := a.client.ServiceClient().NewContainerClient(a.container).NewBlobClient(path).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return blobMetadata(props.Metadata), nil
+}
+
+// blobMetadata converts blob metadata, returned with their HTTP header casing, to lower case keys
+func blobMetadata(props map[string]*string) map[string]string {
+	metadata := make(map[string]string, len(props))
+	for key, value := range props {
+		if value != nil {
+			metadata[strings.ToLower(key)] = *value
+		}
+	}
+	return metadata
+}
+
+// List lists the blobs under path, folders are returned as directory items in non-recursive mode
+func (a AzureStorage) List(ctx context.Context, path string, recursive bool) ([]Item, error) {
+	return collectItems(a.Objects(ctx, path, recursive))
+}
+
+// Objects iterates over the blobs under path one page at a time
+func (a AzureStorage) Objects(ctx context.Context, path string, recursive bool) iter.Seq2[Item, error] {
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return func(yield func(Item, error) bool) {
+		if recursive {
+			pager := a.client.NewListBlobsFlatPager(a.container, &azblob.ListBlobsFlatOptions{Prefix: &path})
+			for pager.More() {
+				resp, err := pager.NextPage(ctx)
+				if err != nil {
+					yield(Item{}, fmt.Errorf("could not list blobs in Azure container %s: %w", a.container, err))
+					return
+				}
+				for _, item := range resp.Segment.BlobItems {
+					if !yield(azureItem(item.Name, item.Properties), nil) {
+						return
+					}
+				}
+			}
+			return
+		}
+
+		containerClient := a.client.ServiceClient().NewContainerClient(a.container)
+		pager := containerClient.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &path})
+		for pager.More() {
+			resp, err := pager.NextPage(ctx)
+			if err != nil {
+				yield(Item{}, fmt.Errorf("could not list blobs in Azure container %s: %w", a.container, err))
+				return
+			}
+			for _, item := range resp.Segment.BlobItems {
+				if !yield(azureItem(item.Name, item.Properties), nil) {
+					return
+				}
+			}
+			for _, prefix := range resp.Segment.BlobPrefixes {
+				if !yield(Item{Key: *prefix.Name, IsDir: true}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func azureItem(name *string, properties *container.BlobProperties) Item {
+	item := Item{Key: *name}
+	if properties == nil {
+		return item
+	}
+	if properties.LastModified != nil {
+		item.LastModified = *properties.LastModified
+	}
+	if properties.ContentLength != nil {
+		item.Size = *properties.ContentLength
+	}
+	if properties.AccessTier != nil {
+		item.StorageClass = string(*properties.AccessTier)
+	}
+	if properties.ETag != nil {
+		item.ETag = strings.Trim(string(*properties.ETag), `"`)
+	}
+	return item
+}
+
+// azureAccessTier maps an S3 storage class to an Azure access tier, nil keeps the account default
+func azureAccessTier(storageClass string) *blob.AccessTier {
+	var tier blob.AccessTier
+	switch storageClass {
+	case "STANDARD":
+		tier = blob.AccessTierHot
+	case "STANDARD_IA", "ONEZONE_IA":
+		tier = blob.AccessTierCool
+	case "GLACIER_IR":
+		tier = blob.AccessTierCold
+	case "GLACIER", "DEEP_ARCHIVE":
+		tier = blob.AccessTierArchive
+	default:
+		return nil
+	}
+	return &tier
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAzureRemote(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "")
+	t.Setenv("AZURE_STORAGE_KEY", "")
+	t.Setenv("AZURITE_ACCOUNT_KEY", "a2V5")
+
+	remote, err := parseAzureRemote("azblob://devstoreaccount1/backups/daily/db?endpoint=http://127.0.0.1:10000/devstoreaccount1&credentials=azurite")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote.Account != "devstoreaccount1" || remote.Container != "backups" || remote.Prefix != "daily/db" {
+		t.Errorf("Unexpected remote %+v", remote)
+	}
+	if remote.Endpoint != "http://127.0.0.1:10000/devstoreaccount1/" || remote.AccountKey != "a2V5" {
+		t.Errorf("Expected endpoint and credentials overrides, got %+v", remote)
+	}
+
+	remote, err = parseAzureRemote("azblob://account/container")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote.Endpoint != "https://account.blob.core.windows.net/" || remote.Prefix != "" {
+		t.Errorf("Unexpected remote %+v", remote)
+	}
+
+	for _, raw := range []string{"azblob://account", "s3://bucket/prefix", "azblob://account/backups?credentials=missing"} {
+		if _, err := parseAzureRemote(raw); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestAzureAccessTier(t *testing.T) {
+	if tier := azureAccessTier("DEEP_ARCHIVE"); tier == nil || *tier != "Archive" {
+		t.Errorf("Expected Archive tier, got %v", tier)
+	}
+	if tier := azureAccessTier(""); tier != nil {
+		t.Errorf("Expected account default tier, got %v", *tier)
+	}
+}
+
+// blobServer serves a single blob with the given Content-MD5 and counts the downloads
+func blobServer(t *testing.T, content []byte, contentMD5 []byte) (*httptest.Server, *int) {
+	t.Helper()
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"0x1"`)
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(contentMD5))
+		if r.Method == http.MethodGet {
+			downloads++
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &downloads
+}
+
+func TestAzureDownloadVerify(t *testing.T) {
+	content := []byte("backup content")
+	sum := md5.Sum(content)
+	wrong := md5.Sum([]byte("other content"))
+
+	for _, tc := range []struct {
+		name       string
+		md5        []byte
+		skipVerify bool
+		downloads  int
+		wantErr    bool
+	}{
+		{name: "valid", md5: sum[:], downloads: 1},
+		{name: "corrupted", md5: wrong[:], downloads: 2, wantErr: true},
+		{name: "skip verify", md5: wrong[:], skipVerify: true, downloads: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, downloads := blobServer(t, content, tc.md5)
+			config := &Config{}
+			storage, err := config.NewAzureStorage(&AzureRemote{Account: "account", Container: "backups", Endpoint: server.URL + "/", SASToken: "sv=test"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			dest := filepath.Join(t.TempDir(), "data.txt")
+			err = storage.Download(context.Background(), "data.txt", dest, DownloadOptions{SkipVerify: tc.skipVerify})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected a corrupted download to fail")
+				}
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Errorf("Expected the corrupted file to be removed")
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *downloads != tc.downloads {
+				t.Errorf("Expected %d downloads, got %d", tc.downloads, *downloads)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// BackupsReport lists the restore points of every destination under a prefix
+type BackupsReport struct {
+	Path         string              `json:"path"`
+	Destinations []BackupDestination `json:"destinations"`
+}
+
+// BackupDestination lists the restore points of a destination from the oldest to the newest
+type BackupDestination struct {
+	Path          string         `json:"path"`
+	RestorePoints []RestorePoint `json:"restore_points"`
+}
+
+// RestorePoint is a backup that can be restored with --file or --as-of
+type RestorePoint struct {
+	File         string    `json:"file"`
+	Time         time.Time `json:"time"`
+	Size         int64     `json:"size"`
+	StorageClass string    `json:"storage_class,omitempty"`
+}
+
+// Backups is the cobra command handler for backups
+func Backups(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	if config.Format == "" {
+		config.Format = formatText
+	}
+	if !slices.Contains(outputFormats, config.Format) {
+		return withExitCode(ExitConfig, fmt.Errorf("invalid format %q, supported values: %v", config.Format, outputFormats))
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	config.Dest = strings.Trim(filepath.ToSlash(config.Dest), "/")
+
+	var objects []Item
+	for item, err := range s3Storage.Objects(cmd.Context(), config.Dest, true) {
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if !item.IsDir {
+			objects = append(objects, item)
+		}
+	}
+	report, ignored := config.backupsReport(config.Dest, objects)
+	if ignored > 0 {
+		slog.Info("Objects without a timestamp in their name ignored", "objects", ignored)
+	}
+	return writeBackupsReport(os.Stdout, report, config.Format)
+}
+
+// backupsReport groups the restore points under root by directory, each directory is a destination.
+// Archives and files whose name carries an archive timestamp are restore points,
+// their time is the timestamp of the name or the modification time.
+// The number of other objects is returned.
+func (c *Config) backupsReport(root string, objects []Item) (BackupsReport, int) {
+	destinations := make(map[string][]RestorePoint)
+	ignored := 0
+	for _, object := range objects {
+		name := path.Base(object.Key)
+		if name == LatestFile {
+			continue
+		}
+		if _, ok := c.archiveTime(name); !ok && !isTarArchive(name) {
+			ignored++
+			continue
+		}
+		dir := path.Dir(object.Key)
+		destinations[dir] = append(destinations[dir], RestorePoint{
+			File:         name,
+			Time:         c.backupTime(object),
+			Size:         object.Size,
+			StorageClass: object.StorageClass,
+		})
+	}
+
+	report := BackupsReport{Path: root, Destinations: []BackupDestination{}}
+	for _, dir := range slices.Sorted(maps.Keys(destinations)) {
+		points := destinations[dir]
+		slices.SortStableFunc(points, func(a, b RestorePoint) int {
+			if c := a.Time.Compare(b.Time); c != 0 {
+				return c
+			}
+			return strings.Compare(a.File, b.File)
+		})
+		report.Destinations = append(report.Destinations, BackupDestination{Path: dir, RestorePoints: points})
+	}
+	return report, ignored
+}
+
+// writeBackupsReport writes the report as a text table, JSON or CSV
+func writeBackupsReport(out io.Writer, report BackupsReport, format string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"destination", "file", "time", "size", "storage_class"})
+		for _, dest := range report.Destinations {
+			for _, point := range dest.RestorePoints {
+				_ = w.Write([]string{dest.Path, point.File, point.Time.Format(time.RFC3339),
+					strconv.FormatInt(point.Size, 10), point.StorageClass})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if len(report.Destinations) == 0 {
+		_, err := fmt.Fprintf(out, "No restore points found under %q\n", report.Path)
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, dest := range report.Destinations {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "Destination %s: %d restore points\n", dest.Path, len(dest.RestorePoints))
+		_, _ = fmt.Fprintln(w, "TIME\tFILE\tSIZE\tCLASS")
+		for _, point := range dest.RestorePoints {
+			class := point.StorageClass
+			if class == "" {
+				class = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", point.Time.Format(time.RFC3339), point.File,
+				goutils.ConvertBytes(uint64(point.Size)), class)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupsReport(t *testing.T) {
+	config := &Config{TimestampFormat: "2006-01-02_15-04-05"}
+	modified := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	objects := []Item{
+		{Key: "backups/db/db_2025-06-03_02-00-00.tar.gz", Size: 300, LastModified: modified},
+		{Key: "backups/db/db_2025-06-01_02-00-00.tar.gz", Size: 100, LastModified: modified},
+		{Key: "backups/db/latest.json", Size: 80, LastModified: modified},
+		{Key: "backups/web.tgz", Size: 50, LastModified: modified},
+		{Key: "backups/notes.txt", Size: 10, LastModified: modified},
+	}
+	report, ignored := config.backupsReport("backups", objects)
+	if ignored != 1 {
+		t.Errorf("ignored = %d, want 1", ignored)
+	}
+	if len(report.Destinations) != 2 || report.Destinations[0].Path != "backups" || report.Destinations[1].Path != "backups/db" {
+		t.Fatalf("destinations = %+v", report.Destinations)
+	}
+	// Archives without a timestamp use their modification time
+	if web := report.Destinations[0].RestorePoints; len(web) != 1 || !web[0].Time.Equal(modified) {
+		t.Errorf("web = %+v", web)
+	}
+	db := report.Destinations[1].RestorePoints
+	if len(db) != 2 || db[0].File != "db_2025-06-01_02-00-00.tar.gz" || db[1].File != "db_2025-06-03_02-00-00.tar.gz" {
+		t.Fatalf("db = %+v", db)
+	}
+	if want := time.Date(2025, 6, 3, 2, 0, 0, 0, time.UTC); !db[1].Time.Equal(want) || db[1].Size != 300 {
+		t.Errorf("db[1] = %+v", db[1])
+	}
+}
+
+func TestWriteBackupsReport(t *testing.T) {
+	report := BackupsReport{Path: "backups", Destinations: []BackupDestination{{
+		Path: "backups/db",
+		RestorePoints: []RestorePoint{
+			{File: "db_2025-06-01_02-00-00.tar.gz", Time: time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC), Size: 100, StorageClass: "STANDARD"},
+		},
+	}}}
+
+	var text bytes.Buffer
+	if err := writeBackupsReport(&text, report, formatText); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Destination backups/db: 1 restore points", "TIME", "2025-06-01T02:00:00Z", "db_2025-06-01_02-00-00.tar.gz", "STANDARD"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text report misses %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeBackupsReport(&out, report, formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded BackupsReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Destinations) != 1 || decoded.Destinations[0].RestorePoints[0].Size != 100 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+
+	var csv bytes.Buffer
+	if err := writeBackupsReport(&csv, report, formatCSV); err != nil {
+		t.Fatal(err)
+	}
+	if want := "backups/db,db_2025-06-01_02-00-00.tar.gz,2025-06-01T02:00:00Z,100,STANDARD"; !strings.Contains(csv.String(), want) {
+		t.Errorf("csv = %q, want line %q", csv.String(), want)
+	}
+
+	var empty bytes.Buffer
+	if err := writeBackupsReport(&empty, BackupsReport{Path: "backups"}, formatText); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(empty.String(), "No restore points found") {
+		t.Errorf("empty = %q", empty.String())
+	}
+}
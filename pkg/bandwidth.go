@@ -0,0 +1,333 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/aws/smithy-go/middleware"
+	goutils "github.com/jkaninda/go-utils"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rates of --bandwidth-schedule besides sizes per second
+const (
+	rateUnlimited = "unlimited"
+	ratePause     = "pause"
+)
+
+const (
+	// pausedRate is the rate of a window holding new requests
+	pausedRate = -1
+	// throttleChunk bounds the bytes read at once from a throttled body, so transfers are paced smoothly
+	throttleChunk = 64 * 1024
+	minutesPerDay = 24 * 60
+)
+
+// bandwidthWindow is a rule of --bandwidth-schedule, from start to end in minutes of the day, every
+// minute with all. A window ending before it starts spans midnight.
+type bandwidthWindow struct {
+	start, end int
+	all        bool
+	// rate is in bytes per second, 0 is unlimited and pausedRate holds new requests
+	rate int64
+}
+
+// contains reports whether the window contains a minute of the day
+func (w bandwidthWindow) contains(minute int) bool {
+	switch {
+	case w.all:
+		return true
+	case w.start <= w.end:
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// bandwidthSchedule holds the windows of --bandwidth-schedule, the first window containing a time applies
+type bandwidthSchedule []bandwidthWindow
+
+// parseBandwidthSchedule parses a schedule such as "00:00-06:00=unlimited,*=5MB", a window is a time range
+// or * for the rest of the day, its rate a size per second, unlimited or pause
+func parseBandwidthSchedule(value string) (bandwidthSchedule, error) {
+	var schedule bandwidthSchedule
+	for _, entry := range strings.Split(value, ",") {
+		span, rate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid bandwidth schedule %q, expected HH:MM-HH:MM=rate or *=rate", entry)
+		}
+		var window bandwidthWindow
+		if span = strings.TrimSpace(span); span == "*" {
+			window.all = true
+		} else {
+			start, end, ok := strings.Cut(span, "-")
+			var err error
+			if window.start, err = parseClock(start); !ok || err != nil || window.start == minutesPerDay {
+				return nil, fmt.Errorf("invalid bandwidth schedule %q, expected HH:MM-HH:MM=rate or *=rate", entry)
+			}
+			if window.end, err = parseClock(end); err != nil || window.start == window.end {
+				return nil, fmt.Errorf("invalid bandwidth schedule %q, expected HH:MM-HH:MM=rate or *=rate", entry)
+			}
+		}
+		rate = strings.TrimSpace(rate)
+		switch strings.ToLower(rate) {
+		case rateUnlimited:
+		case ratePause:
+			window.rate = pausedRate
+		default:
+			size, err := goutils.ConvertToBytes(strings.TrimSuffix(rate, "/s"))
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("invalid bandwidth schedule %q, the rate must be a size per second such as 5MB, %s or %s", entry, rateUnlimited, ratePause)
+			}
+			window.rate = size
+		}
+		schedule = append(schedule, window)
+	}
+	return schedule, nil
+}
+
+// parseClock parses a HH:MM time of the day into minutes, 24:00 ends a window at midnight
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	h, err := strconv.Atoi(hours)
+	if err != nil || !ok {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || len(minutes) != 2 || h < 0 || m < 0 || m > 59 || h*60+m > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// rate returns the rate of the first window containing t, unlimited when none does
+func (s bandwidthSchedule) rate(t time.Time) int64 {
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range s {
+		if window.contains(minute) {
+			return window.rate
+		}
+	}
+	return 0
+}
+
+// nextChange returns the start of the next minute a window starts or ends after t
+func (s bandwidthSchedule) nextChange(t time.Time) time.Time {
+	minute := t.Hour()*60 + t.Minute()
+	wait := minutesPerDay
+	for _, window := range s {
+		if window.all {
+			continue
+		}
+		for _, boundary := range []int{window.start, window.end} {
+			wait = min(wait, (boundary-minute+minutesPerDay-1)%minutesPerDay+1)
+		}
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	return start.Add(time.Duration(wait) * time.Minute)
+}
+
+// bandwidthLimiter paces the transfers of a run with its bandwidth schedule, all the requests share the rate
+type bandwidthLimiter struct {
+	schedule bandwidthSchedule
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+	logger   *slog.Logger
+
+	mu sync.Mutex
+	// next is when the bytes transferred so far are sent at the current rate
+	next time.Time
+	// pausedUntil is the end of the pause last logged
+	pausedUntil time.Time
+}
+
+func newBandwidthLimiter(schedule bandwidthSchedule, location *time.Location, logger *slog.Logger) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		schedule: schedule,
+		now:      func() time.Time { return time.Now().In(location) },
+		sleep:    sleepContext,
+		logger:   logger,
+	}
+}
+
+// sleepContext waits for d or until the context is canceled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitWindow holds a new operation while the schedule pauses transfers
+func (l *bandwidthLimiter) waitWindow(ctx context.Context) error {
+	for {
+		now := l.now()
+		if l.schedule.rate(now) != pausedRate {
+			return nil
+		}
+		resume := l.schedule.nextChange(now)
+		l.mu.Lock()
+		if !l.pausedUntil.Equal(resume) {
+			l.pausedUntil = resume
+			l.logger.Info("Transfers paused by the bandwidth schedule", "until", resume.Format("15:04"))
+		}
+		l.mu.Unlock()
+		if err := l.sleep(ctx, resume.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
+
+// wait paces n transferred bytes at the rate of the current window. Requests sent before a pause
+// complete without limit.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	now := l.now()
+	rate := l.schedule.rate(now)
+	if rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	return l.sleep(ctx, delay)
+}
+
+// transferLimiter returns the limiter of the bandwidth schedule, nil without one. It is created once
+// and shared by the storages of the configuration and of its mirrors.
+func (c *Config) transferLimiter() (*bandwidthLimiter, error) {
+	if c.BandwidthSchedule == "" {
+		return nil, nil
+	}
+	if c.limiter == nil {
+		schedule, err := parseBandwidthSchedule(c.BandwidthSchedule)
+		if err != nil {
+			return nil, err
+		}
+		location, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+		}
+		c.limiter = newBandwidthLimiter(schedule, location, loggerOrDefault(c.logger))
+	}
+	return c.limiter, nil
+}
+
+// addBandwidthPause holds S3 operations while the bandwidth schedule pauses transfers. The operations
+// wait before their requests are signed, so they are not sent with a signature older than the pause.
+func addBandwidthPause(limiter *bandwidthLimiter) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3safe:BandwidthPause",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if err := limiter.waitWindow(ctx); err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+				return next.HandleInitialize(ctx, in)
+			}), middleware.Before)
+	}
+}
+
+// bandwidthPausePolicy holds Azure operations while the bandwidth schedule pauses transfers, it runs
+// once per operation before the requests are authorized
+type bandwidthPausePolicy struct {
+	limiter *bandwidthLimiter
+}
+
+func (p bandwidthPausePolicy) Do(req *policy.Request) (*http.Response, error) {
+	if err := p.limiter.waitWindow(req.Raw().Context()); err != nil {
+		return nil, err
+	}
+	return req.Next()
+}
+
+// throttledTransport paces the bodies of requests and responses with the bandwidth schedule, pauses
+// are applied before signing by addBandwidthPause and bandwidthPausePolicy
+type throttledTransport struct {
+	base    http.RoundTripper
+	limiter *bandwidthLimiter
+}
+
+func (t throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = t.throttle(ctx, req.Body)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return t.throttle(ctx, body), nil
+			}
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = t.throttle(ctx, resp.Body)
+	return resp, nil
+}
+
+func (t throttledTransport) throttle(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	return &throttledBody{ReadCloser: body, ctx: ctx, limiter: t.limiter}
+}
+
+// throttledBody paces the reads of a request or response body
+type throttledBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *bandwidthLimiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.wait(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
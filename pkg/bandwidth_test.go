@@ -0,0 +1,252 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidthSchedule(t *testing.T) {
+	schedule, err := parseBandwidthSchedule("00:00-06:00=unlimited, 22:00-01:00=pause, 09:00-24:00=512KiB/s, *=5MB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(hour, minute int) time.Time { return time.Date(2025, 6, 1, hour, minute, 0, 0, time.UTC) }
+	for _, test := range []struct {
+		at   time.Time
+		rate int64
+	}{
+		{day(0, 0), 0},
+		{day(5, 59), 0},
+		{day(6, 0), 5_000_000},
+		{day(9, 30), 512 * 1024},
+		{day(22, 0), pausedRate},
+		{day(23, 59), pausedRate},
+	} {
+		if rate := schedule.rate(test.at); rate != test.rate {
+			t.Errorf("Expected rate %d at %s, got %d", test.rate, test.at.Format("15:04"), rate)
+		}
+	}
+
+	for _, invalid := range []string{"", "06:00=5MB", "00:00-06:00", "00:00-25:00=5MB", "06:00-06:00=5MB", "00:60-06:00=5MB", "24:00-06:00=5MB", "*=fast", "*=0"} {
+		if _, err := parseBandwidthSchedule(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestBandwidthScheduleNextChange(t *testing.T) {
+	schedule, err := parseBandwidthSchedule("22:00-06:30=pause,*=5MB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for at, want := range map[time.Time]time.Time{
+		time.Date(2025, 6, 1, 23, 15, 20, 0, time.UTC): time.Date(2025, 6, 2, 6, 30, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 6, 30, 0, 0, time.UTC):   time.Date(2025, 6, 1, 22, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC):    time.Date(2025, 6, 1, 6, 30, 0, 0, time.UTC),
+	} {
+		if next := schedule.nextChange(at); !next.Equal(want) {
+			t.Errorf("Expected the change after %s at %s, got %s", at, want, next)
+		}
+	}
+	always, _ := parseBandwidthSchedule("*=pause")
+	if next := always.nextChange(time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)); !next.Equal(time.Date(2025, 6, 2, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a day without window boundaries, got %s", next)
+	}
+}
+
+// fakeClock is the time of a limiter advanced by its sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (f *fakeClock) limiter(t *testing.T, value string) *bandwidthLimiter {
+	schedule, err := parseBandwidthSchedule(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &bandwidthLimiter{
+		schedule: schedule,
+		now:      func() time.Time { return f.now },
+		sleep: func(_ context.Context, d time.Duration) error {
+			f.now = f.now.Add(d)
+			f.slept += d
+			return nil
+		},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestBandwidthLimiterPacing(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := clock.limiter(t, "00:00-06:00=unlimited,*=1K")
+	for range 10 {
+		if err := limiter.wait(context.Background(), 500); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if clock.slept != 5*time.Second {
+		t.Errorf("Expected 5000 bytes at 1000 bytes per second to take 5s, got %s", clock.slept)
+	}
+
+	clock.now = time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+	clock.slept = 0
+	if err := limiter.wait(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if clock.slept != 0 {
+		t.Errorf("Expected no wait in an unlimited window, got %s", clock.slept)
+	}
+}
+
+func TestBandwidthLimiterPause(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 22, 30, 0, 0, time.UTC)}
+	limiter := clock.limiter(t, "22:00-06:00=pause")
+	if err := limiter.waitWindow(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC); !clock.now.Equal(want) {
+		t.Errorf("Expected the request to wait until %s, got %s", want, clock.now)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter = newBandwidthLimiter(limiter.schedule, time.UTC, limiter.logger)
+	limiter.now = func() time.Time { return time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC) }
+	if err := limiter.waitWindow(ctx); err == nil {
+		t.Error("Expected a canceled context to stop the wait")
+	}
+}
+
+func TestBandwidthPauseBeforeSigning(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)}
+	limiter := clock.limiter(t, "22:00-06:00=pause")
+	var events []string
+	sleep := limiter.sleep
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		events = append(events, "pause")
+		return sleep(ctx, d)
+	}
+
+	stack := middleware.NewStack("PutObject", smithyhttp.NewStackRequest)
+	if err := stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("Signing",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			events = append(events, "sign")
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After); err != nil {
+		t.Fatal(err)
+	}
+	if err := addBandwidthPause(limiter)(stack); err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware.DecorateHandler(middleware.HandlerFunc(func(context.Context, any) (any, middleware.Metadata, error) {
+		return nil, middleware.Metadata{}, nil
+	}), stack)
+	if _, _, err := handler.Handle(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(events, []string{"pause", "sign"}) {
+		t.Errorf("Expected the operation to pause before it is signed, got %v", events)
+	}
+}
+
+func TestAzureBandwidthPause(t *testing.T) {
+	content := []byte("backup content")
+	sum := md5.Sum(content)
+	server, _ := blobServer(t, content, sum[:])
+
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)}
+	config := &Config{BandwidthSchedule: "22:00-06:00=pause"}
+	config.limiter = clock.limiter(t, config.BandwidthSchedule)
+	storage, err := config.NewAzureStorage(&AzureRemote{Account: "account", Container: "backups", Endpoint: server.URL + "/", SASToken: "sv=test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Download(context.Background(), "data.txt", filepath.Join(t.TempDir(), "data.txt"), DownloadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC); !clock.now.Equal(want) {
+		t.Errorf("Expected the download to wait until %s, got %s", want, clock.now)
+	}
+}
+
+func TestThrottledTransport(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		_, _ = w.Write([]byte(strings.Repeat("b", 3000)))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	client := &http.Client{Transport: throttledTransport{base: http.DefaultTransport, limiter: clock.limiter(t, "*=1K")}}
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader(strings.Repeat("a", 2000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received != 2000 || len(body) != 3000 {
+		t.Fatalf("Expected 2000 bytes sent and 3000 received, got %d and %d", received, len(body))
+	}
+	if clock.slept != 5*time.Second {
+		t.Errorf("Expected 5000 bytes at 1000 bytes per second to take 5s, got %s", clock.slept)
+	}
+}
+
+func TestNewHTTPClientBandwidthSchedule(t *testing.T) {
+	c := &Config{BandwidthSchedule: "*=5MB"}
+	client, err := c.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := client.Transport.(throttledTransport)
+	if !ok {
+		t.Fatalf("Expected a throttled transport, got %T", client.Transport)
+	}
+	mirror, err := c.ParseRemote("s3://mirror/backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter, _ := mirror.Config.transferLimiter(); limiter != transport.limiter {
+		t.Error("Expected the mirrors to share the limiter of the run")
+	}
+}
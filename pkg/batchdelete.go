@@ -0,0 +1,119 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"slices"
+	"sync"
+)
+
+const (
+	// deleteBatchSize is the maximum number of keys of a DeleteObjects request
+	deleteBatchSize = 1000
+	// deleteBatchConcurrency is the number of DeleteObjects requests sent at once
+	deleteBatchConcurrency = 4
+)
+
+// versionError is an object version that could not be deleted
+type versionError struct {
+	version ObjectVersion
+	err     error
+}
+
+// DeleteVersions permanently deletes object versions and delete markers with DeleteObjects requests of up to
+// 1000 keys, several requests are sent at once. It returns the deleted versions in the order of versions,
+// and the failed ones: the versions rejected by S3 with their error, or all the versions of a failed request.
+func (s S3Storage) DeleteVersions(ctx context.Context, versions []ObjectVersion) ([]ObjectVersion, []versionError) {
+	batches := slices.Collect(slices.Chunk(versions, deleteBatchSize))
+	failures := make([][]versionError, len(batches))
+	sem := make(chan struct{}, deleteBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			failures[i] = s.deleteBatch(ctx, batch)
+		}()
+	}
+	wg.Wait()
+
+	var deleted []ObjectVersion
+	var failed []versionError
+	for i, batch := range batches {
+		rejected := make(map[string]bool, len(failures[i]))
+		for _, f := range failures[i] {
+			rejected[versionKey(f.version)] = true
+		}
+		for _, v := range batch {
+			if !rejected[versionKey(v)] {
+				deleted = append(deleted, v)
+			}
+		}
+		failed = append(failed, failures[i]...)
+	}
+	return deleted, failed
+}
+
+// deleteBatch sends a DeleteObjects request in quiet mode, S3 only reports the keys it could not delete
+func (s S3Storage) deleteBatch(ctx context.Context, batch []ObjectVersion) []versionError {
+	ids := make([]types.ObjectIdentifier, 0, len(batch))
+	for _, v := range batch {
+		ids = append(ids, types.ObjectIdentifier{Key: aws.String(v.Key), VersionId: aws.String(v.VersionID)})
+	}
+	out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		failed := make([]versionError, 0, len(batch))
+		for _, v := range batch {
+			failed = append(failed, versionError{version: v, err: fmt.Errorf("unable to delete version %s of %q: %w", v.VersionID, v.Key, err)})
+		}
+		return failed
+	}
+
+	byKey := make(map[string]ObjectVersion, len(batch))
+	for _, v := range batch {
+		byKey[versionKey(v)] = v
+	}
+	failed := make([]versionError, 0, len(out.Errors))
+	for _, e := range out.Errors {
+		v := ObjectVersion{Key: aws.ToString(e.Key), VersionID: aws.ToString(e.VersionId)}
+		if known, ok := byKey[versionKey(v)]; ok {
+			v = known
+		}
+		failed = append(failed, versionError{version: v, err: fmt.Errorf("unable to delete version %s of %q: %s: %s",
+			v.VersionID, v.Key, aws.ToString(e.Code), aws.ToString(e.Message))})
+	}
+	return failed
+}
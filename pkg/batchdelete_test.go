@@ -0,0 +1,108 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDeleteVersions(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !r.URL.Query().Has("delete") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Objects []struct{ Key, VersionId string } `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		batches = append(batches, len(body.Objects))
+		mu.Unlock()
+		var result strings.Builder
+		result.WriteString("<DeleteResult>")
+		for _, o := range body.Objects {
+			if o.Key == "locked.txt" {
+				fmt.Fprintf(&result, "<Error><Key>%s</Key><VersionId>%s</VersionId><Code>AccessDenied</Code><Message>Access Denied because object protected by object lock.</Message></Error>", o.Key, o.VersionId)
+			}
+		}
+		result.WriteString("</DeleteResult>")
+		_, _ = w.Write([]byte(result.String()))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(t.TempDir(), server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make([]ObjectVersion, 0, 2500)
+	for i := range 2499 {
+		versions = append(versions, ObjectVersion{Key: fmt.Sprintf("file-%04d.txt", i), VersionID: "v1", Size: 1})
+	}
+	versions = append(versions, ObjectVersion{Key: "locked.txt", VersionID: "v1", Size: 10})
+
+	deleted, failed := storage.DeleteVersions(context.Background(), versions)
+	if len(deleted) != 2499 || deleted[0].Key != "file-0000.txt" {
+		t.Errorf("Expected 2499 deleted versions in order, got %d", len(deleted))
+	}
+	if len(failed) != 1 || failed[0].version.Size != 10 || !strings.Contains(failed[0].err.Error(), "AccessDenied") {
+		t.Errorf("Expected locked.txt to fail with AccessDenied, got %v", failed)
+	}
+	if len(batches) != 3 {
+		t.Errorf("Expected 3 DeleteObjects requests, got %v", batches)
+	}
+}
+
+func TestDeleteVersionsRequestError(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(t.TempDir(), server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, failed := storage.DeleteVersions(context.Background(), []ObjectVersion{{Key: "a", VersionID: "1"}, {Key: "b", VersionID: "2"}})
+	if len(deleted) != 0 || len(failed) != 2 {
+		t.Errorf("Expected every version of the failed request to fail, got %d deleted and %d failed", len(deleted), len(failed))
+	}
+}
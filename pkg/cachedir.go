@@ -0,0 +1,61 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// cacheDirTag is the file marking a cache directory, see https://bford.info/cachedir/
+const cacheDirTag = "CACHEDIR.TAG"
+
+// cacheDirSignature starts every valid CACHEDIR.TAG file
+const cacheDirSignature = "Signature: 8a477f597d28d172789f06886806bc55"
+
+// commonCacheDirs are the cache directory names excluded by --exclude-common-caches
+var commonCacheDirs = []string{".cache", "node_modules", "__pycache__"}
+
+// hasCacheDirTag reports whether a directory contains a valid CACHEDIR.TAG file
+func hasCacheDirTag(dir string) bool {
+	file, err := os.Open(filepath.Join(dir, cacheDirTag))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+	signature := make([]byte, len(cacheDirSignature))
+	if _, err := io.ReadFull(file, signature); err != nil {
+		return false
+	}
+	return bytes.Equal(signature, []byte(cacheDirSignature))
+}
+
+// isExcludedCacheDir reports whether a directory is a cache excluded by --exclude-caches or --exclude-common-caches
+func (c *Config) isExcludedCacheDir(dir string) bool {
+	return c.ExcludeCommonCaches && slices.Contains(commonCacheDirs, filepath.Base(dir)) ||
+		c.ExcludeCaches && hasCacheDirTag(dir)
+}
@@ -0,0 +1,145 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// cacheTree creates a directory with a tagged cache directory, a node_modules directory,
+// a directory with an invalid tag and a regular file
+func cacheTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"notes.txt":                     "notes",
+		"build/" + cacheDirTag:          cacheDirSignature + "\n# This file is a cache directory tag.\n",
+		"build/object.o":                "object",
+		"web/node_modules/lib/index.js": "lib",
+		"web/index.js":                  "index",
+		"docs/" + cacheDirTag:           "not a signature",
+		"docs/readme.md":                "readme",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestHasCacheDirTag(t *testing.T) {
+	root := cacheTree(t)
+	if !hasCacheDirTag(filepath.Join(root, "build")) {
+		t.Error("build is tagged")
+	}
+	if hasCacheDirTag(filepath.Join(root, "docs")) {
+		t.Error("docs has an invalid tag")
+	}
+	if hasCacheDirTag(filepath.Join(root, "web")) {
+		t.Error("web is not tagged")
+	}
+}
+
+func TestWalkExcludesCaches(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{"none", Config{}, []string{"build/CACHEDIR.TAG", "build/object.o", "docs/CACHEDIR.TAG", "docs/readme.md", "notes.txt", "web/index.js", "web/node_modules/lib/index.js"}},
+		{"tagged", Config{ExcludeCaches: true}, []string{"docs/CACHEDIR.TAG", "docs/readme.md", "notes.txt", "web/index.js", "web/node_modules/lib/index.js"}},
+		{"common", Config{ExcludeCommonCaches: true}, []string{"build/CACHEDIR.TAG", "build/object.o", "docs/CACHEDIR.TAG", "docs/readme.md", "notes.txt", "web/index.js"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := cacheTree(t)
+			bm := &BackupManager{config: &tt.config}
+			var files []string
+			for file, err := range walkFilesAfter(root, true, "", bm.excluded()) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !file.IsDir {
+					files = append(files, filepath.ToSlash(file.Key))
+				}
+			}
+			slices.Sort(files)
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("files = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressDirectoryExcludesCaches(t *testing.T) {
+	root := cacheTree(t)
+	bm := &BackupManager{config: &Config{ExcludeCaches: true, ExcludeCommonCaches: true}}
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	archived, err := compressDirectory(root, archive, archiveOptions{exclude: bm.excluded()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != 4 {
+		t.Errorf("archived = %d, want 4", archived)
+	}
+	if bm.result.Skipped != 2 {
+		t.Errorf("skipped = %d, want 2", bm.result.Skipped)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"docs/CACHEDIR.TAG", "docs/readme.md", "notes.txt", "web/index.js"}; !slices.Equal(names, want) {
+		t.Errorf("archived %v, want %v", names, want)
+	}
+}
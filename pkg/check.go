@@ -0,0 +1,162 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+// operationCheck is the operation of the run summary of check
+const operationCheck = "check"
+
+// objectReader streams the content of an object with the digest it is verified against
+type objectReader interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, objectDigest, error)
+}
+
+// CheckResult summarizes the check of a backup archive
+type CheckResult struct {
+	// Archive is the object key of the checked archive
+	Archive string
+	// Entries is the number of archive entries and Bytes their total size
+	Entries  int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Check is the cobra command handler for check
+func Check(cmd *cobra.Command) error {
+	rm, err := NewRestoreManager(cmd)
+	if err != nil {
+		return err
+	}
+	introTo(bannerOutput(rm.config.Format))
+	result, err := rm.Check(cmd.Context())
+	summary := RunSummary{
+		Operation:  operationCheck,
+		Path:       rm.location,
+		Archive:    result.Archive,
+		Files:      result.Entries,
+		Bytes:      result.Bytes,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	return errors.Join(err, writeRunSummary(os.Stdout, summary.withError(err), rm.config.Format))
+}
+
+// Check streams a backup archive and reads every entry to the end without writing files,
+// verifying the object checksums, the gzip checksum and the tar headers
+func (rm *RestoreManager) Check(ctx context.Context) (CheckResult, error) {
+	start := time.Now()
+	if rm.config.Latest {
+		if err := rm.resolveLatest(ctx); err != nil {
+			return CheckResult{}, err
+		}
+	}
+	if rm.config.File == "" {
+		return CheckResult{}, withExitCode(ExitConfig, errors.New("--file or --latest is required"))
+	}
+	key := objectKey(rm.config.Path, rm.objectName(rm.config.File))
+	reader, ok := rm.storage.(objectReader)
+	if !ok {
+		return CheckResult{}, errors.New("the storage does not support streaming objects")
+	}
+
+	rm.log().Info("Checking archive", "file", key)
+	body, digest, err := reader.Open(ctx, key)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(body)
+
+	w := newDigestWriter()
+	result, err := checkArchive(io.TeeReader(body, w))
+	result.Archive = key
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("archive %q is not restorable: %w", key, err)
+	}
+	if err := w.check(digest); err != nil {
+		return result, fmt.Errorf("archive %q is corrupted: %w", key, err)
+	}
+	rm.log().Info("Archive is restorable", "file", key, "entries", result.Entries, "bytes", result.Bytes, "duration", result.Duration)
+	return result, nil
+}
+
+// checkArchive reads a tar archive, gzipped or not, to the end of the stream
+func checkArchive(r io.Reader) (CheckResult, error) {
+	var result CheckResult
+	br := bufio.NewReader(r)
+	var stream io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return result, err
+		}
+		defer func(gz *gzip.Reader) {
+			_ = gz.Close()
+		}(gz)
+		stream = gz
+	}
+
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid entry after %d entries: %w", result.Entries, err)
+		}
+		n, err := io.Copy(io.Discard, tr)
+		if err != nil {
+			return result, fmt.Errorf("unable to read %q: %w", header.Name, err)
+		}
+		result.Entries++
+		result.Bytes += n
+	}
+	if result.Entries == 0 {
+		return result, errors.New("no entries found, the object is not a tar archive or is empty")
+	}
+	// The gzip checksum is verified once the whole stream is read
+	if _, err := io.Copy(io.Discard, stream); err != nil {
+		return result, err
+	}
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return result, err
+	}
+	return result, nil
+}
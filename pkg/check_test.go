@@ -0,0 +1,119 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArchive returns a tar.gz archive of two files
+func testArchive(t *testing.T) []byte {
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), bytes.Repeat([]byte(name), 1000), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := compressDirectory(src, archive, archiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCheckArchive(t *testing.T) {
+	data := testArchive(t)
+	result, err := checkArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entries != 2 || result.Bytes != 10000 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if _, err := checkArchive(bytes.NewReader(corrupted)); err == nil {
+		t.Error("Expected a corrupted archive to fail")
+	}
+	if _, err := checkArchive(bytes.NewReader(data[:len(data)-4])); err == nil {
+		t.Error("Expected a truncated archive to fail")
+	}
+	if _, err := checkArchive(strings.NewReader("not an archive")); err == nil {
+		t.Error("Expected a text file to fail")
+	}
+}
+
+func TestRestoreManagerCheck(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	data := testArchive(t)
+	sum := md5.Sum(data)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/backups/backup.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	cfg := testConfig("backups", server.URL)
+	cfg.File = "backup.tar.gz"
+	rm, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := rm.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Archive != "backups/backup.tar.gz" || result.Entries != 2 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	cfg.File = ""
+	rm, err = NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.Check(context.Background()); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected a config error without --file, got %v", err)
+	}
+}
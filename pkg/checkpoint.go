@@ -0,0 +1,207 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkpointInterval is the minimum time between two writes of a backup checkpoint
+const checkpointInterval = 10 * time.Second
+
+// backupCheckpoint is the progress of a folder backup, written to --checkpoint while it runs.
+// Files are walked in a stable order, so the last completed key is enough to resume.
+// A nil checkpoint records nothing.
+type backupCheckpoint struct {
+	Path   string `json:"path"`
+	Bucket string `json:"bucket,omitempty"`
+	Dest   string `json:"dest"`
+	// Key is the last completed key, slash separated and relative to Path
+	Key       string       `json:"key"`
+	Files     int          `json:"files"`
+	Bytes     int64        `json:"bytes"`
+	Skipped   int          `json:"skipped,omitempty"`
+	Deleted   int          `json:"deleted,omitempty"`
+	Failed    []FailedFile `json:"failed,omitempty"`
+	Rejected  []string     `json:"rejected,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+
+	file    string
+	written time.Time
+}
+
+// openBackupCheckpoint loads the checkpoint of an interrupted backup, restart discards it.
+// A checkpoint written by a backup with another source or destination is ignored.
+func openBackupCheckpoint(file string, config *Config, restart bool, logger *slog.Logger) (*backupCheckpoint, error) {
+	c := &backupCheckpoint{
+		Path:   config.Path,
+		Bucket: config.Bucket,
+		Dest:   config.Dest,
+		file:   file,
+	}
+	if restart {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to reset backup checkpoint: %w", err)
+		}
+		return c, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup checkpoint: %w", err)
+	}
+	var saved backupCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		logger.Warn("Ignoring unreadable backup checkpoint", "checkpoint", file, "error", err)
+		return c, nil
+	}
+	if saved.Path != c.Path || saved.Bucket != c.Bucket || saved.Dest != c.Dest {
+		logger.Warn("Ignoring the checkpoint of another backup", "checkpoint", file, "path", saved.Path, "dest", saved.Dest)
+		return c, nil
+	}
+	saved.file = file
+	if saved.Key != "" {
+		logger.Info("Resuming backup from checkpoint", "files", saved.Files, "bytes", saved.Bytes, "after", saved.Key, "checkpoint", file)
+	}
+	return &saved, nil
+}
+
+// after returns the last completed key, the walk resumes after it
+func (c *backupCheckpoint) after() string {
+	if c == nil {
+		return ""
+	}
+	return c.Key
+}
+
+// restore copies the progress of the interrupted backup to the result
+func (c *backupCheckpoint) restore(result *BackupResult) {
+	if c == nil {
+		return
+	}
+	result.Files = c.Files
+	result.Bytes = c.Bytes
+	result.Skipped = c.Skipped
+	result.Deleted = c.Deleted
+	result.Rejected = append(result.Rejected, c.Rejected...)
+	for _, f := range c.Failed {
+		result.Failed = append(result.Failed, FileError{Key: f.Key, Err: errors.New(f.Error)})
+	}
+}
+
+// record marks the key as completed and writes the checkpoint at most every checkpointInterval
+func (c *backupCheckpoint) record(key string, result *BackupResult) error {
+	if c == nil {
+		return nil
+	}
+	c.Key = filepath.ToSlash(key)
+	c.Files = result.Files
+	c.Bytes = result.Bytes
+	c.Skipped = result.Skipped
+	c.Deleted = result.Deleted
+	c.Rejected = result.Rejected
+	c.Failed = c.Failed[:0]
+	for _, f := range result.Failed {
+		c.Failed = append(c.Failed, FailedFile{Key: f.Key, Error: f.Err.Error()})
+	}
+	if time.Since(c.written) < checkpointInterval {
+		return nil
+	}
+	return c.save()
+}
+
+// save writes the checkpoint to a temporary file renamed over the previous one,
+// so a crash while writing leaves the previous checkpoint intact
+func (c *backupCheckpoint) save() error {
+	if c == nil || c.Key == "" {
+		return nil
+	}
+	c.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write backup checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), ".s3safe-checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("failed to write backup checkpoint: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write backup checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write backup checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.file); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write backup checkpoint: %w", err)
+	}
+	c.written = time.Now()
+	return nil
+}
+
+// close writes the checkpoint of an interrupted backup, or removes it once the backup is complete
+func (c *backupCheckpoint) close(complete bool, logger *slog.Logger) error {
+	if c == nil {
+		return nil
+	}
+	if complete {
+		if err := os.Remove(c.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := c.save(); err != nil {
+		return err
+	}
+	if c.Key != "" {
+		logger.Info("Backup checkpoint kept, run the same backup again to continue", "checkpoint", c.file)
+	}
+	return nil
+}
+
+// compareWalkOrder compares slash separated keys in the order of walkDir,
+// directories are sorted by name and walked before their next sibling
+func compareWalkOrder(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
@@ -0,0 +1,173 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompareWalkOrder(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "a/b.txt", "a/c/d.txt", "a-b", "b.txt"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var keys []string
+	for file, err := range WalkFiles(root, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, filepath.ToSlash(file.Key))
+	}
+	for i := 1; i < len(keys); i++ {
+		if compareWalkOrder(keys[i-1], keys[i]) >= 0 {
+			t.Errorf("compareWalkOrder(%q, %q) does not follow the walk order", keys[i-1], keys[i])
+		}
+	}
+
+	var resumed []string
+	for file, err := range walkFilesAfter(root, true, "a/b.txt", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed = append(resumed, filepath.ToSlash(file.Key))
+	}
+	if want := []string{"a/c", "a/c/d.txt", "a-b", "a.txt", "b.txt"}; !slices.Equal(resumed, want) {
+		t.Errorf("walkFilesAfter = %v, want %v", resumed, want)
+	}
+}
+
+func TestBackupCheckpoint(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var fail atomic.Bool
+	fail.Store(true)
+	var mu sync.Mutex
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		if fail.Load() && key == "backups/c.txt" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>denied</Message></Error>`))
+			return
+		}
+		mu.Lock()
+		uploaded = append(uploaded, key)
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.Checkpoint = filepath.Join(t.TempDir(), "backup.checkpoint")
+
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err == nil {
+		t.Fatal("Expected the upload of c.txt to fail")
+	}
+	checkpoint, err := openBackupCheckpoint(cfg.Checkpoint, bm.config, false, bm.log())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Key != "b.txt" || checkpoint.Files != 2 {
+		t.Fatalf("Unexpected checkpoint %+v", checkpoint)
+	}
+
+	fail.Store(false)
+	mu.Lock()
+	uploaded = nil
+	mu.Unlock()
+	bm, err = NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"backups/c.txt", "backups/d.txt"}; !slices.Equal(uploaded, want) {
+		t.Errorf("uploaded keys = %v, want %v", uploaded, want)
+	}
+	if result.Files != 4 {
+		t.Errorf("result.Files = %d, want 4", result.Files)
+	}
+	if _, err := os.Stat(cfg.Checkpoint); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed")
+	}
+}
+
+func TestBackupCheckpointOtherBackup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "backup.checkpoint")
+	config := &Config{Path: "/data", Bucket: "bucket", Dest: "backups"}
+	checkpoint, err := openBackupCheckpoint(file, config, false, loggerOrDefault(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoint.record("a.txt", &BackupResult{Files: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoint.close(false, loggerOrDefault(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &Config{Path: "/data", Bucket: "bucket", Dest: "other"}
+	checkpoint, err = openBackupCheckpoint(file, other, false, loggerOrDefault(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.after() != "" {
+		t.Errorf("Expected the checkpoint of another destination to be ignored")
+	}
+	checkpoint, err = openBackupCheckpoint(file, config, true, loggerOrDefault(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.after() != "" {
+		t.Errorf("Expected restart to discard the checkpoint")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/joho/godotenv"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// CompletionValues returns the values accepted by a flag, for shell completion.
+// Flags without a fixed set of values return nil.
+func CompletionValues(flag string) []string {
+	switch flag {
+	case "provider":
+		return slices.Sorted(maps.Keys(providers))
+	case "storage-class":
+		return enumValues(types.StorageClass("").Values())
+	case "class":
+		classes := map[string]bool{}
+		for _, p := range pricing {
+			for class := range p.Classes {
+				classes[class] = true
+			}
+		}
+		return slices.Sorted(maps.Keys(classes))
+	case "tier":
+		return enumValues(types.Tier("").Values())
+	case "checksum":
+		return append(enumValues(types.ChecksumAlgorithm("").Values()), checksumNone)
+	case "object-lock-mode":
+		return enumValues(types.ObjectLockMode("").Values())
+	case "normalize-unicode":
+		return normalizeForms
+	case "unsafe-keys":
+		return unsafeKeyStrategies
+	case "sanitize-names":
+		return sanitizeStrategies
+	case "format":
+		return outputFormats
+	case "layout":
+		return layouts
+	case "on-unreadable":
+		return unreadablePolicies
+	}
+	return nil
+}
+
+// enumValues converts the values of an AWS SDK enum to strings
+func enumValues[T ~string](values []T) []string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		s = append(s, string(v))
+	}
+	return s
+}
+
+// JobNames returns the job names set by S3SAFE_JOB in the environment, in envFile
+// and in the .env files of dir, sorted and without duplicates.
+func JobNames(dir, envFile string) []string {
+	jobs := map[string]bool{}
+	if job := os.Getenv(utils.JobEnv); job != "" {
+		jobs[job] = true
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.env"))
+	files = append(files, filepath.Join(dir, ".env"))
+	if envFile != "" {
+		files = append(files, envFile)
+	}
+	for _, file := range files {
+		env, err := godotenv.Read(file)
+		if err != nil {
+			continue
+		}
+		if job := env[utils.JobEnv]; job != "" {
+			jobs[job] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(jobs))
+}
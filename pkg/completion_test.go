@@ -0,0 +1,89 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompletionValues(t *testing.T) {
+	tests := []struct {
+		flag, want string
+	}{
+		{"provider", "r2"},
+		{"storage-class", "GLACIER"},
+		{"class", "DEEP_ARCHIVE"},
+		{"tier", "Bulk"},
+		{"checksum", checksumNone},
+		{"object-lock-mode", "COMPLIANCE"},
+		{"normalize-unicode", normalizeNFC},
+		{"unsafe-keys", unsafeKeysEncode},
+		{"sanitize-names", sanitizeSkip},
+		{"format", formatJSON},
+		{"on-unreadable", unreadableSkip},
+	}
+	for _, tt := range tests {
+		if got := CompletionValues(tt.flag); !slices.Contains(got, tt.want) {
+			t.Errorf("CompletionValues(%q) = %v, want %q", tt.flag, got, tt.want)
+		}
+	}
+	if got := CompletionValues("path"); got != nil {
+		t.Errorf("Expected no values for path, got %v", got)
+	}
+}
+
+func TestJobNames(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env":         "S3SAFE_JOB=default\n",
+		"db.env":       "S3SAFE_JOB=database\nAWS_S3_BUCKET_NAME=backups\n",
+		"web.env":      "S3SAFE_JOB=website\n",
+		"copy.env":     "S3SAFE_JOB=website\n",
+		"noop.env":     "AWS_S3_BUCKET_NAME=backups\n",
+		"settings.txt": "S3SAFE_JOB=ignored\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	custom := filepath.Join(t.TempDir(), "custom")
+	if err := os.WriteFile(custom, []byte("S3SAFE_JOB=custom\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("S3SAFE_JOB", "nightly")
+
+	want := []string{"custom", "database", "default", "nightly", "website"}
+	if got := JobNames(dir, custom); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	t.Setenv("S3SAFE_JOB", "")
+	if got := JobNames(t.TempDir(), ""); len(got) != 0 {
+		t.Errorf("Expected no jobs, got %v", got)
+	}
+}
@@ -25,19 +25,31 @@
 package pkg
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/logging"
 	"github.com/jkaninda/s3safe/utils"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -67,17 +79,238 @@ type Config struct {
 	RetentionDays int
 	Exclude       []string
 	EnvFile       string
+	Proxy         string
+	DebugAWS      bool
+	StorageClass  string
+	// StorageClassRules holds comma-separated rules, see ParseStorageClassRules
+	StorageClassRules string
+	Tier              string
+	Days              int
+	Wait              bool
+	PollInterval      time.Duration
+	ContentType       string
+	ACL               string
+	Accelerate        bool
+	ObjectLockMode    string
+	ObjectLockDays    int
+	ObjectLockPolicy  string
+	LegalHold         bool
+	VersionID         string
+	Since             string
+	DryRun            bool
+	OlderThan         string
+	From              string
+	To                string
+	Mirrors           []string
+	Parallel          bool
+	Provider          string
+	Job               string
+	// TimestampFormat is the Go time layout of archive timestamps
+	TimestampFormat string
+	// Timezone is an IANA time zone name, UTC or Local
+	Timezone string
+	// NameTemplate is the template of the compressed archive name, see NameTemplate
+	NameTemplate string
+	// Latest restores the archive referenced by latest.json
+	Latest bool
+	// Match restores only the files whose base name matches the pattern
+	Match string
+	// Newest restores only the most recently modified file
+	Newest bool
+	// Flatten restores every file into Dest by its base name, without the directories of its key
+	Flatten bool
+	// AsOf restores only the newest backup made at or before the time, from the timestamp of its name
+	// or its modification time
+	AsOf string
+	// Before searches only the backups made before the time
+	Before string
+	// Archives makes search read the entries of compressed backups
+	Archives bool
+	// SkipSpaceCheck disables the free disk space check before restoring
+	SkipSpaceCheck bool
+	// List prints the files a restore would download without downloading them
+	List bool
+	// Restart discards the journal of an interrupted restore or the checkpoint of an interrupted backup
+	Restart bool
+	// SkipVerify disables the verification of downloaded files
+	SkipVerify bool
+	// Checksum is the S3 additional checksum algorithm of uploads or NONE, SHA256 by default on AWS only
+	Checksum string
+	// PreservePermissions restores the file mode, and the owner when running as root
+	PreservePermissions bool
+	// NormalizeUnicode is the Unicode normalization form of keys and restored file names: nfc, nfd or none
+	NormalizeUnicode string
+	// UnsafeKeys is the strategy for keys containing control characters, '#' or '?': keep, encode or reject
+	UnsafeKeys string
+	// Inventory is the s3:// URL of an S3 Inventory manifest.json used instead of listing the bucket
+	Inventory string
+	// SanitizeNames is the strategy for keys that are not valid Windows file names: replace, skip or fail
+	SanitizeNames string
+	// PartSize is the S3 multipart part size, e.g. "16MiB", the provider or SDK default when empty
+	PartSize string
+	// Concurrency is the number of parts transferred at once per file, the SDK default when zero
+	Concurrency int
+	// ListShards is the number of prefixes listed at once by recursive S3 listings, 0 or 1 lists sequentially
+	ListShards int
+	// MaxMemory bounds the part buffers of a single S3 transfer, e.g. "256MiB", unbounded when empty
+	MaxMemory string
+	// ConnectTimeout bounds the connection to the endpoint and the TLS handshake, the Go defaults when zero
+	ConnectTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response headers once a request is sent, unbounded when zero
+	ResponseHeaderTimeout time.Duration
+	// MaxIdleConns is the number of idle connections kept open per host, the Go default of 2 when zero
+	MaxIdleConns int
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool
+	// DisableHTTP2 uses HTTP/1.1 only, for gateways that misbehave with HTTP/2
+	DisableHTTP2 bool
+	// DebugRequests logs every S3 API call at debug level with its status and request ID
+	DebugRequests bool
+	// BandwidthSchedule caps the transfer rate by time of day, such as "00:00-06:00=unlimited,*=5MB", see parseBandwidthSchedule
+	BandwidthSchedule string
+	// FIPS uses the FIPS endpoints of AWS S3 and FIPS-approved crypto only, see validateFIPS
+	FIPS bool
+	// MaxErrors aborts a run with IgnoreErrors once more files failed, a count such as "10" or a percentage such as "5%"
+	MaxErrors string
+	// NoRecompress stores files that are already compressed without compressing them again
+	NoRecompress bool
+	// CompressFiles gzips each file of a non-archive backup, objects get the .gz suffix
+	CompressFiles bool
+	// Verify compares every uploaded object with the local file
+	Verify bool
+	// DeleteSource deletes local files once uploaded and verified on every destination, it requires Verify
+	DeleteSource bool
+	// SkipIdentical skips the upload of files whose content is already stored under the same key
+	SkipIdentical bool
+	// NoClobberRemote uploads with a conditional write failing when the object already exists
+	NoClobberRemote bool
+	// VerifySample compares a random sample of the uploaded files with their objects after the backup,
+	// a number of files such as "20" or a percentage such as "5%"
+	VerifySample string
+	// Checkpoint is the local file the progress of a folder backup is written to, so an interrupted backup resumes
+	Checkpoint string
+	// FailureReport is the local path or s3:// URL the failed files are written to
+	FailureReport string
+	// ReportHTML is the local path or s3:// URL the HTML report of the run is written to
+	ReportHTML string
+	// AuditLog is the prefix in the bucket, or the s3:// URL, of the monthly NDJSON audit log objects
+	// backup, restore, undelete and purge-versions runs are appended to
+	AuditLog string
+	// Report is the failure report read by retry
+	Report string
+	// PruneReport is the local path or s3:// URL the report of purge-versions is written to,
+	// a file name is written under the pruned path
+	PruneReport string
+	// Class is the comma-separated storage classes priced by cost, all classes of the provider when empty
+	Class string
+	// PricePerGB overrides the storage price per GB-month of every class priced by cost
+	PricePerGB float64
+	// Format is the output format of reports, listings and run summaries: text, json or csv
+	Format string
+	// Runs is the number of runs per job in the usage report
+	Runs int
+	// Depth is the number of directories naming a job in the usage report, runs are the directories below
+	Depth int
+	// PluginsDir is the directory of the executables invoked with a JSON event on stdin at each stage of a backup
+	PluginsDir string
+	// ObfuscateKeys replaces object keys by a keyed hash of the key, the key is stored encrypted with KeySecret
+	// in the object metadata
+	ObfuscateKeys bool
+	KeySecret     string
+	// KeepLocal is the number of compressed archives kept in the backed up directory, 0 keeps them all
+	KeepLocal int
+	// Layout places the uploads under the destination prefix as is (flat) or under dated YYYY/MM/DD directories (date)
+	Layout string
+	// OneFileSystem leaves out the directories on another file system than the backed up directory
+	OneFileSystem bool
+	// ExcludeCaches leaves out the directories tagged with a CACHEDIR.TAG file
+	ExcludeCaches bool
+	// ExcludeCommonCaches leaves out the directories named .cache, node_modules or __pycache__
+	ExcludeCommonCaches bool
+	// RespectGitignore leaves out the files and directories matched by the .gitignore files of the backed up directories
+	RespectGitignore bool
+	// IncludeSpecial archives FIFOs and device files as tar entries, they are skipped by default
+	IncludeSpecial bool
+	// SELinux stores the SELinux context of backed up files, in the archive or the object metadata, and restores it
+	SELinux bool
+	// OnUnreadable is the policy for the files of a backup that cannot be read: skip, warn or fail,
+	// the default is warn with IgnoreErrors and fail otherwise
+	OnUnreadable string
+	// MaxDepth limits recursive walks and listings to the files at most MaxDepth levels below the path, 0 is unlimited
+	MaxDepth int
+	// Snapshot is the volume backed up from a snapshot, kind:target such as lvm:vg/lv, zfs:pool/data, btrfs:/srv or vss:C:
+	Snapshot string
+	// Require lists the bucket settings checked before the backup: versioning, object-lock and encryption
+	Require []string
+	// RequireWarn logs the unmet requirements instead of failing
+	RequireWarn bool
+	// TransitionGlacier, TransitionDeepArchive and Expire are the ages, such as 30d, of the lifecycle rule applied to Dest
+	TransitionGlacier     string
+	TransitionDeepArchive string
+	Expire                string
+	// MPUCleanupDays adds a lifecycle rule aborting the incomplete multipart uploads under Dest after that many days, 0 disables it
+	MPUCleanupDays int
+	// logger is passed to the storages, the slog default logger when nil
+	logger *slog.Logger
+	// limiter paces the transfers of all the storages with BandwidthSchedule, see transferLimiter
+	limiter *bandwidthLimiter
 }
 
 type S3Storage struct {
-	bucket  string
-	session *session.Session
+	bucket string
+	// connection identifies the endpoint and credentials, storages sharing it can copy server-side
+	connection string
+	client     *s3.Client
+	// partSize overrides the multipart part size when set
+	partSize int64
+	// concurrency overrides the number of parts transferred at once when set
+	concurrency int
+	// maxMemory bounds the part buffers of a transfer when set, see uploadSettings
+	maxMemory int64
+	// listShards lists the prefixes of recursive listings in parallel when above 1, see shardedObjects
+	listShards int
+	// checksum is the additional checksum of uploads without ChecksumAlgorithm, set on AWS only
+	checksum string
+	logger   *slog.Logger
+}
+
+// UploadOptions holds per-object settings applied on upload
+type UploadOptions struct {
+	StorageClass string
+	ContentType  string
+	ACL          string
+	// ObjectLockMode and RetainUntil set Object Lock retention on the uploaded object
+	ObjectLockMode string
+	RetainUntil    time.Time
+	LegalHold      bool
+	// ChecksumAlgorithm is the S3 additional checksum computed on upload, empty for the default of the
+	// storage and NONE to disable
+	ChecksumAlgorithm string
+	// Metadata holds the source file attributes, see fileMetadata
+	Metadata map[string]string
+	// NoClobber fails the upload when an object already exists under the target key
+	NoClobber bool
+}
+
+// DownloadOptions holds per-object settings applied on download
+type DownloadOptions struct {
+	Force     bool
+	VersionID string
+	// SkipVerify disables the size and checksum verification of the downloaded file
+	SkipVerify bool
+	// PreservePermissions applies the mode and owner stored in the object metadata
+	PreservePermissions bool
+	// SELinux applies the SELinux context stored in the object metadata
+	SELinux bool
 }
 
 type Item struct {
 	Key          string
 	LastModified time.Time
 	IsDir        bool
+	StorageClass string
+	Size         int64
+	ETag         string
 }
 
 // NewConfig creates a new Config instance from cobra command flags
@@ -93,9 +326,12 @@ func NewConfig(cmd *cobra.Command) *Config {
 	// Load AWS configuration
 	c.loadAWSConfig()
 
-	// Process path and file configurations
-	c.processPaths()
+	// Apply the defaults, the provider preset and process path and file configurations
+	c.prepare()
 
+	if c.DebugRequests {
+		enableDebugLogs()
+	}
 	return c
 }
 
@@ -115,28 +351,311 @@ func (c *Config) loadBasicFlags(cmd *cobra.Command) {
 	c.Timestamp, _ = cmd.Flags().GetBool("timestamp")
 	c.Bucket, _ = cmd.Flags().GetString("bucket")
 	c.IgnoreErrors, _ = cmd.Flags().GetBool("ignore-errors")
+	c.OnUnreadable, _ = cmd.Flags().GetString("on-unreadable")
 	c.Recursive, _ = cmd.Flags().GetBool("recursive")
+	c.MaxDepth, _ = cmd.Flags().GetInt("max-depth")
 	c.Force, _ = cmd.Flags().GetBool("force")
+	c.Proxy, _ = cmd.Flags().GetString("proxy")
+	c.DebugAWS, _ = cmd.Flags().GetBool("debug-aws")
+	c.StorageClass, _ = cmd.Flags().GetString("storage-class")
+	c.StorageClassRules, _ = cmd.Flags().GetString("storage-class-rules")
+	c.Tier, _ = cmd.Flags().GetString("tier")
+	c.Days, _ = cmd.Flags().GetInt("days")
+	c.Wait, _ = cmd.Flags().GetBool("wait")
+	c.PollInterval, _ = cmd.Flags().GetDuration("poll-interval")
+	c.ContentType, _ = cmd.Flags().GetString("content-type")
+	c.ACL, _ = cmd.Flags().GetString("acl")
+	c.Accelerate, _ = cmd.Flags().GetBool("accelerate")
+	c.ObjectLockMode, _ = cmd.Flags().GetString("object-lock-mode")
+	c.ObjectLockDays, _ = cmd.Flags().GetInt("object-lock-days")
+	c.ObjectLockPolicy, _ = cmd.Flags().GetString("object-lock-policy")
+	c.LegalHold, _ = cmd.Flags().GetBool("legal-hold")
+	c.VersionID, _ = cmd.Flags().GetString("version-id")
+	c.Since, _ = cmd.Flags().GetString("since")
+	c.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	c.OlderThan, _ = cmd.Flags().GetString("older-than")
+	c.From, _ = cmd.Flags().GetString("from")
+	c.To, _ = cmd.Flags().GetString("to")
+	c.Mirrors, _ = cmd.Flags().GetStringArray("mirror")
+	c.Parallel, _ = cmd.Flags().GetBool("parallel")
+	c.Provider, _ = cmd.Flags().GetString("provider")
+	c.Job, _ = cmd.Flags().GetString("job")
+	c.NormalizeUnicode, _ = cmd.Flags().GetString("normalize-unicode")
+	c.TimestampFormat, _ = cmd.Flags().GetString("timestamp-format")
+	c.Timezone, _ = cmd.Flags().GetString("timezone")
+	c.NameTemplate, _ = cmd.Flags().GetString("name-template")
+	c.Latest, _ = cmd.Flags().GetBool("latest")
+	c.Match, _ = cmd.Flags().GetString("match")
+	c.Newest, _ = cmd.Flags().GetBool("newest")
+	c.Flatten, _ = cmd.Flags().GetBool("flatten")
+	c.AsOf, _ = cmd.Flags().GetString("as-of")
+	c.Before, _ = cmd.Flags().GetString("before")
+	c.Archives, _ = cmd.Flags().GetBool("archives")
+	c.SkipSpaceCheck, _ = cmd.Flags().GetBool("skip-space-check")
+	c.List, _ = cmd.Flags().GetBool("list")
+	c.Restart, _ = cmd.Flags().GetBool("restart")
+	c.SkipVerify, _ = cmd.Flags().GetBool("skip-verify")
+	c.Checksum, _ = cmd.Flags().GetString("checksum")
+	c.PreservePermissions, _ = cmd.Flags().GetBool("preserve-permissions")
+	c.SanitizeNames, _ = cmd.Flags().GetString("sanitize-names")
+	c.UnsafeKeys, _ = cmd.Flags().GetString("unsafe-keys")
+	c.Inventory, _ = cmd.Flags().GetString("inventory")
+	c.PartSize, _ = cmd.Flags().GetString("part-size")
+	c.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+	c.ListShards, _ = cmd.Flags().GetInt("list-shards")
+	c.ConnectTimeout, _ = cmd.Flags().GetDuration("connect-timeout")
+	c.ResponseHeaderTimeout, _ = cmd.Flags().GetDuration("response-header-timeout")
+	c.MaxIdleConns, _ = cmd.Flags().GetInt("max-idle-conns")
+	c.DisableKeepAlives, _ = cmd.Flags().GetBool("disable-keep-alives")
+	c.DisableHTTP2, _ = cmd.Flags().GetBool("disable-http2")
+	c.DebugRequests, _ = cmd.Flags().GetBool("debug-requests")
+	c.BandwidthSchedule, _ = cmd.Flags().GetString("bandwidth-schedule")
+	c.FIPS, _ = cmd.Flags().GetBool("fips")
+	c.MaxMemory, _ = cmd.Flags().GetString("max-memory")
+	c.FailureReport, _ = cmd.Flags().GetString("failure-report")
+	c.ReportHTML, _ = cmd.Flags().GetString("report-html")
+	c.AuditLog, _ = cmd.Flags().GetString("audit-log")
+	c.MaxErrors, _ = cmd.Flags().GetString("max-errors")
+	c.Checkpoint, _ = cmd.Flags().GetString("checkpoint")
+	c.Verify, _ = cmd.Flags().GetBool("verify")
+	c.SkipIdentical, _ = cmd.Flags().GetBool("skip-identical")
+	c.NoClobberRemote, _ = cmd.Flags().GetBool("no-clobber-remote")
+	c.VerifySample, _ = cmd.Flags().GetString("verify-sample")
+	c.CompressFiles, _ = cmd.Flags().GetBool("compress-files")
+	c.NoRecompress, _ = cmd.Flags().GetBool("no-recompress")
+	c.DeleteSource, _ = cmd.Flags().GetBool("delete-source")
+	c.Report, _ = cmd.Flags().GetString("report")
+	c.PruneReport, _ = cmd.Flags().GetString("prune-report")
+	c.Class, _ = cmd.Flags().GetString("class")
+	c.PricePerGB, _ = cmd.Flags().GetFloat64("price-per-gb")
+	c.Format, _ = cmd.Flags().GetString("format")
+	c.Runs, _ = cmd.Flags().GetInt("runs")
+	c.Depth, _ = cmd.Flags().GetInt("depth")
+	c.PluginsDir, _ = cmd.Flags().GetString("plugins-dir")
+	c.ObfuscateKeys, _ = cmd.Flags().GetBool("obfuscate-keys")
+	c.KeepLocal, _ = cmd.Flags().GetInt("keep-local")
+	c.Layout, _ = cmd.Flags().GetString("layout")
+	c.OneFileSystem, _ = cmd.Flags().GetBool("one-file-system")
+	c.ExcludeCaches, _ = cmd.Flags().GetBool("exclude-caches")
+	c.ExcludeCommonCaches, _ = cmd.Flags().GetBool("exclude-common-caches")
+	c.RespectGitignore, _ = cmd.Flags().GetBool("respect-gitignore")
+	c.IncludeSpecial, _ = cmd.Flags().GetBool("include-special")
+	c.Snapshot, _ = cmd.Flags().GetString("snapshot")
+	if require, _ := cmd.Flags().GetString("require"); require != "" {
+		c.Require = strings.Split(require, ",")
+	}
+	c.RequireWarn, _ = cmd.Flags().GetBool("require-warn")
+	c.TransitionGlacier, _ = cmd.Flags().GetString("transition-glacier")
+	c.TransitionDeepArchive, _ = cmd.Flags().GetString("transition-deep-archive")
+	c.Expire, _ = cmd.Flags().GetString("expire")
+	c.MPUCleanupDays, _ = cmd.Flags().GetInt("ensure-mpu-cleanup-rule")
+	c.SELinux, _ = cmd.Flags().GetBool("selinux")
 
 	exclude, _ := cmd.Flags().GetString("exclude")
 	c.Exclude = strings.Split(exclude, ",")
 }
 
+// loadAWSConfig fills the settings left unset from the environment variables
 func (c *Config) loadAWSConfig() {
-	c.Region = utils.Env(utils.RegionEnv)
-	c.KeyID = utils.Env(utils.KeyIDEnv)
-	c.Secret = utils.Env(utils.SecretEnv)
-	c.EndPoint = utils.Env(utils.EndPointEnv)
-	c.ForcePath = utils.Env(utils.ForcePathEnv) == "true"
-	c.DisableSSL = utils.Env(utils.DisableSSLEnv) == "true"
-
+	if c.Region == "" {
+		c.Region = utils.Env(utils.RegionEnv)
+	}
+	if c.KeyID == "" {
+		c.KeyID = utils.Env(utils.KeyIDEnv)
+	}
+	if c.Secret == "" {
+		c.Secret = utils.Env(utils.SecretEnv)
+	}
 	if c.EndPoint == "" {
-		c.EndPoint = utils.AwsS3Url
+		c.EndPoint = utils.Env(utils.EndPointEnv)
 	}
+	c.ForcePath = c.ForcePath || utils.Env(utils.ForcePathEnv) == "true"
+	c.DisableSSL = c.DisableSSL || utils.Env(utils.DisableSSLEnv) == "true"
+	c.Accelerate = c.Accelerate || utils.BoolEnv(utils.AccelerateEnv)
 
 	if c.Bucket == "" {
 		c.Bucket = utils.Env(utils.BucketEnv)
 	}
+	if c.Proxy == "" {
+		c.Proxy = utils.Env(utils.ProxyEnv)
+	}
+	if c.Provider == "" {
+		c.Provider = utils.Env(utils.ProviderEnv)
+	}
+	if c.Job == "" {
+		c.Job = utils.Env(utils.JobEnv)
+	}
+	if c.TimestampFormat == "" {
+		c.TimestampFormat = utils.Env(utils.TimestampFormatEnv)
+	}
+	if c.Timezone == "" {
+		c.Timezone = utils.Env(utils.TimezoneEnv)
+	}
+	if c.NameTemplate == "" {
+		c.NameTemplate = utils.Env(utils.NameTemplateEnv)
+	}
+	if c.Checksum == "" {
+		c.Checksum = utils.Env(utils.ChecksumEnv)
+	}
+	if c.NormalizeUnicode == "" {
+		c.NormalizeUnicode = utils.Env(utils.NormalizeUnicodeEnv)
+	}
+	if c.UnsafeKeys == "" {
+		c.UnsafeKeys = utils.Env(utils.UnsafeKeysEnv)
+	}
+	if c.Inventory == "" {
+		c.Inventory = utils.Env(utils.InventoryEnv)
+	}
+	if c.PartSize == "" {
+		c.PartSize = utils.Env(utils.PartSizeEnv)
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency, _ = strconv.Atoi(utils.Env(utils.ConcurrencyEnv))
+	}
+	if c.ListShards == 0 {
+		c.ListShards, _ = strconv.Atoi(utils.Env(utils.ListShardsEnv))
+	}
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout, _ = time.ParseDuration(utils.Env(utils.ConnectTimeoutEnv))
+	}
+	if c.ResponseHeaderTimeout == 0 {
+		c.ResponseHeaderTimeout, _ = time.ParseDuration(utils.Env(utils.ResponseHeaderTimeoutEnv))
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns, _ = strconv.Atoi(utils.Env(utils.MaxIdleConnsEnv))
+	}
+	c.DisableKeepAlives = c.DisableKeepAlives || utils.BoolEnv(utils.DisableKeepAlivesEnv)
+	c.DisableHTTP2 = c.DisableHTTP2 || utils.BoolEnv(utils.DisableHTTP2Env)
+	c.DebugRequests = c.DebugRequests || utils.BoolEnv(utils.DebugRequestsEnv)
+	if c.BandwidthSchedule == "" {
+		c.BandwidthSchedule = utils.Env(utils.BandwidthScheduleEnv)
+	}
+	c.FIPS = c.FIPS || utils.BoolEnv(utils.FIPSEnv)
+	if c.MaxMemory == "" {
+		c.MaxMemory = utils.Env(utils.MaxMemoryEnv)
+	}
+	if c.FailureReport == "" {
+		c.FailureReport = utils.Env(utils.FailureReportEnv)
+	}
+	if c.ReportHTML == "" {
+		c.ReportHTML = utils.Env(utils.ReportHTMLEnv)
+	}
+	if c.MaxErrors == "" {
+		c.MaxErrors = utils.Env(utils.MaxErrorsEnv)
+	}
+	if c.Checkpoint == "" {
+		c.Checkpoint = utils.Env(utils.CheckpointEnv)
+	}
+	if c.VerifySample == "" {
+		c.VerifySample = utils.Env(utils.VerifySampleEnv)
+	}
+	c.SkipIdentical = c.SkipIdentical || utils.BoolEnv(utils.SkipIdenticalEnv)
+	c.NoClobberRemote = c.NoClobberRemote || utils.BoolEnv(utils.NoClobberRemoteEnv)
+	if c.AuditLog == "" {
+		c.AuditLog = utils.Env(utils.AuditLogEnv)
+	}
+	if c.PruneReport == "" {
+		c.PruneReport = utils.Env(utils.PruneReportEnv)
+	}
+	if c.PluginsDir == "" {
+		c.PluginsDir = utils.Env(utils.PluginsDirEnv)
+	}
+	c.ObfuscateKeys = c.ObfuscateKeys || utils.BoolEnv(utils.ObfuscateKeysEnv)
+	if c.KeySecret == "" {
+		c.KeySecret = utils.Env(utils.KeySecretEnv)
+	}
+	if c.KeepLocal == 0 {
+		c.KeepLocal, _ = strconv.Atoi(utils.Env(utils.KeepLocalEnv))
+	}
+	if c.Layout == "" {
+		c.Layout = utils.Env(utils.LayoutEnv)
+	}
+	c.OneFileSystem = c.OneFileSystem || utils.BoolEnv(utils.OneFileSystemEnv)
+	c.ExcludeCaches = c.ExcludeCaches || utils.BoolEnv(utils.ExcludeCachesEnv)
+	c.ExcludeCommonCaches = c.ExcludeCommonCaches || utils.BoolEnv(utils.ExcludeCommonCachesEnv)
+	c.RespectGitignore = c.RespectGitignore || utils.BoolEnv(utils.RespectGitignoreEnv)
+	if c.OnUnreadable == "" {
+		c.OnUnreadable = utils.Env(utils.OnUnreadableEnv)
+	}
+	c.SELinux = c.SELinux || utils.BoolEnv(utils.SELinuxEnv)
+	if c.Snapshot == "" {
+		c.Snapshot = utils.Env(utils.SnapshotEnv)
+	}
+	if len(c.Require) == 0 && utils.Env(utils.RequireEnv) != "" {
+		c.Require = strings.Split(utils.Env(utils.RequireEnv), ",")
+	}
+	c.RequireWarn = c.RequireWarn || utils.BoolEnv(utils.RequireWarnEnv)
+	if c.MPUCleanupDays == 0 {
+		c.MPUCleanupDays, _ = strconv.Atoi(utils.Env(utils.MPUCleanupDaysEnv))
+	}
+	if c.SanitizeNames == "" {
+		c.SanitizeNames = utils.Env(utils.SanitizeNamesEnv)
+	}
+	if c.StorageClass == "" {
+		c.StorageClass = utils.Env(utils.StorageClassEnv)
+	}
+	if c.StorageClassRules == "" {
+		c.StorageClassRules = utils.Env(utils.StorageClassRulesEnv)
+	}
+	if c.ACL == "" {
+		c.ACL = utils.Env(utils.ACLEnv)
+	}
+	if len(c.Mirrors) == 0 && utils.Env(utils.MirrorsEnv) != "" {
+		c.Mirrors = strings.Split(utils.Env(utils.MirrorsEnv), ",")
+	}
+	if c.ObjectLockMode == "" {
+		c.ObjectLockMode = utils.Env(utils.ObjectLockModeEnv)
+	}
+	if c.ObjectLockDays == 0 {
+		c.ObjectLockDays, _ = strconv.Atoi(utils.Env(utils.ObjectLockDaysEnv))
+	}
+	if c.ObjectLockPolicy == "" {
+		c.ObjectLockPolicy = utils.Env(utils.ObjectLockPolicyEnv)
+	}
+}
+
+// prepare applies the defaults and the provider preset, and splits the file from its path
+func (c *Config) prepare() {
+	c.applyDefaults()
+	c.applyProvider()
+	c.processPaths()
+}
+
+// applyDefaults sets the default values of unset settings and normalizes their case
+func (c *Config) applyDefaults() {
+	// The SDK resolves the endpoint of the region in its partition, such as aws-cn or aws-us-gov
+	if c.EndPoint == "" || isAWSEndpoint(c.EndPoint, c.Region) {
+		c.EndPoint = utils.AwsS3Url
+	}
+	if c.TimestampFormat == "" {
+		c.TimestampFormat = utils.DefaultTimestampFormat
+	}
+	if c.NormalizeUnicode == "" {
+		c.NormalizeUnicode = normalizeNone
+	}
+	if c.UnsafeKeys == "" {
+		c.UnsafeKeys = unsafeKeysKeep
+	}
+	if c.SanitizeNames == "" {
+		c.SanitizeNames = sanitizeReplace
+	}
+	if c.Layout == "" {
+		c.Layout = layoutFlat
+	}
+	if c.OnUnreadable == "" {
+		c.OnUnreadable = unreadableFail
+		if c.IgnoreErrors {
+			c.OnUnreadable = unreadableWarn
+		}
+	}
+	c.Checksum = strings.ToUpper(c.Checksum)
+	c.NormalizeUnicode = strings.ToLower(c.NormalizeUnicode)
+	c.UnsafeKeys = strings.ToLower(c.UnsafeKeys)
+	c.SanitizeNames = strings.ToLower(c.SanitizeNames)
+	c.Layout = strings.ToLower(c.Layout)
+	c.OnUnreadable = strings.ToLower(c.OnUnreadable)
+	c.StorageClass = strings.ToUpper(c.StorageClass)
+	c.ObjectLockMode = strings.ToUpper(c.ObjectLockMode)
 }
 
 func (c *Config) processPaths() {
@@ -147,6 +666,10 @@ func (c *Config) processPaths() {
 	// Handle file path processing
 	if c.File != "" && c.File != "." {
 		path := filepath.Join(c.Path, filepath.Dir(c.File))
+		// Keep the scheme separator of remote URLs such as azblob://
+		if isAzureRemote(c.Path) {
+			path = strings.TrimSuffix(c.Path+"/"+filepath.ToSlash(filepath.Dir(c.File)), "/.")
+		}
 		file := filepath.Base(c.File)
 		c.File = file
 		c.Path = path
@@ -154,20 +677,33 @@ func (c *Config) processPaths() {
 }
 
 // Validate checks the configuration and ensures all required fields are present
-func (c *Config) Validate() error {
+func (c *Config) Validate(ctx context.Context) error {
+	return c.validate(ctx, true)
+}
+
+// validate checks the configuration, and that the bucket exists when checkConnection is set
+func (c *Config) validate(ctx context.Context, checkConnection bool) error {
+	// Azure Blob Storage backups and restores don't need the S3 settings
+	if isAzureRemote(c.Dest) || isAzureRemote(c.Path) {
+		return withExitCode(ExitConfig, c.validateOptions())
+	}
 	if err := c.validateRequiredFields(); err != nil {
-		return err
+		return withExitCode(ExitConfig, err)
+	}
+	if err := c.validateOptions(); err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	if !checkConnection {
+		return nil
 	}
 
-	return c.validateS3Connection()
+	return c.validateS3Connection(ctx)
 }
 
 func (c *Config) validateRequiredFields() error {
 	requiredFields := map[string]string{
 		c.Region:   "region is required, set AWS_REGION env variable",
 		c.Bucket:   "bucket is required, set AWS_BUCKET env variable",
-		c.KeyID:    "key id is required, set AWS_ACCESS_KEY_ID env variable",
-		c.Secret:   "secret is required, set AWS_SECRET_KEY env variable",
 		c.EndPoint: "endpoint is required, set AWS_ENDPOINT env variable",
 	}
 
@@ -177,46 +713,482 @@ func (c *Config) validateRequiredFields() error {
 		}
 	}
 
+	// Static credentials are optional, the default AWS credential chain is used when both are empty
+	if c.KeyID == "" && c.Secret != "" {
+		return errors.New("key id is required, set AWS_ACCESS_KEY_ID env variable")
+	}
+	if c.KeyID != "" && c.Secret == "" {
+		return errors.New("secret is required, set AWS_SECRET_KEY env variable")
+	}
+
+	return nil
+}
+
+func (c *Config) validateOptions() error {
+	if c.StorageClass != "" && !isValidStorageClass(c.StorageClass) {
+		return fmt.Errorf("invalid storage class %q, supported values: %v", c.StorageClass, types.StorageClass("").Values())
+	}
+	if _, err := ParseStorageClassRules(c.StorageClassRules); err != nil {
+		return err
+	}
+	if c.Accelerate && (c.ForcePath || c.EndPoint != utils.AwsS3Url) {
+		return errors.New("transfer acceleration requires the default AWS endpoint and virtual-hosted style, unset AWS_ENDPOINT and AWS_FORCE_PATH")
+	}
+	if p := partitionOf(c.Region); c.Accelerate && !p.accelerate {
+		return fmt.Errorf("transfer acceleration is not available in the %s partition of region %s", p.name, c.Region)
+	}
+	if c.ACL != "" && !slices.Contains(types.ObjectCannedACL("").Values(), types.ObjectCannedACL(c.ACL)) {
+		return fmt.Errorf("invalid acl %q, supported values: %v", c.ACL, types.ObjectCannedACL("").Values())
+	}
+	if c.VersionID != "" && c.File == "" {
+		return errors.New("version id requires a single file, use --file")
+	}
+	if c.Since != "" {
+		if _, err := utils.ParseTime(c.Since); err != nil {
+			return err
+		}
+	}
+	if c.OlderThan != "" {
+		if _, err := utils.ParseDuration(c.OlderThan); err != nil {
+			return fmt.Errorf("invalid older-than duration: %w", err)
+		}
+	}
+	if err := c.validateObjectLock(); err != nil {
+		return err
+	}
+	if err := c.validateLifecycle(); err != nil {
+		return err
+	}
+	if err := c.validateProvider(); err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	if c.BandwidthSchedule != "" {
+		if _, err := parseBandwidthSchedule(c.BandwidthSchedule); err != nil {
+			return err
+		}
+	}
+	if c.FIPS {
+		if err := c.validateFIPS(); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(c.TimestampFormat, `/\`) {
+		return fmt.Errorf("invalid timestamp format %q, path separators are not allowed", c.TimestampFormat)
+	}
+	if c.Checksum != checksumNone && c.Checksum != "" && !slices.Contains(types.ChecksumAlgorithm("").Values(), types.ChecksumAlgorithm(c.Checksum)) {
+		return fmt.Errorf("invalid checksum algorithm %q, supported values: %v or %s", c.Checksum, types.ChecksumAlgorithm("").Values(), checksumNone)
+	}
+	if _, err := filepath.Match(c.Match, ""); err != nil {
+		return fmt.Errorf("invalid match pattern %q: %w", c.Match, err)
+	}
+	if (c.Match != "" || c.Newest) && (c.File != "" || c.Latest) {
+		return errors.New("--match and --newest cannot be used with --file or --latest")
+	}
+	if c.AsOf != "" {
+		if c.Newest || c.File != "" || c.Latest {
+			return errors.New("--as-of cannot be used with --newest, --file or --latest")
+		}
+		if _, err := c.parseAsOf(c.AsOf); err != nil {
+			return err
+		}
+	}
+	if c.NameTemplate != "" {
+		sample := NameTemplate{Base: "backup", Host: "host", Timestamp: "timestamp"}
+		if _, err := sample.expand(c.NameTemplate); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(normalizeForms, c.NormalizeUnicode) {
+		return fmt.Errorf("invalid unicode normalization %q, supported values: %v", c.NormalizeUnicode, normalizeForms)
+	}
+	if c.Inventory != "" {
+		if _, err := c.ParseRemote(c.Inventory); err != nil {
+			return fmt.Errorf("invalid inventory: %w", err)
+		}
+		if c.File != "" || c.Latest {
+			return errors.New("--inventory cannot be used with --file or --latest")
+		}
+		if isAzureRemote(c.Path) {
+			return errors.New("--inventory requires an S3 bucket")
+		}
+	}
+	if _, err := parseErrorThreshold(c.MaxErrors); err != nil {
+		return err
+	}
+	if c.CompressFiles && c.Compress {
+		return errors.New("--compress-files cannot be used with --compress")
+	}
+	if c.DeleteSource && !c.Verify {
+		return errors.New("--delete-source requires --verify")
+	}
+	if c.DeleteSource && c.Compress {
+		return errors.New("--delete-source cannot be used with --compress")
+	}
+	if _, err := parseUploadSample(c.VerifySample); err != nil {
+		return err
+	}
+	if c.VerifySample != "" && c.Compress {
+		return errors.New("--verify-sample cannot be used with --compress, use \"s3safe check\" to verify archives")
+	}
+	if c.Checkpoint != "" && c.Compress {
+		return errors.New("--checkpoint cannot be used with --compress")
+	}
+	if strings.HasPrefix(c.FailureReport, "s3://") {
+		if _, err := c.ParseRemote(c.FailureReport); err != nil {
+			return fmt.Errorf("invalid failure report: %w", err)
+		}
+	}
+	if strings.HasPrefix(c.ReportHTML, "s3://") {
+		if _, err := c.ParseRemote(c.ReportHTML); err != nil {
+			return fmt.Errorf("invalid HTML report: %w", err)
+		}
+	}
+	if strings.HasPrefix(c.PruneReport, "s3://") {
+		if _, err := c.ParseRemote(c.PruneReport); err != nil {
+			return fmt.Errorf("invalid prune report: %w", err)
+		}
+	}
+	if strings.HasPrefix(c.AuditLog, "s3://") {
+		if _, err := c.ParseRemote(c.AuditLog); err != nil {
+			return fmt.Errorf("invalid audit log: %w", err)
+		}
+	} else if c.AuditLog != "" && c.Bucket == "" {
+		return errors.New("--audit-log requires a bucket, or an s3://bucket/prefix URL")
+	}
+	if !slices.Contains(unsafeKeyStrategies, c.UnsafeKeys) {
+		return fmt.Errorf("invalid unsafe keys strategy %q, supported values: %v", c.UnsafeKeys, unsafeKeyStrategies)
+	}
+	if !slices.Contains(sanitizeStrategies, c.SanitizeNames) {
+		return fmt.Errorf("invalid sanitize strategy %q, supported values: %v", c.SanitizeNames, sanitizeStrategies)
+	}
+	if !slices.Contains(layouts, c.Layout) {
+		return fmt.Errorf("invalid layout %q, supported values: %v", c.Layout, layouts)
+	}
+	if !slices.Contains(unreadablePolicies, c.OnUnreadable) {
+		return fmt.Errorf("invalid --on-unreadable %q, supported values: %v", c.OnUnreadable, unreadablePolicies)
+	}
+	if c.ListShards < 0 || c.ListShards > maxListShards {
+		return fmt.Errorf("invalid --list-shards %d, it must be between 0 and %d", c.ListShards, maxListShards)
+	}
+	if c.ConnectTimeout < 0 || c.ResponseHeaderTimeout < 0 || c.MaxIdleConns < 0 {
+		return errors.New("--connect-timeout, --response-header-timeout and --max-idle-conns must be positive")
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("invalid --max-depth %d, it must be positive", c.MaxDepth)
+	}
+	if len(c.Require) > 0 && isAzureRemote(c.Dest) {
+		return errors.New("--require only checks S3 buckets")
+	}
+	if c.MPUCleanupDays < 0 {
+		return fmt.Errorf("invalid --ensure-mpu-cleanup-rule %d, it must be positive", c.MPUCleanupDays)
+	}
+	if c.MPUCleanupDays > 0 && isAzureRemote(c.Dest) {
+		return errors.New("--ensure-mpu-cleanup-rule only applies to S3 buckets")
+	}
+	for i, requirement := range c.Require {
+		c.Require[i] = strings.ToLower(strings.TrimSpace(requirement))
+		if !slices.Contains(bucketRequirements, c.Require[i]) {
+			return fmt.Errorf("invalid --require %q, supported values: %v", requirement, bucketRequirements)
+		}
+	}
+	if c.Format != "" && !slices.Contains(outputFormats, c.Format) {
+		return fmt.Errorf("invalid format %q, supported values: %v", c.Format, outputFormats)
+	}
+	if c.Snapshot != "" {
+		if _, err := parseSnapshotSpec(c.Snapshot); err != nil {
+			return err
+		}
+		if c.DeleteSource {
+			return errors.New("--delete-source cannot be used with --snapshot, snapshots are read-only")
+		}
+	}
+	if c.KeepLocal < 0 {
+		return fmt.Errorf("invalid --keep-local %d, it must be positive", c.KeepLocal)
+	}
+	if c.KeepLocal > 0 && !c.Compress {
+		return errors.New("--keep-local requires --compress")
+	}
+	if c.IncludeSpecial && !c.Compress {
+		return errors.New("--include-special requires --compress, special files cannot be uploaded as objects")
+	}
+	if c.ObfuscateKeys && c.KeySecret == "" {
+		return fmt.Errorf("--obfuscate-keys requires a key secret, set %s", utils.KeySecretEnv)
+	}
+	if _, _, err := c.transferLimits(); err != nil {
+		return err
+	}
+	if isDirectoryBucket(c.Bucket) {
+		if err := c.validateDirectoryBucket(); err != nil {
+			return err
+		}
+	}
+	if c.Tier != "" && !slices.Contains(types.Tier("").Values(), types.Tier(c.Tier)) {
+		return fmt.Errorf("invalid tier %q, supported values: %v", c.Tier, types.Tier("").Values())
+	}
+	return nil
+}
+
+func (c *Config) validateObjectLock() error {
+	if c.ObjectLockMode == "" {
+		if c.ObjectLockDays != 0 {
+			return errors.New("object lock days requires an object lock mode (GOVERNANCE or COMPLIANCE)")
+		}
+		if c.ObjectLockPolicy != "" {
+			return errors.New("object lock policy requires an object lock mode (GOVERNANCE or COMPLIANCE)")
+		}
+		return nil
+	}
+	if !slices.Contains(types.ObjectLockMode("").Values(), types.ObjectLockMode(c.ObjectLockMode)) {
+		return fmt.Errorf("invalid object lock mode %q, supported values: %v", c.ObjectLockMode, types.ObjectLockMode("").Values())
+	}
+	if c.ObjectLockPolicy != "" {
+		policy, err := parseObjectLockPolicy(c.ObjectLockPolicy)
+		if err != nil {
+			return err
+		}
+		if _, ok := policy[lockDaily]; !ok && c.ObjectLockDays <= 0 {
+			return errors.New("object lock policy without a daily tier requires object lock days for the other runs")
+		}
+		return nil
+	}
+	if c.ObjectLockDays <= 0 {
+		return errors.New("object lock mode requires a positive number of object lock days")
+	}
+	return nil
+}
+
+// now returns the current time in the configured timezone, local time by default
+func (c *Config) now() time.Time {
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Now().In(location)
+}
+
+// isDirectoryBucket reports whether the bucket is an S3 Express One Zone directory bucket,
+// named bucket-base-name--zone-id--x-s3. The SDK resolves its zonal endpoint and session credentials.
+func isDirectoryBucket(bucket string) bool {
+	return strings.HasSuffix(bucket, "--x-s3")
+}
+
+// validateDirectoryBucket rejects the features directory buckets do not support
+func (c *Config) validateDirectoryBucket() error {
+	if c.EndPoint != utils.AwsS3Url || c.ForcePath || c.Accelerate {
+		return errors.New("directory buckets require the default AWS endpoint and virtual-hosted style, unset AWS_ENDPOINT, AWS_FORCE_PATH and --accelerate")
+	}
+	if p := partitionOf(c.Region); !p.directoryBuckets {
+		return fmt.Errorf("directory buckets are not available in the %s partition of region %s", p.name, c.Region)
+	}
+	if c.StorageClass != "" && c.StorageClass != string(types.StorageClassExpressOnezone) {
+		return fmt.Errorf("directory buckets only support the %s storage class", types.StorageClassExpressOnezone)
+	}
+	if c.StorageClassRules != "" {
+		return errors.New("storage class rules are not supported by directory buckets")
+	}
+	if c.ACL != "" || c.ObjectLockMode != "" || c.LegalHold || c.VersionID != "" {
+		return errors.New("acl, object lock and versioning are not supported by directory buckets")
+	}
 	return nil
 }
 
-func (c *Config) validateS3Connection() error {
-	s3Storage, err := c.NewS3Storage()
+func isValidStorageClass(class string) bool {
+	return slices.Contains(types.StorageClass("").Values(), types.StorageClass(class))
+}
+
+func (c *Config) validateS3Connection(ctx context.Context) error {
+	s3Storage, err := c.NewS3Storage(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create S3 storage: %w", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to create S3 storage: %w", err))
 	}
 
-	exists, err := bucketExists(s3.New(s3Storage.session), c.Bucket)
+	exists, err := bucketExists(ctx, s3Storage.client, c.Bucket)
 	if err != nil {
-		return fmt.Errorf("failed to check bucket existence: %w", err)
+		return withExitCode(ExitConnection, fmt.Errorf("failed to check bucket existence: %w", err))
 	}
 	if !exists {
-		return fmt.Errorf("bucket %s does not exist", c.Bucket)
+		return withExitCode(ExitConfig, fmt.Errorf("bucket %s does not exist", c.Bucket))
 	}
 
 	return nil
 }
 
 // NewS3Storage creates a new S3Storage instance from the configuration
-func (c *Config) NewS3Storage() (*S3Storage, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String(c.Region),
-		Credentials:      credentials.NewStaticCredentials(c.KeyID, c.Secret, ""),
-		Endpoint:         aws.String(c.EndPoint),
-		DisableSSL:       aws.Bool(c.DisableSSL),
-		S3ForcePathStyle: aws.Bool(c.ForcePath),
+func (c *Config) NewS3Storage(ctx context.Context) (*S3Storage, error) {
+	httpClient, err := c.newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := c.transferLimiter()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(c.Region),
+		awsconfig.WithHTTPClient(httpClient),
+	}
+	if c.KeyID != "" {
+		options = append(options, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(c.KeyID, c.Secret, "")))
+	}
+	if c.DebugAWS {
+		options = append(options,
+			awsconfig.WithClientLogMode(aws.LogRequest|aws.LogResponse|aws.LogRetries),
+			awsconfig.WithLogger(newRedactingLogger()),
+		)
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// The default AWS endpoint is resolved per region by the SDK
+		if c.EndPoint != utils.AwsS3Url {
+			o.BaseEndpoint = aws.String(endpointURL(c.EndPoint, c.DisableSSL))
+		}
+		o.UsePathStyle = c.ForcePath
+		o.UseAccelerate = c.Accelerate
+		if c.FIPS {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
+		// Keep the SDK checksums opt-in, many S3-compatible providers reject its default CRC32 headers.
+		// Uploads to AWS carry the SHA-256 checksum of the storage instead.
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		o.APIOptions = append(o.APIOptions, awsmiddleware.AddUserAgentKeyValue(utils.AppName, utils.Version))
+		if c.DebugRequests {
+			o.APIOptions = append(o.APIOptions, addRequestLogging(loggerOrDefault(c.logger)))
+		}
+		if limiter != nil {
+			o.APIOptions = append(o.APIOptions, addBandwidthPause(limiter))
+		}
 	})
 
+	provider, err := c.provider()
+	if err != nil {
+		return nil, err
+	}
+	partSize, maxMemory, err := c.transferLimits()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 session: %w", err)
+		return nil, err
+	}
+	if partSize == 0 {
+		partSize = provider.PartSize
+	}
+	var checksum string
+	if c.EndPoint == utils.AwsS3Url {
+		checksum = defaultChecksum
 	}
 
 	return &S3Storage{
-		bucket:  c.Bucket,
-		session: sess,
+		bucket:      c.Bucket,
+		connection:  strings.Join([]string{c.Region, c.EndPoint, c.KeyID}, "|"),
+		client:      client,
+		partSize:    partSize,
+		concurrency: c.Concurrency,
+		maxMemory:   maxMemory,
+		listShards:  c.ListShards,
+		checksum:    checksum,
+		logger:      c.logger,
 	}, nil
 }
 
+// endpointURL adds a scheme to endpoints configured without one
+func endpointURL(endpoint string, disableSSL bool) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	if disableSSL {
+		return "http://" + endpoint
+	}
+	return "https://" + endpoint
+}
+
+// newHTTPClient builds the HTTP client used by the S3 session.
+// Without an explicit proxy, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored.
+func (c *Config) newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if c.Proxy != "" {
+		proxyURL, err := parseProxyURL(c.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	c.tuneTransport(transport)
+
+	limiter, err := c.transferLimiter()
+	if err != nil {
+		return nil, err
+	}
+	if limiter != nil {
+		return &http.Client{Transport: throttledTransport{base: transport, limiter: limiter}}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// tuneTransport applies the connection settings of the configuration, unset values keep the Go defaults
+func (c *Config) tuneTransport(transport *http.Transport) {
+	if c.ConnectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: c.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = c.ConnectTimeout
+	}
+	transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	if c.MaxIdleConns > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdleConns
+		transport.MaxIdleConns = max(transport.MaxIdleConns, c.MaxIdleConns)
+	}
+	transport.DisableKeepAlives = c.DisableKeepAlives
+	if c.DisableHTTP2 {
+		// A non-nil empty map disables the HTTP/2 upgrade negotiated with TLS
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if c.FIPS {
+		transport.TLSClientConfig = fipsTLSConfig(transport.TLSClientConfig)
+	}
+}
+
+// parseProxyURL parses and validates a proxy URL, supported schemes are http, https and socks5
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %q: %w", proxy, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q: missing host", proxy)
+	}
+	return proxyURL, nil
+}
+
+// sensitiveHeaders matches request headers that must never be written to debug logs
+var sensitiveHeaders = regexp.MustCompile(`(?im)^(Authorization|X-Amz-Security-Token|X-Amz-Server-Side-Encryption-Customer-Key):[^\r\n]*`)
+
+// newRedactingLogger returns an AWS logger writing SDK debug output to stderr with credentials redacted
+func newRedactingLogger() logging.Logger {
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	return logging.LoggerFunc(func(classification logging.Classification, format string, v ...interface{}) {
+		logger.Printf("%s %s", classification, redactHeaders(fmt.Sprintf(format, v...)))
+	})
+}
+
+// redactHeaders replaces the value of sensitive headers in an HTTP dump
+func redactHeaders(dump string) string {
+	return sensitiveHeaders.ReplaceAllString(dump, "$1: [REDACTED]")
+}
+
 func loadEnv(file string) {
 	slog.Info("Loading environment variables", "file", file)
 	if err := godotenv.Load(file); err != nil {
@@ -224,8 +1196,8 @@ func loadEnv(file string) {
 	}
 }
 
-func bucketExists(s3Client *s3.S3, bucket string) (bool, error) {
-	_, err := s3Client.HeadBucket(&s3.HeadBucketInput{
+func bucketExists(ctx context.Context, s3Client *s3.Client, bucket string) (bool, error) {
+	_, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(bucket),
 	})
 
@@ -233,8 +1205,8 @@ func bucketExists(s3Client *s3.S3, bucket string) (bool, error) {
 		return true, nil
 	}
 
-	var aErr awserr.RequestFailure
-	if errors.As(err, &aErr) && aErr.StatusCode() == http.StatusNotFound {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
 		return false, nil
 	}
 
@@ -0,0 +1,333 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// gigabyte is the unit of storage prices, providers bill binary gigabytes
+const gigabyte = 1 << 30
+
+// storagePrice is the list price of a storage class
+type storagePrice struct {
+	// Storage is the price per GB-month and Retrieval the price per GB read back
+	Storage   float64
+	Retrieval float64
+	// MinDays is the minimum storage duration, deleting an object earlier is billed as if it was kept
+	MinDays int
+	// MinObjectSize is the minimum billed size of an object
+	MinObjectSize int64
+	// Overhead is the metadata billed per object at the class price, StandardOverhead at the STANDARD price
+	Overhead         int64
+	StandardOverhead int64
+}
+
+// providerPricing lists the prices of the storage classes of a provider
+type providerPricing struct {
+	Currency string
+	Classes  map[string]storagePrice
+}
+
+// pricing holds the public list prices of the providers, in their first region. They are an estimate,
+// free tiers, request and transfer fees and negotiated prices are not included.
+var pricing = map[string]providerPricing{
+	// Amazon S3 us-east-1, archive retrievals use the Standard tier
+	"aws": {
+		Currency: "USD",
+		Classes: map[string]storagePrice{
+			"STANDARD":            {Storage: 0.023},
+			"REDUCED_REDUNDANCY":  {Storage: 0.024},
+			"INTELLIGENT_TIERING": {Storage: 0.023},
+			"STANDARD_IA":         {Storage: 0.0125, Retrieval: 0.01, MinDays: 30, MinObjectSize: 128 << 10},
+			"ONEZONE_IA":          {Storage: 0.01, Retrieval: 0.01, MinDays: 30, MinObjectSize: 128 << 10},
+			"GLACIER_IR":          {Storage: 0.004, Retrieval: 0.03, MinDays: 90, MinObjectSize: 128 << 10},
+			"GLACIER":             {Storage: 0.0036, Retrieval: 0.01, MinDays: 90, Overhead: 32 << 10, StandardOverhead: 8 << 10},
+			"DEEP_ARCHIVE":        {Storage: 0.00099, Retrieval: 0.02, MinDays: 180, Overhead: 32 << 10, StandardOverhead: 8 << 10},
+			"EXPRESS_ONEZONE":     {Storage: 0.11},
+		},
+	},
+	"r2": {
+		Currency: "USD",
+		Classes: map[string]storagePrice{
+			"STANDARD":    {Storage: 0.015},
+			"STANDARD_IA": {Storage: 0.01, Retrieval: 0.01, MinDays: 30},
+		},
+	},
+	// Wasabi bills objects deleted before 90 days and has no retrieval fees
+	"wasabi": {
+		Currency: "USD",
+		Classes: map[string]storagePrice{
+			"STANDARD": {Storage: 0.00699, MinDays: 90},
+		},
+	},
+	// DigitalOcean Spaces beyond the storage included in the subscription
+	"digitalocean": {
+		Currency: "USD",
+		Classes: map[string]storagePrice{
+			"STANDARD": {Storage: 0.02},
+		},
+	},
+	"scaleway": {
+		Currency: "EUR",
+		Classes: map[string]storagePrice{
+			"STANDARD":   {Storage: 0.0146},
+			"ONEZONE_IA": {Storage: 0.0075},
+			"GLACIER":    {Storage: 0.00254, Retrieval: 0.009},
+		},
+	},
+}
+
+// CostLine is the estimated cost of objects stored in a class
+type CostLine struct {
+	Class   string
+	Objects int
+	// Size is the total object size and Billed the size billed with minimum sizes and overheads
+	Size   int64
+	Billed int64
+	// Monthly is the storage cost per month and Retrieval the cost of reading every object once
+	Monthly   float64
+	Retrieval float64
+	MinDays   int
+}
+
+// CostEstimate compares the current storage cost of a prefix with the cost in other classes
+type CostEstimate struct {
+	Provider string
+	Currency string
+	// Current groups the objects by their storage class, Total sums it
+	Current []CostLine
+	Total   CostLine
+	// Estimates prices every object in each requested class
+	Estimates []CostLine
+}
+
+// Cost is the cobra command handler for cost
+func Cost(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	prices, err := config.pricing()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	classes, err := prices.classes(config.Class)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	config.Path = strings.TrimPrefix(filepath.ToSlash(config.Path), "/")
+
+	var objects []Item
+	for item, err := range s3Storage.Objects(cmd.Context(), config.Path, true) {
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if !item.IsDir {
+			objects = append(objects, item)
+		}
+	}
+	if len(objects) == 0 {
+		slog.Info("No objects found", "path", config.Path)
+		return nil
+	}
+
+	estimate := prices.estimate(objects, classes)
+	estimate.Provider = config.pricingProvider()
+	for _, line := range estimate.Estimates {
+		if config.RetentionDays > 0 && config.RetentionDays < line.MinDays {
+			slog.Warn("Retention is shorter than the minimum storage duration, objects are billed for the minimum duration",
+				"class", line.Class, "retentionDays", config.RetentionDays, "minDays", line.MinDays)
+		}
+	}
+	return writeCostEstimate(os.Stdout, estimate)
+}
+
+// pricingProvider returns the provider whose prices are used, Amazon S3 when none is set
+func (c *Config) pricingProvider() string {
+	if c.Provider == "" {
+		return "aws"
+	}
+	return c.Provider
+}
+
+// pricing returns the prices of the configured provider, with --price-per-gb applied
+func (c *Config) pricing() (providerPricing, error) {
+	prices, ok := pricing[c.pricingProvider()]
+	if c.PricePerGB < 0 {
+		return providerPricing{}, errors.New("--price-per-gb must be positive")
+	}
+	if c.PricePerGB == 0 {
+		if !ok {
+			return providerPricing{}, fmt.Errorf("no prices for provider %s, set --price-per-gb, priced providers: %v", c.pricingProvider(), slices.Sorted(maps.Keys(pricing)))
+		}
+		return prices, nil
+	}
+	if !ok {
+		// Self-hosted storage is priced as a single class
+		prices = providerPricing{Classes: map[string]storagePrice{"STANDARD": {}}}
+	}
+	custom := providerPricing{Currency: prices.Currency, Classes: make(map[string]storagePrice, len(prices.Classes))}
+	for class, price := range prices.Classes {
+		price.Storage = c.PricePerGB
+		custom.Classes[class] = price
+	}
+	return custom, nil
+}
+
+// classes returns the priced classes of a comma-separated list, every class of the provider when empty
+func (p providerPricing) classes(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return p.sortedClasses(), nil
+	}
+	var classes []string
+	for _, class := range strings.Split(list, ",") {
+		class = strings.ToUpper(strings.TrimSpace(class))
+		if _, ok := p.Classes[class]; !ok {
+			return nil, fmt.Errorf("no price for storage class %q, priced classes: %v", class, p.sortedClasses())
+		}
+		if !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	return classes, nil
+}
+
+// sortedClasses returns the classes from the most to the least expensive to store
+func (p providerPricing) sortedClasses() []string {
+	classes := slices.Collect(maps.Keys(p.Classes))
+	slices.SortFunc(classes, func(a, b string) int {
+		if p.Classes[a].Storage != p.Classes[b].Storage {
+			if p.Classes[a].Storage > p.Classes[b].Storage {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return classes
+}
+
+// add prices an object stored in class
+func (p providerPricing) add(line *CostLine, class string, size int64) {
+	price, ok := p.Classes[class]
+	if !ok {
+		// Unpriced classes are estimated at the STANDARD price
+		price = p.Classes["STANDARD"]
+	}
+	billed := max(size, price.MinObjectSize) + price.Overhead
+	line.Objects++
+	line.Size += size
+	line.Billed += billed + price.StandardOverhead
+	line.Monthly += float64(billed)/gigabyte*price.Storage + float64(price.StandardOverhead)/gigabyte*p.Classes["STANDARD"].Storage
+	line.Retrieval += float64(size) / gigabyte * price.Retrieval
+	line.MinDays = max(line.MinDays, price.MinDays)
+}
+
+// estimate prices the objects in their current class and in each of the classes
+func (p providerPricing) estimate(objects []Item, classes []string) CostEstimate {
+	estimate := CostEstimate{Currency: p.Currency, Total: CostLine{Class: "TOTAL"}}
+	current := make(map[string]*CostLine)
+	estimates := make([]CostLine, len(classes))
+	for i, class := range classes {
+		estimates[i].Class = class
+	}
+	for _, object := range objects {
+		class := object.StorageClass
+		if class == "" {
+			class = "STANDARD"
+		}
+		line, ok := current[class]
+		if !ok {
+			line = &CostLine{Class: class}
+			current[class] = line
+		}
+		p.add(line, class, object.Size)
+		p.add(&estimate.Total, class, object.Size)
+		for i, class := range classes {
+			p.add(&estimates[i], class, object.Size)
+		}
+	}
+	for _, class := range slices.Sorted(maps.Keys(current)) {
+		estimate.Current = append(estimate.Current, *current[class])
+	}
+	estimate.Estimates = estimates
+	return estimate
+}
+
+// writeCostEstimate prints the current cost per class and the estimates
+func writeCostEstimate(out io.Writer, estimate CostEstimate) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	// Costs below one are printed with four decimals, so small prefixes are not shown as free
+	money := func(format string, v float64) string {
+		if v != 0 && math.Abs(v) < 1 {
+			format = strings.Replace(format, ".2f", ".4f", 1)
+		}
+		return strings.TrimSpace(fmt.Sprintf(format+" %s", v, estimate.Currency))
+	}
+	row := func(line CostLine, extra string) {
+		minDays := "-"
+		if line.MinDays > 0 {
+			minDays = strconv.Itoa(line.MinDays)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s%s\n", line.Class, line.Objects, goutils.ConvertBytes(uint64(line.Size)),
+			goutils.ConvertBytes(uint64(line.Billed)), money("%.2f", line.Monthly), money("%.2f", line.Retrieval), minDays, extra)
+	}
+	header := "CLASS\tOBJECTS\tSIZE\tBILLED SIZE\tSTORAGE/MONTH\tRETRIEVAL\tMIN DAYS"
+
+	_, _ = fmt.Fprintf(w, "Current storage, %s list prices:\n", estimate.Provider)
+	_, _ = fmt.Fprintln(w, header)
+	for _, line := range estimate.Current {
+		row(line, "")
+	}
+	if len(estimate.Current) > 1 {
+		row(estimate.Total, "")
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Estimate with every object in a single class:")
+	_, _ = fmt.Fprintln(w, header+"\tCHANGE/MONTH")
+	for _, line := range estimate.Estimates {
+		row(line, "\t"+money("%+.2f", line.Monthly-estimate.Total.Monthly))
+	}
+	return w.Flush()
+}
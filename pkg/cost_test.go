@@ -0,0 +1,137 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCostEstimate(t *testing.T) {
+	prices := pricing["aws"]
+	objects := []Item{
+		{Key: "backups/a.tar.gz", Size: gigabyte},
+		{Key: "backups/b.tar.gz", Size: gigabyte, StorageClass: "STANDARD"},
+		{Key: "backups/small.json", Size: 1024, StorageClass: "STANDARD_IA"},
+	}
+	estimate := prices.estimate(objects, []string{"STANDARD_IA", "DEEP_ARCHIVE"})
+
+	if len(estimate.Current) != 2 || estimate.Current[0].Class != "STANDARD" || estimate.Current[0].Objects != 2 {
+		t.Fatalf("current = %+v", estimate.Current)
+	}
+	// Objects smaller than 128KiB are billed as 128KiB in STANDARD_IA
+	if ia := estimate.Current[1]; ia.Size != 1024 || ia.Billed != 128<<10 || ia.MinDays != 30 {
+		t.Errorf("STANDARD_IA = %+v", ia)
+	}
+	if estimate.Total.Objects != 3 || estimate.Total.Size != 2*gigabyte+1024 {
+		t.Errorf("total = %+v", estimate.Total)
+	}
+
+	ia := estimate.Estimates[0]
+	want := 2*0.0125 + float64(128<<10)/gigabyte*0.0125
+	if math.Abs(ia.Monthly-want) > 1e-9 {
+		t.Errorf("STANDARD_IA monthly = %f, want %f", ia.Monthly, want)
+	}
+	if math.Abs(ia.Retrieval-float64(2*gigabyte+1024)/gigabyte*0.01) > 1e-9 {
+		t.Errorf("STANDARD_IA retrieval = %f", ia.Retrieval)
+	}
+	// Deep Archive bills 32KiB of metadata per object at its price and 8KiB at the STANDARD price
+	deep := estimate.Estimates[1]
+	if deep.Billed != 2*gigabyte+1024+3*(40<<10) {
+		t.Errorf("DEEP_ARCHIVE billed = %d", deep.Billed)
+	}
+	if deep.Monthly >= ia.Monthly || deep.MinDays != 180 {
+		t.Errorf("DEEP_ARCHIVE = %+v", deep)
+	}
+}
+
+func TestCostClasses(t *testing.T) {
+	prices := pricing["aws"]
+	classes, err := prices.classes("standard_ia, GLACIER,STANDARD_IA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(classes, ",") != "STANDARD_IA,GLACIER" {
+		t.Errorf("classes = %v", classes)
+	}
+	if _, err := prices.classes("COLD"); err == nil {
+		t.Error("expected an error for an unpriced class")
+	}
+	all, _ := prices.classes("")
+	if len(all) != len(prices.Classes) || all[0] != "EXPRESS_ONEZONE" || all[len(all)-1] != "DEEP_ARCHIVE" {
+		t.Errorf("all classes = %v", all)
+	}
+}
+
+func TestCostPricing(t *testing.T) {
+	cfg := &Config{}
+	prices, err := cfg.pricing()
+	if err != nil || prices.Currency != "USD" {
+		t.Fatalf("default pricing = %+v, %v", prices, err)
+	}
+
+	cfg.Provider = "minio"
+	if _, err := cfg.pricing(); err == nil {
+		t.Error("expected an error for a provider without prices")
+	}
+	cfg.PricePerGB = 0.005
+	prices, err = cfg.pricing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices.Classes["STANDARD"].Storage != 0.005 {
+		t.Errorf("custom pricing = %+v", prices)
+	}
+
+	cfg.Provider = "aws"
+	prices, _ = cfg.pricing()
+	if p := prices.Classes["GLACIER"]; p.Storage != 0.005 || p.MinDays != 90 {
+		t.Errorf("overridden GLACIER price = %+v", p)
+	}
+	if pricing["aws"].Classes["GLACIER"].Storage == 0.005 {
+		t.Error("the built-in prices must not be modified")
+	}
+	cfg.PricePerGB = -1
+	if _, err := cfg.pricing(); err == nil {
+		t.Error("expected an error for a negative price")
+	}
+}
+
+func TestWriteCostEstimate(t *testing.T) {
+	prices := pricing["aws"]
+	estimate := prices.estimate([]Item{{Key: "a", Size: 100 * gigabyte}}, []string{"STANDARD_IA"})
+	estimate.Provider = "aws"
+	var out bytes.Buffer
+	if err := writeCostEstimate(&out, estimate); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"aws list prices", "STANDARD  ", "2.30 USD", "STANDARD_IA", "1.25 USD", "-1.05 USD", "30"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+// logLevel is the level of the command line logs, lowered to debug by --debug-requests
+var logLevel = new(slog.LevelVar)
+
+// enableDebugLogs writes debug logs of the command line, with the default logger or the JSON logs of --k8s
+func enableDebugLogs() {
+	logLevel.Set(slog.LevelDebug)
+	slog.SetLogLoggerLevel(slog.LevelDebug)
+}
+
+// addRequestLogging logs every S3 API call at debug level once it completes, after its retries: the
+// operation, bucket, key or prefix, duration, attempts, HTTP status, request ID and host ID.
+// Requests and responses are not dumped, so credentials and signatures never reach the logs.
+func addRequestLogging(logger *slog.Logger) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("s3safe:RequestLogging",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if !logger.Enabled(ctx, slog.LevelDebug) {
+					return next.HandleInitialize(ctx, in)
+				}
+				start := time.Now()
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				attrs := []any{
+					"operation", awsmiddleware.GetOperationName(ctx),
+					"bucket", inputField(in.Parameters, "Bucket"),
+				}
+				if key := inputField(in.Parameters, "Key"); key != "" {
+					attrs = append(attrs, "key", key)
+				} else if prefix := inputField(in.Parameters, "Prefix"); prefix != "" {
+					attrs = append(attrs, "prefix", prefix)
+				}
+				attrs = append(attrs, "duration", time.Since(start))
+				if attempts, ok := retry.GetAttemptResults(metadata); ok && len(attempts.Results) > 1 {
+					attrs = append(attrs, "attempts", len(attempts.Results))
+				}
+				attrs = append(attrs, responseAttrs(metadata, err)...)
+				if err != nil {
+					attrs = append(attrs, "error", err)
+				}
+				logger.DebugContext(ctx, "S3 request", attrs...)
+				return out, metadata, err
+			}), middleware.After)
+	}
+}
+
+// responseAttrs returns the HTTP status, request ID and host ID of a response, read from the error of
+// failed calls
+func responseAttrs(metadata middleware.Metadata, err error) []any {
+	var status int
+	if response, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+		status = response.StatusCode
+	}
+	requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+	hostID, _ := s3.GetHostIDMetadata(metadata)
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status = respErr.HTTPStatusCode()
+		requestID = respErr.ServiceRequestID()
+	}
+	var hostErr interface{ ServiceHostID() string }
+	if errors.As(err, &hostErr) {
+		hostID = hostErr.ServiceHostID()
+	}
+
+	var attrs []any
+	if status != 0 {
+		attrs = append(attrs, "status", status)
+	}
+	if requestID != "" {
+		attrs = append(attrs, "requestID", requestID)
+	}
+	if hostID != "" {
+		attrs = append(attrs, "hostID", hostID)
+	}
+	return attrs
+}
+
+// inputField returns a string field of an operation input, such as the Bucket or Key of s3.PutObjectInput
+func inputField(params any, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return ""
+	}
+	if value, ok := field.Interface().(*string); ok {
+		return aws.ToString(value)
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amz-request-id", "REQ"+r.Method)
+		w.Header().Set("x-amz-id-2", "HOST"+r.Method)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDSECRETKEYID", "secret-access-key", ""),
+		APIOptions:   []func(*middleware.Stack) error{addRequestLogging(logger)},
+	})
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("backups/a.txt"),
+		Body:   strings.NewReader("hello"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("backups/missing.txt"),
+	}); err == nil {
+		t.Fatal("Expected the missing object to fail")
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per call, got %q", logs.String())
+	}
+	for _, want := range []string{"operation=PutObject", "bucket=bucket", "key=backups/a.txt", "status=200", "requestID=REQPUT", "hostID=HOSTPUT", "duration="} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("Expected %q in %q", want, lines[0])
+		}
+	}
+	for _, want := range []string{"operation=HeadObject", "key=backups/missing.txt", "status=404", "requestID=REQHEAD", "hostID=HOSTHEAD", "error="} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("Expected %q in %q", want, lines[1])
+		}
+	}
+	if strings.Contains(logs.String(), "AKIDSECRETKEYID") || strings.Contains(logs.String(), "secret-access-key") {
+		t.Errorf("Expected credentials to stay out of the logs, got %q", logs.String())
+	}
+}
+
+func TestInputField(t *testing.T) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String("bucket"), Prefix: aws.String("backups/")}
+	if got := inputField(input, "Prefix"); got != "backups/" {
+		t.Errorf("Prefix = %q", got)
+	}
+	if got := inputField(input, "Key"); got != "" {
+		t.Errorf("Key = %q, want empty", got)
+	}
+	if got := inputField(nil, "Key"); got != "" {
+		t.Errorf("Key of nil = %q, want empty", got)
+	}
+}
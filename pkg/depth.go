@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"iter"
+	"path/filepath"
+	"strings"
+)
+
+// depthOf returns the number of path elements of a slash separated path relative to the walked directory,
+// 1 for the files directly in it
+func depthOf(rel string) int {
+	return strings.Count(strings.Trim(rel, "/"), "/") + 1
+}
+
+// beyondMaxDepth reports whether the files of a local directory below root are deeper than --max-depth
+func (c *Config) beyondMaxDepth(root, dir string) bool {
+	if c.MaxDepth == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	return depthOf(filepath.ToSlash(rel)) >= c.MaxDepth
+}
+
+// limitDepth drops the objects more than maxDepth levels below prefix, 0 keeps them all
+func limitDepth(objects iter.Seq2[Item, error], prefix string, maxDepth int) iter.Seq2[Item, error] {
+	if maxDepth == 0 {
+		return objects
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return func(yield func(Item, error) bool) {
+		for item, err := range objects {
+			if err == nil && depthOf(strings.TrimPrefix(item.Key, prefix)) > maxDepth {
+				continue
+			}
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLimitDepth(t *testing.T) {
+	objects := []Item{
+		{Key: "backups/a.txt"},
+		{Key: "backups/db/", IsDir: true},
+		{Key: "backups/db/b.txt"},
+		{Key: "backups/db/2025/c.txt"},
+	}
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{0, []string{"backups/a.txt", "backups/db/", "backups/db/b.txt", "backups/db/2025/c.txt"}},
+		{1, []string{"backups/a.txt", "backups/db/"}},
+		{2, []string{"backups/a.txt", "backups/db/", "backups/db/b.txt"}},
+	}
+	for _, tt := range tests {
+		items, err := collectItems(limitDepth(itemSeq(objects), "backups", tt.maxDepth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, item := range items {
+			keys = append(keys, item.Key)
+		}
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("max depth %d: keys = %v, want %v", tt.maxDepth, keys, tt.want)
+		}
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "db/b.txt", "db/2025/c.txt"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{1, []string{"a.txt"}},
+		{2, []string{"a.txt", "db/b.txt"}},
+		{3, []string{"a.txt", "db/2025/c.txt", "db/b.txt"}},
+	}
+	for _, tt := range tests {
+		bm := &BackupManager{config: &Config{Path: root, MaxDepth: tt.maxDepth}}
+		var files []string
+		for file, err := range walkFilesAfter(root, true, "", bm.excluded()) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !file.IsDir {
+				files = append(files, filepath.ToSlash(file.Key))
+			}
+		}
+		slices.Sort(files)
+		if !slices.Equal(files, tt.want) {
+			t.Errorf("max depth %d: files = %v, want %v", tt.maxDepth, files, tt.want)
+		}
+		if bm.result.Skipped != 0 {
+			t.Errorf("max depth %d: skipped = %d, want 0", tt.maxDepth, bm.result.Skipped)
+		}
+	}
+}
+
+func TestValidateMaxDepth(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", MaxDepth: 2})
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	cfg.MaxDepth = -1
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected a negative --max-depth to be rejected")
+	}
+}
@@ -0,0 +1,360 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Uploader uploads local files to a backup destination
+type Uploader interface {
+	Upload(ctx context.Context, path string, target string, opts UploadOptions) error
+}
+
+// Storage is a backup storage backend that can also be restored from
+type Storage interface {
+	Uploader
+	Download(ctx context.Context, path string, dest string, opts DownloadOptions) error
+	List(ctx context.Context, path string, recursive bool) ([]Item, error)
+	// Objects iterates over the items of List as they are listed
+	Objects(ctx context.Context, path string, recursive bool) iter.Seq2[Item, error]
+}
+
+// itemSeq returns a sequence over items
+func itemSeq(items []Item) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// collectItems reads all the items of a sequence, the items read so far are returned with the first error
+func collectItems(seq iter.Seq2[Item, error]) ([]Item, error) {
+	items := make([]Item, 0)
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// destination is a backup target, the main bucket or a mirror.
+// A destination that fails is skipped for the rest of the run.
+type destination struct {
+	name     string
+	uploader Uploader
+	// prefix is where files are uploaded, under the date directory of --layout date, base the prefix
+	// without it where the latest.json marker is written
+	prefix string
+	base   string
+	err    error
+}
+
+// dir returns the date directory of --layout date under the base prefix, empty with the flat layout
+func (d *destination) dir() string {
+	return strings.TrimPrefix(strings.TrimPrefix(d.prefix, d.base), "/")
+}
+
+// newDestinations returns the main destination followed by the configured mirrors
+func newDestinations(ctx context.Context, config *Config) ([]*destination, error) {
+	var main *destination
+	if isAzureRemote(config.Dest) {
+		d, err := newAzureDestination(config, config.Dest)
+		if err != nil {
+			return nil, err
+		}
+		main = d
+	} else {
+		s3Storage, err := config.NewS3Storage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+		}
+		main = &destination{
+			name:     fmt.Sprintf("s3://%s/%s", config.Bucket, config.Dest),
+			uploader: s3Storage,
+			prefix:   config.Dest,
+		}
+	}
+	destinations := []*destination{main}
+
+	for _, mirror := range config.Mirrors {
+		if strings.HasPrefix(mirror, "file://") {
+			dir, err := parseLocalRemote(mirror)
+			if err != nil {
+				return nil, err
+			}
+			destinations = append(destinations, &destination{
+				name:     mirror,
+				uploader: LocalStorage{},
+				prefix:   dir,
+			})
+			continue
+		}
+		if isAzureRemote(mirror) {
+			d, err := newAzureDestination(config, mirror)
+			if err != nil {
+				return nil, err
+			}
+			destinations = append(destinations, d)
+			continue
+		}
+
+		remote, err := config.ParseRemote(mirror)
+		if err != nil {
+			return nil, err
+		}
+		storage, err := newRemoteStorage(ctx, remote)
+		if err != nil {
+			return nil, fmt.Errorf("mirror %s: %w", remote, err)
+		}
+		destinations = append(destinations, &destination{
+			name:     remote.String(),
+			uploader: storage,
+			prefix:   remote.Prefix,
+		})
+	}
+	// The date is taken once, a backup running past midnight stays in the directory of its start
+	for _, d := range destinations {
+		d.base = d.prefix
+		d.prefix = config.layoutPrefix(d.prefix)
+	}
+	return destinations, nil
+}
+
+func newAzureDestination(config *Config, raw string) (*destination, error) {
+	remote, err := parseAzureRemote(raw)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := config.NewAzureStorage(remote)
+	if err != nil {
+		return nil, fmt.Errorf("destination %s: %w", remote, err)
+	}
+	return &destination{
+		name:     remote.String(),
+		uploader: storage,
+		prefix:   remote.Prefix,
+	}, nil
+}
+
+// upload uploads a file to every healthy destination, in parallel when enabled.
+// With a single destination the upload error is returned, otherwise failures are recorded per destination
+// and an error is returned only once every destination has failed.
+func (bm *BackupManager) upload(ctx context.Context, sourcePath, key string) error {
+	size := int64(-1)
+	info, statErr := os.Stat(sourcePath)
+	if statErr == nil {
+		size = info.Size()
+	}
+	opts := bm.uploadOptions(sourcePath)
+	uploadPath, uploadKey, cleanup, err := bm.compressedUpload(sourcePath, key, &opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if bm.config.SkipIdentical {
+		// The SHA-256 is stored with the object so the next runs compare it exactly
+		sum, err := fileSHA256(uploadPath)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %w", key, err)
+		}
+		if opts.Metadata == nil {
+			opts.Metadata = make(map[string]string)
+		}
+		opts.Metadata[sha256MetadataKey] = sum
+	}
+	bm.report().FileStarted(key, size)
+	uploadedKey, err := bm.uploadWith(ctx, uploadPath, uploadKey, opts)
+	identical := errors.Is(err, errIdenticalObject)
+	unverified := errors.Is(err, errUnverifiable)
+	if identical || unverified {
+		err = nil
+	}
+	bm.report().FileCompleted(key, size, err)
+	if err != nil {
+		return err
+	}
+	if identical {
+		bm.result.Skipped++
+		return nil
+	}
+	bm.result.Files++
+	bm.result.Bytes += max(size, 0)
+	bm.audit.add(objectKey(bm.destinations[0].prefix, uploadedKey))
+	bm.fileUploaded(ctx, sourcePath, objectKey(bm.destinations[0].prefix, uploadedKey), size)
+	if statErr != nil {
+		return nil
+	}
+	if bm.config.DeleteSource {
+		if unverified {
+			bm.log().Warn("Keeping source file, the upload could not be verified with a checksum", "file", sourcePath)
+			return nil
+		}
+		bm.deleteSource(sourcePath, info)
+		return nil
+	}
+	bm.sampleUpload(sourcePath, uploadedKey, uploadPath != sourcePath, info)
+	return nil
+}
+
+// deleteSource removes a file uploaded and verified on every destination, a file modified
+// during the upload is kept
+func (bm *BackupManager) deleteSource(sourcePath string, before os.FileInfo) {
+	for _, d := range bm.destinations {
+		if d.err != nil {
+			bm.log().Warn("Keeping source file, a destination failed", "file", sourcePath, "destination", d.name)
+			return
+		}
+	}
+	after, err := os.Stat(sourcePath)
+	if err != nil || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		bm.log().Warn("Keeping source file, it changed during the upload", "file", sourcePath)
+		return
+	}
+	if err := os.Remove(sourcePath); err != nil {
+		bm.log().Warn("Unable to delete source file", "file", sourcePath, "error", err)
+		return
+	}
+	bm.log().Info("Deleted source file", "file", sourcePath)
+	bm.result.Deleted++
+}
+
+// uploadWith uploads a file to every healthy destination with the given object settings,
+// the key of the objects relative to the destination prefixes is returned.
+// errIdenticalObject is returned when --skip-identical skipped the upload on every destination, and
+// errUnverifiable when an upload succeeded but only its size could be verified.
+func (bm *BackupManager) uploadWith(ctx context.Context, sourcePath, key string, opts UploadOptions) (string, error) {
+	return bm.uploadUnder(ctx, sourcePath, key, opts, func(d *destination) string { return d.prefix })
+}
+
+// uploadUnder uploads a file as uploadWith does, under the prefix of each destination returned by prefix
+func (bm *BackupManager) uploadUnder(ctx context.Context, sourcePath, key string, opts UploadOptions, prefix func(d *destination) string) (string, error) {
+	key = normalizeUnicode(key, bm.config.NormalizeUnicode)
+	var metadata map[string]string
+	var err error
+	if bm.keys != nil {
+		key, metadata, err = bm.obfuscatedKey(key, opts.Metadata)
+	} else {
+		key, metadata, err = bm.safeKey(key, opts.Metadata)
+	}
+	if err != nil {
+		return "", err
+	}
+	opts.Metadata = metadata
+	var wg sync.WaitGroup
+	var attempted int
+	var skipped atomic.Int32
+	var unverified atomic.Bool
+	for _, d := range bm.destinations {
+		if d.err != nil {
+			continue
+		}
+		attempted++
+		run := func(d *destination) {
+			if bm.identicalUpload(ctx, d, sourcePath, objectKey(prefix(d), key), opts) {
+				skipped.Add(1)
+				return
+			}
+			err := d.uploader.Upload(ctx, sourcePath, objectKey(prefix(d), key), opts)
+			if err == nil && bm.config.Verify {
+				err = verifyUpload(ctx, d.uploader, sourcePath, objectKey(prefix(d), key))
+				if errors.Is(err, errUnverifiable) {
+					bm.log().Warn("Upload verified by size only", "file", sourcePath, "destination", d.name, "reason", err)
+					unverified.Store(true)
+					err = nil
+				}
+			}
+			if err != nil {
+				d.err = err
+				if len(bm.destinations) > 1 {
+					bm.log().Error("Destination failed, skipping it for the rest of the backup", "destination", d.name, "error", err)
+				}
+			}
+		}
+		if bm.config.Parallel {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(d)
+			}()
+			continue
+		}
+		run(d)
+	}
+	wg.Wait()
+
+	if attempted > 0 && int(skipped.Load()) == attempted {
+		return key, errIdenticalObject
+	}
+	if len(bm.destinations) == 1 && bm.destinations[0].err != nil {
+		return key, bm.destinations[0].err
+	}
+	for _, d := range bm.destinations {
+		if d.err == nil {
+			if unverified.Load() {
+				return key, errUnverifiable
+			}
+			return key, nil
+		}
+	}
+	return "", errors.New("all destinations failed")
+}
+
+// verifyUpload checks the uploaded object against the local file
+func verifyUpload(ctx context.Context, uploader Uploader, path, target string) error {
+	verifier, ok := uploader.(UploadVerifier)
+	if !ok {
+		return fmt.Errorf("verification of %q is not supported by the destination", target)
+	}
+	return verifier.VerifyUpload(ctx, path, target)
+}
+
+// destinationsError reports the destinations that failed during the backup
+func (bm *BackupManager) destinationsError() error {
+	if len(bm.destinations) == 1 {
+		return nil
+	}
+	var errs []error
+	for _, d := range bm.destinations {
+		if d.err != nil {
+			errs = append(errs, fmt.Errorf("destination %s: %w", d.name, d.err))
+			continue
+		}
+		bm.log().Info("Destination completed successfully", "destination", d.name)
+	}
+	return errors.Join(errs...)
+}
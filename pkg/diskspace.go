@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// decompressionFactor estimates the extracted size of an archive relative to its size
+	decompressionFactor = 3
+	// spaceHeadroomPercent is kept free in addition to the restored data
+	spaceHeadroomPercent = 5
+)
+
+var errFreeSpaceUnsupported = errors.New("free space detection is not supported on this platform")
+
+// ObjectSizer is implemented by storages that read the size of a single object without listing its parent
+type ObjectSizer interface {
+	Size(ctx context.Context, key string) (int64, error)
+}
+
+// requiredSpace returns the disk space needed to restore the files, including decompression of archives
+func requiredSpace(files []Item, exclude []string, decompress bool) uint64 {
+	var total uint64
+	for _, file := range files {
+		total += fileSpace(file, exclude, decompress)
+	}
+	return withHeadroom(total)
+}
+
+// fileSpace returns the disk space needed to restore a single file, without headroom
+func fileSpace(file Item, exclude []string, decompress bool) uint64 {
+	if file.IsDir || file.Size <= 0 || slices.Contains(exclude, filepath.Base(file.Key)) {
+		return 0
+	}
+	size := uint64(file.Size)
+	if decompress && isArchiveName(file.Key) {
+		return size + size*decompressionFactor
+	}
+	return size
+}
+
+// withHeadroom adds the free space kept in addition to the restored data
+func withHeadroom(total uint64) uint64 {
+	return total + total*spaceHeadroomPercent/100
+}
+
+// isArchiveName reports whether a key has a gzip archive extension
+func isArchiveName(key string) bool {
+	return strings.HasSuffix(key, ".gz") || strings.HasSuffix(key, ".tgz")
+}
+
+// checkDiskSpace fails when the destination filesystem cannot hold the files to restore
+func checkDiskSpace(dest string, files []Item, exclude []string, decompress bool) error {
+	return checkRequiredSpace(dest, requiredSpace(files, exclude, decompress))
+}
+
+// checkRequiredSpace fails when the destination filesystem has less than required bytes free
+func checkRequiredSpace(dest string, required uint64) error {
+	if required == 0 {
+		return nil
+	}
+	free, err := freeSpace(dest)
+	if err != nil {
+		slog.Warn("Unable to check free disk space", "dest", dest, "error", err)
+		return nil
+	}
+	if free < required {
+		return fmt.Errorf("not enough free space in %q: %s required, %s available, use --skip-space-check to restore anyway",
+			dest, goutils.ConvertBytes(required), goutils.ConvertBytes(free))
+	}
+	slog.Debug("Free disk space checked", "dest", dest, "required", goutils.ConvertBytes(required), "available", goutils.ConvertBytes(free))
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequiredSpace(t *testing.T) {
+	files := []Item{
+		{Key: "backups/data.tar.gz", Size: 1000},
+		{Key: "backups/notes.txt", Size: 100},
+		{Key: "backups/skip.txt", Size: 5000},
+		{Key: "backups/dir/", IsDir: true},
+	}
+	if got := requiredSpace(files, []string{"skip.txt"}, false); got != 1155 {
+		t.Errorf("Expected 1155 bytes, got %d", got)
+	}
+	if got := requiredSpace(files, []string{"skip.txt"}, true); got != 4305 {
+		t.Errorf("Expected 4305 bytes with decompression, got %d", got)
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if err := checkDiskSpace(dir, []Item{{Key: "small.txt", Size: 1}}, nil, false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := checkDiskSpace(dir, []Item{{Key: "huge.bin", Size: 1 << 62}}, nil, false); err == nil {
+		t.Errorf("Expected a huge restore to be rejected")
+	}
+}
+
+// listingStorage is a Storage that only lists a fixed set of objects
+type listingStorage struct {
+	Storage
+	items []Item
+}
+
+func (l listingStorage) Objects(context.Context, string, bool) iter.Seq2[Item, error] {
+	return itemSeq(l.items)
+}
+
+func TestCheckTotalSpace(t *testing.T) {
+	dir := t.TempDir()
+	free, err := freeSpace(dir)
+	if err != nil {
+		t.Skipf("Free space detection unavailable: %v", err)
+	}
+	// Each file fits on its own, both together do not
+	items := []Item{
+		{Key: "backups/a.bin", Size: int64(free/2 + free/10), ETag: "a"},
+		{Key: "backups/b.bin", Size: int64(free/2 + free/10), ETag: "b"},
+	}
+	rm := &RestoreManager{
+		config:  &Config{Path: "backups", Dest: dir},
+		storage: listingStorage{items: items},
+		journal: &restoreJournal{done: map[string]journalEntry{}},
+	}
+	if err := checkDiskSpace(dir, items[:1], nil, false); err != nil {
+		t.Fatalf("A single file must fit: %v", err)
+	}
+	if err := rm.checkTotalSpace(context.Background()); err == nil {
+		t.Errorf("Expected the summed size to be rejected")
+	}
+	rm.journal.done["backups/a.bin"] = journalEntry{Key: "backups/a.bin", ETag: "a", Size: items[0].Size}
+	if err := rm.checkTotalSpace(context.Background()); err != nil {
+		t.Errorf("Files already restored must not be counted: %v", err)
+	}
+}
+
+func TestCheckFileSpace(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/bucket/backups/huge.bin" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "4611686018427387904")
+	}))
+	defer server.Close()
+
+	cfg := testConfig("backups", server.URL)
+	cfg.Dest = t.TempDir()
+	rm, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.checkFileSpace(context.Background(), "backups/huge.bin"); err == nil || !strings.Contains(err.Error(), "not enough free space") {
+		t.Errorf("Expected the size of the object to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// doctorTimeout bounds each network check
+	doctorTimeout = 10 * time.Second
+	// maxClockSkew is the skew S3 rejects requests at, clocks are reported from warnClockSkew
+	maxClockSkew  = 15 * time.Minute
+	warnClockSkew = time.Minute
+	// warnCertificateExpiry reports endpoint certificates expiring soon
+	warnCertificateExpiry = 14 * 24 * time.Hour
+)
+
+// Statuses of doctor checks
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+// DoctorCheck is the result of a doctor check, Fix suggests how to solve a warning or a failure
+type DoctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+	// code is the exit code of a failed check
+	code int
+}
+
+// doctor diagnoses the configuration and the connection to the S3 endpoint
+type doctor struct {
+	config *Config
+	checks []DoctorCheck
+	// now returns the local time compared with the endpoint clock
+	now func() time.Time
+}
+
+// Doctor is the cobra command handler for doctor
+func Doctor(cmd *cobra.Command) error {
+	d := &doctor{config: NewConfig(cmd), now: time.Now}
+	d.run(cmd.Context())
+	return d.report(os.Stdout)
+}
+
+func (d *doctor) add(check DoctorCheck) {
+	d.checks = append(d.checks, check)
+}
+
+// failed reports whether a check named name failed
+func (d *doctor) failed(name string) bool {
+	for _, c := range d.checks {
+		if c.Name == name && c.Status == doctorFail {
+			return true
+		}
+	}
+	return false
+}
+
+// run performs the checks, a check is skipped when the checks it depends on failed
+func (d *doctor) run(ctx context.Context) {
+	d.checkConfig()
+	if d.failed("configuration") {
+		return
+	}
+	endpoint, err := d.endpoint()
+	if err != nil {
+		d.add(DoctorCheck{Name: "endpoint", Status: doctorFail, Detail: err.Error(), Fix: "set AWS_ENDPOINT to a URL such as https://s3.example.com", code: ExitConfig})
+		return
+	}
+	d.checkDNS(ctx, endpoint)
+	if d.failed("dns") {
+		return
+	}
+	d.checkEndpoint(ctx, endpoint)
+	if d.failed("endpoint") {
+		return
+	}
+	storage, err := d.config.NewS3Storage(ctx)
+	if err != nil {
+		d.add(DoctorCheck{Name: "credentials", Status: doctorFail, Detail: err.Error(), code: ExitConfig})
+		return
+	}
+	d.checkCredentials(ctx, storage)
+	if d.failed("credentials") {
+		return
+	}
+	d.checkBucket(ctx, storage)
+}
+
+// checkConfig reports missing settings and invalid options
+func (d *doctor) checkConfig() {
+	c := d.config
+	if isAzureRemote(c.Dest) || isAzureRemote(c.Path) {
+		d.add(DoctorCheck{Name: "configuration", Status: doctorSkip, Detail: "Azure Blob Storage locations are not diagnosed"})
+		return
+	}
+	if err := c.validateRequiredFields(); err != nil {
+		d.add(DoctorCheck{Name: "configuration", Status: doctorFail, Detail: err.Error(),
+			Fix: "set the missing environment variables, in the environment or in the --env-file file", code: ExitConfig})
+		return
+	}
+	if err := c.validateOptions(); err != nil {
+		d.add(DoctorCheck{Name: "configuration", Status: doctorFail, Detail: err.Error(), code: ExitConfig})
+		return
+	}
+	detail := fmt.Sprintf("region %s, bucket %s, endpoint %s", c.Region, c.Bucket, c.EndPoint)
+	if c.Provider != "" {
+		detail += ", provider " + c.Provider
+	}
+	d.add(DoctorCheck{Name: "configuration", Status: doctorOK, Detail: detail})
+}
+
+// endpoint returns the URL of the S3 endpoint, the regional endpoint for Amazon S3
+func (d *doctor) endpoint() (*url.URL, error) {
+	c := d.config
+	raw := endpointURL(c.EndPoint, c.DisableSSL)
+	if c.EndPoint == utils.AwsS3Url {
+		raw = regionalEndpoint(c.Region)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", c.EndPoint, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: missing host", c.EndPoint)
+	}
+	return u, nil
+}
+
+// checkDNS resolves the endpoint host, proxies resolve it themselves
+func (d *doctor) checkDNS(ctx context.Context, endpoint *url.URL) {
+	if proxy := d.proxy(endpoint); proxy != "" {
+		d.add(DoctorCheck{Name: "dns", Status: doctorSkip, Detail: "resolved by the proxy " + proxy})
+		return
+	}
+	host := endpoint.Hostname()
+	if net.ParseIP(host) != nil {
+		d.add(DoctorCheck{Name: "dns", Status: doctorOK, Detail: host + " is an IP address"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		d.add(DoctorCheck{Name: "dns", Status: doctorFail, Detail: err.Error(),
+			Fix: "check the host name of AWS_ENDPOINT and the DNS servers of this machine", code: ExitConnection})
+		return
+	}
+	d.add(DoctorCheck{Name: "dns", Status: doctorOK, Detail: fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))})
+}
+
+// proxy returns the proxy used to reach the endpoint, empty for direct connections
+func (d *doctor) proxy(endpoint *url.URL) string {
+	if d.config.Proxy != "" {
+		return d.config.Proxy
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: endpoint})
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return proxy.Redacted()
+}
+
+// checkEndpoint connects to the endpoint, verifies its certificate and compares its clock with the local clock
+func (d *doctor) checkEndpoint(ctx context.Context, endpoint *url.URL) {
+	client, err := d.config.newHTTPClient()
+	if err != nil {
+		d.add(DoctorCheck{Name: "endpoint", Status: doctorFail, Detail: err.Error(), Fix: "check --proxy or S3SAFE_PROXY", code: ExitConfig})
+		return
+	}
+	if bundle := os.Getenv("AWS_CA_BUNDLE"); bundle != "" {
+		if err := addCABundle(client, bundle); err != nil {
+			d.add(DoctorCheck{Name: "endpoint", Status: doctorFail, Detail: err.Error(), Fix: "set AWS_CA_BUNDLE to a PEM file", code: ExitConfig})
+			return
+		}
+	}
+	client.Timeout = doctorTimeout
+	// Anonymous requests are usually denied, any response proves the endpoint is reachable
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint.String(), nil)
+	if err != nil {
+		d.add(DoctorCheck{Name: "endpoint", Status: doctorFail, Detail: err.Error(), code: ExitConfig})
+		return
+	}
+	start := d.now()
+	resp, err := client.Do(req)
+	if err != nil {
+		d.add(DoctorCheck{Name: "endpoint", Status: doctorFail, Detail: err.Error(), Fix: connectionFix(err), code: ExitConnection})
+		return
+	}
+	_ = resp.Body.Close()
+
+	if resp.TLS == nil {
+		d.add(DoctorCheck{Name: "tls", Status: doctorWarn, Detail: "the endpoint is reached over plain HTTP",
+			Fix: "use an https:// endpoint, credentials are signed but data is not encrypted in transit"})
+	} else {
+		cert := resp.TLS.PeerCertificates[0]
+		detail := fmt.Sprintf("%s, certificate of %s valid until %s", tls.VersionName(resp.TLS.Version), cert.Subject.CommonName, cert.NotAfter.Format(time.DateOnly))
+		if cert.NotAfter.Sub(d.now()) < warnCertificateExpiry {
+			d.add(DoctorCheck{Name: "tls", Status: doctorWarn, Detail: detail, Fix: "renew the certificate of the endpoint"})
+		} else {
+			d.add(DoctorCheck{Name: "tls", Status: doctorOK, Detail: detail})
+		}
+	}
+	d.add(DoctorCheck{Name: "endpoint", Status: doctorOK, Detail: fmt.Sprintf("%s answered %s", endpoint.Host, resp.Status)})
+	d.checkClock(resp.Header.Get("Date"), start)
+}
+
+// checkClock compares the Date header of the endpoint with the local time of the request
+func (d *doctor) checkClock(date string, local time.Time) {
+	remote, err := http.ParseTime(date)
+	if err != nil {
+		d.add(DoctorCheck{Name: "clock", Status: doctorSkip, Detail: "the endpoint sent no Date header"})
+		return
+	}
+	// The Date header has a one second resolution
+	skew := local.Sub(remote).Truncate(time.Second)
+	detail := fmt.Sprintf("local clock is %s ahead of the endpoint", skew)
+	if skew < 0 {
+		detail = fmt.Sprintf("local clock is %s behind the endpoint", -skew)
+	}
+	fix := "synchronize the clock with NTP, e.g. timedatectl set-ntp true, S3 rejects requests signed with a skewed clock (RequestTimeTooSkewed, SignatureDoesNotMatch)"
+	switch {
+	case skew.Abs() >= maxClockSkew:
+		d.add(DoctorCheck{Name: "clock", Status: doctorFail, Detail: detail, Fix: fix, code: ExitConnection})
+	case skew.Abs() >= warnClockSkew:
+		d.add(DoctorCheck{Name: "clock", Status: doctorWarn, Detail: detail, Fix: fix})
+	default:
+		d.add(DoctorCheck{Name: "clock", Status: doctorOK, Detail: detail})
+	}
+}
+
+// checkCredentials resolves the credentials from the configuration or the default credential chain
+func (d *doctor) checkCredentials(ctx context.Context, storage *S3Storage) {
+	creds, err := storage.client.Options().Credentials.Retrieve(ctx)
+	if err != nil {
+		d.add(DoctorCheck{Name: "credentials", Status: doctorFail, Detail: err.Error(),
+			Fix: "set AWS_ACCESS_KEY_ID and AWS_SECRET_KEY, or configure a profile or an instance role", code: ExitConfig})
+		return
+	}
+	d.add(DoctorCheck{Name: "credentials", Status: doctorOK, Detail: fmt.Sprintf("access key %s from %s", maskKeyID(creds.AccessKeyID), creds.Source)})
+}
+
+// maskKeyID keeps the first and last characters of an access key ID
+func maskKeyID(id string) string {
+	if len(id) <= 8 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:4] + strings.Repeat("*", len(id)-8) + id[len(id)-4:]
+}
+
+// checkBucket checks that the bucket exists, is accessible and is in the configured region
+func (d *doctor) checkBucket(ctx context.Context, storage *S3Storage) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	out, err := storage.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(d.config.Bucket)})
+	if err != nil {
+		check := DoctorCheck{Name: "bucket", Status: doctorFail, Detail: err.Error(), code: ExitConnection}
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) {
+			region := respErr.Response.Header.Get("X-Amz-Bucket-Region")
+			switch {
+			case respErr.HTTPStatusCode() == http.StatusNotFound:
+				check.Detail = fmt.Sprintf("bucket %s does not exist", d.config.Bucket)
+				check.Fix = "check AWS_BUCKET, and AWS_FORCE_PATH=true for endpoints without virtual-hosted buckets"
+				check.code = ExitConfig
+			case region != "" && region != d.config.Region:
+				check.Fix = fmt.Sprintf("the bucket is in %s, set AWS_REGION=%s", region, region)
+				check.code = ExitConfig
+			case respErr.HTTPStatusCode() == http.StatusForbidden:
+				check.Fix = "check AWS_ACCESS_KEY_ID and AWS_SECRET_KEY, the credentials need s3:ListBucket on the bucket"
+			}
+		}
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "SignatureDoesNotMatch" {
+			check.Fix = "check AWS_SECRET_KEY and the local clock"
+		}
+		d.add(check)
+		return
+	}
+	region := aws.ToString(out.BucketRegion)
+	if region != "" && region != d.config.Region && d.config.EndPoint == utils.AwsS3Url {
+		d.add(DoctorCheck{Name: "bucket", Status: doctorWarn, Detail: fmt.Sprintf("bucket %s is in %s, not %s", d.config.Bucket, region, d.config.Region),
+			Fix: fmt.Sprintf("set AWS_REGION=%s to avoid redirects", region)})
+		return
+	}
+	detail := fmt.Sprintf("bucket %s is accessible", d.config.Bucket)
+	if region != "" {
+		detail += " in " + region
+	}
+	d.add(DoctorCheck{Name: "bucket", Status: doctorOK, Detail: detail})
+}
+
+// connectionFix suggests a fix for a failed connection to the endpoint
+func connectionFix(err error) string {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &hostnameErr):
+		return "the certificate does not match the endpoint host, check AWS_ENDPOINT"
+	case errors.As(err, &unknownAuthority), errors.As(err, &certErr):
+		return "the certificate is not trusted, set AWS_CA_BUNDLE to the CA certificate of the endpoint"
+	case errors.As(err, &recordErr), strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return "the endpoint does not serve HTTPS, use an http:// endpoint or set AWS_DISABLE_SSL=true"
+	case errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err):
+		return "the endpoint did not answer, check firewalls and set --proxy or HTTPS_PROXY when a proxy is required"
+	case errors.As(err, &opErr):
+		return "check the host and port of AWS_ENDPOINT and that the service is running"
+	}
+	return "check AWS_ENDPOINT and the network"
+}
+
+// addCABundle trusts the certificates of a PEM file in addition to the system ones
+func addCABundle(client *http.Client, path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read AWS_CA_BUNDLE: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificate found in AWS_CA_BUNDLE %s", path)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return nil
+}
+
+// report prints the checks and returns an error with the exit code of the first failure
+func (d *doctor) report(out io.Writer) error {
+	failures := 0
+	code := ExitOK
+	for _, c := range d.checks {
+		_, _ = fmt.Fprintf(out, "[%-4s] %-13s %s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+		if c.Fix != "" {
+			_, _ = fmt.Fprintf(out, "       %-13s fix: %s\n", "", c.Fix)
+		}
+		if c.Status == doctorFail {
+			failures++
+			if code == ExitOK {
+				code = c.code
+			}
+		}
+	}
+	if failures > 0 {
+		return withExitCode(code, fmt.Errorf("%d checks failed", failures))
+	}
+	_, _ = fmt.Fprintln(out, "No problem found")
+	return nil
+}
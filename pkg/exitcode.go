@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// Process exit codes, see ExitCode
+const (
+	// ExitOK is returned when every file was processed
+	ExitOK = 0
+	// ExitFailure is returned when the operation failed
+	ExitFailure = 1
+	// ExitConfig is returned for invalid flags, environment variables or a missing bucket
+	ExitConfig = 2
+	// ExitConnection is returned when the storage is unreachable or rejects the credentials
+	ExitConnection = 3
+	// ExitPartial is returned when some files or destinations failed and the others succeeded
+	ExitPartial = 4
+)
+
+// authErrorCodes are the S3 error codes of rejected credentials
+var authErrorCodes = []string{
+	"AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken",
+	"InvalidToken", "TokenRefreshRequired", "AuthorizationHeaderMalformed",
+}
+
+// exitError attaches a process exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode attaches an exit code to err, nil stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit code of an error returned by the package
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if isConnectionError(err) {
+		return ExitConnection
+	}
+	return ExitFailure
+}
+
+// isConnectionError reports whether err is a network failure or rejected credentials
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && slices.Contains(authErrorCodes, apiErr.ErrorCode()) {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && isAuthStatus(respErr.HTTPStatusCode()) {
+		return true
+	}
+	var azureErr *azcore.ResponseError
+	return errors.As(err, &azureErr) && isAuthStatus(azureErr.StatusCode)
+}
+
+func isAuthStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
@@ -0,0 +1,86 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jkaninda/s3safe/utils"
+)
+
+// fipsMode reports whether the FIPS 140-3 module of Go is enabled, with GODEBUG=fips140=on
+var fipsMode = fips140.Enabled()
+
+// validateFIPS rejects the settings --fips cannot honor: the FIPS 140-3 module of Go must be enabled,
+// and requests go to the FIPS endpoints of AWS S3 only, resolved by the SDK for the region
+func (c *Config) validateFIPS() error {
+	if !fipsMode {
+		return errors.New("--fips requires the Go FIPS 140-3 module, run with GODEBUG=fips140=on")
+	}
+	if isAzureRemote(c.Path) || isAzureRemote(c.Dest) {
+		return errors.New("--fips supports AWS S3 only, Azure Blob Storage has no FIPS endpoint")
+	}
+	if c.Provider != "" && c.Provider != "aws" {
+		return fmt.Errorf("--fips supports AWS S3 only, not provider %s", c.Provider)
+	}
+	if c.EndPoint != utils.AwsS3Url {
+		return errors.New("--fips resolves the FIPS endpoint of the region, unset AWS_ENDPOINT")
+	}
+	if p := partitionOf(c.Region); !p.fips {
+		return fmt.Errorf("the %s partition of region %s has no FIPS endpoint", p.name, c.Region)
+	}
+	if c.DisableSSL {
+		return errors.New("--fips requires TLS, unset AWS_DISABLE_SSL")
+	}
+	if c.Accelerate {
+		return errors.New("transfer acceleration has no FIPS endpoint, unset --accelerate")
+	}
+	if isDirectoryBucket(c.Bucket) {
+		return fmt.Errorf("directory bucket %q has no FIPS endpoint", c.Bucket)
+	}
+	// The other algorithms are CRCs or SHA-1, SHA-256 is the approved hash of uploads
+	if c.Checksum != "" && c.Checksum != string(types.ChecksumAlgorithmSha256) {
+		return fmt.Errorf("--fips requires the %s checksum, got %s", types.ChecksumAlgorithmSha256, c.Checksum)
+	}
+	return nil
+}
+
+// md5Allowed reports whether MD5 may be computed, MD5 is not approved in FIPS 140-3 mode so ETags and
+// Content-MD5 are not compared with local files, SHA-256 checksums are
+func md5Allowed() bool {
+	return !fipsMode
+}
+
+// fipsTLSConfig restricts the connections to TLS 1.2 or later, Go negotiates approved cipher suites and
+// curves only in FIPS 140-3 mode
+func fipsTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.MinVersion = max(config.MinVersion, tls.VersionTLS12)
+	return config
+}
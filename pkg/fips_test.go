@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jkaninda/s3safe/utils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// withFIPSMode runs a test as if the FIPS 140-3 module of Go was enabled
+func withFIPSMode(t *testing.T) {
+	enabled := fipsMode
+	fipsMode = true
+	t.Cleanup(func() { fipsMode = enabled })
+}
+
+func TestValidateFIPS(t *testing.T) {
+	base := Config{FIPS: true, EndPoint: utils.AwsS3Url, Bucket: "backups", Path: "/data", Dest: "backups/job"}
+	if fipsMode {
+		t.Skip("the FIPS 140-3 module is enabled")
+	}
+	if err := base.validateFIPS(); err == nil || !strings.Contains(err.Error(), "GODEBUG=fips140=on") {
+		t.Fatalf("Expected --fips to require the FIPS module, got %v", err)
+	}
+
+	withFIPSMode(t)
+	if err := base.validateFIPS(); err != nil {
+		t.Fatalf("Expected the AWS configuration to be valid, got %v", err)
+	}
+	for name, change := range map[string]func(c *Config){
+		"azure":            func(c *Config) { c.Dest = "azblob://container/backups" },
+		"provider":         func(c *Config) { c.Provider = "minio" },
+		"endpoint":         func(c *Config) { c.EndPoint = "https://minio.example.com" },
+		"disable ssl":      func(c *Config) { c.DisableSSL = true },
+		"accelerate":       func(c *Config) { c.Accelerate = true },
+		"directory bucket": func(c *Config) { c.Bucket = "backups--use1-az4--x-s3" },
+		"checksum":         func(c *Config) { c.Checksum = "CRC32" },
+		"china":            func(c *Config) { c.Region = "cn-north-1" },
+	} {
+		c := base
+		change(&c)
+		if err := c.validateFIPS(); err == nil {
+			t.Errorf("Expected %s to be rejected with --fips", name)
+		}
+	}
+
+	remote, err := base.ParseRemote("s3://mirror/backups?region=us-gov-west-1")
+	if err != nil || remote.Config.Region != "us-gov-west-1" {
+		t.Fatalf("Expected an AWS mirror to be valid, got %v", err)
+	}
+	if _, err := base.ParseRemote("s3://mirror/backups?endpoint=minio.example.com"); err == nil {
+		t.Error("Expected a mirror endpoint to be rejected with --fips")
+	}
+}
+
+func TestFIPSDigest(t *testing.T) {
+	withFIPSMode(t)
+	w := newDigestWriter()
+	_, _ = w.Write([]byte("hello"))
+	if err := w.check(objectDigest{Size: 5, MD5: "not compared"}); err != nil {
+		t.Errorf("Expected MD5 to be ignored in FIPS mode, got %v", err)
+	}
+	if err := w.check(objectDigest{Size: 5, SHA256: "0000"}); err == nil {
+		t.Error("Expected a SHA-256 mismatch to fail in FIPS mode")
+	}
+	if digest := storedDigest(&s3.HeadObjectOutput{ETag: aws.String(`"5d41402abc4b2a76b9719d911017c592"`)}); digest.MD5 != "" {
+		t.Errorf("Expected the ETag to be ignored in FIPS mode, got %q", digest.MD5)
+	}
+}
+
+func TestNewS3StorageFIPS(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	c := &Config{FIPS: true, EndPoint: utils.AwsS3Url, Region: "us-east-1", Bucket: "backups", KeyID: "key", Secret: "secret"}
+	storage, err := c.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := storage.client.Options().EndpointOptions.UseFIPSEndpoint; state != aws.FIPSEndpointStateEnabled {
+		t.Errorf("Expected the FIPS endpoint to be enabled, got %v", state)
+	}
+	client, err := c.newHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config := client.Transport.(*http.Transport).TLSClientConfig; config == nil || config.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Expected TLS 1.2 or later, got %+v", config)
+	}
+}
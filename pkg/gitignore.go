@@ -0,0 +1,161 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreFile is the ignore file read in every directory by --respect-gitignore
+const gitignoreFile = ".gitignore"
+
+// ignoreRule is a pattern of an ignore file, in the gitignore syntax
+type ignoreRule struct {
+	// pattern is slash separated, without its leading and trailing slashes
+	pattern string
+	// negate re-includes the matched paths, for patterns starting with !
+	negate bool
+	// dirOnly matches only directories, for patterns ending with /
+	dirOnly bool
+	// anchored matches the path relative to the ignore file directory, for patterns containing a slash,
+	// other patterns match the base name at any depth
+	anchored bool
+}
+
+// parseIgnoreRules reads the rules of an ignore file, blank lines and comments are skipped
+func parseIgnoreRules(content string) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimSuffix(scanner.Text(), "\r"), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// match reports whether the rule matches a slash separated path relative to the ignore file directory
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, ** matches any number of directories
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return len(name) > 0
+			}
+			for i := range len(name) + 1 {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreMatcher matches paths below root against the ignore files of their directories.
+// The rules of deeper directories take precedence, and the last matching rule of a file wins.
+type ignoreMatcher struct {
+	root string
+	name string
+	// rules caches the rules of every directory read so far
+	rules map[string][]ignoreRule
+}
+
+// newIgnoreMatcher returns a matcher of the ignore files with the given name below root
+func newIgnoreMatcher(root, name string) *ignoreMatcher {
+	return &ignoreMatcher{root: root, name: name, rules: make(map[string][]ignoreRule)}
+}
+
+// dirRules returns the rules of the ignore file of a directory, none when it has no readable ignore file
+func (m *ignoreMatcher) dirRules(dir string) []ignoreRule {
+	rules, ok := m.rules[dir]
+	if !ok {
+		if content, err := os.ReadFile(filepath.Join(dir, m.name)); err == nil {
+			rules = parseIgnoreRules(string(content))
+		}
+		m.rules[dir] = rules
+	}
+	return rules
+}
+
+// match reports whether a path below root is ignored
+func (m *ignoreMatcher) match(filePath string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, filePath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	dir, dirRel := m.root, ""
+	for _, segment := range strings.Split(rel, "/") {
+		relToDir := strings.TrimPrefix(rel, dirRel)
+		for _, rule := range m.dirRules(dir) {
+			if rule.match(relToDir, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		dir, dirRel = filepath.Join(dir, segment), dirRel+segment+"/"
+	}
+	return ignored
+}
@@ -0,0 +1,121 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnoreRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		isDir   bool
+		want    bool
+	}{
+		{"*.o", "main.o", false, true},
+		{"*.o", "src/lib/main.o", false, true},
+		{"*.o", "main.go", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/vendor", "vendor", true, true},
+		{"/vendor", "src/vendor", true, false},
+		{"docs/*.html", "docs/index.html", false, true},
+		{"docs/*.html", "src/docs/index.html", false, false},
+		{"**/tmp", "a/b/tmp", true, true},
+		{"**/tmp", "tmp", true, true},
+		{"logs/**", "logs/app/today.log", false, true},
+		{"logs/**", "logs", true, false},
+		{"a/**/z", "a/z", false, true},
+		{"a/**/z", "a/b/c/z", false, true},
+		{`\#notes`, "#notes", false, true},
+	}
+	for _, tt := range tests {
+		rules := parseIgnoreRules(tt.pattern)
+		if len(rules) != 1 {
+			t.Fatalf("%q parsed as %+v", tt.pattern, rules)
+		}
+		if got := rules[0].match(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestParseIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules("# comment\n\n*.log  \r\n!keep.log\n/\n")
+	want := []ignoreRule{{pattern: "*.log"}, {pattern: "keep.log", negate: true}}
+	if !slices.Equal(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+}
+
+func TestWalkRespectsGitignore(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":          "*.log\n!keep.log\nbin/\n/vendor\n",
+		"main.go":             "package main",
+		"app.log":             "log",
+		"keep.log":            "log",
+		"bin/app":             "binary",
+		"vendor/lib/lib.go":   "package lib",
+		"web/.gitignore":      "dist\n!debug.log\n",
+		"web/index.js":        "index",
+		"web/debug.log":       "log",
+		"web/dist/app.js":     "app",
+		"web/vendor/local.js": "local",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bm := &BackupManager{config: &Config{Path: root, RespectGitignore: true}}
+	var walked []string
+	for file, err := range walkFilesAfter(root, true, "", bm.excluded()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !file.IsDir {
+			walked = append(walked, filepath.ToSlash(file.Key))
+		}
+	}
+	slices.Sort(walked)
+	want := []string{".gitignore", "keep.log", "main.go", "web/.gitignore", "web/debug.log", "web/index.js", "web/vendor/local.js"}
+	if !slices.Equal(walked, want) {
+		t.Errorf("walked = %v, want %v", walked, want)
+	}
+	// app.log, bin and vendor at the root, web/dist
+	if bm.result.Skipped != 4 {
+		t.Errorf("skipped = %d, want 4", bm.result.Skipped)
+	}
+}
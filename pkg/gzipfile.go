@@ -0,0 +1,207 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Objects gzipped by --compress-files get the .gz suffix and the x-amz-meta-compression metadata,
+// restores decompress them to their original name
+const (
+	compressionMetadataKey = "compression"
+	compressionGzip        = "gzip"
+	gzipSuffix             = ".gz"
+)
+
+// gzipFile compresses a file to a temporary file, the caller removes it
+func gzipFile(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(src *os.File) {
+		_ = src.Close()
+	}(src)
+
+	tmp, err := os.CreateTemp("", "s3safe-*"+gzipSuffix)
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(tmp)
+	gz.Name = filepath.Base(path)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// compressedUpload gzips a file uploaded with --compress-files, it returns the path and key to upload
+// and a cleanup function. Files already gzipped, or already compressed with NoRecompress, are uploaded unchanged.
+func (bm *BackupManager) compressedUpload(sourcePath, key string, opts *UploadOptions) (string, string, func(), error) {
+	if !bm.config.CompressFiles || isCompressed(sourcePath) || bm.config.NoRecompress && isIncompressibleFile(sourcePath) {
+		return sourcePath, key, func() {}, nil
+	}
+	compressed, err := gzipFile(sourcePath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+	opts.ContentType = "application/gzip"
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]string)
+	}
+	opts.Metadata[compressionMetadataKey] = compressionGzip
+	return compressed, key + gzipSuffix, func() {
+		_ = os.Remove(compressed)
+	}, nil
+}
+
+// isGzipObject reports whether an object was gzipped by --compress-files, only .gz keys are inspected
+func (rm *RestoreManager) isGzipObject(ctx context.Context, key string) bool {
+	reader, ok := rm.storage.(MetadataReader)
+	if !ok || !strings.HasSuffix(key, gzipSuffix) {
+		return false
+	}
+	metadata, err := reader.Metadata(ctx, key)
+	if err != nil {
+		rm.log().Warn("Unable to read object metadata", "file", key, "error", err)
+		return false
+	}
+	return metadata[compressionMetadataKey] == compressionGzip
+}
+
+// restoredKey returns the key of the restored file, without the encoding, the obfuscation or the .gz suffix
+// added by the backup
+func (rm *RestoreManager) restoredKey(ctx context.Context, key string) (string, bool) {
+	if rm.keys != nil {
+		return rm.revealedKey(ctx, key)
+	}
+	restored := rm.decodedKey(ctx, key)
+	if rm.isGzipObject(ctx, key) {
+		return strings.TrimSuffix(restored, gzipSuffix), true
+	}
+	return restored, false
+}
+
+// download downloads an object to dest, gzipped objects are decompressed.
+// It returns errFileExists when dest exists and opts.Force is not set.
+func (rm *RestoreManager) download(ctx context.Context, key, dest string, opts DownloadOptions, gzipped bool) error {
+	if gzipped {
+		return rm.downloadGzip(ctx, key, dest, opts)
+	}
+	return rm.storage.Download(ctx, key, dest, opts)
+}
+
+// downloadGzip downloads an object gzipped by --compress-files and decompresses it to dest
+func (rm *RestoreManager) downloadGzip(ctx context.Context, key, dest string, opts DownloadOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(dest); err == nil {
+			return errFileExists
+		}
+	}
+	tmp := dest + ".s3safe" + gzipSuffix
+	downloadOpts := opts
+	downloadOpts.Force = true
+	if err := rm.storage.Download(ctx, key, tmp, downloadOpts); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+	if err := gunzipFile(tmp, dest, opts.PreservePermissions); err != nil {
+		_ = os.Remove(dest)
+		return fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	if opts.SELinux {
+		if label, err := fileLabel(tmp); err == nil {
+			restoreLabel(dest, label)
+		}
+	}
+	return nil
+}
+
+// gunzipFile decompresses src to dest, the mode and modification time restored on src are applied to dest
+func gunzipFile(src, dest string, permissions bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func(in *os.File) {
+		_ = in.Close()
+	}(in)
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, gz); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if permissions {
+		if err := os.Chmod(dest, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if uid, gid, ok := fileOwner(info); ok && os.Geteuid() == 0 {
+			if err := os.Lchown(dest, uid, gid); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Chtimes(dest, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,183 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGzipFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "data.txt")
+	content := bytes.Repeat([]byte("s3safe "), 1000)
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := gzipFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(compressed)
+	if !isCompressed(compressed) {
+		t.Fatal("Expected a gzip file")
+	}
+
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(compressed, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "restored.txt")
+	if err := gunzipFile(compressed, dest, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Unexpected restored content")
+	}
+	if info, err := os.Stat(dest); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("Expected the modification time to be applied, got %v", info.ModTime())
+	}
+}
+
+func TestBackupCompressFiles(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	uploaded := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uploaded[strings.TrimPrefix(r.URL.Path, "/bucket/")] = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte("hello"))
+	_ = gz.Close()
+	if err := os.WriteFile(filepath.Join(src, "b.log.gz"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.CompressFiles = true
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	header, ok := uploaded["backups/a.txt.gz"]
+	if !ok || header.Get("X-Amz-Meta-Compression") != compressionGzip {
+		t.Errorf("Expected a.txt to be uploaded gzipped, got %v", uploaded)
+	}
+	if header, ok := uploaded["backups/b.log.gz"]; !ok || header.Get("X-Amz-Meta-Compression") != "" {
+		t.Errorf("Expected the gzip file to be uploaded unchanged, got %v", uploaded)
+	}
+
+	cfg.Compress = true
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected --compress-files with --compress to be rejected, got %v", err)
+	}
+}
+
+func TestRestoreCompressedFile(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte("hello"))
+	_ = gz.Close()
+	body := buf.Bytes()
+	sum := md5.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/backups/a.txt.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-Amz-Meta-Compression", compressionGzip)
+		w.Header().Set("X-Amz-Meta-Mtime", "1704164645")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer server.Close()
+
+	dest := t.TempDir()
+	cfg := testConfig("backups", server.URL)
+	cfg.Dest = dest
+	cfg.File = "a.txt.gz"
+	cfg.SkipSpaceCheck = true
+	rm, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "a.txt"))
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("Expected the decompressed file, got %q: %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "a.txt.gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected no compressed file in the destination")
+	}
+	if info, err := os.Stat(filepath.Join(dest, "a.txt")); err != nil || info.ModTime().Unix() != 1704164645 {
+		t.Errorf("Expected the modification time to be restored")
+	}
+
+	// The existing file is skipped, not counted as restored
+	rm, err = NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := rm.Restore(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 0 || result.Bytes != 0 || result.Skipped != 1 {
+		t.Errorf("result = %+v, want 0 files and 1 skipped", result)
+	}
+}
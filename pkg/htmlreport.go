@@ -0,0 +1,312 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"html/template"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// maxReportFiles bounds the transfers listed by an HTML report, the totals include every file
+	maxReportFiles = 1000
+	// chartBuckets is the number of bars of the throughput chart
+	chartBuckets = 60
+	chartWidth   = 720
+	chartHeight  = 160
+)
+
+// runRecorder records the transfers of a run for its HTML report
+type runRecorder struct {
+	mu        sync.Mutex
+	started   map[string]time.Time
+	transfers []transfer
+}
+
+// transfer is a file uploaded or downloaded by a run
+type transfer struct {
+	Key      string
+	Size     int64
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+func newRunRecorder() *runRecorder {
+	return &runRecorder{started: make(map[string]time.Time)}
+}
+
+func (r *runRecorder) FileStarted(key string, _ int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started[key] = time.Now()
+}
+
+func (r *runRecorder) FileCompleted(key string, size int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start, ok := r.started[key]
+	if !ok {
+		start = time.Now()
+	}
+	delete(r.started, key)
+	r.transfers = append(r.transfers, transfer{Key: key, Size: size, Start: start, Duration: time.Since(start), Err: err})
+}
+
+// reset forgets the transfers of a previous run
+func (r *runRecorder) reset() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = make(map[string]time.Time)
+	r.transfers = nil
+}
+
+// recorded returns the transfers of the run
+func (r *runRecorder) recorded() []transfer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]transfer(nil), r.transfers...)
+}
+
+// multiReporter reports the progress to several reporters
+type multiReporter []Reporter
+
+func (m multiReporter) FileStarted(key string, size int64) {
+	for _, r := range m {
+		r.FileStarted(key, size)
+	}
+}
+
+func (m multiReporter) FileCompleted(key string, size int64, err error) {
+	for _, r := range m {
+		r.FileCompleted(key, size, err)
+	}
+}
+
+// withRunRecorder returns the reporter of a manager recording the transfers of --report-html, nil when it is unset
+func withRunRecorder(config *Config, reporter Reporter) (Reporter, *runRecorder) {
+	if config.ReportHTML == "" {
+		return reporter, nil
+	}
+	recorder := newRunRecorder()
+	if reporter == nil {
+		return recorder, recorder
+	}
+	return multiReporter{reporter, recorder}, recorder
+}
+
+// htmlReport is the content of the HTML report of a run
+type htmlReport struct {
+	Operation string
+	Bucket    string
+	Path      string
+	Dest      string
+	Archive   string
+	Start     time.Time
+	Duration  time.Duration
+	Files     int
+	Bytes     int64
+	Skipped   int
+	Failed    []FileError
+	Error     string
+	Transfers []transfer
+	// Unlisted is the number of transfers left out of the table
+	Unlisted int
+	Chart    []chartBar
+	// Peak is the highest throughput of the chart, in bytes per second
+	Peak float64
+}
+
+// chartBar is a bar of the throughput chart, in SVG coordinates
+type chartBar struct {
+	X, Y, Width, Height float64
+	// Title is shown when the bar is hovered
+	Title string
+}
+
+// saveHTMLReport writes the HTML report of the run when --report-html is set
+func saveHTMLReport(ctx context.Context, config *Config, report htmlReport, recorder *runRecorder, err error) error {
+	if config.ReportHTML == "" || recorder == nil {
+		return err
+	}
+	report.Bucket = config.Bucket
+	report.Dest = config.Dest
+	if err != nil {
+		report.Error = err.Error()
+	}
+	transfers := recorder.recorded()
+	report.Chart, report.Peak = throughputChart(transfers, report.Start, report.Duration)
+	if len(transfers) > maxReportFiles {
+		report.Unlisted = len(transfers) - maxReportFiles
+		transfers = transfers[:maxReportFiles]
+	}
+	report.Transfers = transfers
+
+	var buf bytes.Buffer
+	if renderErr := htmlReportTemplate.Execute(&buf, report); renderErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to render HTML report: %w", renderErr))
+	}
+	if writeErr := writeReportData(ctx, config, config.ReportHTML, buf.Bytes(), "text/html; charset=utf-8"); writeErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to write HTML report: %w", writeErr))
+	}
+	slog.Info("HTML report written", "report", config.ReportHTML)
+	return err
+}
+
+// throughputChart returns the bars of the throughput of the run and the peak throughput in bytes per second.
+// The bytes of each transfer are spread over its duration.
+func throughputChart(transfers []transfer, start time.Time, duration time.Duration) ([]chartBar, float64) {
+	if duration <= 0 || len(transfers) == 0 {
+		return nil, 0
+	}
+	bucket := duration / chartBuckets
+	if bucket <= 0 {
+		bucket = 1
+	}
+	var bytesPerBucket [chartBuckets]float64
+	for _, t := range transfers {
+		if t.Err != nil || t.Size <= 0 {
+			continue
+		}
+		from := max(t.Start.Sub(start), 0)
+		to := from + max(t.Duration, 1)
+		for i := int(from / bucket); i < chartBuckets && time.Duration(i)*bucket < to; i++ {
+			lo := max(from, time.Duration(i)*bucket)
+			hi := min(to, time.Duration(i+1)*bucket)
+			if hi > lo {
+				bytesPerBucket[i] += float64(t.Size) * float64(hi-lo) / float64(to-from)
+			}
+		}
+	}
+	var peak float64
+	for _, b := range bytesPerBucket {
+		peak = max(peak, b/bucket.Seconds())
+	}
+	if peak == 0 {
+		return nil, 0
+	}
+	width := float64(chartWidth) / chartBuckets
+	bars := make([]chartBar, 0, chartBuckets)
+	for i, b := range bytesPerBucket {
+		rate := b / bucket.Seconds()
+		height := rate / peak * chartHeight
+		bars = append(bars, chartBar{
+			X:      float64(i) * width,
+			Y:      chartHeight - height,
+			Width:  width - 1,
+			Height: height,
+			Title:  fmt.Sprintf("%s: %s/s", (time.Duration(i) * bucket).Round(time.Second), goutils.ConvertBytes(uint64(rate))),
+		})
+	}
+	return bars, peak
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes": func(n int64) string {
+		return goutils.ConvertBytes(uint64(max(n, 0)))
+	},
+	"rate": func(bytesPerSecond float64) string {
+		return goutils.ConvertBytes(uint64(bytesPerSecond)) + "/s"
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"average": func(n int64, d time.Duration) string {
+		if d <= 0 {
+			return "-"
+		}
+		return goutils.ConvertBytes(uint64(float64(max(n, 0))/d.Seconds())) + "/s"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>s3safe {{ .Operation }} report - {{ .Start.Format "2006-01-02 15:04:05" }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 12px; text-align: left; }
+td.number { text-align: right; }
+.status { display: inline-block; padding: 4px 12px; border-radius: 4px; color: #fff; }
+.ok { background: #2e7d32; }
+.failed { background: #c62828; }
+.error { color: #c62828; }
+svg rect { fill: #1565c0; }
+</style>
+</head>
+<body>
+<h1>s3safe {{ .Operation }} report</h1>
+{{ if .Error }}<p class="status failed">Failed</p>
+<p class="error">{{ .Error }}</p>{{ else }}<p class="status ok">Completed</p>{{ end }}
+<table>
+<tr><th>Started</th><td>{{ .Start.Format "2006-01-02 15:04:05 MST" }}</td></tr>
+<tr><th>Duration</th><td>{{ duration .Duration }}</td></tr>
+{{ if .Bucket }}<tr><th>Bucket</th><td>{{ .Bucket }}</td></tr>{{ end }}
+<tr><th>Path</th><td>{{ .Path }}</td></tr>
+<tr><th>Destination</th><td>{{ .Dest }}</td></tr>
+{{ if .Archive }}<tr><th>Archive</th><td>{{ .Archive }}</td></tr>{{ end }}
+<tr><th>Files</th><td>{{ .Files }}</td></tr>
+<tr><th>Size</th><td>{{ bytes .Bytes }}</td></tr>
+<tr><th>Average throughput</th><td>{{ average .Bytes .Duration }}</td></tr>
+<tr><th>Skipped</th><td>{{ .Skipped }}</td></tr>
+<tr><th>Failed</th><td>{{ len .Failed }}</td></tr>
+</table>
+{{ if .Chart }}
+<h2>Throughput</h2>
+<p>Peak {{ rate .Peak }}</p>
+<svg width="720" height="160" viewBox="0 0 720 160" role="img" aria-label="Throughput over the run">
+{{ range .Chart }}<rect x="{{ printf "%.1f" .X }}" y="{{ printf "%.1f" .Y }}" width="{{ printf "%.1f" .Width }}" height="{{ printf "%.1f" .Height }}"><title>{{ .Title }}</title></rect>
+{{ end }}</svg>
+{{ end }}
+{{ if .Failed }}
+<h2>Failed files</h2>
+<table>
+<tr><th>File</th><th>Error</th></tr>
+{{ range .Failed }}<tr><td>{{ .Key }}</td><td class="error">{{ .Err }}</td></tr>
+{{ end }}</table>
+{{ end }}
+{{ if .Transfers }}
+<h2>Files</h2>
+<table>
+<tr><th>File</th><th>Size</th><th>Duration</th><th>Throughput</th><th>Error</th></tr>
+{{ range .Transfers }}<tr><td>{{ .Key }}</td><td class="number">{{ if ge .Size 0 }}{{ bytes .Size }}{{ end }}</td><td class="number">{{ duration .Duration }}</td><td class="number">{{ average .Size .Duration }}</td><td class="error">{{ if .Err }}{{ .Err }}{{ end }}</td></tr>
+{{ end }}</table>
+{{ if .Unlisted }}<p>{{ .Unlisted }} more files are not listed.</p>{{ end }}
+{{ end }}
+</body>
+</html>
+`))
@@ -0,0 +1,150 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupHTMLReport(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello", "<b>.txt": "world!"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+	cfg := testConfig(src, server.URL)
+	cfg.ReportHTML = reportPath
+	reporter := &recordingReporter{}
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck(), WithReporter(reporter))
+	if err != nil {
+		t.Fatalf("NewBackupManagerFromConfig: %v", err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if len(reporter.events) != 4 {
+		t.Errorf("the reporter of the library got %q", reporter.events)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("the HTML report is not written: %v", err)
+	}
+	html := string(data)
+	for _, want := range []string{"s3safe backup report", "Completed", "<td>a.txt</td>", "&lt;b&gt;.txt", "<tr><th>Files</th><td>2</td></tr>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("the report does not contain %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<b>.txt") {
+		t.Error("file names are not escaped")
+	}
+
+	// A second run reports its own transfers
+	if err := os.Remove(filepath.Join(src, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if data, _ := os.ReadFile(reportPath); strings.Contains(string(data), "<td>a.txt</td>") {
+		t.Error("the report lists the files of the previous run")
+	}
+}
+
+func TestSaveHTMLReportError(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+	config := &Config{ReportHTML: reportPath, Dest: "backups"}
+	recorder := newRunRecorder()
+	recorder.FileStarted("a.txt", 5)
+	recorder.FileCompleted("a.txt", 5, errors.New("connection reset"))
+	runErr := errors.New("upload failed")
+	report := htmlReport{Operation: operationBackup, Start: time.Now(), Duration: time.Second,
+		Failed: []FileError{{Key: "b.txt", Err: errors.New("permission denied")}}}
+	if err := saveHTMLReport(context.Background(), config, report, recorder, runErr); !errors.Is(err, runErr) {
+		t.Errorf("saveHTMLReport = %v, want the error of the run", err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Failed", "upload failed", "permission denied", "connection reset"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("the report does not contain %q", want)
+		}
+	}
+
+	config.ReportHTML = filepath.Join(t.TempDir(), "missing", "report.html")
+	if err := saveHTMLReport(context.Background(), config, report, recorder, nil); err == nil {
+		t.Error("a report that cannot be written is not reported")
+	}
+}
+
+func TestThroughputChart(t *testing.T) {
+	start := time.Now()
+	transfers := []transfer{
+		{Key: "a", Size: 60 << 20, Start: start, Duration: 30 * time.Second},
+		{Key: "b", Size: 30 << 20, Start: start.Add(30 * time.Second), Duration: 30 * time.Second},
+		{Key: "failed", Size: 1 << 30, Start: start, Duration: time.Second, Err: errors.New("failed")},
+	}
+	bars, peak := throughputChart(transfers, start, time.Minute)
+	if len(bars) != chartBuckets {
+		t.Fatalf("%d bars, want %d", len(bars), chartBuckets)
+	}
+	if want := float64(2 << 20); peak != want {
+		t.Errorf("peak = %f, want %f", peak, want)
+	}
+	if bars[0].Height != chartHeight || bars[chartBuckets-1].Height != chartHeight/2 {
+		t.Errorf("bar heights = %f and %f", bars[0].Height, bars[chartBuckets-1].Height)
+	}
+	if bars, _ := throughputChart(nil, start, time.Minute); bars != nil {
+		t.Error("a run without transfers has a chart")
+	}
+}
+
+func TestWithRunRecorder(t *testing.T) {
+	if reporter, recorder := withRunRecorder(&Config{}, nil); reporter != nil || recorder != nil {
+		t.Error("transfers are recorded without --report-html")
+	}
+	user := &recordingReporter{}
+	reporter, recorder := withRunRecorder(&Config{ReportHTML: "report.html"}, user)
+	reporter.FileStarted("a.txt", 5)
+	reporter.FileCompleted("a.txt", 5, nil)
+	if len(user.events) != 2 || !slices.ContainsFunc(recorder.recorded(), func(t transfer) bool { return t.Key == "a.txt" }) {
+		t.Errorf("events = %q, recorded = %v", user.events, recorder.recorded())
+	}
+}
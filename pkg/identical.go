@@ -0,0 +1,196 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// errIdenticalObject is returned by uploadWith when every destination already holds the content of the file
+var errIdenticalObject = errors.New("identical object already exists")
+
+// IdenticalChecker is implemented by uploaders that can tell whether an object already holds the content
+// of a local file, sum is the hex SHA-256 of the file
+type IdenticalChecker interface {
+	Identical(ctx context.Context, path string, target string, sum string) (bool, error)
+}
+
+// identicalUpload reports whether the upload of a file to a destination can be skipped with --skip-identical,
+// the file is uploaded when the destination cannot tell or the check fails
+func (bm *BackupManager) identicalUpload(ctx context.Context, d *destination, path, target string, opts UploadOptions) bool {
+	sum := opts.Metadata[sha256MetadataKey]
+	checker, ok := d.uploader.(IdenticalChecker)
+	if !bm.config.SkipIdentical || sum == "" || !ok {
+		return false
+	}
+	identical, err := checker.Identical(ctx, path, target, sum)
+	if err != nil {
+		bm.log().Warn("Unable to compare with the existing object, uploading it", "file", path, "target", target, "error", err)
+		return false
+	}
+	if identical {
+		bm.log().Info("Skipping identical object", "file", path, "target", target, "destination", d.name)
+	}
+	return identical
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fileETag returns the ETag S3 computes for a file, the MD5 of the content for a single part upload
+// (parts is 0), the MD5 of the concatenated MD5 of each part of partSize bytes followed by -<parts> for
+// a multipart upload
+func fileETag(path string, partSize int64, parts int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	if parts == 0 {
+		hash := md5.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	sums := md5.New()
+	for range parts {
+		hash := md5.New()
+		if _, err := io.CopyN(hash, file, partSize); err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		sums.Write(hash.Sum(nil))
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), parts), nil
+}
+
+// multipartETag returns the ETag and the number of parts of an object uploaded in parts, the ETag of an
+// encrypted object is not derived from its content
+func multipartETag(head *s3.HeadObjectOutput) (string, int, bool) {
+	etag := strings.ToLower(strings.Trim(aws.ToString(head.ETag), `"`))
+	sum, count, found := strings.Cut(etag, "-")
+	if !found || encryptedObject(head) || !isMD5(sum) || !md5Allowed() {
+		return "", 0, false
+	}
+	parts, err := strconv.Atoi(count)
+	if err != nil || parts < 1 {
+		return "", 0, false
+	}
+	return etag, parts, true
+}
+
+// Identical reports whether an object holds the content of a local file. The SHA-256 stored in the
+// metadata or the full object checksum is compared first, then the MD5 ETag of single part uploads and
+// the ETag of multipart uploads, computed with the part size of the uploads of this storage.
+func (s S3Storage) Identical(ctx context.Context, path string, target string, sum string) (bool, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(target),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if isStatus(err, http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read %q: %w", target, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	digest := headDigest(head)
+	switch {
+	case digest.Size != info.Size():
+		return false, nil
+	case digest.SHA256 != "":
+		return digest.SHA256 == sum, nil
+	case digest.MD5 != "":
+		etag, err := fileETag(path, 0, 0)
+		return err == nil && etag == digest.MD5, err
+	}
+	etag, parts, ok := multipartETag(head)
+	if !ok {
+		return false, nil
+	}
+	// An object uploaded with another part size cannot be compared
+	partSize := s.uploadSettings(info.Size()).partSize
+	if int64(parts) != (info.Size()+partSize-1)/partSize {
+		return false, nil
+	}
+	local, err := fileETag(path, partSize, parts)
+	return err == nil && local == etag, err
+}
+
+// Identical reports whether the copy of a file has the same content
+func (l LocalStorage) Identical(ctx context.Context, path string, target string, sum string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	target = filepath.FromSlash(target)
+	info, err := os.Stat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	local, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != local.Size() {
+		return false, nil
+	}
+	copied, err := fileSHA256(target)
+	return err == nil && copied == sum, err
+}
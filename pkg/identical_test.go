@@ -0,0 +1,211 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func hexMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// identicalServer answers HEAD requests with the headers of the stored objects and records uploads
+func identicalServer(t *testing.T, objects map[string]map[string]string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch r.Method {
+		case http.MethodHead:
+			headers, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+		case http.MethodPut:
+			mu.Lock()
+			uploaded = append(uploaded, key)
+			mu.Unlock()
+			w.Header().Set("ETag", `"etag"`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		keys := slices.Clone(uploaded)
+		slices.Sort(keys)
+		return keys
+	}
+}
+
+func TestFileETag(t *testing.T) {
+	content := []byte("0123456789")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if etag, err := fileETag(path, 0, 0); err != nil || etag != hexMD5(content) {
+		t.Errorf("single part ETag = %q, %v", etag, err)
+	}
+	var parts []byte
+	for _, part := range [][]byte{content[:4], content[4:8], content[8:]} {
+		sum := md5.Sum(part)
+		parts = append(parts, sum[:]...)
+	}
+	if etag, err := fileETag(path, 4, 3); err != nil || etag != hexMD5(parts)+"-3" {
+		t.Errorf("multipart ETag = %q, %v", etag, err)
+	}
+}
+
+func TestS3StorageIdentical(t *testing.T) {
+	content := []byte("hello")
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	sha := hex.EncodeToString(sum[:])
+	partSum := md5.Sum(content)
+	multipart := hexMD5(partSum[:]) + "-1"
+
+	server, _ := identicalServer(t, map[string]map[string]string{
+		"size":        {"Content-Length": "6", "X-Amz-Meta-Sha256": sha},
+		"sha256":      {"Content-Length": "5", "X-Amz-Meta-Sha256": sha, "ETag": `"etag-2"`},
+		"sha256-diff": {"Content-Length": "5", "X-Amz-Meta-Sha256": strings.Repeat("0", 64), "ETag": `"` + hexMD5(content) + `"`},
+		"md5":         {"Content-Length": "5", "ETag": `"` + hexMD5(content) + `"`},
+		"md5-diff":    {"Content-Length": "5", "ETag": `"` + hexMD5([]byte("world")) + `"`},
+		"multipart":   {"Content-Length": "5", "ETag": `"` + multipart + `"`},
+		"parts":       {"Content-Length": "5", "ETag": `"` + hexMD5(content) + `-2"`},
+		"kms":         {"Content-Length": "5", "ETag": `"` + multipart + `"`, "X-Amz-Server-Side-Encryption": "aws:kms"},
+	})
+	defer server.Close()
+
+	storage := S3Storage{bucket: "bucket", client: s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})}
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"missing", false},
+		{"size", false},
+		{"sha256", true},
+		{"sha256-diff", false},
+		{"md5", true},
+		{"md5-diff", false},
+		{"multipart", true},
+		{"parts", false},
+		{"kms", false},
+	}
+	for _, tt := range tests {
+		got, err := storage.Identical(context.Background(), path, tt.target, sha)
+		if err != nil {
+			t.Errorf("%s: %v", tt.target, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: identical = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestLocalStorageIdentical(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"same.txt": "hello", "other.txt": "world"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for target, want := range map[string]bool{"same.txt": true, "other.txt": false, "missing.txt": false} {
+		got, err := LocalStorage{}.Identical(context.Background(), path, filepath.ToSlash(filepath.Join(dir, target)), sha)
+		if err != nil || got != want {
+			t.Errorf("%s: identical = %v, %v, want %v", target, got, err, want)
+		}
+	}
+}
+
+func TestBackupSkipIdentical(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	src := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello", "b.txt": "world!"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum := sha256.Sum256([]byte("hello"))
+	server, keys := identicalServer(t, map[string]map[string]string{
+		"backups/a.txt": {"Content-Length": "5", "X-Amz-Meta-Sha256": hex.EncodeToString(sum[:])},
+		"backups/b.txt": {"Content-Length": "6", "X-Amz-Meta-Sha256": strings.Repeat("0", 64)},
+	})
+	defer server.Close()
+
+	cfg := testConfig(src, server.URL)
+	cfg.SkipIdentical = true
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if result.Files != 1 || result.Bytes != 6 || result.Skipped != 1 {
+		t.Errorf("result = %+v, want 1 file, 6 bytes and 1 skipped", result)
+	}
+	if got := keys(); !slices.Equal(got, []string{"backups/b.txt"}) {
+		t.Errorf("uploaded keys = %v", got)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// compressedExtensions are the extensions of formats that are already compressed
+var compressedExtensions = []string{
+	".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".avif",
+	".mp4", ".m4v", ".mov", ".mkv", ".webm", ".avi",
+	".mp3", ".m4a", ".aac", ".ogg", ".opus", ".flac",
+	".zip", ".gz", ".tgz", ".bz2", ".xz", ".zst", ".lz4", ".br", ".7z", ".rar",
+	".jar", ".apk", ".docx", ".xlsx", ".pptx", ".odt", ".ods",
+}
+
+// compressedMagics are the leading bytes of formats that are already compressed
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},                       // gzip
+	{'P', 'K', 0x03, 0x04},             // zip and zip based documents
+	{'B', 'Z', 'h'},                    // bzip2
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	{'R', 'a', 'r', '!'},               // rar
+	{0xff, 0xd8, 0xff},                 // jpeg
+	{0x89, 'P', 'N', 'G'},              // png
+	{'G', 'I', 'F', '8'},               // gif
+	{0x04, 0x22, 0x4d, 0x18},           // lz4
+}
+
+// isIncompressible reports whether a file is already compressed, from its extension or its leading bytes
+func isIncompressible(file io.ReaderAt, name string) bool {
+	if slices.Contains(compressedExtensions, strings.ToLower(filepath.Ext(name))) {
+		return true
+	}
+	buf := make([]byte, 12)
+	n, _ := file.ReadAt(buf, 0)
+	buf = buf[:n]
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(buf, magic) {
+			return true
+		}
+	}
+	// ISO base media files (mp4, mov, heic) and RIFF WebP
+	return n == 12 && (string(buf[4:8]) == "ftyp" || string(buf[:4]) == "RIFF" && string(buf[8:12]) == "WEBP")
+}
+
+// isIncompressibleFile is isIncompressible for a file path, unreadable files are reported as compressible
+func isIncompressibleFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	return isIncompressible(file, path)
+}
+
+// gzipMembers writes a gzip stream as consecutive members so the compression level can change between
+// archive entries, readers decompress concatenated members as a single stream
+type gzipMembers struct {
+	out   io.Writer
+	gw    *gzip.Writer
+	level int
+}
+
+func newGzipMembers(out io.Writer) *gzipMembers {
+	gw := gzip.NewWriter(out)
+	// The comment of the first member identifies the archives of s3safe for --keep-local
+	gw.Comment = archiveComment
+	return &gzipMembers{out: out, gw: gw, level: gzip.DefaultCompression}
+}
+
+func (m *gzipMembers) Write(p []byte) (int, error) {
+	return m.gw.Write(p)
+}
+
+// setLevel starts a new member when the compression level changes
+func (m *gzipMembers) setLevel(level int) error {
+	if level == m.level {
+		return nil
+	}
+	if err := m.gw.Close(); err != nil {
+		return err
+	}
+	gw, err := gzip.NewWriterLevel(m.out, level)
+	if err != nil {
+		return err
+	}
+	m.gw, m.level = gw, level
+	return nil
+}
+
+func (m *gzipMembers) Close() error {
+	return m.gw.Close()
+}
@@ -0,0 +1,91 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsIncompressible(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{"photo.JPG", []byte("anything"), true},
+		{"notes.txt", []byte("plain text content"), false},
+		{"archive", []byte{0x1f, 0x8b, 0x08, 0x00}, true},
+		{"document", []byte("PK\x03\x04rest"), true},
+		{"video", []byte("\x00\x00\x00\x18ftypmp42"), true},
+		{"image", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), true},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isIncompressible(bytes.NewReader(tt.content), tt.name); got != tt.want {
+			t.Errorf("isIncompressible(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompressDirectoryNoRecompress(t *testing.T) {
+	src := t.TempDir()
+	text := bytes.Repeat([]byte("compressible "), 10000)
+	for _, name := range []string{"a.txt", "b.jpg", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), text, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sizes := make(map[bool]int64)
+	for _, noRecompress := range []bool{false, true} {
+		archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+		if _, err := compressDirectory(src, archive, archiveOptions{noRecompress: noRecompress}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[noRecompress] = info.Size()
+
+		dest := t.TempDir()
+		if err := decompressDirectory(archive, dest, false); err != nil {
+			t.Fatalf("noRecompress=%v: %v", noRecompress, err)
+		}
+		for _, name := range []string{"a.txt", "b.jpg", "c.txt"} {
+			got, err := os.ReadFile(filepath.Join(dest, name))
+			if err != nil || !bytes.Equal(got, text) {
+				t.Errorf("noRecompress=%v: unexpected content of %s: %v", noRecompress, name, err)
+			}
+		}
+	}
+	// b.jpg is stored as is
+	if sizes[true] < int64(len(text)) || sizes[false] >= int64(len(text)) {
+		t.Errorf("Unexpected archive sizes %v", sizes)
+	}
+}
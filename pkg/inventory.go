@@ -0,0 +1,246 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InventoryManifest is the manifest.json of an S3 Inventory report
+type InventoryManifest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	DestinationBucket string `json:"destinationBucket"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key  string `json:"key"`
+		Size int64  `json:"size"`
+	} `json:"files"`
+}
+
+// createdAt returns the creation time of the report, stored in milliseconds
+func (m *InventoryManifest) createdAt() time.Time {
+	ms, err := strconv.ParseInt(m.CreationTimestamp, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// inventoryColumns are the indexes of the fields read from inventory rows, -1 when absent
+type inventoryColumns struct {
+	key, size, lastModified, etag, storageClass, isLatest, isDeleteMarker int
+}
+
+// parseInventorySchema returns the columns of a CSV inventory, the Key column is required
+func parseInventorySchema(schema string) (inventoryColumns, error) {
+	columns := inventoryColumns{-1, -1, -1, -1, -1, -1, -1}
+	for i, name := range strings.Split(schema, ",") {
+		switch strings.TrimSpace(name) {
+		case "Key":
+			columns.key = i
+		case "Size":
+			columns.size = i
+		case "LastModifiedDate":
+			columns.lastModified = i
+		case "ETag":
+			columns.etag = i
+		case "StorageClass":
+			columns.storageClass = i
+		case "IsLatest":
+			columns.isLatest = i
+		case "IsDeleteMarker":
+			columns.isDeleteMarker = i
+		}
+	}
+	if columns.key < 0 {
+		return columns, fmt.Errorf("invalid inventory schema %q, the Key field is missing", schema)
+	}
+	return columns, nil
+}
+
+// inventoryItems yields the objects under path from a CSV inventory file,
+// without recursive only direct children and the first level folders are returned
+func inventoryItems(r io.Reader, columns inventoryColumns, path string, recursive bool, seen map[string]bool, yield func(Item, error) bool) error {
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid inventory file: %w", err)
+		}
+		// Versioned inventories list every version, only current objects are restored
+		if field(record, columns.isDeleteMarker) == "true" || field(record, columns.isLatest) == "false" {
+			continue
+		}
+		// Keys are URL encoded
+		key, err := url.QueryUnescape(field(record, columns.key))
+		if err != nil {
+			return fmt.Errorf("invalid inventory key %q: %w", field(record, columns.key), err)
+		}
+		if !strings.HasPrefix(key, path) || key == path {
+			continue
+		}
+		if !recursive {
+			if i := strings.Index(key[len(path):], "/"); i >= 0 {
+				dir := key[:len(path)+i+1]
+				if !seen[dir] {
+					seen[dir] = true
+					if !yield(Item{Key: dir, IsDir: true}, nil) {
+						return errStopIteration
+					}
+				}
+				continue
+			}
+		}
+		size, _ := strconv.ParseInt(field(record, columns.size), 10, 64)
+		lastModified, _ := time.Parse(time.RFC3339, field(record, columns.lastModified))
+		item := Item{
+			Key:          key,
+			LastModified: lastModified,
+			IsDir:        size == 0 && strings.HasSuffix(key, "/"),
+			StorageClass: field(record, columns.storageClass),
+			Size:         size,
+			ETag:         strings.Trim(field(record, columns.etag), `"`),
+		}
+		if !yield(item, nil) {
+			return errStopIteration
+		}
+	}
+}
+
+// InventoryObjects iterates over the objects under path from the S3 Inventory report of manifestKey,
+// the report must be stored in the bucket of s. Inventory files are read one at a time.
+func (s S3Storage) InventoryObjects(ctx context.Context, manifestKey, sourceBucket, path string, recursive bool) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		manifest, columns, err := s.readInventoryManifest(ctx, manifestKey, sourceBucket)
+		if err != nil {
+			yield(Item{}, err)
+			return
+		}
+		seen := map[string]bool{}
+		for _, file := range manifest.Files {
+			if err := s.readInventoryFile(ctx, file.Key, columns, path, recursive, seen, yield); err != nil {
+				if !errors.Is(err, errStopIteration) {
+					yield(Item{}, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// readInventoryManifest reads and checks the manifest of a CSV inventory of sourceBucket
+func (s S3Storage) readInventoryManifest(ctx context.Context, manifestKey, sourceBucket string) (*InventoryManifest, inventoryColumns, error) {
+	obj, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(manifestKey)})
+	if err != nil {
+		return nil, inventoryColumns{}, fmt.Errorf("unable to read inventory manifest %q: %w", manifestKey, err)
+	}
+	var manifest InventoryManifest
+	err = json.NewDecoder(obj.Body).Decode(&manifest)
+	_ = obj.Body.Close()
+	if err != nil {
+		return nil, inventoryColumns{}, fmt.Errorf("invalid inventory manifest %q: %w", manifestKey, err)
+	}
+	if manifest.SourceBucket != sourceBucket {
+		return nil, inventoryColumns{}, fmt.Errorf("inventory manifest %q lists bucket %q, not %q", manifestKey, manifest.SourceBucket, sourceBucket)
+	}
+	if !strings.EqualFold(manifest.FileFormat, "CSV") {
+		return nil, inventoryColumns{}, fmt.Errorf("inventory format %s is not supported, configure a CSV inventory", manifest.FileFormat)
+	}
+	columns, err := parseInventorySchema(manifest.FileSchema)
+	if err != nil {
+		return nil, inventoryColumns{}, err
+	}
+	s.log().Info("Listing from S3 Inventory, objects changed since the report are not included",
+		"manifest", manifestKey, "created", manifest.createdAt(), "files", len(manifest.Files))
+	return &manifest, columns, nil
+}
+
+// readInventoryFile yields the objects of a gzip compressed CSV inventory file
+func (s S3Storage) readInventoryFile(ctx context.Context, key string, columns inventoryColumns, path string, recursive bool, seen map[string]bool, yield func(Item, error) bool) error {
+	obj, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("unable to read inventory file %q: %w", key, err)
+	}
+	defer func() {
+		_ = obj.Body.Close()
+	}()
+	gzr, err := gzip.NewReader(obj.Body)
+	if err != nil {
+		return fmt.Errorf("invalid inventory file %q: %w", key, err)
+	}
+	defer func() {
+		_ = gzr.Close()
+	}()
+	err = inventoryItems(gzr, columns, path, recursive, seen, yield)
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return err
+}
+
+// files iterates over the files to restore, from the S3 Inventory report when configured
+func (rm *RestoreManager) files(ctx context.Context) iter.Seq2[Item, error] {
+	if rm.config.Inventory == "" {
+		return limitDepth(rm.storage.Objects(ctx, rm.config.Path, rm.config.Recursive), rm.config.Path, rm.config.MaxDepth)
+	}
+	remote, err := rm.config.ParseRemote(rm.config.Inventory)
+	if err != nil {
+		return func(yield func(Item, error) bool) { yield(Item{}, err) }
+	}
+	storage, err := remote.Config.NewS3Storage(ctx)
+	if err != nil {
+		return func(yield func(Item, error) bool) { yield(Item{}, fmt.Errorf("inventory %s: %w", remote, err)) }
+	}
+	return limitDepth(storage.InventoryObjects(ctx, remote.Prefix, rm.config.Bucket, rm.config.Path, rm.config.Recursive),
+		rm.config.Path, rm.config.MaxDepth)
+}
@@ -0,0 +1,134 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const inventorySchema = "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag, StorageClass"
+
+const inventoryCSV = `"data","backups/a.txt","v1","true","false","10","2025-01-02T03:04:05.000Z","etag1","STANDARD"
+"data","backups/a.txt","v0","false","false","9","2025-01-01T03:04:05.000Z","etag0","STANDARD"
+"data","backups/old.txt","v2","true","true","","2025-01-01T03:04:05.000Z","",""
+"data","backups/sub/b+c%23.txt","v3","true","false","20","2025-01-03T03:04:05.000Z","etag3","GLACIER"
+"data","backups/sub/d.txt","v4","true","false","30","2025-01-03T03:04:05.000Z","etag4","GLACIER"
+"data","other/e.txt","v5","true","false","40","2025-01-03T03:04:05.000Z","etag5","STANDARD"
+`
+
+func readInventoryCSV(data string, columns inventoryColumns, path string, recursive bool) ([]Item, error) {
+	var items []Item
+	err := inventoryItems(strings.NewReader(data), columns, path, recursive, map[string]bool{}, func(item Item, _ error) bool {
+		items = append(items, item)
+		return true
+	})
+	return items, err
+}
+
+func TestInventoryItems(t *testing.T) {
+	columns, err := parseInventorySchema(inventorySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := readInventoryCSV(inventoryCSV, columns, "backups", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	if want := "backups/a.txt,backups/sub/b c#.txt,backups/sub/d.txt"; strings.Join(keys, ",") != want {
+		t.Errorf("Expected %q, got %q", want, strings.Join(keys, ","))
+	}
+	if items[0].Size != 10 || items[0].ETag != "etag1" || items[0].LastModified.Day() != 2 || items[1].StorageClass != "GLACIER" {
+		t.Errorf("Unexpected item %+v", items[0])
+	}
+
+	items, err = readInventoryCSV(inventoryCSV, columns, "backups", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Key != "backups/a.txt" || items[1].Key != "backups/sub/" || !items[1].IsDir {
+		t.Errorf("Unexpected non-recursive items %+v", items)
+	}
+}
+
+func TestParseInventorySchema(t *testing.T) {
+	if _, err := parseInventorySchema("Bucket, Size"); err == nil {
+		t.Error("Expected an error without the Key field")
+	}
+}
+
+func TestListInventory(t *testing.T) {
+	var data bytes.Buffer
+	gzw := gzip.NewWriter(&data)
+	_, _ = gzw.Write([]byte(inventoryCSV))
+	_ = gzw.Close()
+	objects := map[string][]byte{
+		"/inventory/manifest.json": []byte(`{"sourceBucket":"data","creationTimestamp":"1735790400000","fileFormat":"CSV","fileSchema":"` + inventorySchema + `","files":[{"key":"inventory/data/1.csv.gz"}]}`),
+		"/inventory/data/1.csv.gz": data.Bytes(),
+		"/inventory/parquet.json":  []byte(`{"sourceBucket":"data","fileFormat":"Parquet","fileSchema":"message s3.inventory {}"}`),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := objects[strings.TrimPrefix(r.URL.Path, "/reports")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	storage := S3Storage{bucket: "reports", client: s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})}
+	items, err := collectItems(storage.InventoryObjects(context.Background(), "inventory/manifest.json", "data", "backups/sub", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("Expected 2 items, got %+v", items)
+	}
+	if _, err := collectItems(storage.InventoryObjects(context.Background(), "inventory/manifest.json", "other", "", true)); err == nil {
+		t.Error("Expected an error for the inventory of another bucket")
+	}
+	if _, err := collectItems(storage.InventoryObjects(context.Background(), "inventory/parquet.json", "data", "", true)); err == nil || !strings.Contains(err.Error(), "CSV") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
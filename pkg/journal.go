@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// journalFile records the files of an interrupted folder restore, it is removed once the restore completes
+const journalFile = ".s3safe-restore.journal"
+
+// journalEntry is a restored file, one JSON object per line
+type journalEntry struct {
+	Key  string `json:"key"`
+	ETag string `json:"etag,omitempty"`
+	Size int64  `json:"size"`
+}
+
+// restoreJournal tracks restored files so an interrupted restore continues where it stopped
+type restoreJournal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	done map[string]journalEntry
+}
+
+// openRestoreJournal loads the journal of the destination directory, restart discards it
+func openRestoreJournal(dir string, restart bool) (*restoreJournal, error) {
+	j := &restoreJournal{
+		path: filepath.Join(dir, journalFile),
+		done: make(map[string]journalEntry),
+	}
+	if restart {
+		if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to reset restore journal: %w", err)
+		}
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open restore journal: %w", err)
+	}
+	j.file = file
+
+	if len(j.done) > 0 {
+		var size int64
+		for _, entry := range j.done {
+			size += entry.Size
+		}
+		slog.Info("Resuming restore from journal", "files", len(j.done), "bytes", size, "journal", j.path)
+	}
+	return j, nil
+}
+
+func (j *restoreJournal) load() error {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read restore journal: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry journalEntry
+		// A line cut by an interruption is ignored, the file is downloaded again
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			continue
+		}
+		j.done[entry.Key] = entry
+	}
+	return scanner.Err()
+}
+
+// completed reports whether the file was restored by a previous run and has not changed since
+func (j *restoreJournal) completed(file Item) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.done[file.Key]
+	return ok && entry.Size == file.Size && entry.ETag == file.ETag
+}
+
+// record appends a restored file to the journal
+func (j *restoreJournal) record(file Item) error {
+	data, err := json.Marshal(journalEntry{Key: file.Key, ETag: file.ETag, Size: file.Size})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done[file.Key] = journalEntry{Key: file.Key, ETag: file.ETag, Size: file.Size}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write restore journal: %w", err)
+	}
+	return nil
+}
+
+// close closes the journal, remove deletes it once the restore is complete
+func (j *restoreJournal) close(remove bool) error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	if remove {
+		return os.Remove(j.path)
+	}
+	slog.Info("Restore journal kept, run the same restore again to continue", "journal", j.path)
+	return nil
+}
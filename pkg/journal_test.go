@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestoreJournal(t *testing.T) {
+	dir := t.TempDir()
+	file := Item{Key: "backups/data.txt", ETag: "abc", Size: 42}
+
+	journal, err := openRestoreJournal(dir, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if journal.completed(file) {
+		t.Errorf("Expected an empty journal")
+	}
+	if err := journal.record(file); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := journal.close(false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	journal, err = openRestoreJournal(dir, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !journal.completed(file) {
+		t.Errorf("Expected %q to be resumed from the journal", file.Key)
+	}
+	if journal.completed(Item{Key: file.Key, ETag: "changed", Size: 42}) {
+		t.Errorf("Expected a changed object to be restored again")
+	}
+	if err := journal.close(true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the journal to be removed")
+	}
+}
+
+func TestRestoreResumeInterruptedDownload(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	content := bytes.Repeat([]byte("s3safe "), 1000)
+	sum := md5.Sum(content)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	var interrupt atomic.Bool
+	interrupt.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Has("list-type"):
+			_, _ = fmt.Fprintf(w, `<ListBucketResult><Name>bucket</Name><KeyCount>1</KeyCount><IsTruncated>false</IsTruncated>`+
+				`<Contents><Key>backups/data.txt</Key><Size>%d</Size><ETag>%s</ETag></Contents></ListBucketResult>`, len(content), etag)
+		case r.URL.Path != "/bucket/backups/data.txt":
+			http.NotFound(w, r)
+		case r.Method == http.MethodGet && interrupt.Load():
+			// The connection drops in the middle of the body
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write(content[:len(content)/2])
+		default:
+			w.Header().Set("ETag", etag)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	defer server.Close()
+
+	dest := t.TempDir()
+	cfg := testConfig("backups", server.URL)
+	cfg.Dest = dest
+	cfg.SkipSpaceCheck = true
+	restore := func() (RestoreResult, error) {
+		rm, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rm.Restore(context.Background())
+	}
+	if _, err := restore(); err == nil {
+		t.Fatal("Expected the interrupted download to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "data.txt")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no truncated file after the interrupted download, got %v", err)
+	}
+
+	// The resumed restore downloads the file again without --force
+	interrupt.Store(false)
+	result, err := restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "data.txt"))
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("Expected the complete file, got %d bytes: %v", len(got), err)
+	}
+	if result.Files != 1 || result.Skipped != 0 {
+		t.Errorf("result = %+v, want 1 file restored", result)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "data.txt"+partialSuffix)); !os.IsNotExist(err) {
+		t.Errorf("Expected no partial file left, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// archiveComment is the gzip comment of the compressed archives written by s3safe
+const archiveComment = "s3safe"
+
+// localArchive is a compressed archive left in the backed up directory
+type localArchive struct {
+	path    string
+	modTime time.Time
+}
+
+// isLocalArchive reports whether a file is a compressed archive written by s3safe
+func isLocalArchive(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return false
+	}
+	return gzr.Comment == archiveComment
+}
+
+// pruneLocalArchives deletes the archives of the directory of archive beyond the newest --keep-local ones.
+// Only archives written by s3safe are deleted, failures are logged.
+func (bm *BackupManager) pruneLocalArchives(archive string) {
+	if bm.config.KeepLocal <= 0 {
+		return
+	}
+	dir := filepath.Dir(archive)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		bm.log().Warn("Unable to list local archives", "dir", dir, "error", err)
+		return
+	}
+	var archives []localArchive
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if isLocalArchive(path) {
+			archives = append(archives, localArchive{path: path, modTime: info.ModTime()})
+		}
+	}
+	// Newest first, the archive of this backup is always kept
+	slices.SortFunc(archives, func(a, b localArchive) int {
+		if a.path == archive {
+			return -1
+		}
+		if b.path == archive {
+			return 1
+		}
+		return b.modTime.Compare(a.modTime)
+	})
+	for _, a := range archives[min(bm.config.KeepLocal, len(archives)):] {
+		if err := os.Remove(a.path); err != nil {
+			bm.log().Warn("Unable to delete local archive", "file", a.path, "error", err)
+			continue
+		}
+		bm.log().Info("Deleted local archive", "file", a.path)
+	}
+}
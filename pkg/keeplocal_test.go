@@ -0,0 +1,187 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeArchive writes a gzip file, an s3safe archive when written with newGzipMembers
+func writeArchive(t *testing.T, path string, s3safe bool, modTime time.Time) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3safe {
+		gw := newGzipMembers(file)
+		_, _ = gw.Write([]byte("archive"))
+		_ = gw.Close()
+	} else {
+		gw := gzip.NewWriter(file)
+		_, _ = gw.Write([]byte("user file"))
+		_ = gw.Close()
+	}
+	_ = file.Close()
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// archiveEntries returns the type of every entry of a tar.gz archive by name
+func archiveEntries(t *testing.T, archive string) map[string]byte {
+	t.Helper()
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]byte)
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = header.Typeflag
+	}
+}
+
+func TestPruneLocalArchives(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"data-1.tar.gz", "data-2.tar.gz", "data-3.tar.gz", "data-4.tar.gz"} {
+		writeArchive(t, filepath.Join(dir, name), true, now.Add(time.Duration(i-4)*time.Hour))
+	}
+	writeArchive(t, filepath.Join(dir, "user.tar.gz"), false, now.Add(-24*time.Hour))
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The archive of the backup is kept even with an older modification time
+	current := filepath.Join(dir, "data-0.tar.gz")
+	writeArchive(t, current, true, now.Add(-48*time.Hour))
+
+	bm := &BackupManager{config: &Config{KeepLocal: 3}}
+	bm.pruneLocalArchives(current)
+
+	for name, kept := range map[string]bool{
+		"data-0.tar.gz": true,
+		"data-1.tar.gz": false,
+		"data-2.tar.gz": false,
+		"data-3.tar.gz": true,
+		"data-4.tar.gz": true,
+		"user.tar.gz":   true,
+		"notes.txt":     true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept && err != nil {
+			t.Errorf("Expected %s to be kept: %v", name, err)
+		}
+		if !kept && !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", name)
+		}
+	}
+}
+
+func TestCompressDirectoryArchiveComment(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := compressDirectory(src, archive, archiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !isLocalArchive(archive) {
+		t.Error("Expected the archive to be identified as an s3safe archive")
+	}
+	if isLocalArchive(filepath.Join(src, "a.txt")) {
+		t.Error("Expected a plain file not to be an archive")
+	}
+}
+
+func TestValidateKeepLocal(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", KeepLocal: 2})
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected --keep-local without --compress to be rejected")
+	}
+	cfg.Compress = true
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	cfg.KeepLocal = -1
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected a negative --keep-local to be rejected")
+	}
+}
+
+func TestBackupSkipsKeptArchives(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.Compress = true
+	cfg.KeepLocal = 2
+	cfg.Timestamp = true
+	cfg.TimestampFormat = "20060102T150405.000000000"
+	var archives []string
+	for range 2 {
+		bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := bm.Backup(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, filepath.Join(src, result.Archive))
+	}
+	if _, err := os.Stat(archives[0]); err != nil {
+		t.Fatalf("Expected the first archive to be kept: %v", err)
+	}
+	entries := archiveEntries(t, archives[1])
+	if len(entries) != 1 || entries["a.txt"] != tar.TypeReg {
+		t.Errorf("Expected the second archive to hold a.txt only, got %v", entries)
+	}
+}
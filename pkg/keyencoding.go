@@ -0,0 +1,146 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"maps"
+	"net/url"
+	"strings"
+)
+
+// Strategies applied to keys containing characters that break URLs, consoles or XML listings
+const (
+	unsafeKeysKeep   = "keep"
+	unsafeKeysEncode = "encode"
+	unsafeKeysReject = "reject"
+)
+
+var unsafeKeyStrategies = []string{unsafeKeysKeep, unsafeKeysEncode, unsafeKeysReject}
+
+// keyEncodingMetadataKey records how the key of an object was encoded, an underscore keeps it valid on Azure
+const (
+	keyEncodingMetadataKey = "key_encoding"
+	keyEncodingPercent     = "percent"
+)
+
+// errUnsafeKey is returned for files rejected by the reject strategy
+var errUnsafeKey = errors.New("key contains unsafe characters")
+
+// MetadataReader reads the user metadata of an object
+type MetadataReader interface {
+	Metadata(ctx context.Context, key string) (map[string]string, error)
+}
+
+// isUnsafeKeyChar reports whether c must be percent-encoded by the encode strategy
+func isUnsafeKeyChar(c byte) bool {
+	return c < 0x20 || c == 0x7f || c == '#' || c == '?'
+}
+
+// hasUnsafeKeyChars reports whether a key contains control characters, '#' or '?'
+func hasUnsafeKeyChars(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if isUnsafeKeyChar(key[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeKey percent-encodes unsafe characters and '%', path separators are kept
+func encodeKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if isUnsafeKeyChar(c) || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeKey reverses encodeKey
+func decodeKey(key string) (string, error) {
+	return url.PathUnescape(key)
+}
+
+// decodeListKey decodes a key returned by a listing requested with the url encoding type
+func decodeListKey(encoding types.EncodingType, key string) string {
+	if encoding != types.EncodingTypeUrl {
+		return key
+	}
+	decoded, err := url.QueryUnescape(key)
+	if err != nil {
+		return key
+	}
+	return decoded
+}
+
+// safeKey applies the unsafe keys strategy, metadata is returned with the key encoding when the key was encoded
+func (bm *BackupManager) safeKey(key string, metadata map[string]string) (string, map[string]string, error) {
+	if !hasUnsafeKeyChars(key) {
+		return key, metadata, nil
+	}
+	switch bm.config.UnsafeKeys {
+	case unsafeKeysReject:
+		return "", nil, fmt.Errorf("%q: %w", key, errUnsafeKey)
+	case unsafeKeysEncode:
+		encoded := maps.Clone(metadata)
+		if encoded == nil {
+			encoded = map[string]string{}
+		}
+		encoded[keyEncodingMetadataKey] = keyEncodingPercent
+		return encodeKey(key), encoded, nil
+	default:
+		return key, metadata, nil
+	}
+}
+
+// decodedKey returns the original key of an object uploaded with an encoded key
+func (rm *RestoreManager) decodedKey(ctx context.Context, key string) string {
+	reader, ok := rm.storage.(MetadataReader)
+	if !ok || !strings.Contains(key, "%") {
+		return key
+	}
+	metadata, err := reader.Metadata(ctx, key)
+	if err != nil {
+		rm.log().Warn("Unable to read object metadata", "file", key, "error", err)
+		return key
+	}
+	if metadata[keyEncodingMetadataKey] != keyEncodingPercent {
+		return key
+	}
+	decoded, err := decodeKey(key)
+	if err != nil {
+		rm.log().Warn("Unable to decode key", "file", key, "error", err)
+		return key
+	}
+	return decoded
+}
@@ -0,0 +1,105 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"testing"
+)
+
+func TestEncodeKey(t *testing.T) {
+	keys := []string{"logs/app.log", "notes/line\nbreak.txt", "music/#1 hits?.mp3", "100%/tab\there", "del\x7f"}
+	for _, key := range keys {
+		encoded := encodeKey(key)
+		if hasUnsafeKeyChars(encoded) {
+			t.Errorf("encodeKey(%q) = %q still contains unsafe characters", key, encoded)
+		}
+		decoded, err := decodeKey(encoded)
+		if err != nil || decoded != key {
+			t.Errorf("decodeKey(%q) = %q, %v, want %q", encoded, decoded, err, key)
+		}
+	}
+	if got := encodeKey("music/#1 hits?.mp3"); got != "music/%231 hits%3F.mp3" {
+		t.Errorf("Unexpected encoded key %q", got)
+	}
+}
+
+func TestDecodeListKey(t *testing.T) {
+	if got := decodeListKey(types.EncodingTypeUrl, "notes/line%0Abreak+1.txt"); got != "notes/line\nbreak 1.txt" {
+		t.Errorf("Unexpected decoded key %q", got)
+	}
+	if got := decodeListKey("", "a+b%20c"); got != "a+b%20c" {
+		t.Errorf("Expected the key unchanged, got %q", got)
+	}
+}
+
+func TestSafeKey(t *testing.T) {
+	bm := &BackupManager{config: &Config{UnsafeKeys: unsafeKeysKeep}}
+	metadata := map[string]string{mtimeMetadataKey: "1"}
+	if key, md, err := bm.safeKey("a?b", metadata); err != nil || key != "a?b" || md[keyEncodingMetadataKey] != "" {
+		t.Errorf("keep: got %q, %v, %v", key, md, err)
+	}
+
+	bm.config.UnsafeKeys = unsafeKeysEncode
+	key, md, err := bm.safeKey("a?b", metadata)
+	if err != nil || key != "a%3Fb" || md[keyEncodingMetadataKey] != keyEncodingPercent || md[mtimeMetadataKey] != "1" {
+		t.Errorf("encode: got %q, %v, %v", key, md, err)
+	}
+	if _, ok := metadata[keyEncodingMetadataKey]; ok {
+		t.Error("encode modified the shared metadata")
+	}
+	if key, md, _ := bm.safeKey("100%.txt", metadata); key != "100%.txt" || md[keyEncodingMetadataKey] != "" {
+		t.Errorf("safe keys must not be encoded, got %q", key)
+	}
+
+	bm.config.UnsafeKeys = unsafeKeysReject
+	if _, _, err := bm.safeKey("a\nb", metadata); !errors.Is(err, errUnsafeKey) {
+		t.Errorf("Expected errUnsafeKey, got %v", err)
+	}
+}
+
+// metadataStorage is a Storage that only serves object metadata
+type metadataStorage struct {
+	Storage
+	metadata map[string]map[string]string
+}
+
+func (m metadataStorage) Metadata(_ context.Context, key string) (map[string]string, error) {
+	return m.metadata[key], nil
+}
+
+func TestDecodedKey(t *testing.T) {
+	rm := &RestoreManager{config: &Config{}, storage: metadataStorage{metadata: map[string]map[string]string{
+		"backups/a%3Fb": {keyEncodingMetadataKey: keyEncodingPercent},
+	}}}
+	if got := rm.decodedKey(context.Background(), "backups/a%3Fb"); got != "backups/a?b" {
+		t.Errorf("Expected backups/a?b, got %q", got)
+	}
+	if got := rm.decodedKey(context.Background(), "backups/100%25"); got != "backups/100%25" {
+		t.Errorf("Keys without key encoding must not be decoded, got %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+const (
+	// keyNameMetadataKey holds the encrypted key of an object uploaded with an obfuscated key
+	keyNameMetadataKey = "key_name"
+	// obfuscatedKeyLength is the number of hex characters of obfuscated keys, 128 bits of the HMAC
+	obfuscatedKeyLength = 32
+)
+
+// keyCipher obfuscates object keys with an HMAC and encrypts them with AES-GCM,
+// both keys are derived from the secret
+type keyCipher struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+// newKeyCipher returns the cipher of the configured secret, nil when obfuscation is disabled
+func newKeyCipher(config *Config) (*keyCipher, error) {
+	if !config.ObfuscateKeys {
+		return nil, nil
+	}
+	hashKey, err := hkdf.Key(sha256.New, []byte(config.KeySecret), nil, "s3safe key name", 32)
+	if err != nil {
+		return nil, err
+	}
+	encryptionKey, err := hkdf.Key(sha256.New, []byte(config.KeySecret), nil, "s3safe key encryption", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	// The nonce is random and prepended to the ciphertext, as approved in FIPS 140-3 mode
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return nil, err
+	}
+	return &keyCipher{hashKey: hashKey, aead: aead}, nil
+}
+
+// name returns the obfuscated name of a key, a key always has the same name so that
+// backups overwrite their previous objects
+func (k *keyCipher) name(key string) string {
+	mac := hmac.New(sha256.New, k.hashKey)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))[:obfuscatedKeyLength]
+}
+
+// seal encrypts a key, the object name is authenticated so the metadata cannot be moved to another object
+func (k *keyCipher) seal(key, name string) (string, error) {
+	return base64.RawURLEncoding.EncodeToString(k.aead.Seal(nil, nil, []byte(key), []byte(name))), nil
+}
+
+// open decrypts a key sealed for the object name
+func (k *keyCipher) open(sealed, name string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < k.aead.Overhead() {
+		return "", errors.New("invalid encrypted key")
+	}
+	key, err := k.aead.Open(nil, nil, data, []byte(name))
+	if err != nil {
+		return "", errors.New("unable to decrypt key, is the key secret the one of the backup")
+	}
+	return string(key), nil
+}
+
+// obfuscatedKey replaces the key by its obfuscated name, metadata is returned with the encrypted key
+func (bm *BackupManager) obfuscatedKey(key string, metadata map[string]string) (string, map[string]string, error) {
+	name := bm.keys.name(key)
+	sealed, err := bm.keys.seal(key, name)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt key %q: %w", key, err)
+	}
+	obfuscated := maps.Clone(metadata)
+	if obfuscated == nil {
+		obfuscated = map[string]string{}
+	}
+	obfuscated[keyNameMetadataKey] = sealed
+	return name, obfuscated, nil
+}
+
+// objectName returns the name of the object of a file name given on the command line, obfuscated when enabled
+func (rm *RestoreManager) objectName(name string) string {
+	if rm.keys == nil {
+		return name
+	}
+	return rm.keys.name(name)
+}
+
+// revealedKey returns the key of an object uploaded with an obfuscated key, decrypted from its metadata,
+// and whether it was gzipped by --compress-files
+func (rm *RestoreManager) revealedKey(ctx context.Context, key string) (string, bool) {
+	reader, ok := rm.storage.(MetadataReader)
+	if !ok {
+		return key, false
+	}
+	metadata, err := reader.Metadata(ctx, key)
+	if err != nil {
+		rm.log().Warn("Unable to read object metadata", "file", key, "error", err)
+		return key, false
+	}
+	sealed, ok := metadata[keyNameMetadataKey]
+	if !ok {
+		return key, false
+	}
+	name := key[strings.LastIndex(key, "/")+1:]
+	revealed, err := rm.keys.open(sealed, name)
+	if err != nil {
+		rm.log().Warn("Unable to reveal key", "file", key, "error", err)
+		return key, false
+	}
+	revealed = strings.TrimSuffix(key, name) + revealed
+	if metadata[compressionMetadataKey] == compressionGzip && strings.HasSuffix(revealed, gzipSuffix) {
+		return strings.TrimSuffix(revealed, gzipSuffix), true
+	}
+	return revealed, false
+}
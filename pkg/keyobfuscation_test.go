@@ -0,0 +1,165 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testKeyCipher(t *testing.T, secret string) *keyCipher {
+	t.Helper()
+	keys, err := newKeyCipher(&Config{ObfuscateKeys: true, KeySecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestKeyCipher(t *testing.T) {
+	keys := testKeyCipher(t, "secret")
+	name := keys.name("photos/2024/beach.jpg")
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(name) {
+		t.Errorf("Unexpected obfuscated name %q", name)
+	}
+	if keys.name("photos/2024/beach.jpg") != name {
+		t.Error("Expected the same name for the same key")
+	}
+	if testKeyCipher(t, "other").name("photos/2024/beach.jpg") == name {
+		t.Error("Expected another name with another secret")
+	}
+
+	sealed, err := keys.seal("photos/2024/beach.jpg", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "beach") {
+		t.Errorf("Key not encrypted: %q", sealed)
+	}
+	if key, err := keys.open(sealed, name); err != nil || key != "photos/2024/beach.jpg" {
+		t.Errorf("open = %q, %v", key, err)
+	}
+	if _, err := keys.open(sealed, keys.name("other.jpg")); err == nil {
+		t.Error("Expected the key sealed for another object to be rejected")
+	}
+	if _, err := testKeyCipher(t, "other").open(sealed, name); err == nil {
+		t.Error("Expected another secret to be rejected")
+	}
+	if keys, err := newKeyCipher(&Config{KeySecret: "secret"}); keys != nil || err != nil {
+		t.Errorf("Expected no cipher without --obfuscate-keys, got %v, %v", keys, err)
+	}
+}
+
+func TestRevealedKey(t *testing.T) {
+	keys := testKeyCipher(t, "secret")
+	metadata := map[string]map[string]string{}
+	for key, extra := range map[string]map[string]string{
+		"docs/report.pdf": nil,
+		"logs/app.log.gz": {compressionMetadataKey: compressionGzip},
+	} {
+		name := keys.name(key)
+		sealed, err := keys.seal(key, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata["backups/"+name] = map[string]string{keyNameMetadataKey: sealed}
+		for k, v := range extra {
+			metadata["backups/"+name][k] = v
+		}
+	}
+	metadata["backups/plain.txt"] = map[string]string{}
+	rm := &RestoreManager{config: &Config{}, keys: keys, storage: metadataStorage{metadata: metadata}}
+
+	tests := []struct {
+		key, want string
+		gzipped   bool
+	}{
+		{"backups/" + keys.name("docs/report.pdf"), "backups/docs/report.pdf", false},
+		{"backups/" + keys.name("logs/app.log.gz"), "backups/logs/app.log", true},
+		{"backups/plain.txt", "backups/plain.txt", false},
+	}
+	for _, tt := range tests {
+		if got, gzipped := rm.restoredKey(context.Background(), tt.key); got != tt.want || gzipped != tt.gzipped {
+			t.Errorf("restoredKey(%q) = %q, %v, want %q, %v", tt.key, got, gzipped, tt.want, tt.gzipped)
+		}
+	}
+	if got := rm.objectName("docs/report.pdf"); got != keys.name("docs/report.pdf") {
+		t.Errorf("objectName = %q", got)
+	}
+}
+
+func TestBackupObfuscateKeys(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	uploaded := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uploaded[strings.TrimPrefix(r.URL.Path, "/bucket/")] = r.Header.Clone()
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "private"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "private", "passwords.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.Recursive = true
+	cfg.ObfuscateKeys = true
+	cfg.KeySecret = "secret"
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	keys := testKeyCipher(t, "secret")
+	name := keys.name("private/passwords.txt")
+	header, ok := uploaded["backups/"+name]
+	if !ok || len(uploaded) != 1 {
+		t.Fatalf("Expected backups/%s to be uploaded, got %v", name, uploaded)
+	}
+	if key, err := keys.open(header.Get("X-Amz-Meta-Key_name"), name); err != nil || key != "private/passwords.txt" {
+		t.Errorf("Expected the encrypted key in the metadata, got %q, %v", key, err)
+	}
+
+	cfg.KeySecret = ""
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected --obfuscate-keys without a secret to be rejected, got %v", err)
+	}
+}
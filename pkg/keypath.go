@@ -0,0 +1,65 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Object keys always use forward slashes while local paths use the native separator,
+// keys must be built with objectKey and mapped back to files with keyToLocal
+
+// objectKey joins local paths or key elements into an object key
+func objectKey(elem ...string) string {
+	parts := make([]string, len(elem))
+	for i, e := range elem {
+		parts[i] = filepath.ToSlash(e)
+	}
+	return path.Join(parts...)
+}
+
+// keyToLocal returns the local path of an object key relative to root
+func keyToLocal(root, key string) string {
+	return filepath.Join(root, filepath.FromSlash(key))
+}
+
+// relativeKey returns a key relative to the prefix it was listed under. The prefix matches whole path
+// elements in any case, a key equal to the prefix is relative as its base name and a key outside the
+// prefix is kept without leading slashes.
+func relativeKey(key, prefix string) string {
+	prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+	key = strings.TrimLeft(key, "/")
+	switch {
+	case prefix == "":
+		return key
+	case strings.EqualFold(key, prefix):
+		return path.Base(key)
+	case len(key) > len(prefix) && key[len(prefix)] == '/' && strings.EqualFold(key[:len(prefix)], prefix):
+		return strings.TrimLeft(key[len(prefix):], "/")
+	}
+	return key
+}
@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		elem []string
+		want string
+	}{
+		{[]string{"backups", "etc/app.conf"}, "backups/etc/app.conf"},
+		{[]string{"", "app.conf"}, "app.conf"},
+		{[]string{"backups/", "daily", "db.tar.gz"}, "backups/daily/db.tar.gz"},
+		{[]string{"backups", filepath.Join("etc", "app.conf")}, "backups/etc/app.conf"},
+	}
+	for _, tt := range tests {
+		if got := objectKey(tt.elem...); got != tt.want {
+			t.Errorf("objectKey(%q) = %q, want %q", tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestKeyToLocal(t *testing.T) {
+	root := t.TempDir()
+	want := filepath.Join(root, "etc", "app.conf")
+	if got := keyToLocal(root, "etc/app.conf"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestRelativeKey(t *testing.T) {
+	tests := []struct {
+		key, prefix, want string
+	}{
+		{"backups/job/a.txt", "backups/job", "a.txt"},
+		{"backups/job/etc/app.conf", "backups/job/", "etc/app.conf"},
+		{"backups/job/a.txt", "/backups/job", "a.txt"},
+		{"/backups/job/a.txt", "backups/job", "a.txt"},
+		{"backups/job//a.txt", "backups/job", "a.txt"},
+		{"Backups/Job/a.txt", "backups/job", "a.txt"},
+		{"backups/job.tar.gz", "backups/job.tar.gz", "job.tar.gz"},
+		{"backups/JOB.tar.gz", "backups/job.tar.gz", "JOB.tar.gz"},
+		{"backups/job2/a.txt", "backups/job", "backups/job2/a.txt"},
+		{"other/a.txt", "backups", "other/a.txt"},
+		{"a.txt", "", "a.txt"},
+		{"backups/job/a.txt", filepath.Join("backups", "job"), "a.txt"},
+	}
+	for _, tt := range tests {
+		if got := relativeKey(tt.key, tt.prefix); got != tt.want {
+			t.Errorf("relativeKey(%q, %q) = %q, want %q", tt.key, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectKeyWindows(t *testing.T) {
+	if got := objectKey("backups", `etc\app.conf`); got != "backups/etc/app.conf" {
+		t.Errorf("Expected backups/etc/app.conf, got %q", got)
+	}
+	if got := objectKey(`C:\mirror`, `etc\app.conf`); got != "C:/mirror/etc/app.conf" {
+		t.Errorf("Expected C:/mirror/etc/app.conf, got %q", got)
+	}
+}
+
+func TestKeyToLocalWindows(t *testing.T) {
+	if got := keyToLocal(`C:\restore`, "etc/app.conf"); got != `C:\restore\etc\app.conf` {
+		t.Errorf(`Expected C:\restore\etc\app.conf, got %q`, got)
+	}
+}
+
+func TestLocalPathWindows(t *testing.T) {
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: `C:\restore`, SanitizeNames: sanitizeReplace}}
+	if got, err := rm.localPath("backups/etc/app.conf"); err != nil || got != `C:\restore\etc\app.conf` {
+		t.Errorf(`Expected C:\restore\etc\app.conf, got %q (%v)`, got, err)
+	}
+	if got, err := rm.localPath("backups/dev/CON.txt"); err != nil || got != `C:\restore\dev\CON_.txt` {
+		t.Errorf(`Expected C:\restore\dev\CON_.txt, got %q (%v)`, got, err)
+	}
+	long := strings.Repeat("a", 300)
+	if got, err := rm.localPath("backups/" + long); err != nil || !strings.HasPrefix(got, `\\?\C:\restore\`) {
+		t.Errorf("Expected a long path prefix, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// kubernetesConfigDir is the default mount path of the ConfigMap and Secret volumes of the job
+	kubernetesConfigDir = "/etc/s3safe"
+	// kubernetesAnnotationsFile is the default mount path of the pod annotations exposed by the Downward API
+	kubernetesAnnotationsFile = "/etc/podinfo/annotations"
+	// kubernetesReadyFile is the default file created once the job is set up, checked by readiness probes
+	kubernetesReadyFile = "/tmp/s3safe-ready"
+	// kubernetesTerminationLog is the default terminationMessagePath of a container
+	kubernetesTerminationLog = "/dev/termination-log"
+	// maxTerminationMessage is the size Kubernetes keeps of a termination message
+	maxTerminationMessage = 4096
+	// annotationPrefix prefixes the pod annotations setting flags, such as s3safe/path
+	annotationPrefix = "s3safe/"
+	// flagEnvPrefix prefixes the environment variables setting flags in Kubernetes mode, such as S3SAFE_PATH
+	flagEnvPrefix = "S3SAFE_"
+)
+
+// envName matches the ConfigMap and Secret keys read as environment variables
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// kubernetesMode is enabled by --k8s
+var kubernetesMode bool
+
+// SetupKubernetes enables the Kubernetes mode of --k8s or S3SAFE_K8S, for CronJobs and sidecars:
+// the ConfigMap and Secret files of the config directory are loaded as environment variables,
+// unset flags are read from S3SAFE_<FLAG> variables and s3safe/<flag> pod annotations,
+// logs are written as JSON to stdout, errors to the termination log, and SIGTERM cancels the operation.
+func SetupKubernetes(cmd *cobra.Command) error {
+	enabled, _ := cmd.Flags().GetBool("k8s")
+	if !enabled && !utils.BoolEnv(utils.K8sEnv) {
+		return nil
+	}
+	kubernetesMode = true
+	slog.SetDefault(slog.New(&terminationHandler{
+		Handler: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}),
+		path:    envOr(utils.K8sTerminationLogEnv, kubernetesTerminationLog),
+	}))
+	if err := loadConfigDir(envOr(utils.K8sConfigDirEnv, kubernetesConfigDir)); err != nil {
+		return err
+	}
+	annotations, err := readAnnotations(envOr(utils.K8sAnnotationsFileEnv, kubernetesAnnotationsFile))
+	if err != nil {
+		return err
+	}
+	// Annotations describe the pod, they take precedence over the environment of the job
+	if err := setFlags(cmd.Flags(), func(name string) (string, bool) {
+		value, ok := annotations[annotationPrefix+name]
+		return value, ok
+	}); err != nil {
+		return fmt.Errorf("invalid annotation: %w", err)
+	}
+	if err := setFlags(cmd.Flags(), func(name string) (string, bool) {
+		return os.LookupEnv(flagEnv(name))
+	}); err != nil {
+		return fmt.Errorf("invalid environment variable: %w", err)
+	}
+	ctx, _ := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, os.Interrupt)
+	cmd.SetContext(ctx)
+	return nil
+}
+
+// flagEnv returns the environment variable of a flag in Kubernetes mode, S3SAFE_STORAGE_CLASS for --storage-class
+func flagEnv(name string) string {
+	return flagEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// setFlags sets the flags left unset on the command line from the values returned by lookup
+func setFlags(flags *pflag.FlagSet, lookup func(name string) (string, bool)) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" || flag.Name == "k8s" {
+			return
+		}
+		value, ok := lookup(flag.Name)
+		if !ok || value == "" {
+			return
+		}
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("--%s %q: %w", flag.Name, value, setErr)
+		}
+	})
+	return err
+}
+
+// loadConfigDir sets the environment variables named after the files of a mounted ConfigMap or Secret,
+// variables already set are kept. A missing directory is ignored.
+func loadConfigDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read config directory: %w", err)
+	}
+	loaded := 0
+	for _, entry := range entries {
+		// Mounted volumes hold their data in hidden ..data directories, the keys are symbolic links to them
+		name := entry.Name()
+		if !envName.MatchString(name) || entry.IsDir() {
+			continue
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("unable to read config file: %w", err)
+		}
+		if err := os.Setenv(name, strings.TrimRight(string(value), "\r\n")); err != nil {
+			return err
+		}
+		loaded++
+	}
+	slog.Info("Loaded configuration", "dir", dir, "variables", loaded)
+	return nil
+}
+
+// readAnnotations reads the pod annotations file of the Downward API, one key="value" line per annotation.
+// A missing file returns no annotations.
+func readAnnotations(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read pod annotations: %w", err)
+	}
+	defer file.Close()
+	annotations := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, quoted, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod annotation %s: %w", key, err)
+		}
+		annotations[key] = value
+	}
+	return annotations, scanner.Err()
+}
+
+// markReady creates the file checked by the readiness probe of a sidecar once the job is set up
+func markReady() {
+	path := envOr(utils.K8sReadyFileEnv, kubernetesReadyFile)
+	if err := os.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644); err != nil {
+		slog.Warn("Unable to create the readiness file", "file", path, "error", err)
+	}
+}
+
+// terminationHandler writes the last error logged to the termination log, shown by kubectl describe pod
+type terminationHandler struct {
+	slog.Handler
+	path string
+}
+
+func (h *terminationHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		message := record.Message
+		record.Attrs(func(attr slog.Attr) bool {
+			if attr.Key == "error" {
+				message += ": " + attr.Value.String()
+			}
+			return true
+		})
+		if len(message) > maxTerminationMessage {
+			message = message[:maxTerminationMessage]
+		}
+		// The termination log only exists in a container
+		_ = os.WriteFile(h.path, []byte(message), 0o644)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *terminationHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &terminationHandler{Handler: h.Handler.WithAttrs(attrs), path: h.path}
+}
+
+func (h *terminationHandler) WithGroup(name string) slog.Handler {
+	return &terminationHandler{Handler: h.Handler.WithGroup(name), path: h.path}
+}
+
+// envOr returns an environment variable, or value when it is unset
+func envOr(key, value string) string {
+	if env := utils.Env(key); env != "" {
+		return env
+	}
+	return value
+}
@@ -0,0 +1,170 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, value := range map[string]string{"S3SAFE_TEST_BUCKET": "backups\n", "S3SAFE_TEST_KEPT": "configmap", "not-an-env": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("S3SAFE_TEST_BUCKET", "")
+	os.Unsetenv("S3SAFE_TEST_BUCKET")
+	t.Setenv("S3SAFE_TEST_KEPT", "environment")
+
+	if err := loadConfigDir(dir); err != nil {
+		t.Fatalf("loadConfigDir: %v", err)
+	}
+	if got := os.Getenv("S3SAFE_TEST_BUCKET"); got != "backups" {
+		t.Errorf("S3SAFE_TEST_BUCKET = %q, want backups", got)
+	}
+	if got := os.Getenv("S3SAFE_TEST_KEPT"); got != "environment" {
+		t.Errorf("S3SAFE_TEST_KEPT = %q, the environment takes precedence", got)
+	}
+	if err := loadConfigDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("a missing config directory fails: %v", err)
+	}
+}
+
+func TestReadAnnotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations")
+	content := "kubernetes.io/config.seen=\"2025-01-02T03:04:05Z\"\ns3safe/path=\"/data\"\ns3safe/exclude=\"*.tmp,\\\"cache\\\"\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	annotations, err := readAnnotations(path)
+	if err != nil {
+		t.Fatalf("readAnnotations: %v", err)
+	}
+	if annotations["s3safe/path"] != "/data" || annotations["s3safe/exclude"] != `*.tmp,"cache"` {
+		t.Errorf("annotations = %v", annotations)
+	}
+	if annotations, err := readAnnotations(path + ".missing"); err != nil || annotations != nil {
+		t.Errorf("missing annotations file = %v, %v", annotations, err)
+	}
+}
+
+func TestSetupKubernetes(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer func() { kubernetesMode = false }()
+	dir := t.TempDir()
+	annotations := filepath.Join(dir, "annotations")
+	if err := os.WriteFile(annotations, []byte("s3safe/path=\"/mnt/pvc\"\ns3safe/compress=\"true\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("S3SAFE_K8S", "true")
+	t.Setenv("S3SAFE_CONFIG_DIR", filepath.Join(dir, "config"))
+	t.Setenv("S3SAFE_ANNOTATIONS_FILE", annotations)
+	t.Setenv("S3SAFE_PATH", "/from/env")
+	t.Setenv("S3SAFE_DEST", "backups/pvc")
+	t.Setenv("S3SAFE_STORAGE_CLASS", "GLACIER")
+	t.Setenv("S3SAFE_TIMESTAMP", "true")
+
+	cmd := &cobra.Command{Use: "backup"}
+	cmd.Flags().Bool("k8s", false, "")
+	for _, name := range []string{"path", "dest", "storage-class"} {
+		cmd.Flags().String(name, "", "")
+	}
+	cmd.Flags().Bool("compress", false, "")
+	cmd.Flags().Bool("timestamp", false, "")
+	if err := cmd.Flags().Parse([]string{"--storage-class", "STANDARD_IA"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetContext(context.Background())
+	if err := SetupKubernetes(cmd); err != nil {
+		t.Fatalf("SetupKubernetes: %v", err)
+	}
+	for name, want := range map[string]string{
+		"path":          "/mnt/pvc",
+		"dest":          "backups/pvc",
+		"storage-class": "STANDARD_IA",
+		"compress":      "true",
+		"timestamp":     "true",
+	} {
+		if got := cmd.Flags().Lookup(name).Value.String(); got != want {
+			t.Errorf("--%s = %q, want %q", name, got, want)
+		}
+	}
+	if !kubernetesMode {
+		t.Error("Kubernetes mode is not enabled")
+	}
+
+	t.Setenv("S3SAFE_TIMESTAMP", "sometimes")
+	cmd.Flags().Lookup("timestamp").Changed = false
+	if err := SetupKubernetes(cmd); err == nil {
+		t.Error("an invalid flag value is accepted")
+	}
+}
+
+func TestTerminationHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "termination-log")
+	logger := slog.New(&terminationHandler{Handler: slog.NewTextHandler(io.Discard, nil), path: path})
+	logger.Warn("Skipping file", "error", "permission denied")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a warning is written to the termination log: %v", err)
+	}
+	logger.With("command", "backup").Error("Backup error", "error", "connection refused", "file", "a.txt")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "Backup error: connection refused" {
+		t.Errorf("termination message = %q", got)
+	}
+	logger.Error(strings.Repeat("x", maxTerminationMessage+10))
+	if got, _ := os.ReadFile(path); len(got) != maxTerminationMessage {
+		t.Errorf("termination message is %d bytes, want %d", len(got), maxTerminationMessage)
+	}
+}
+
+func TestMarkReady(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ready")
+	t.Setenv("S3SAFE_READY_FILE", path)
+	markReady()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the readiness file is not created: %v", err)
+	}
+}
+
+func TestFlagEnv(t *testing.T) {
+	if got := flagEnv("storage-class"); got != "S3SAFE_STORAGE_CLASS" {
+		t.Errorf("flagEnv = %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LatestFile is the name of the marker written next to compressed backups
+const LatestFile = "latest.json"
+
+// LatestMarker points at the newest compressed backup of a destination prefix
+type LatestMarker struct {
+	File string `json:"file"`
+	// Dir is the directory of the file under the prefix of the marker, the date directory of --layout date
+	Dir       string    `json:"dir,omitempty"`
+	Size      int64     `json:"size"`
+	Hostname  string    `json:"hostname"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// writeLatest uploads the latest.json marker of the archive to every healthy destination, under the prefix
+// without the date directory of --layout date so restores find it at the same place
+func (bm *BackupManager) writeLatest(ctx context.Context, archive string) error {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+	hostname, _ := os.Hostname()
+	data, err := json.MarshalIndent(LatestMarker{
+		File:      filepath.Base(archive),
+		Dir:       bm.destinations[0].dir(),
+		Size:      info.Size(),
+		Hostname:  hostname,
+		CreatedAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+
+	tmp, err := os.CreateTemp("", "s3safe-latest-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+
+	// The marker is rewritten on every backup, keep it readable and mutable
+	opts := UploadOptions{ContentType: "application/json", ACL: bm.config.ACL}
+	_, err = bm.uploadUnder(ctx, tmp.Name(), LatestFile, opts, func(d *destination) string { return d.base })
+	if err != nil && !errors.Is(err, errUnverifiable) {
+		return fmt.Errorf("failed to write %s: %w", LatestFile, err)
+	}
+	bm.log().Info("Updated latest marker", "file", filepath.Base(archive))
+	return nil
+}
+
+// resolveLatest reads the latest.json marker of the restore path and selects its archive
+func (rm *RestoreManager) resolveLatest(ctx context.Context) error {
+	if rm.config.File != "" {
+		return errors.New("--latest cannot be used with --file")
+	}
+	tmp, err := os.MkdirTemp("", "s3safe-latest-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+
+	local := filepath.Join(tmp, LatestFile)
+	if err := rm.storage.Download(ctx, objectKey(rm.config.Path, rm.objectName(LatestFile)), local, DownloadOptions{Force: true}); err != nil {
+		return fmt.Errorf("failed to read %s, was the backup made with --compress: %w", LatestFile, err)
+	}
+	marker, err := readLatestMarker(local)
+	if err != nil {
+		return err
+	}
+
+	rm.log().Info("Resolved latest backup", "file", marker.File, "created_at", marker.CreatedAt, "hostname", marker.Hostname)
+	rm.config.File = marker.File
+	if marker.Dir != "" {
+		rm.config.Path = objectKey(rm.config.Path, marker.Dir)
+	}
+	return nil
+}
+
+func readLatestMarker(path string) (*LatestMarker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LatestFile, err)
+	}
+	var marker LatestMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LatestFile, err)
+	}
+	if marker.File == "" || marker.File != filepath.Base(marker.File) {
+		return nil, fmt.Errorf("invalid %s: unexpected file %q", LatestFile, marker.File)
+	}
+	if marker.Dir != "" && (!fs.ValidPath(marker.Dir) || marker.Dir == ".") {
+		return nil, fmt.Errorf("invalid %s: unexpected dir %q", LatestFile, marker.Dir)
+	}
+	return &marker, nil
+}
@@ -0,0 +1,63 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLatestMarker(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, LatestFile)
+
+	if err := os.WriteFile(path, []byte(`{"file":"data-2025-06-01_12-00-00.tar.gz","size":42,"hostname":"web-1","created_at":"2025-06-01T12:00:00Z"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	marker, err := readLatestMarker(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if marker.File != "data-2025-06-01_12-00-00.tar.gz" || marker.Size != 42 {
+		t.Errorf("Unexpected marker %+v", marker)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"file":"data.tar.gz","dir":"2025/06/01"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if marker, err = readLatestMarker(path); err != nil || marker.Dir != "2025/06/01" {
+		t.Errorf("Expected the date directory of the archive, got %+v (%v)", marker, err)
+	}
+
+	for _, invalid := range []string{`{"file":""}`, `{"file":"../etc/passwd"}`, `{"file":"a.tar.gz","dir":"../other"}`, `{"file":"a.tar.gz","dir":"/etc"}`, `not json`} {
+		if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readLatestMarker(path); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLayoutPrefix(t *testing.T) {
+	c := &Config{Layout: layoutFlat, Timezone: "UTC"}
+	if got := c.layoutPrefix("backups"); got != "backups" {
+		t.Errorf("flat: got %q", got)
+	}
+	c.Layout = layoutDate
+	today := time.Now().UTC().Format("2006/01/02")
+	if got := c.layoutPrefix("backups"); got != "backups/"+today {
+		t.Errorf("date: got %q, want backups/%s", got, today)
+	}
+	if got := c.layoutPrefix(""); got != today {
+		t.Errorf("date without prefix: got %q, want %s", got, today)
+	}
+}
+
+func TestBackupDateLayout(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, object := conditionalServer(t, nil)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.Layout = "DATE"
+	cfg.Timezone = "UTC"
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	key := "backups/" + time.Now().UTC().Format("2006/01/02") + "/a.txt"
+	if got := object(key); string(got) != "hello" {
+		t.Errorf("Expected %s to be uploaded, got %q", key, got)
+	}
+
+	cfg.Layout = "weekly"
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected an invalid layout to be rejected, got %v", err)
+	}
+}
+
+func TestRestoreLatestDateLayout(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, object := conditionalServer(t, nil)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.Layout = layoutDate
+	cfg.Compress = true
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if object("backups/"+LatestFile) == nil {
+		t.Fatalf("Expected %s under the undated prefix", LatestFile)
+	}
+
+	dest := t.TempDir()
+	restore := testConfig(dest, server.URL)
+	restore.Path = "backups"
+	restore.Dest = dest
+	restore.Latest = true
+	restore.SkipSpaceCheck = true
+	rm, err := NewRestoreManagerFromConfig(context.Background(), restore, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.Restore(context.Background()); err != nil {
+		t.Fatalf("Expected --latest to restore the dated archive, got %v", err)
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil || len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".tar.gz") {
+		t.Errorf("Expected the archive to be restored into %s, got %v (%v)", dest, entries, err)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Option configures a manager created by NewBackupManagerFromConfig or NewRestoreManagerFromConfig
+type Option func(*managerOptions)
+
+type managerOptions struct {
+	// environment fills the unset settings from the environment variables
+	environment bool
+	// skipConnectionCheck skips the bucket check of the validation
+	skipConnectionCheck bool
+	logger              *slog.Logger
+	reporter            Reporter
+}
+
+// WithEnvironment fills the settings left unset from the AWS_* and S3SAFE_* environment variables,
+// and from Config.EnvFile when set, as the command line does
+func WithEnvironment() Option {
+	return func(o *managerOptions) {
+		o.environment = true
+	}
+}
+
+// WithoutConnectionCheck skips the bucket existence check when the manager is created
+func WithoutConnectionCheck() Option {
+	return func(o *managerOptions) {
+		o.skipConnectionCheck = true
+	}
+}
+
+// WithLogger logs to logger instead of the slog default logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *managerOptions) {
+		o.logger = logger
+	}
+}
+
+// WithReporter reports the progress of each uploaded or downloaded file to reporter
+func WithReporter(reporter Reporter) Option {
+	return func(o *managerOptions) {
+		o.reporter = reporter
+	}
+}
+
+func newManagerOptions(opts []Option) managerOptions {
+	var o managerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// config returns a copy of cfg with the defaults applied, the caller's slices are not modified
+func (o managerOptions) config(cfg Config) *Config {
+	cfg.Exclude = slices.Clone(cfg.Exclude)
+	cfg.Mirrors = slices.Clone(cfg.Mirrors)
+	if o.environment {
+		if cfg.EnvFile != "" {
+			loadEnv(cfg.EnvFile)
+		}
+		cfg.loadAWSConfig()
+	}
+	cfg.prepare()
+	return &cfg
+}
+
+// FileError is the error of a single file
+type FileError struct {
+	Key string
+	Err error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// BackupResult summarizes a backup
+type BackupResult struct {
+	// Files is the number of uploaded files and Bytes their total size
+	Files int
+	Bytes int64
+	// Skipped is the number of excluded files and of files already stored, with SkipIdentical
+	Skipped int
+	// Deleted is the number of source files deleted with DeleteSource
+	Deleted int
+	// Verified is the number of files compared with their uploaded object by VerifySample
+	Verified int
+	// Archive is the name of the uploaded archive of a compressed backup
+	Archive string
+	// Rejected holds the files that were not uploaded because their key contains unsafe characters
+	Rejected []string
+	// Failed holds the files skipped with IgnoreErrors because they could not be read
+	Failed   []FileError
+	Duration time.Duration
+}
+
+// RestoreResult summarizes a restore
+type RestoreResult struct {
+	// Files is the number of downloaded files and Bytes their total size
+	Files int
+	Bytes int64
+	// Skipped is the number of excluded files, of files restored by an interrupted run and of skipped invalid names
+	Skipped int
+	// Failed holds the files whose errors were ignored with IgnoreErrors
+	Failed   []FileError
+	Duration time.Duration
+}
+
+// NewBackupManagerFromConfig creates a BackupManager from a configuration, for use as a library.
+// Unset settings get the command line defaults, path templates are expanded and the configuration is validated.
+func NewBackupManagerFromConfig(ctx context.Context, cfg Config, opts ...Option) (*BackupManager, error) {
+	o := newManagerOptions(opts)
+	return newBackupManager(ctx, o.config(cfg), o)
+}
+
+// NewRestoreManagerFromConfig creates a RestoreManager from a configuration, for use as a library.
+// Unset settings get the command line defaults, path templates are expanded and the configuration is validated.
+func NewRestoreManagerFromConfig(ctx context.Context, cfg Config, opts ...Option) (*RestoreManager, error) {
+	o := newManagerOptions(opts)
+	return newRestoreManager(ctx, o.config(cfg), o)
+}
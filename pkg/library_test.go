@@ -0,0 +1,191 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewBackupManagerFromConfig(t *testing.T) {
+	// The SDK cannot apply a CA bundle to the custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, keys := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello", "b.txt": "world!", "skip.txt": "skipped"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.Exclude = []string{"skip.txt"}
+	var logs bytes.Buffer
+	reporter := &recordingReporter{}
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck(),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))), WithReporter(reporter))
+	if err != nil {
+		t.Fatalf("NewBackupManagerFromConfig: %v", err)
+	}
+	if bm.config.TimestampFormat == "" || bm.config.UnsafeKeys != unsafeKeysKeep {
+		t.Errorf("defaults not applied: %+v", bm.config)
+	}
+
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != 11 || result.Skipped != 1 {
+		t.Errorf("result = %+v, want 2 files, 11 bytes and 1 skipped", result)
+	}
+	if got := keys(); !slices.Equal(got, []string{"backups/a.txt", "backups/b.txt"}) {
+		t.Errorf("uploaded keys = %v", got)
+	}
+	if !slices.Equal(reporter.events, []string{"start a.txt 5", "done a.txt 5 <nil>", "start b.txt 6", "done b.txt 6 <nil>"}) {
+		t.Errorf("reported events = %v", reporter.events)
+	}
+	if !strings.Contains(logs.String(), "Upload completed successfully") {
+		t.Errorf("logs were not written to the logger: %q", logs.String())
+	}
+}
+
+func TestBackupIgnoreErrors(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, keys := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(src, "missing"), filepath.Join(src, "broken")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	cfg := testConfig(src, server.URL)
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err == nil {
+		t.Fatal("Expected the unreadable file to abort the backup")
+	}
+
+	cfg.IgnoreErrors = true
+	bm, err = NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if ExitCode(err) != ExitPartial {
+		t.Errorf("Expected a partial failure, got %v", err)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Key != "broken" || result.Files != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if got := keys(); !slices.Contains(got, "backups/a.txt") {
+		t.Errorf("uploaded keys = %v", got)
+	}
+}
+
+// uploadServer is a fake S3 endpoint accepting uploads, keys returns the sorted uploaded keys
+func uploadServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var uploaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		uploaded = append(uploaded, strings.TrimPrefix(r.URL.Path, "/bucket/"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		keys := slices.Clone(uploaded)
+		slices.Sort(keys)
+		return keys
+	}
+}
+
+// testConfig backs up src to the backups prefix of a fake S3 endpoint
+func testConfig(src, endpoint string) Config {
+	return Config{
+		Path:      src,
+		Dest:      "backups",
+		Region:    "us-east-1",
+		Bucket:    "bucket",
+		KeyID:     "key",
+		Secret:    "secret",
+		EndPoint:  endpoint,
+		ForcePath: true,
+		Checksum:  checksumNone,
+	}
+}
+
+// recordingReporter records the reported progress
+type recordingReporter struct {
+	events []string
+}
+
+func (r *recordingReporter) FileStarted(key string, size int64) {
+	r.events = append(r.events, fmt.Sprintf("start %s %d", key, size))
+}
+
+func (r *recordingReporter) FileCompleted(key string, size int64, err error) {
+	r.events = append(r.events, fmt.Sprintf("done %s %d %v", key, size, err))
+}
+
+func TestNewRestoreManagerFromConfigEnvironment(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_BUCKET", "from-env")
+	cfg := Config{Path: "backups", Dest: t.TempDir(), Region: "us-east-1"}
+
+	if _, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); err == nil {
+		t.Errorf("environment variables must only be read with WithEnvironment")
+	}
+	rm, err := NewRestoreManagerFromConfig(context.Background(), cfg, WithEnvironment(), WithoutConnectionCheck())
+	if err != nil {
+		t.Fatalf("NewRestoreManagerFromConfig: %v", err)
+	}
+	if rm.config.Bucket != "from-env" {
+		t.Errorf("bucket = %q, want from-env", rm.config.Bucket)
+	}
+}
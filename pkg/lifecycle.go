@@ -0,0 +1,258 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// IDs of the lifecycle rules managed by s3safe are followed by the prefix of the rule
+const (
+	// lifecycleRulePrefix starts the IDs of the rules of lifecycle apply
+	lifecycleRulePrefix = "s3safe:"
+	// mpuCleanupRulePrefix starts the IDs of the rules of --ensure-mpu-cleanup-rule
+	mpuCleanupRulePrefix = "s3safe-mpu:"
+)
+
+// LifecycleApply is the cobra command handler for lifecycle apply
+func LifecycleApply(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	return applyLifecycle(cmd.Context(), config, s3Storage)
+}
+
+// applyLifecycle creates the lifecycle rule of the destination prefix, or replaces the rule s3safe created before.
+// The other rules of the bucket are kept.
+func applyLifecycle(ctx context.Context, config *Config, s3Storage *S3Storage) error {
+	if config.Dest == "" {
+		return withExitCode(ExitConfig, errors.New("--dest is required, the lifecycle rule applies to the backup prefix"))
+	}
+	rule, err := config.lifecycleRule()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	rules, err := s3Storage.LifecycleRules(ctx)
+	if err != nil {
+		return err
+	}
+	action := "Creating"
+	if i := slices.IndexFunc(rules, func(r types.LifecycleRule) bool { return aws.ToString(r.ID) == aws.ToString(rule.ID) }); i >= 0 {
+		rules[i] = rule
+		action = "Updating"
+	} else {
+		rules = append(rules, rule)
+	}
+
+	attrs := []any{"bucket", config.Bucket, "prefix", aws.ToString(rule.Filter.Prefix)}
+	for _, transition := range rule.Transitions {
+		attrs = append(attrs, strings.ToLower(string(transition.StorageClass)), fmt.Sprintf("%dd", aws.ToInt32(transition.Days)))
+	}
+	if rule.Expiration != nil {
+		attrs = append(attrs, "expire", fmt.Sprintf("%dd", aws.ToInt32(rule.Expiration.Days)))
+	}
+	if config.DryRun {
+		slog.Info("Dry run: "+strings.ToLower(action)+" lifecycle rule", attrs...)
+		return nil
+	}
+	slog.Info(action+" lifecycle rule", attrs...)
+	return s3Storage.PutLifecycleRules(ctx, rules)
+}
+
+// lifecycleRule returns the lifecycle rule of the destination prefix from --transition-glacier,
+// --transition-deep-archive and --expire
+func (c *Config) lifecycleRule() (types.LifecycleRule, error) {
+	prefix := lifecyclePrefix(c.Dest)
+	rule := types.LifecycleRule{
+		ID:     aws.String(lifecycleRulePrefix + prefix),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+	}
+	glacier, _ := ageDays(c.TransitionGlacier)
+	deepArchive, _ := ageDays(c.TransitionDeepArchive)
+	expire, _ := ageDays(c.Expire)
+	if glacier > 0 {
+		rule.Transitions = append(rule.Transitions, types.Transition{Days: aws.Int32(glacier), StorageClass: types.TransitionStorageClassGlacier})
+	}
+	if deepArchive > 0 {
+		rule.Transitions = append(rule.Transitions, types.Transition{Days: aws.Int32(deepArchive), StorageClass: types.TransitionStorageClassDeepArchive})
+	}
+	if expire > 0 {
+		rule.Expiration = &types.LifecycleExpiration{Days: aws.Int32(expire)}
+	}
+	if len(rule.Transitions) == 0 && rule.Expiration == nil {
+		return rule, errors.New("set --transition-glacier, --transition-deep-archive or --expire")
+	}
+	return rule, nil
+}
+
+// validateLifecycle checks the ages of the lifecycle rule, the transitions must happen before the expiration
+func (c *Config) validateLifecycle() error {
+	var ages []int32
+	for _, age := range []struct{ flag, value string }{
+		{"--transition-glacier", c.TransitionGlacier},
+		{"--transition-deep-archive", c.TransitionDeepArchive},
+		{"--expire", c.Expire},
+	} {
+		if age.value == "" {
+			continue
+		}
+		days, err := ageDays(age.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", age.flag, err)
+		}
+		if len(ages) > 0 && days <= ages[len(ages)-1] {
+			return errors.New("lifecycle ages must increase: --transition-glacier, then --transition-deep-archive, then --expire")
+		}
+		ages = append(ages, days)
+	}
+	return nil
+}
+
+// ensureMPUCleanupRule adds a lifecycle rule aborting the incomplete multipart uploads under Dest after MPUCleanupDays,
+// unless a rule of the bucket already aborts them as soon. Failed runs would otherwise leave parts billed but invisible.
+// Errors are logged and the backup goes on.
+func (c *Config) ensureMPUCleanupRule(ctx context.Context) {
+	if c.MPUCleanupDays <= 0 {
+		return
+	}
+	logger := loggerOrDefault(c.logger)
+	prefix := lifecyclePrefix(c.Dest)
+	s3Storage, err := c.NewS3Storage(ctx)
+	if err != nil {
+		logger.Warn("Could not ensure the multipart upload cleanup rule", "error", err)
+		return
+	}
+	rules, err := s3Storage.LifecycleRules(ctx)
+	if err != nil {
+		logger.Warn("Could not ensure the multipart upload cleanup rule", "error", err)
+		return
+	}
+	for _, rule := range rules {
+		if abortsUploads(rule, prefix, int32(c.MPUCleanupDays)) {
+			logger.Debug("Incomplete multipart uploads are already aborted", "rule", aws.ToString(rule.ID))
+			return
+		}
+	}
+
+	rule := types.LifecycleRule{
+		ID:                             aws.String(mpuCleanupRulePrefix + prefix),
+		Status:                         types.ExpirationStatusEnabled,
+		Filter:                         &types.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+		AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(int32(c.MPUCleanupDays))},
+	}
+	if i := slices.IndexFunc(rules, func(r types.LifecycleRule) bool { return aws.ToString(r.ID) == aws.ToString(rule.ID) }); i >= 0 {
+		rules[i] = rule
+	} else {
+		rules = append(rules, rule)
+	}
+	if err := s3Storage.PutLifecycleRules(ctx, rules); err != nil {
+		logger.Warn("Could not ensure the multipart upload cleanup rule", "error", err)
+		return
+	}
+	logger.Info("Added lifecycle rule aborting incomplete multipart uploads", "bucket", c.Bucket, "prefix", prefix, "days", c.MPUCleanupDays)
+}
+
+// abortsUploads reports whether an enabled rule aborts the incomplete multipart uploads under prefix within days
+func abortsUploads(rule types.LifecycleRule, prefix string, days int32) bool {
+	if rule.Status != types.ExpirationStatusEnabled || rule.AbortIncompleteMultipartUpload == nil ||
+		aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation) > days {
+		return false
+	}
+	// Rules filtered on tags or sizes don't apply to multipart uploads
+	rulePrefix := aws.ToString(rule.Prefix)
+	if filter := rule.Filter; filter != nil {
+		if filter.Tag != nil || filter.ObjectSizeGreaterThan != nil || filter.ObjectSizeLessThan != nil || filter.And != nil {
+			return false
+		}
+		rulePrefix = aws.ToString(filter.Prefix)
+	}
+	return strings.HasPrefix(prefix, rulePrefix)
+}
+
+// lifecyclePrefix returns the key prefix of a destination for lifecycle filters, empty for the whole bucket
+func lifecyclePrefix(dest string) string {
+	prefix := strings.Trim(filepath.ToSlash(dest), "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// ageDays converts an age such as 30d or 12w to days, lifecycle rules and Object Lock policies only count whole days
+func ageDays(value string) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := utils.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 || d%(24*time.Hour) != 0 {
+		return 0, fmt.Errorf("%q is not a positive number of days", value)
+	}
+	return int32(d / (24 * time.Hour)), nil
+}
+
+// LifecycleRules returns the lifecycle rules of the bucket, none when it has no lifecycle configuration
+func (s S3Storage) LifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	out, err := s.client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(s.bucket)})
+	if apiErrorCode(err) == "NoSuchLifecycleConfiguration" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the lifecycle configuration of S3 bucket %s: %w", s.bucket, err)
+	}
+	return out.Rules, nil
+}
+
+// PutLifecycleRules replaces the lifecycle rules of the bucket
+func (s S3Storage) PutLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	_, err := s.client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(s.bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: rules},
+	})
+	if err != nil {
+		return fmt.Errorf("could not write the lifecycle configuration of S3 bucket %s: %w", s.bucket, err)
+	}
+	return nil
+}
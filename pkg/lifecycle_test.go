@@ -0,0 +1,172 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLifecycleDays(t *testing.T) {
+	for value, want := range map[string]int32{"": 0, "30d": 30, "2w": 14, "48h": 2} {
+		if got, err := ageDays(value); err != nil || got != want {
+			t.Errorf("ageDays(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"36h", "0d", "-1d", "soon"} {
+		if _, err := ageDays(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestValidateLifecycle(t *testing.T) {
+	cfg := Config{TransitionGlacier: "30d", TransitionDeepArchive: "120d", Expire: "365d"}
+	if err := cfg.validateLifecycle(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	cfg = Config{TransitionGlacier: "90d", Expire: "30d"}
+	if err := cfg.validateLifecycle(); err == nil {
+		t.Error("Expected an error when objects expire before their transition")
+	}
+}
+
+func TestApplyLifecycle(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var put string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.Query().Has("lifecycle") {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			put = string(body)
+			return
+		}
+		_, _ = w.Write([]byte(`<LifecycleConfiguration>` +
+			`<Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>7</Days></Expiration></Rule>` +
+			`<Rule><ID>s3safe:backups/db/</ID><Filter><Prefix>backups/db/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days></Expiration></Rule>` +
+			`</LifecycleConfiguration>`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(t.TempDir(), server.URL)
+	cfg.Dest = "/backups/db"
+	cfg.TransitionGlacier = "30d"
+	cfg.Expire = "365d"
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyLifecycle(context.Background(), &cfg, storage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Count(put, "<Rule>") != 2 || !strings.Contains(put, "<ID>logs</ID>") {
+		t.Errorf("Expected the other rule to be kept and the s3safe rule replaced, got %s", put)
+	}
+	for _, want := range []string{"<Prefix>backups/db/</Prefix>", "<Days>30</Days><StorageClass>GLACIER</StorageClass>", "<Expiration><Days>365</Days></Expiration>"} {
+		if !strings.Contains(put, want) {
+			t.Errorf("Expected %s in %s", want, put)
+		}
+	}
+
+	put = ""
+	cfg.DryRun = true
+	if err := applyLifecycle(context.Background(), &cfg, storage); err != nil || put != "" {
+		t.Errorf("Expected no change with --dry-run, got %v, %s", err, put)
+	}
+}
+
+func TestAbortsUploads(t *testing.T) {
+	rule := func(prefix string, days int32) types.LifecycleRule {
+		return types.LifecycleRule{
+			Status:                         types.ExpirationStatusEnabled,
+			Filter:                         &types.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+			AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(days)},
+		}
+	}
+	disabled := rule("", 1)
+	disabled.Status = types.ExpirationStatusDisabled
+	tagged := rule("", 1)
+	tagged.Filter = &types.LifecycleRuleFilter{Tag: &types.Tag{Key: aws.String("tier"), Value: aws.String("hot")}}
+
+	for _, tt := range []struct {
+		name string
+		rule types.LifecycleRule
+		want bool
+	}{
+		{"whole bucket", rule("", 7), true},
+		{"parent prefix", rule("backups/", 3), true},
+		{"later", rule("backups/", 30), false},
+		{"other prefix", rule("logs/", 1), false},
+		{"disabled", disabled, false},
+		{"tag filter", tagged, false},
+		{"no abort", types.LifecycleRule{Status: types.ExpirationStatusEnabled, Filter: &types.LifecycleRuleFilter{Prefix: aws.String("")}}, false},
+	} {
+		if got := abortsUploads(tt.rule, "backups/db/", 7); got != tt.want {
+			t.Errorf("%s: abortsUploads = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureMPUCleanupRule(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	existing := `<LifecycleConfiguration><Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status>` +
+		`<Expiration><Days>7</Days></Expiration></Rule></LifecycleConfiguration>`
+	var put string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			put = string(body)
+			return
+		}
+		_, _ = w.Write([]byte(existing))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(t.TempDir(), server.URL)
+	cfg.Dest = "backups/db"
+	cfg.MPUCleanupDays = 7
+	cfg.ensureMPUCleanupRule(context.Background())
+	for _, want := range []string{"<ID>logs</ID>", "<ID>s3safe-mpu:backups/db/</ID>", "<AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation>"} {
+		if !strings.Contains(put, want) {
+			t.Errorf("Expected %s in %s", want, put)
+		}
+	}
+
+	put = ""
+	existing = `<LifecycleConfiguration><Rule><ID>mpu</ID><Filter><Prefix></Prefix></Filter><Status>Enabled</Status>` +
+		`<AbortIncompleteMultipartUpload><DaysAfterInitiation>2</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`
+	cfg.ensureMPUCleanupRule(context.Background())
+	if put != "" {
+		t.Errorf("Expected the bucket rule to be kept as is, got %s", put)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage writes backups to a local or network mounted filesystem
+type LocalStorage struct{}
+
+// parseLocalRemote returns the directory of a file:// URL
+func parseLocalRemote(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid destination %q: %w", raw, err)
+	}
+	if u.Scheme != "file" || u.Path == "" {
+		return "", fmt.Errorf("invalid destination %q, expected file:///path/to/dir", raw)
+	}
+	return filepath.Clean(filepath.FromSlash(u.Host + u.Path)), nil
+}
+
+// Upload copies a file to target, the file is written to a temporary file and renamed once complete
+func (l LocalStorage) Upload(ctx context.Context, path string, target string, opts UploadOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target = filepath.FromSlash(target)
+	slog.Info("Copying file", "file", path, "target", target)
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("copy error: %w", err)
+	}
+	defer func(src *os.File) {
+		err := src.Close()
+		if err != nil {
+			slog.Error("error closing file", "error", err)
+		}
+	}(src)
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("copy error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return fmt.Errorf("copy error: %w", err)
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("unable to copy %q to %q: %w", path, target, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("unable to copy %q to %q: %w", path, target, err)
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		slog.Warn("Unable to preserve modification time", "file", target, "error", err)
+	}
+	if opts.NoClobber {
+		// A hard link fails when the target exists, unlike a rename
+		err = os.Link(tmp.Name(), target)
+		_ = os.Remove(tmp.Name())
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("unable to copy %q to %q: %w", path, target, errObjectExists)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to copy %q to %q: %w", path, target, err)
+		}
+	} else if err := os.Rename(tmp.Name(), target); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("unable to copy %q to %q: %w", path, target, err)
+	}
+
+	slog.Info("Copy completed successfully", "file", path, "target", target)
+	return nil
+}
+
+// VerifyUpload compares the content of the copy with the local file
+func (l LocalStorage) VerifyUpload(ctx context.Context, path string, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target = filepath.FromSlash(target)
+	file, err := os.Open(target)
+	if err != nil {
+		return fmt.Errorf("unable to verify %q: %w", target, err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("unable to verify %q: %w", target, err)
+	}
+	if err := verifyLocalFile(path, objectDigest{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}); err != nil {
+		return fmt.Errorf("verification of %q failed: %w", target, err)
+	}
+	return nil
+}
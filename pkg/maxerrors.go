@@ -0,0 +1,83 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// minPercentSample is the number of processed files before a percentage threshold aborts a run early,
+// so that a few failures among the first files do not stop it
+const minPercentSample = 100
+
+// errorThreshold is the parsed --max-errors value, the zero value never aborts
+type errorThreshold struct {
+	value   string
+	count   int
+	percent float64
+}
+
+// parseErrorThreshold parses a number of files such as "10" or a percentage such as "5%"
+func parseErrorThreshold(value string) (errorThreshold, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return errorThreshold{}, nil
+	}
+	if n, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return errorThreshold{}, fmt.Errorf("invalid max errors %q, use a number of files or a percentage between 0%% and 100%%", value)
+		}
+		return errorThreshold{value: value, percent: percent, count: -1}, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return errorThreshold{}, fmt.Errorf("invalid max errors %q, use a number of files or a percentage such as 5%%", value)
+	}
+	return errorThreshold{value: value, count: count}, nil
+}
+
+// check returns an error once failed files exceed the threshold, percentages are only
+// checked after minPercentSample files or when final is set
+func (t errorThreshold) check(failed, processed int, final bool) error {
+	if t.value == "" || failed == 0 {
+		return nil
+	}
+	if t.count >= 0 {
+		if failed <= t.count {
+			return nil
+		}
+	} else {
+		if processed == 0 || (!final && processed < minPercentSample) {
+			return nil
+		}
+		if float64(failed)*100 <= t.percent*float64(processed) {
+			return nil
+		}
+	}
+	return withExitCode(ExitFailure, fmt.Errorf("too many errors: %d of %d files failed, exceeding --max-errors %s", failed, processed, t.value))
+}
@@ -0,0 +1,113 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseErrorThreshold(t *testing.T) {
+	for _, value := range []string{"", "0", "10", "5%", "0.5%", "100%"} {
+		if _, err := parseErrorThreshold(value); err != nil {
+			t.Errorf("parseErrorThreshold(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"-1", "ten", "101%", "%", "-5%"} {
+		if _, err := parseErrorThreshold(value); err == nil {
+			t.Errorf("parseErrorThreshold(%q) expected an error", value)
+		}
+	}
+}
+
+func TestErrorThresholdCheck(t *testing.T) {
+	tests := []struct {
+		value     string
+		failed    int
+		processed int
+		final     bool
+		abort     bool
+	}{
+		{"", 1000, 1000, true, false},
+		{"0", 1, 1, false, true},
+		{"2", 2, 10, false, false},
+		{"2", 3, 10, false, true},
+		{"10%", 5, 10, false, false},
+		{"10%", 5, 10, true, true},
+		{"10%", 10, 100, false, false},
+		{"10%", 11, 100, false, true},
+		{"10%", 1, 10, true, false},
+	}
+	for _, tt := range tests {
+		threshold, err := parseErrorThreshold(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = threshold.check(tt.failed, tt.processed, tt.final)
+		if (err != nil) != tt.abort {
+			t.Errorf("%q.check(%d, %d, %v) = %v, want abort %v", tt.value, tt.failed, tt.processed, tt.final, err, tt.abort)
+		}
+		if err != nil && ExitCode(err) != ExitFailure {
+			t.Errorf("ExitCode = %d, want %d", ExitCode(err), ExitFailure)
+		}
+	}
+}
+
+func TestBackupMaxErrors(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"broken1", "broken2"} {
+		if err := os.Symlink(filepath.Join(src, "missing"), filepath.Join(src, name)); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.IgnoreErrors = true
+	cfg.MaxErrors = "1"
+	for _, compress := range []bool{false, true} {
+		cfg.Compress = compress
+		bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bm.Backup(context.Background()); ExitCode(err) != ExitFailure {
+			t.Errorf("compress=%v: expected the backup to abort, got %v", compress, err)
+		}
+	}
+
+	cfg.MaxErrors = "eleven"
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected a config error, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// User metadata keys of the source file attributes, stored as x-amz-meta-<key> with the formats used by rclone
+const (
+	// mtimeMetadataKey holds the modification time, Unix seconds with an optional fraction
+	mtimeMetadataKey = "mtime"
+	// modeMetadataKey holds the permission bits in octal
+	modeMetadataKey = "mode"
+	uidMetadataKey  = "uid"
+	gidMetadataKey  = "gid"
+)
+
+// fileMetadata returns the metadata of a source file, the mode and owner are stored when withOwner is set
+func fileMetadata(info os.FileInfo, withOwner bool) map[string]string {
+	metadata := map[string]string{mtimeMetadataKey: formatMtime(info.ModTime())}
+	if !withOwner {
+		return metadata
+	}
+	metadata[modeMetadataKey] = strconv.FormatUint(uint64(info.Mode().Perm()), 8)
+	if uid, gid, ok := fileOwner(info); ok {
+		metadata[uidMetadataKey] = strconv.Itoa(uid)
+		metadata[gidMetadataKey] = strconv.Itoa(gid)
+	}
+	return metadata
+}
+
+// formatMtime formats a modification time as the mtime metadata value
+func formatMtime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// parseMtime parses an mtime metadata value
+func parseMtime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	secs, frac, _ := strings.Cut(value, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsec int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		n, err := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		nsec = n
+	}
+	return time.Unix(sec, nsec), true
+}
+
+// applyMetadata sets the attributes of a restored file from the object metadata.
+// The modification time is always applied, the mode with PreservePermissions, the owner with PreservePermissions
+// when running as root, and the SELinux context with SELinux.
+func applyMetadata(path string, metadata map[string]string, opts DownloadOptions) {
+	if opts.SELinux {
+		restoreLabel(path, metadata[selinuxMetadataKey])
+	}
+	if opts.PreservePermissions {
+		if mode, err := strconv.ParseUint(metadata[modeMetadataKey], 8, 32); err == nil {
+			if err := os.Chmod(path, os.FileMode(mode).Perm()); err != nil {
+				slog.Warn("Unable to restore file mode", "file", path, "error", err)
+			}
+		}
+		uid, uidErr := strconv.Atoi(metadata[uidMetadataKey])
+		gid, gidErr := strconv.Atoi(metadata[gidMetadataKey])
+		if uidErr == nil && gidErr == nil && os.Geteuid() == 0 {
+			if err := os.Lchown(path, uid, gid); err != nil {
+				slog.Warn("Unable to restore file owner", "file", path, "error", err)
+			}
+		}
+	}
+	if mtime, ok := parseMtime(metadata[mtimeMetadataKey]); ok {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			slog.Warn("Unable to restore modification time", "file", path, "error", err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseMtime(t *testing.T) {
+	mtime := time.Date(2025, 6, 1, 12, 30, 0, 123456789, time.UTC)
+	parsed, ok := parseMtime(formatMtime(mtime))
+	if !ok || !parsed.Equal(mtime) {
+		t.Errorf("Expected %v, got %v", mtime, parsed)
+	}
+
+	// rclone style values with a shorter fraction
+	parsed, ok = parseMtime("1748781000.5")
+	if !ok || parsed.Unix() != 1748781000 || parsed.Nanosecond() != 500000000 {
+		t.Errorf("Unexpected mtime %v", parsed)
+	}
+
+	for _, invalid := range []string{"", "yesterday", "12.x"} {
+		if _, ok := parseMtime(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("backup"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata := fileMetadata(info, false); len(metadata) != 1 || metadata[mtimeMetadataKey] == "" {
+		t.Errorf("Expected only the mtime for archives, got %v", metadata)
+	}
+	metadata := fileMetadata(info, true)
+	if runtime.GOOS != "windows" && (metadata[modeMetadataKey] != "640" || metadata[uidMetadataKey] == "") {
+		t.Errorf("Expected mode and owner, got %v", metadata)
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("backup"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	metadata := map[string]string{mtimeMetadataKey: formatMtime(mtime), modeMetadataKey: "600"}
+
+	applyMetadata(path, metadata, DownloadOptions{})
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("Expected %v, got %v", mtime, info.ModTime())
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("Expected the mode to be kept without permissions, got %v", info.Mode().Perm())
+	}
+
+	applyMetadata(path, metadata, DownloadOptions{PreservePermissions: true})
+	if info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestS3StorageUploadNoClobber(t *testing.T) {
+	var conditions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, r.Header.Get("If-None-Match"))
+		if strings.HasSuffix(r.URL.Path, "/exists") && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = w.Write([]byte(`<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`))
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	storage := S3Storage{bucket: "bucket", client: s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})}
+	opts := UploadOptions{ContentType: "text/plain", NoClobber: true}
+	if err := storage.Upload(context.Background(), path, "new", opts); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if err := storage.Upload(context.Background(), path, "exists", opts); !errors.Is(err, errObjectExists) {
+		t.Errorf("Expected the existing object to be kept, got %v", err)
+	}
+	if err := storage.Upload(context.Background(), path, "exists", UploadOptions{ContentType: "text/plain"}); err != nil {
+		t.Errorf("Expected the object to be overwritten without NoClobber, got %v", err)
+	}
+	if strings.Join(conditions, ",") != "*,*," {
+		t.Errorf("If-None-Match headers = %q", conditions)
+	}
+}
+
+func TestLocalStorageUploadNoClobber(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "dest", "a.txt")
+	opts := UploadOptions{NoClobber: true}
+	if err := (LocalStorage{}).Upload(context.Background(), path, filepath.ToSlash(target), opts); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (LocalStorage{}).Upload(context.Background(), path, filepath.ToSlash(target), opts); !errors.Is(err, errObjectExists) {
+		t.Errorf("Expected the existing copy to be kept, got %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "hello" {
+		t.Errorf("copy = %q, %v, want the first upload", data, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(target))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Tiers of --object-lock-policy, a run belongs to every tier its start date matches
+const (
+	lockDaily   = "daily"
+	lockWeekly  = "weekly"
+	lockMonthly = "monthly"
+	lockYearly  = "yearly"
+)
+
+var lockTiers = []string{lockDaily, lockWeekly, lockMonthly, lockYearly}
+
+// objectLockPolicy is the retention in days of the tiers of --object-lock-policy
+type objectLockPolicy map[string]int
+
+// parseObjectLockPolicy parses a policy such as "daily=35d,weekly=90d,monthly=400d"
+func parseObjectLockPolicy(value string) (objectLockPolicy, error) {
+	policy := objectLockPolicy{}
+	for _, entry := range strings.Split(value, ",") {
+		tier, age, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		tier = strings.ToLower(strings.TrimSpace(tier))
+		if !ok || !slices.Contains(lockTiers, tier) {
+			return nil, fmt.Errorf("invalid object lock policy %q, expected tier=age with the tiers %v", entry, lockTiers)
+		}
+		days, err := ageDays(strings.TrimSpace(age))
+		if err != nil || days == 0 {
+			return nil, fmt.Errorf("invalid object lock policy %q, the age must be a positive number of days such as 35d", entry)
+		}
+		policy[tier] = int(days)
+	}
+	return policy, nil
+}
+
+// days returns the longest retention of the tiers matched by a run started at start,
+// fallback when it matches none of them
+func (p objectLockPolicy) days(start time.Time, fallback int) int {
+	days := 0
+	for tier, tierDays := range p {
+		if lockTierMatches(tier, start) {
+			days = max(days, tierDays)
+		}
+	}
+	if days == 0 {
+		return fallback
+	}
+	return days
+}
+
+// lockTierMatches reports whether a run started at start belongs to a tier:
+// weekly runs start on Sunday, monthly runs on the 1st and yearly runs on January 1st
+func lockTierMatches(tier string, start time.Time) bool {
+	switch tier {
+	case lockDaily:
+		return true
+	case lockWeekly:
+		return start.Weekday() == time.Sunday
+	case lockMonthly:
+		return start.Day() == 1
+	case lockYearly:
+		return start.YearDay() == 1
+	}
+	return false
+}
+
+// retainUntil returns the Object Lock retain-until date of the uploads of a run started at start,
+// from --object-lock-policy or --object-lock-days. All the objects of a run expire together.
+func (c *Config) retainUntil(start time.Time) time.Time {
+	days := c.ObjectLockDays
+	if c.ObjectLockPolicy != "" {
+		policy, _ := parseObjectLockPolicy(c.ObjectLockPolicy)
+		days = policy.days(start, c.ObjectLockDays)
+	}
+	return start.UTC().AddDate(0, 0, days)
+}
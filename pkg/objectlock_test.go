@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectLockPolicyDays(t *testing.T) {
+	policy, err := parseObjectLockPolicy("daily=35d, weekly=12w,Monthly=400d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		start time.Time
+		want  int
+	}{
+		{time.Date(2025, 6, 3, 2, 0, 0, 0, time.UTC), 35},  // Tuesday
+		{time.Date(2025, 6, 8, 2, 0, 0, 0, time.UTC), 84},  // Sunday
+		{time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC), 400}, // Sunday and 1st of the month
+	} {
+		if got := policy.days(tt.start, 0); got != tt.want {
+			t.Errorf("days(%s) = %d, want %d", tt.start.Format(time.DateOnly), got, tt.want)
+		}
+	}
+
+	monthly, _ := parseObjectLockPolicy("monthly=400d")
+	if got := monthly.days(time.Date(2025, 6, 3, 2, 0, 0, 0, time.UTC), 30); got != 30 {
+		t.Errorf("Expected the fallback for a run matching no tier, got %d", got)
+	}
+
+	for _, value := range []string{"hourly=1d", "daily", "daily=36h", "daily=0d"} {
+		if _, err := parseObjectLockPolicy(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestRetainUntil(t *testing.T) {
+	start := time.Date(2025, 1, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600))
+	cfg := Config{ObjectLockMode: "COMPLIANCE", ObjectLockPolicy: "daily=35d,yearly=2555d"}
+	if err := cfg.validateObjectLock(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.retainUntil(start), time.Date(2031, 12, 31, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("retainUntil = %s, want %s", got, want)
+	}
+
+	cfg = Config{ObjectLockMode: "COMPLIANCE", ObjectLockPolicy: "monthly=400d"}
+	if err := cfg.validateObjectLock(); err == nil {
+		t.Error("Expected an error for a policy without daily tier nor object lock days")
+	}
+	cfg = Config{ObjectLockPolicy: "daily=35d"}
+	if err := cfg.validateObjectLock(); err == nil {
+		t.Error("Expected an error for a policy without object lock mode")
+	}
+}
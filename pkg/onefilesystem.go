@@ -0,0 +1,45 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import "os"
+
+// otherFileSystem returns a function reporting whether a directory is on another device than the backed up
+// directory, such as a mount point of /proc, an NFS share or a bind-mounted volume.
+// Nothing is reported when the device of the backed up directory is unknown, such as on Windows.
+func (bm *BackupManager) otherFileSystem() func(info os.FileInfo) bool {
+	root, err := os.Stat(bm.sourcePath())
+	if err != nil {
+		return func(os.FileInfo) bool { return false }
+	}
+	rootDevice, ok := deviceID(root)
+	if !ok {
+		bm.log().Warn("File system boundaries are not detected on this platform, --one-file-system is ignored")
+		return func(os.FileInfo) bool { return false }
+	}
+	return func(info os.FileInfo) bool {
+		device, ok := deviceID(info)
+		return ok && device != rootDevice
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOtherFileSystem(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bm := &BackupManager{config: &Config{Path: root}}
+	otherDevice := bm.otherFileSystem()
+	sub, err := os.Lstat(filepath.Join(root, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherDevice(sub) {
+		t.Error("a subdirectory is on the same file system")
+	}
+
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only mounted on Linux")
+	}
+	proc, err := os.Lstat("/proc")
+	if err != nil {
+		t.Skip("/proc is not mounted")
+	}
+	if !otherDevice(proc) {
+		t.Error("/proc is on another file system")
+	}
+}
+
+func TestExcludedOneFileSystem(t *testing.T) {
+	bm := &BackupManager{config: &Config{Path: t.TempDir(), OneFileSystem: true}}
+	exclude := bm.excluded()
+	if exclude == nil {
+		t.Fatal("--one-file-system sets a filter")
+	}
+	info, err := os.Lstat(bm.config.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exclude(bm.config.Path, info) || bm.result.Skipped != 0 {
+		t.Error("the backed up directory is excluded")
+	}
+}
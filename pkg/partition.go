@@ -0,0 +1,86 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// awsPartition is a group of AWS regions sharing an endpoint domain, the SDK resolves the endpoints of a
+// region in its partition
+type awsPartition struct {
+	name string
+	// dnsSuffix is the domain of the S3 endpoints, s3.<region>.<dnsSuffix>
+	dnsSuffix string
+	// accelerate, fips and directoryBuckets report the S3 features available in the partition
+	accelerate       bool
+	fips             bool
+	directoryBuckets bool
+}
+
+// commercialPartition holds the regions without a partition prefix
+var commercialPartition = awsPartition{name: "aws", dnsSuffix: "amazonaws.com", accelerate: true, fips: true, directoryBuckets: true}
+
+// awsPartitions maps the region prefixes to their partition, longer prefixes first
+var awsPartitions = []struct {
+	prefix    string
+	partition awsPartition
+}{
+	{"cn-", awsPartition{name: "aws-cn", dnsSuffix: "amazonaws.com.cn"}},
+	{"us-gov-", awsPartition{name: "aws-us-gov", dnsSuffix: "amazonaws.com", fips: true}},
+	{"us-isob-", awsPartition{name: "aws-iso-b", dnsSuffix: "sc2s.sgov.gov", fips: true}},
+	{"us-iso-", awsPartition{name: "aws-iso", dnsSuffix: "c2s.ic.gov", fips: true}},
+}
+
+// partitionOf returns the partition of a region
+func partitionOf(region string) awsPartition {
+	for _, p := range awsPartitions {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return commercialPartition
+}
+
+// regionalEndpoint returns the URL of the S3 endpoint of a region
+func regionalEndpoint(region string) string {
+	return fmt.Sprintf("https://s3.%s.%s", region, partitionOf(region).dnsSuffix)
+}
+
+// isAWSEndpoint reports whether an endpoint is the S3 endpoint of the region or the global endpoint of
+// its partition, such as s3.cn-north-1.amazonaws.com.cn, which the SDK resolves without an override
+func isAWSEndpoint(endpoint, region string) bool {
+	if region == "" {
+		return false
+	}
+	u, err := url.Parse(endpointURL(endpoint, false))
+	if err != nil || u.Scheme != "https" || (u.Path != "" && u.Path != "/") || u.Port() != "" {
+		return false
+	}
+	suffix := partitionOf(region).dnsSuffix
+	host := strings.ToLower(u.Hostname())
+	return host == "s3."+region+"."+suffix || host == "s3-"+region+"."+suffix || host == "s3."+suffix
+}
@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jkaninda/s3safe/utils"
+	"testing"
+)
+
+func TestRegionalEndpoint(t *testing.T) {
+	for region, want := range map[string]string{
+		"eu-west-1":      "https://s3.eu-west-1.amazonaws.com",
+		"us-gov-west-1":  "https://s3.us-gov-west-1.amazonaws.com",
+		"cn-north-1":     "https://s3.cn-north-1.amazonaws.com.cn",
+		"cn-northwest-1": "https://s3.cn-northwest-1.amazonaws.com.cn",
+		"us-iso-east-1":  "https://s3.us-iso-east-1.c2s.ic.gov",
+		"us-isob-east-1": "https://s3.us-isob-east-1.sc2s.sgov.gov",
+	} {
+		if endpoint := regionalEndpoint(region); endpoint != want {
+			t.Errorf("Expected %s for %s, got %s", want, region, endpoint)
+		}
+	}
+}
+
+func TestIsAWSEndpoint(t *testing.T) {
+	for _, test := range []struct {
+		endpoint, region string
+		want             bool
+	}{
+		{"https://s3.cn-north-1.amazonaws.com.cn", "cn-north-1", true},
+		{"s3.cn-north-1.amazonaws.com.cn", "cn-north-1", true},
+		{"https://s3.amazonaws.com.cn", "cn-northwest-1", true},
+		{"https://s3.us-gov-west-1.amazonaws.com", "us-gov-west-1", true},
+		{"https://s3-us-gov-west-1.amazonaws.com/", "us-gov-west-1", true},
+		{"https://s3.eu-west-1.amazonaws.com", "eu-west-1", true},
+		{"https://s3.us-east-1.amazonaws.com", "cn-north-1", false},
+		{"https://s3.cn-north-1.amazonaws.com", "cn-north-1", false},
+		{"http://s3.cn-north-1.amazonaws.com.cn", "cn-north-1", false},
+		{"https://s3.eu-west-1.amazonaws.com:8443", "eu-west-1", false},
+		{"https://minio.example.com", "us-east-1", false},
+		{"https://s3.amazonaws.com", "", false},
+	} {
+		if got := isAWSEndpoint(test.endpoint, test.region); got != test.want {
+			t.Errorf("Expected %v for %s in %s, got %v", test.want, test.endpoint, test.region, got)
+		}
+	}
+}
+
+func TestPartitionEndpointResolution(t *testing.T) {
+	c := &Config{Region: "cn-north-1", EndPoint: "https://s3.cn-north-1.amazonaws.com.cn"}
+	c.applyDefaults()
+	if c.EndPoint != utils.AwsS3Url {
+		t.Fatalf("Expected the regional endpoint to be resolved by the SDK, got %s", c.EndPoint)
+	}
+
+	t.Setenv("AWS_CA_BUNDLE", "")
+	storage, err := c.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := storage.client.Options().EndpointResolverV2
+	for _, test := range []struct {
+		params s3.EndpointParameters
+		host   string
+	}{
+		{s3.EndpointParameters{Bucket: aws.String("backups"), Region: aws.String("cn-north-1")}, "backups.s3.cn-north-1.amazonaws.com.cn"},
+		{s3.EndpointParameters{Bucket: aws.String("backups"), Region: aws.String("us-gov-west-1")}, "backups.s3.us-gov-west-1.amazonaws.com"},
+		{s3.EndpointParameters{Bucket: aws.String("backups"), Region: aws.String("us-gov-west-1"), UseFIPS: aws.Bool(true)}, "backups.s3-fips.us-gov-west-1.amazonaws.com"},
+	} {
+		endpoint, err := resolver.ResolveEndpoint(context.Background(), test.params.WithDefaults())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if endpoint.URI.Host != test.host {
+			t.Errorf("Expected the endpoint %s in %s, got %s", test.host, aws.ToString(test.params.Region), endpoint.URI.Host)
+		}
+	}
+
+	for _, c := range []*Config{
+		{Region: "cn-north-1", EndPoint: utils.AwsS3Url, Accelerate: true},
+		{Region: "us-gov-west-1", EndPoint: utils.AwsS3Url, Accelerate: true},
+	} {
+		if err := c.validateOptions(); err == nil {
+			t.Errorf("Expected transfer acceleration to be rejected in %s", c.Region)
+		}
+	}
+	directory := &Config{Region: "cn-north-1", EndPoint: utils.AwsS3Url, Bucket: "backups--cnn1-az1--x-s3"}
+	if err := directory.validateDirectoryBucket(); err == nil {
+		t.Error("Expected directory buckets to be rejected in aws-cn")
+	}
+}
@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/cobra"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// permissionSentinel prefixes the key of the object written and deleted by check-permissions
+const permissionSentinel = ".s3safe-permission-check-"
+
+// Results of permission probes
+const (
+	permissionGranted = "granted"
+	permissionMissing = "missing"
+	permissionError   = "error"
+	permissionSkipped = "not tested"
+)
+
+// PermissionProbe is the result of an operation testing an IAM permission
+type PermissionProbe struct {
+	Permission string
+	Operation  string
+	// UsedBy lists the commands needing the permission
+	UsedBy string
+	Result string
+	Err    error
+}
+
+// CheckPermissions is the cobra command handler for check-permissions
+func CheckPermissions(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if err := config.validate(cmd.Context(), false); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	config.Path = strings.Trim(filepath.ToSlash(config.Path), "/")
+
+	probes := s3Storage.probePermissions(cmd.Context(), config.Path)
+	if err := writePermissionProbes(os.Stdout, probes); err != nil {
+		return err
+	}
+	return permissionsError(probes)
+}
+
+// probePermissions runs harmless operations under prefix: the bucket and the prefix are listed,
+// a sentinel object is written, read and deleted. The version of the sentinel and its delete marker
+// are deleted too on versioned buckets.
+func (s S3Storage) probePermissions(ctx context.Context, prefix string) []PermissionProbe {
+	var probes []PermissionProbe
+	probe := func(permission, operation, usedBy string, err error) bool {
+		result := permissionGranted
+		if err != nil {
+			result = permissionError
+			if isAccessDenied(err) {
+				result = permissionMissing
+			}
+		}
+		probes = append(probes, PermissionProbe{Permission: permission, Operation: operation, UsedBy: usedBy, Result: result, Err: err})
+		return err == nil
+	}
+	skip := func(permission, operation, usedBy string) {
+		probes = append(probes, PermissionProbe{Permission: permission, Operation: operation, UsedBy: usedBy, Result: permissionSkipped})
+	}
+
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	probe("s3:ListBucket", "HeadBucket", "all commands", err)
+	if isStatus(err, http.StatusNotFound) {
+		return probes
+	}
+	listPrefix := prefix
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+	_, err = s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(listPrefix),
+		MaxKeys: aws.Int32(1),
+	})
+	probe("s3:ListBucket", "ListObjectsV2", "backup, restore, usage, cost", err)
+
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	key := objectKey(prefix, permissionSentinel+hex.EncodeToString(suffix))
+	content := []byte("s3safe permission check, safe to delete\n")
+	put, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+		ContentType:   aws.String("text/plain"),
+	})
+	if !probe("s3:PutObject", "PutObject "+key, "backup, replicate", err) {
+		skip("s3:GetObject", "GetObject", "restore, check, --verify")
+		skip("s3:DeleteObject", "DeleteObject", "")
+		return probes
+	}
+
+	get, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err == nil {
+		_, err = io.Copy(io.Discard, get.Body)
+		_ = get.Body.Close()
+	}
+	probe("s3:GetObject", "GetObject "+key, "restore, check, --verify", err)
+
+	deleted, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if !probe("s3:DeleteObject", "DeleteObject "+key, "", err) {
+		s.log().Warn("The sentinel object could not be deleted, delete it manually", "key", key)
+	}
+
+	// On versioned buckets the sentinel stays as a noncurrent version until its versions are deleted
+	if put.VersionId == nil {
+		return probes
+	}
+	versions := []*string{put.VersionId}
+	if err == nil && deleted.VersionId != nil {
+		versions = append(versions, deleted.VersionId)
+	}
+	var versionErr error
+	for _, id := range versions {
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key), VersionId: id})
+		versionErr = errors.Join(versionErr, err)
+	}
+	probe("s3:DeleteObjectVersion", "DeleteObject "+key+" versions", "purge-versions, undelete", versionErr)
+	return probes
+}
+
+// isAccessDenied reports whether err is an S3 access denial
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && slices.Contains([]string{"AccessDenied", "AllAccessDisabled", "Forbidden"}, apiErr.ErrorCode()) {
+		return true
+	}
+	return isStatus(err, http.StatusForbidden)
+}
+
+// writePermissionProbes prints a line per probe
+func writePermissionProbes(out io.Writer, probes []PermissionProbe) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PERMISSION\tRESULT\tUSED BY\tOPERATION")
+	for _, p := range probes {
+		usedBy := p.UsedBy
+		if usedBy == "" {
+			usedBy = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Permission, p.Result, usedBy, p.Operation)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, p := range probes {
+		if p.Result == permissionError {
+			_, _ = fmt.Fprintf(out, "%s: %v\n", p.Operation, p.Err)
+		}
+	}
+	return nil
+}
+
+// permissionsError returns an error when a probe did not succeed
+func permissionsError(probes []PermissionProbe) error {
+	var missing []string
+	var failed error
+	for _, p := range probes {
+		switch p.Result {
+		case permissionMissing:
+			if !slices.Contains(missing, p.Permission) {
+				missing = append(missing, p.Permission)
+			}
+		case permissionError:
+			failed = errors.Join(failed, p.Err)
+		}
+	}
+	if len(missing) > 0 {
+		return withExitCode(ExitConnection, fmt.Errorf("missing permissions: %s", strings.Join(missing, ", ")))
+	}
+	if failed != nil && probes[0].Result == permissionError && isStatus(probes[0].Err, http.StatusNotFound) {
+		return withExitCode(ExitConfig, fmt.Errorf("bucket does not exist: %w", failed))
+	}
+	return failed
+}
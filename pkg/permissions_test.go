@@ -0,0 +1,168 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// permissionServer is a fake S3 endpoint denying the requests whose method and kind are in deny,
+// kinds are "bucket", "list", "object" and "version"
+func permissionServer(t *testing.T, versioned bool, deny ...string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var objects []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/bucket"), "/")
+		kind := "object"
+		switch {
+		case key == "" && r.URL.Query().Has("list-type"):
+			kind = "list"
+		case key == "":
+			kind = "bucket"
+		case r.URL.Query().Has("versionId"):
+			kind = "version"
+		}
+		for _, d := range deny {
+			if d == r.Method+" "+kind {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+				return
+			}
+		}
+		switch {
+		case kind == "list":
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>bucket</Name><KeyCount>0</KeyCount></ListBucketResult>`))
+		case r.Method == http.MethodPut:
+			objects = append(objects, key)
+			if versioned {
+				w.Header().Set("X-Amz-Version-Id", "v1")
+			}
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte("content"))
+		case r.Method == http.MethodDelete && kind == "object":
+			if versioned {
+				w.Header().Set("X-Amz-Version-Id", "marker")
+				w.Header().Set("X-Amz-Delete-Marker", "true")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			objects = append(objects, "deleted "+r.URL.Query().Get("versionId"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return objects
+	}
+}
+
+func probeResults(probes []PermissionProbe) string {
+	var results []string
+	for _, p := range probes {
+		results = append(results, p.Permission+"="+p.Result)
+	}
+	return strings.Join(results, " ")
+}
+
+func TestProbePermissions(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, requests := permissionServer(t, false)
+	defer server.Close()
+	cfg := testConfig("backups", server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := storage.probePermissions(context.Background(), "backups")
+	want := "s3:ListBucket=granted s3:ListBucket=granted s3:PutObject=granted s3:GetObject=granted s3:DeleteObject=granted"
+	if got := probeResults(probes); got != want {
+		t.Errorf("probes = %s\nwant %s", got, want)
+	}
+	if err := permissionsError(probes); err != nil {
+		t.Errorf("permissionsError = %v", err)
+	}
+	if puts := requests(); len(puts) != 1 || !strings.HasPrefix(puts[0], "backups/"+permissionSentinel) {
+		t.Errorf("sentinel = %v", puts)
+	}
+}
+
+func TestProbePermissionsMissing(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := permissionServer(t, false, "PUT object")
+	defer server.Close()
+	cfg := testConfig("", server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := storage.probePermissions(context.Background(), "")
+	want := "s3:ListBucket=granted s3:ListBucket=granted s3:PutObject=missing s3:GetObject=not tested s3:DeleteObject=not tested"
+	if got := probeResults(probes); got != want {
+		t.Errorf("probes = %s\nwant %s", got, want)
+	}
+	err = permissionsError(probes)
+	if ExitCode(err) != ExitConnection || !strings.Contains(err.Error(), "s3:PutObject") {
+		t.Errorf("permissionsError = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := writePermissionProbes(&out, probes); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "s3:PutObject") || !strings.Contains(out.String(), "missing") {
+		t.Errorf("output:\n%s", out.String())
+	}
+}
+
+func TestProbePermissionsVersioned(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, requests := permissionServer(t, true)
+	defer server.Close()
+	cfg := testConfig("", server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := storage.probePermissions(context.Background(), "")
+	if got := probeResults(probes); !strings.HasSuffix(got, "s3:DeleteObjectVersion=granted") {
+		t.Errorf("probes = %s", got)
+	}
+	// The sentinel version and its delete marker are removed
+	if got := strings.Join(requests()[1:], ","); got != "deleted v1,deleted marker" {
+		t.Errorf("version deletes = %s", got)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Plugin stages, the stage is the first argument of the plugin and the stage of its event
+const (
+	stagePreScan      = "pre-scan"
+	stageFileUploaded = "per-file-uploaded"
+	stagePostRun      = "post-run"
+	// pluginTimeout bounds the run of a plugin for one event
+	pluginTimeout = 5 * time.Minute
+)
+
+// PluginEvent is the JSON document written to the stdin of the plugins at each stage of a backup
+type PluginEvent struct {
+	Stage     string    `json:"stage"`
+	Operation string    `json:"operation"`
+	Time      time.Time `json:"time"`
+	Job       string    `json:"job,omitempty"`
+	Bucket    string    `json:"bucket"`
+	// Path is the backed up local path and Dest the S3 path
+	Path string `json:"path"`
+	Dest string `json:"dest"`
+	// File is the uploaded file of the per-file-uploaded stage
+	File *PluginFile `json:"file,omitempty"`
+	// Result is the outcome of the backup in the post-run stage
+	Result *PluginResult `json:"result,omitempty"`
+}
+
+// PluginFile describes an uploaded file, Key is its object key on the first destination
+type PluginFile struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// PluginResult summarizes a backup for the post-run stage
+type PluginResult struct {
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	Skipped    int    `json:"skipped"`
+	Failed     int    `json:"failed"`
+	Archive    string `json:"archive,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// plugins holds the executables of the plugins directory, in name order.
+// A nil plugins runs nothing.
+type plugins struct {
+	paths []string
+}
+
+// loadPlugins lists the executables of dir, hidden files and directories are ignored
+func loadPlugins(dir string) (*plugins, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, withExitCode(ExitConfig, fmt.Errorf("invalid plugins directory: %w", err))
+	}
+	p := &plugins{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Symbolic links are followed
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || !isExecutable(entry.Name(), info.Mode()) {
+			continue
+		}
+		p.paths = append(p.paths, path)
+	}
+	return p, nil
+}
+
+// run invokes every plugin with the event, the errors of the failed plugins are joined
+func (p *plugins) run(ctx context.Context, logger *slog.Logger, event PluginEvent) error {
+	if p == nil {
+		return nil
+	}
+	event.Time = time.Now().UTC()
+	input, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin event: %w", err)
+	}
+	var errs []error
+	for _, path := range p.paths {
+		if err := runPlugin(ctx, logger, path, event.Stage, input); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runPlugin runs a plugin with the stage as argument and the event on stdin, its output is logged
+func runPlugin(ctx context.Context, logger *slog.Logger, path, stage string, input []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, stage)
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	name := filepath.Base(path)
+	if out := strings.TrimSpace(output.String()); out != "" {
+		logger.Info("Plugin output", "plugin", name, "stage", stage, "output", out)
+	}
+	if err != nil {
+		return withExitCode(ExitFailure, fmt.Errorf("plugin %s failed at stage %s: %w", name, stage, err))
+	}
+	return nil
+}
+
+// pluginEvent returns the event of a backup stage
+func (bm *BackupManager) pluginEvent(stage string) PluginEvent {
+	return PluginEvent{
+		Stage:     stage,
+		Operation: operationBackup,
+		Job:       bm.config.Job,
+		Bucket:    bm.config.Bucket,
+		Path:      bm.config.Path,
+		Dest:      bm.config.Dest,
+	}
+}
+
+// preScan runs the plugins before the files are listed, a failed plugin aborts the backup
+func (bm *BackupManager) preScan(ctx context.Context) error {
+	return bm.plugins.run(ctx, bm.log(), bm.pluginEvent(stagePreScan))
+}
+
+// fileUploaded runs the plugins once a file is uploaded, failed plugins are logged
+func (bm *BackupManager) fileUploaded(ctx context.Context, path, key string, size int64) {
+	event := bm.pluginEvent(stageFileUploaded)
+	event.File = &PluginFile{Path: path, Key: key, Size: size}
+	if err := bm.plugins.run(ctx, bm.log(), event); err != nil {
+		bm.log().Warn("Plugin failed", "file", path, "error", err)
+	}
+}
+
+// postRun runs the plugins with the outcome of the backup, failed plugins are logged
+func (bm *BackupManager) postRun(ctx context.Context, err error) {
+	event := bm.pluginEvent(stagePostRun)
+	event.Result = &PluginResult{
+		Files:      bm.result.Files,
+		Bytes:      bm.result.Bytes,
+		Skipped:    bm.result.Skipped,
+		Failed:     len(bm.result.Failed),
+		Archive:    bm.result.Archive,
+		DurationMs: bm.result.Duration.Milliseconds(),
+		ExitCode:   ExitCode(err),
+	}
+	if err != nil {
+		event.Result.Error = err.Error()
+	}
+	if pluginErr := bm.plugins.run(ctx, bm.log(), event); pluginErr != nil {
+		bm.log().Warn("Plugin failed", "error", pluginErr)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes a shell script plugin to dir
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pluginEvents reads the events appended to a file by a recording plugin
+func pluginEvents(t *testing.T, path string) []PluginEvent {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []PluginEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event PluginEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestLoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "20-notify", "exit 0\n")
+	writePlugin(t, dir, "10-lock", "exit 0\n")
+	writePlugin(t, dir, ".hidden", "exit 0\n")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadPlugins(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "10-lock"), filepath.Join(dir, "20-notify")}
+	if !slices.Equal(p.paths, want) {
+		t.Errorf("plugins = %v, want %v", p.paths, want)
+	}
+	if p, err := loadPlugins(""); p != nil || err != nil {
+		t.Errorf("Expected no plugins, got %v, %v", p, err)
+	}
+	if _, err := loadPlugins(filepath.Join(dir, "missing")); ExitCode(err) != ExitConfig {
+		t.Errorf("Expected a configuration error, got %v", err)
+	}
+}
+
+func TestBackupPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := conditionalServer(t, nil)
+	defer server.Close()
+
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("content of "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := filepath.Join(t.TempDir(), "events.ndjson")
+	t.Setenv("PLUGIN_EVENTS", events)
+	dir := t.TempDir()
+	writePlugin(t, dir, "record", "cat >> \"$PLUGIN_EVENTS\"\necho >> \"$PLUGIN_EVENTS\"\n")
+
+	cfg := testConfig(src, server.URL)
+	cfg.Job = "nightly"
+	cfg.PluginsDir = dir
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	got := pluginEvents(t, events)
+	var stages, keys []string
+	for _, event := range got {
+		stages = append(stages, event.Stage)
+		if event.File != nil {
+			keys = append(keys, event.File.Key)
+		}
+		if event.Job != "nightly" || event.Operation != operationBackup || event.Path != src {
+			t.Errorf("event = %+v", event)
+		}
+	}
+	if want := []string{stagePreScan, stageFileUploaded, stageFileUploaded, stagePostRun}; !slices.Equal(stages, want) {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+	if want := []string{"backups/a.txt", "backups/b.txt"}; !slices.Equal(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if result := got[3].Result; result == nil || result.Files != 2 || result.ExitCode != ExitOK {
+		t.Errorf("post-run result = %+v", result)
+	}
+}
+
+func TestBackupPluginPreScanFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, _ := conditionalServer(t, nil)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events := filepath.Join(t.TempDir(), "events.ndjson")
+	t.Setenv("PLUGIN_EVENTS", events)
+	dir := t.TempDir()
+	writePlugin(t, dir, "lock", "if [ \"$1\" = pre-scan ]; then echo locked; exit 1; fi\ncat >> \"$PLUGIN_EVENTS\"\necho >> \"$PLUGIN_EVENTS\"\n")
+
+	cfg := testConfig(src, server.URL)
+	cfg.PluginsDir = dir
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "plugin lock failed at stage pre-scan") {
+		t.Fatalf("Expected a pre-scan plugin error, got %v", err)
+	}
+	if result.Files != 0 {
+		t.Errorf("Expected no uploaded files, got %d", result.Files)
+	}
+	got := pluginEvents(t, events)
+	if len(got) != 1 || got[0].Stage != stagePostRun || got[0].Result.ExitCode != ExitFailure || got[0].Result.Error == "" {
+		t.Errorf("Expected a failed post-run event, got %+v", got)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// localPath returns the local path a key is restored to, names invalid on Windows are handled with the sanitize strategy
+func (rm *RestoreManager) localPath(key string) (string, error) {
+	name := normalizeUnicode(relativeKey(key, rm.config.Path), rm.config.NormalizeUnicode)
+	if rm.config.Flatten {
+		var err error
+		if name, err = rm.flatName(key, name); err != nil {
+			return "", err
+		}
+	}
+	if windowsPaths {
+		var err error
+		if name, err = windowsName(name, rm.config.SanitizeNames); err != nil {
+			return "", err
+		}
+	}
+	return longPath(keyToLocal(rm.config.Dest, name)), nil
+}
+
+// flatName returns the base name a key is restored to with --flatten, keys of the same name in different
+// directories are refused instead of overwriting each other
+func (rm *RestoreManager) flatName(key, name string) (string, error) {
+	name = path.Base(name)
+	if other, ok := rm.flattened[name]; ok && other != key {
+		return "", fmt.Errorf("--flatten restores %q and %q to the same file %q, restore them separately", other, key, name)
+	}
+	if rm.flattened == nil {
+		rm.flattened = make(map[string]string)
+	}
+	rm.flattened[name] = key
+	return name, nil
+}
+
+// restoreAction returns what restore does with a file: download, exclude or skip an existing file
+func (rm *RestoreManager) restoreAction(file Item, localPath string) string {
+	if slices.Contains(rm.config.Exclude, filepath.Base(file.Key)) {
+		return "exclude"
+	}
+	if !rm.config.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return "skip (exists)"
+		}
+	}
+	return "download"
+}
+
+// RestoreListEntry is a file of the restore listing of --list
+type RestoreListEntry struct {
+	Key       string `json:"key"`
+	LocalPath string `json:"local_path"`
+	Size      int64  `json:"size"`
+	Action    string `json:"action"`
+
+	LastModified time.Time `json:"last_modified,omitzero"`
+	StorageClass string    `json:"storage_class,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+}
+
+// printRestoreList prints the keys that would be restored and their local paths without downloading,
+// as a text table, JSON lines or CSV
+func (rm *RestoreManager) printRestoreList(out io.Writer, files []Item, localPath func(Item) (string, error)) error {
+	entries := make([]RestoreListEntry, 0, len(files))
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		path, err := localPath(file)
+		action := ""
+		switch {
+		case errors.Is(err, errSkippedName):
+			path, action = "-", "skip (invalid name)"
+		case err != nil:
+			path, action = "-", "fail (invalid name)"
+		default:
+			action = rm.restoreAction(file, path)
+		}
+		entries = append(entries, RestoreListEntry{Key: file.Key, LocalPath: path, Size: file.Size, Action: action,
+			LastModified: file.LastModified, StorageClass: file.StorageClass, ETag: file.ETag})
+	}
+
+	switch rm.config.Format {
+	case formatJSON:
+		return writeJSONLines(out, entries)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"key", "local_path", "size", "action"})
+		for _, entry := range entries {
+			_ = w.Write([]string{entry.Key, entry.LocalPath, strconv.FormatInt(entry.Size, 10), entry.Action})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tLOCAL PATH\tSIZE\tACTION")
+	for _, entry := range entries {
+		size := "-"
+		if entry.Size > 0 {
+			size = goutils.ConvertBytes(uint64(entry.Size))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Key, entry.LocalPath, size, entry.Action)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,126 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintRestoreList(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "exists.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: dest, Exclude: []string{"skip.txt"}}}
+	files := []Item{
+		{Key: "backups/nested/data.txt", Size: 2048},
+		{Key: "backups/exists.txt"},
+		{Key: "backups/skip.txt"},
+		{Key: "backups/nested/", IsDir: true},
+	}
+
+	var out bytes.Buffer
+	if err := rm.printRestoreList(&out, files, func(file Item) (string, error) { return rm.localPath(file.Key) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and 3 files, got %q", out.String())
+	}
+	expected := []string{
+		filepath.Join(dest, "nested", "data.txt"),
+		"skip (exists)",
+		"exclude",
+	}
+	for i, want := range expected {
+		if !strings.Contains(lines[i+1], want) {
+			t.Errorf("Expected line %q to contain %q", lines[i+1], want)
+		}
+	}
+}
+
+func TestPrintRestoreListCSV(t *testing.T) {
+	dest := t.TempDir()
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: dest, Format: formatCSV}}
+	files := []Item{{Key: "backups/a,b.txt", Size: 2048}, {Key: "backups/nested/", IsDir: true}}
+
+	var out bytes.Buffer
+	if err := rm.printRestoreList(&out, files, func(file Item) (string, error) { return rm.localPath(file.Key) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "key,local_path,size,action\n\"backups/a,b.txt\",\"" + filepath.Join(dest, "a,b.txt") + "\",2048,download\n"
+	if out.String() != want {
+		t.Errorf("CSV listing = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrintRestoreListJSON(t *testing.T) {
+	dest := t.TempDir()
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: dest, Format: formatJSON}}
+	modified := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	files := []Item{
+		{Key: "backups/a.txt", Size: 2048, LastModified: modified, StorageClass: "STANDARD_IA", ETag: "abc"},
+		{Key: "backups/nested/", IsDir: true},
+		{Key: "backups/b.txt", Size: 1},
+	}
+
+	var out bytes.Buffer
+	if err := rm.printRestoreList(&out, files, func(file Item) (string, error) { return rm.localPath(file.Key) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `{"key":"backups/a.txt","local_path":` + strconv.Quote(filepath.Join(dest, "a.txt")) +
+		`,"size":2048,"action":"download","last_modified":"2025-06-01T10:00:00Z","storage_class":"STANDARD_IA","etag":"abc"}` + "\n" +
+		`{"key":"backups/b.txt","local_path":` + strconv.Quote(filepath.Join(dest, "b.txt")) + `,"size":1,"action":"download"}` + "\n"
+	if out.String() != want {
+		t.Errorf("JSON listing = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLocalPathFlatten(t *testing.T) {
+	dest := t.TempDir()
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: dest, Flatten: true}}
+	for key, want := range map[string]string{
+		"backups/db/daily/db.sql.gz": filepath.Join(dest, "db.sql.gz"),
+		"backups/etc/app.conf":       filepath.Join(dest, "app.conf"),
+		"backups":                    filepath.Join(dest, "backups"),
+	} {
+		got, err := rm.localPath(key)
+		if err != nil || got != want {
+			t.Errorf("Expected %s for %s, got %s (%v)", want, key, got, err)
+		}
+	}
+	if _, err := rm.localPath("backups/db/daily/db.sql.gz"); err != nil {
+		t.Errorf("Expected the same key to map to its file again, got %v", err)
+	}
+	if _, err := rm.localPath("backups/db/weekly/db.sql.gz"); err == nil {
+		t.Error("Expected keys of the same name to be refused with --flatten")
+	}
+}
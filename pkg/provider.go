@@ -0,0 +1,169 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/jkaninda/s3safe/utils"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Provider is a preset for an S3-compatible service, selected with --provider.
+// It fills defaults and rejects features the service does not implement.
+type Provider struct {
+	// Region is used when AWS_REGION is not set
+	Region string
+	// Endpoint is used when AWS_ENDPOINT is not set, {region} is replaced by the region
+	Endpoint string
+	// RequiresEndpoint is set for self-hosted services without a public endpoint
+	RequiresEndpoint bool
+	// ForcePath enables path-style addressing
+	ForcePath bool
+	// StorageClasses lists the supported storage classes, empty allows all
+	StorageClasses []string
+	// NoACL drops canned ACLs, the service rejects them
+	NoACL bool
+	// NoObjectLock rejects Object Lock retention and legal holds
+	NoObjectLock bool
+	// NoChecksum disables S3 additional checksums on upload, the service rejects them
+	NoChecksum bool
+	// PartSize is the multipart upload part size in bytes, zero keeps the SDK default
+	PartSize int64
+}
+
+var providers = map[string]Provider{
+	// Amazon S3 is the default, the preset only selects its prices in the cost command
+	"aws": {},
+	// Cloudflare R2 ignores the region, has no ACLs nor Object Lock API,
+	// and requires equally sized multipart parts
+	"r2": {
+		Region:           "auto",
+		RequiresEndpoint: true,
+		StorageClasses:   []string{"STANDARD", "STANDARD_IA"},
+		NoACL:            true,
+		NoObjectLock:     true,
+		PartSize:         16 * 1024 * 1024,
+	},
+	"minio": {
+		Region:           "us-east-1",
+		RequiresEndpoint: true,
+		ForcePath:        true,
+		StorageClasses:   []string{"STANDARD", "REDUCED_REDUNDANCY"},
+	},
+	"ceph": {
+		Region:           "default",
+		RequiresEndpoint: true,
+		ForcePath:        true,
+		StorageClasses:   []string{"STANDARD"},
+	},
+	// Wasabi has a single storage class and enforces a minimum storage duration instead
+	"wasabi": {
+		Region:         "us-east-1",
+		Endpoint:       "https://s3.{region}.wasabisys.com",
+		StorageClasses: []string{"STANDARD"},
+	},
+	// DigitalOcean Spaces has no Object Lock and a single storage class
+	"digitalocean": {
+		Region:         "nyc3",
+		Endpoint:       "https://{region}.digitaloceanspaces.com",
+		StorageClasses: []string{"STANDARD"},
+		NoObjectLock:   true,
+		NoChecksum:     true,
+	},
+	"scaleway": {
+		Region:         "fr-par",
+		Endpoint:       "https://s3.{region}.scw.cloud",
+		StorageClasses: []string{"STANDARD", "ONEZONE_IA", "GLACIER"},
+	},
+}
+
+// provider returns the configured provider preset, the zero Provider when none is set
+func (c *Config) provider() (Provider, error) {
+	if c.Provider == "" {
+		return Provider{}, nil
+	}
+	p, ok := providers[c.Provider]
+	if !ok {
+		return Provider{}, fmt.Errorf("unknown provider %q, supported values: %v", c.Provider, slices.Sorted(maps.Keys(providers)))
+	}
+	return p, nil
+}
+
+// applyProvider fills the settings left unset from the provider preset
+func (c *Config) applyProvider() {
+	c.Provider = strings.ToLower(c.Provider)
+	p, err := c.provider()
+	if err != nil {
+		// Reported by validateProvider
+		return
+	}
+	if c.Region == "" {
+		c.Region = p.Region
+	}
+	if p.Endpoint != "" && c.EndPoint == utils.AwsS3Url {
+		c.EndPoint = strings.ReplaceAll(p.Endpoint, "{region}", c.Region)
+	}
+	if p.ForcePath {
+		c.ForcePath = true
+	}
+	if p.NoChecksum && c.Checksum == "" {
+		c.Checksum = checksumNone
+	}
+	if p.NoACL && c.ACL != "" {
+		slog.Warn("ACLs are not supported by the provider, ignoring acl", "provider", c.Provider, "acl", c.ACL)
+		c.ACL = ""
+	}
+}
+
+// validateProvider rejects options the provider does not support
+func (c *Config) validateProvider() error {
+	p, err := c.provider()
+	if err != nil {
+		return err
+	}
+	if p.RequiresEndpoint && c.EndPoint == utils.AwsS3Url {
+		return fmt.Errorf("provider %s requires an endpoint, set AWS_ENDPOINT env variable", c.Provider)
+	}
+	if p.NoObjectLock && (c.ObjectLockMode != "" || c.LegalHold) {
+		return fmt.Errorf("object lock is not supported by provider %s", c.Provider)
+	}
+	if len(p.StorageClasses) == 0 {
+		return nil
+	}
+	classes := []string{c.StorageClass}
+	rules, _ := ParseStorageClassRules(c.StorageClassRules)
+	for _, rule := range rules {
+		classes = append(classes, rule.StorageClass)
+	}
+	for _, class := range classes {
+		if class != "" && !slices.Contains(p.StorageClasses, class) {
+			return fmt.Errorf("storage class %q is not supported by provider %s, supported values: %v", class, c.Provider, p.StorageClasses)
+		}
+	}
+	return nil
+}
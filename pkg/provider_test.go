@@ -0,0 +1,77 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import "testing"
+
+func TestProviderR2(t *testing.T) {
+	c := &Config{Provider: "R2", ACL: "public-read"}
+	c.applyProvider()
+	if c.Region != "auto" || c.ACL != "" || c.Checksum != "" {
+		t.Errorf("Expected auto region and no acl, got %q and %q", c.Region, c.ACL)
+	}
+	if err := c.validateProvider(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, invalid := range []*Config{
+		{Provider: "r2", ObjectLockMode: "COMPLIANCE"},
+		{Provider: "r2", StorageClass: "GLACIER"},
+		{Provider: "r2", StorageClassRules: "size>1GB:DEEP_ARCHIVE"},
+		{Provider: "unknown"},
+	} {
+		if err := invalid.validateProvider(); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestProviderEndpointTemplate(t *testing.T) {
+	c := &Config{Provider: "wasabi", Region: "eu-central-1", EndPoint: "https://s3.amazonaws.com"}
+	c.applyProvider()
+	if c.EndPoint != "https://s3.eu-central-1.wasabisys.com" {
+		t.Errorf("Unexpected endpoint %q", c.EndPoint)
+	}
+
+	c = &Config{Provider: "minio", EndPoint: "https://s3.amazonaws.com"}
+	c.applyProvider()
+	if !c.ForcePath || c.Region != "us-east-1" {
+		t.Errorf("Expected path-style and default region, got %+v", c)
+	}
+	if err := c.validateProvider(); err == nil {
+		t.Errorf("Expected minio without endpoint to be rejected")
+	}
+}
+
+func TestParseRemoteProvider(t *testing.T) {
+	c := &Config{Region: "us-east-1", EndPoint: "https://s3.amazonaws.com", Bucket: "primary"}
+	remote, err := c.ParseRemote("s3://dr/backups?provider=digitalocean&region=ams3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote.Config.EndPoint != "https://ams3.digitaloceanspaces.com" || remote.Config.Region != "ams3" {
+		t.Errorf("Unexpected remote config %+v", remote.Config)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// PruneReport records the versions deleted by purge-versions and the versions it retained
+type PruneReport struct {
+	Operation string `json:"operation"`
+	Bucket    string `json:"bucket,omitempty"`
+	Path      string `json:"path"`
+	OlderThan string `json:"older_than"`
+	// DryRun reports lists the versions that would be deleted
+	DryRun    bool      `json:"dry_run,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ReclaimedBytes is the total size of the deleted versions
+	ReclaimedBytes int64          `json:"reclaimed_bytes"`
+	Deleted        []VersionEntry `json:"deleted"`
+	Retained       []VersionEntry `json:"retained"`
+	// Failed holds the versions whose deletion failed with --ignore-errors, in the key?versionId=id form
+	Failed []FailedFile `json:"failed,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// VersionEntry is an object version of a prune report
+type VersionEntry struct {
+	Key          string    `json:"key"`
+	VersionID    string    `json:"version_id"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	IsLatest     bool      `json:"is_latest,omitempty"`
+	DeleteMarker bool      `json:"delete_marker,omitempty"`
+	StorageClass string    `json:"storage_class,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+}
+
+func newVersionEntry(v ObjectVersion) VersionEntry {
+	return VersionEntry{
+		Key:          v.Key,
+		VersionID:    v.VersionID,
+		Size:         v.Size,
+		LastModified: v.LastModified,
+		IsLatest:     v.IsLatest,
+		DeleteMarker: v.DeleteMarker,
+		StorageClass: v.StorageClass,
+		ETag:         v.ETag,
+	}
+}
+
+func (vm *VersionManager) newPruneReport() *PruneReport {
+	return &PruneReport{
+		Operation: operationPurgeVersions,
+		Bucket:    vm.config.Bucket,
+		Path:      vm.config.Path,
+		OlderThan: vm.config.OlderThan,
+		DryRun:    vm.config.DryRun,
+		Deleted:   []VersionEntry{},
+		Retained:  []VersionEntry{},
+	}
+}
+
+// deleted records a deleted version, or a version a dry run would delete
+func (r *PruneReport) deleted(v ObjectVersion) {
+	r.Deleted = append(r.Deleted, newVersionEntry(v))
+	r.ReclaimedBytes += v.Size
+}
+
+// savePruneReport completes the report with the retained versions and the error of the run,
+// and writes it when --prune-report is set. A relative s3:// key is written next to the pruned path.
+func (vm *VersionManager) savePruneReport(ctx context.Context, report *PruneReport, versions []ObjectVersion, err error) error {
+	if vm.config.PruneReport == "" {
+		return err
+	}
+	gone := make(map[string]bool, len(report.Deleted)+len(report.Failed))
+	for _, v := range report.Deleted {
+		gone[v.Key+"?versionId="+v.VersionID] = true
+	}
+	for _, f := range report.Failed {
+		gone[f.Key] = true
+	}
+	for _, v := range versions {
+		if !gone[versionKey(v)] {
+			report.Retained = append(report.Retained, newVersionEntry(v))
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.CreatedAt = time.Now().UTC()
+
+	location := vm.pruneReportLocation(report.CreatedAt)
+	if writeErr := writeReport(ctx, vm.config, location, report); writeErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to write prune report: %w", writeErr))
+	}
+	slog.Info("Prune report written", "report", location, "deleted", len(report.Deleted), "retained", len(report.Retained))
+	return err
+}
+
+// pruneReportLocation returns the location of the report, a value without a slash nor a scheme is
+// a report name uploaded under the pruned path, {{ .Timestamp }} is replaced by the report time
+func (vm *VersionManager) pruneReportLocation(at time.Time) string {
+	location := strings.ReplaceAll(vm.config.PruneReport, "{{ .Timestamp }}", at.Format("20060102T150405Z"))
+	if strings.ContainsAny(location, `/\`) {
+		return location
+	}
+	return fmt.Sprintf("s3://%s/%s", vm.config.Bucket, objectKey(vm.config.Path, location))
+}
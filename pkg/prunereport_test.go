@@ -0,0 +1,99 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavePruneReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prune.json")
+	vm := &VersionManager{config: &Config{Bucket: "backups", Path: "db", OlderThan: "90d", PruneReport: path}}
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	versions := []ObjectVersion{
+		{Key: "db/a.tar.gz", VersionID: "v3", IsLatest: true, Size: 30, LastModified: at.Add(2 * time.Hour)},
+		{Key: "db/a.tar.gz", VersionID: "v2", Size: 20, LastModified: at.Add(time.Hour)},
+		{Key: "db/a.tar.gz", VersionID: "v1", Size: 10, LastModified: at},
+	}
+
+	report := vm.newPruneReport()
+	report.deleted(versions[2])
+	report.Failed = append(report.Failed, FailedFile{Key: versionKey(versions[1]), Error: "access denied"})
+	runErr := errors.New("interrupted")
+	if err := vm.savePruneReport(context.Background(), report, versions, runErr); !errors.Is(err, runErr) {
+		t.Fatalf("savePruneReport = %v, want the run error", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved PruneReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Operation != operationPurgeVersions || saved.Bucket != "backups" || saved.ReclaimedBytes != 10 || saved.Error != "interrupted" {
+		t.Errorf("report = %+v", saved)
+	}
+	if len(saved.Deleted) != 1 || saved.Deleted[0].VersionID != "v1" {
+		t.Errorf("deleted = %+v", saved.Deleted)
+	}
+	// The version that failed to delete is neither deleted nor retained
+	if len(saved.Retained) != 1 || saved.Retained[0].VersionID != "v3" || !saved.Retained[0].IsLatest {
+		t.Errorf("retained = %+v", saved.Retained)
+	}
+}
+
+func TestSavePruneReportDisabled(t *testing.T) {
+	vm := &VersionManager{config: &Config{}}
+	runErr := errors.New("failed")
+	if err := vm.savePruneReport(context.Background(), vm.newPruneReport(), nil, runErr); err != runErr {
+		t.Errorf("savePruneReport = %v, want the run error unchanged", err)
+	}
+}
+
+func TestPruneReportLocation(t *testing.T) {
+	at := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		report, want string
+	}{
+		{"prune.json", "s3://backups/db/prune.json"},
+		{"prune-{{ .Timestamp }}.json", "s3://backups/db/prune-20250601T100000Z.json"},
+		{"./prune.json", "./prune.json"},
+		{"s3://audit/prune.json", "s3://audit/prune.json"},
+	}
+	for _, tt := range tests {
+		vm := &VersionManager{config: &Config{Bucket: "backups", Path: "db", PruneReport: tt.report}}
+		if got := vm.pruneReportLocation(at); got != tt.want {
+			t.Errorf("pruneReportLocation(%q) = %q, want %q", tt.report, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/jkaninda/s3safe/utils"
+	"net/url"
+	"strings"
+)
+
+// Remote is an S3 location given as s3://bucket/prefix.
+// The connection settings default to the main configuration and can be overridden with query parameters:
+// endpoint, region, force-path, disable-ssl, provider, and credentials=NAME to read NAME_ACCESS_KEY_ID and NAME_SECRET_KEY.
+type Remote struct {
+	Config *Config
+	Prefix string
+}
+
+// ParseRemote parses an s3:// URL into a Remote based on the configuration
+func (c *Config) ParseRemote(raw string) (*Remote, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote %q: %w", raw, err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote %q, expected s3://bucket/prefix", raw)
+	}
+
+	// The mirrors share the bandwidth schedule of the run
+	if _, err := c.transferLimiter(); err != nil {
+		return nil, err
+	}
+	config := *c
+	config.Bucket = u.Host
+	query := u.Query()
+	if provider := query.Get("provider"); provider != "" {
+		// The provider defaults replace the main region and endpoint
+		config.Provider = provider
+		config.Region = ""
+		config.EndPoint = utils.AwsS3Url
+	}
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		config.EndPoint = endpoint
+	}
+	if region := query.Get("region"); region != "" {
+		config.Region = region
+	}
+	if query.Has("provider") {
+		config.applyProvider()
+		if config.Region == "" {
+			config.Region = c.Region
+		}
+	}
+	if query.Has("force-path") {
+		config.ForcePath = query.Get("force-path") != "false"
+	}
+	if query.Has("disable-ssl") {
+		config.DisableSSL = query.Get("disable-ssl") != "false"
+	}
+	if name := query.Get("credentials"); name != "" {
+		name = strings.ToUpper(name)
+		config.KeyID = utils.Env(name + "_ACCESS_KEY_ID")
+		config.Secret = utils.Env(name + "_SECRET_KEY")
+		if config.KeyID == "" || config.Secret == "" {
+			return nil, fmt.Errorf("credentials %s not found, set %s_ACCESS_KEY_ID and %s_SECRET_KEY env variables", name, name, name)
+		}
+	}
+	if isAWSEndpoint(config.EndPoint, config.Region) {
+		config.EndPoint = utils.AwsS3Url
+	}
+	// The connection settings of a remote cannot leave the FIPS endpoints
+	if config.FIPS {
+		if err := config.validateFIPS(); err != nil {
+			return nil, fmt.Errorf("invalid remote %q: %w", raw, err)
+		}
+	}
+
+	return &Remote{
+		Config: &config,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// String returns the remote as an s3:// URL without connection settings
+func (r *Remote) String() string {
+	return fmt.Sprintf("s3://%s/%s", r.Config.Bucket, r.Prefix)
+}
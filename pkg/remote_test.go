@@ -0,0 +1,62 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import "testing"
+
+func TestParseRemote(t *testing.T) {
+	t.Setenv("MINIO_ACCESS_KEY_ID", "minio")
+	t.Setenv("MINIO_SECRET_KEY", "secret")
+	config := &Config{Bucket: "main", Region: "us-east-1", EndPoint: "https://s3.amazonaws.com", KeyID: "key", Secret: "main-secret"}
+
+	remote, err := config.ParseRemote("s3://dr-bucket/backups/daily/?endpoint=https://minio.local:9000&force-path=true&credentials=minio")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote.Config.Bucket != "dr-bucket" || remote.Prefix != "backups/daily" {
+		t.Errorf("Unexpected bucket or prefix: %s %s", remote.Config.Bucket, remote.Prefix)
+	}
+	if remote.Config.EndPoint != "https://minio.local:9000" || !remote.Config.ForcePath || remote.Config.Region != "us-east-1" {
+		t.Errorf("Unexpected connection settings: %+v", remote.Config)
+	}
+	if remote.Config.KeyID != "minio" || remote.Config.Secret != "secret" {
+		t.Errorf("Expected credentials from MINIO env variables, got %s", remote.Config.KeyID)
+	}
+	if config.Bucket != "main" || config.KeyID != "key" {
+		t.Errorf("Expected main configuration to be unchanged")
+	}
+
+	for _, invalid := range []string{"dr-bucket/backups", "s3:///backups", "s3://bucket?credentials=missing"} {
+		if _, err := config.ParseRemote(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCopySource(t *testing.T) {
+	if got := copySource("bucket", "backups/my file#1.tar.gz"); got != "bucket/backups/my%20file%231.tar.gz" {
+		t.Errorf("Unexpected copy source %q", got)
+	}
+}
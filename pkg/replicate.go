@@ -0,0 +1,215 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxCopyObjectSize is the largest object CopyObject accepts in a single request
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// ReplicateManager handles incremental copies between buckets
+type ReplicateManager struct {
+	config *Config
+	source *Remote
+	dest   *Remote
+	src    *S3Storage
+	dst    *S3Storage
+}
+
+// Replicate is the cobra command handler for replicate
+func Replicate(cmd *cobra.Command) error {
+	rm, err := NewReplicateManager(cmd)
+	if err != nil {
+		return err
+	}
+	return rm.Replicate(cmd.Context())
+}
+
+// NewReplicateManager creates a new ReplicateManager instance
+func NewReplicateManager(cmd *cobra.Command) (*ReplicateManager, error) {
+	config := NewConfig(cmd)
+	if config.From == "" || config.To == "" {
+		return nil, errors.New("both --from and --to are required")
+	}
+
+	source, err := config.ParseRemote(config.From)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := config.ParseRemote(config.To)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := newRemoteStorage(cmd.Context(), source)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", source, err)
+	}
+	dst, err := newRemoteStorage(cmd.Context(), dest)
+	if err != nil {
+		return nil, fmt.Errorf("destination %s: %w", dest, err)
+	}
+
+	return &ReplicateManager{
+		config: config,
+		source: source,
+		dest:   dest,
+		src:    src,
+		dst:    dst,
+	}, nil
+}
+
+// newRemoteStorage validates a remote configuration and creates its storage
+func newRemoteStorage(ctx context.Context, remote *Remote) (*S3Storage, error) {
+	if err := remote.Config.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	return remote.Config.NewS3Storage(ctx)
+}
+
+// Replicate copies new and changed objects from the source to the destination
+func (rm *ReplicateManager) Replicate(ctx context.Context) error {
+	intro()
+	slog.Info("Replicating data...", "from", rm.source, "to", rm.dest)
+
+	sourceFiles, err := rm.src.List(ctx, rm.source.Prefix, true)
+	if err != nil {
+		return fmt.Errorf("failed to list source: %w", err)
+	}
+	destFiles, err := rm.dst.List(ctx, rm.dest.Prefix, true)
+	if err != nil {
+		return fmt.Errorf("failed to list destination: %w", err)
+	}
+	existing := make(map[string]Item, len(destFiles))
+	for _, file := range destFiles {
+		existing[file.Key] = file
+	}
+
+	var copied, skipped, failed int
+	var bytes int64
+	for _, file := range sourceFiles {
+		if file.IsDir || slices.Contains(rm.config.Exclude, filepath.Base(file.Key)) {
+			continue
+		}
+		destKey := path.Join(rm.dest.Prefix, relativeKey(file.Key, rm.source.Prefix))
+		if current, ok := existing[destKey]; ok && !needsReplication(file, current) {
+			skipped++
+			continue
+		}
+
+		if rm.config.DryRun {
+			slog.Info("Would copy object", "key", file.Key, "target", destKey, "size", goutils.ConvertBytes(uint64(file.Size)))
+			continue
+		}
+		if err := rm.dst.CopyFrom(ctx, rm.src, file, destKey); err != nil {
+			if rm.config.IgnoreErrors {
+				slog.Warn("Ignoring error", "error", err)
+				failed++
+				continue
+			}
+			return err
+		}
+		copied++
+		bytes += file.Size
+		slog.Info("Copied object", "key", file.Key, "target", destKey)
+	}
+
+	if failed > 0 {
+		slog.Warn("Replication completed with errors", "copied", copied, "unchanged", skipped, "failed", failed, "size", goutils.ConvertBytes(uint64(bytes)))
+		return withExitCode(ExitPartial, fmt.Errorf("%d objects failed to copy, their errors were ignored", failed))
+	}
+	slog.Info("Replication completed successfully", "copied", copied, "unchanged", skipped, "size", goutils.ConvertBytes(uint64(bytes)), "dryRun", rm.config.DryRun)
+	return nil
+}
+
+// needsReplication reports whether the source object differs from its existing copy
+func needsReplication(source, dest Item) bool {
+	return source.Size != dest.Size || source.LastModified.After(dest.LastModified)
+}
+
+// CopyFrom copies an object from another storage into this one.
+// Objects on the same endpoint are copied server-side, others are streamed through this host.
+func (s S3Storage) CopyFrom(ctx context.Context, src *S3Storage, file Item, target string) error {
+	if s.connection == src.connection && file.Size <= maxCopyObjectSize {
+		_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(target),
+			CopySource: aws.String(copySource(src.bucket, file.Key)),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to copy %q to %q: %w", file.Key, target, err)
+		}
+		return nil
+	}
+
+	obj, err := src.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(src.bucket),
+		Key:    aws.String(file.Key),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read %q: %w", file.Key, err)
+	}
+	defer func() {
+		if err := obj.Body.Close(); err != nil {
+			s.log().Error("error closing object body", "error", err)
+		}
+	}()
+
+	_, err = manager.NewUploader(s.client, s.uploaderOptions(aws.ToInt64(obj.ContentLength))).Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(target),
+		Body:        obj.Body,
+		ContentType: obj.ContentType,
+		Metadata:    obj.Metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to upload %q to %q: %w", file.Key, s.bucket, err)
+	}
+	return nil
+}
+
+// copySource returns the URL-encoded bucket/key value of the x-amz-copy-source header
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
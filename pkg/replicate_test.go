@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplicateIgnoreErrors(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("prefix") == "source/":
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>bucket</Name><KeyCount>1</KeyCount><IsTruncated>false</IsTruncated>` +
+				`<Contents><Key>source/a.txt</Key><Size>5</Size></Contents></ListBucketResult>`))
+		case r.URL.Query().Has("list-type"):
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>bucket</Name><KeyCount>0</KeyCount><IsTruncated>false</IsTruncated></ListBucketResult>`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig("source", server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := &ReplicateManager{
+		config: &cfg,
+		source: &Remote{Config: &cfg, Prefix: "source"},
+		dest:   &Remote{Config: &cfg, Prefix: "dest"},
+		src:    storage,
+		dst:    storage,
+	}
+	if err := rm.Replicate(context.Background()); err == nil || ExitCode(err) == ExitPartial {
+		t.Errorf("Expected the failed copy to fail the replication, got %v", err)
+	}
+	cfg.IgnoreErrors = true
+	if err := rm.Replicate(context.Background()); ExitCode(err) != ExitPartial {
+		t.Errorf("Expected the ignored failure to be a partial failure, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Operations recorded in failure reports
+const (
+	operationBackup  = "backup"
+	operationRestore = "restore"
+)
+
+// FailureReport lists the files that failed during a backup or restore run with --ignore-errors
+type FailureReport struct {
+	Operation string `json:"operation"`
+	Bucket    string `json:"bucket,omitempty"`
+	// Path and Dest are the source and destination of the run, as configured
+	Path       string `json:"path"`
+	Dest       string `json:"dest"`
+	Decompress bool   `json:"decompress,omitempty"`
+	// CompressFiles records a backup run with --compress-files
+	CompressFiles bool         `json:"compress_files,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Files         []FailedFile `json:"files"`
+}
+
+// FailedFile is a file of a failure report, its key is relative to the backup path or the object key of a restore
+type FailedFile struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+func newFailureReport(operation string, config *Config, path string, failed []FileError) *FailureReport {
+	report := &FailureReport{
+		Operation:     operation,
+		Bucket:        config.Bucket,
+		Path:          path,
+		Dest:          config.Dest,
+		Decompress:    config.Decompress,
+		CompressFiles: config.CompressFiles && operation == operationBackup,
+		CreatedAt:     time.Now().UTC(),
+	}
+	for _, f := range failed {
+		report.Files = append(report.Files, FailedFile{Key: f.Key, Error: f.Err.Error()})
+	}
+	return report
+}
+
+// writeFailureReport writes the report to a local file or to an s3:// URL
+func writeFailureReport(ctx context.Context, config *Config, location string, report *FailureReport) error {
+	if err := writeReport(ctx, config, location, report); err != nil {
+		return fmt.Errorf("failed to write failure report: %w", err)
+	}
+	return nil
+}
+
+// writeReport writes a JSON report to a local file or to an s3:// URL
+func writeReport(ctx context.Context, config *Config, location string, report any) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeReportData(ctx, config, location, data, "application/json")
+}
+
+// writeReportData writes a report of the content type to a local file or to an s3:// URL
+func writeReportData(ctx context.Context, config *Config, location string, data []byte, contentType string) error {
+	if !strings.HasPrefix(location, "s3://") {
+		return os.WriteFile(location, data, 0o600)
+	}
+
+	storage, key, err := reportStorage(ctx, config, location)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "s3safe-report-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return storage.Upload(ctx, tmp.Name(), key, UploadOptions{ContentType: contentType})
+}
+
+// readFailureReport reads a report from a local file or from an s3:// URL
+func readFailureReport(ctx context.Context, config *Config, location string) (*FailureReport, error) {
+	path := location
+	if strings.HasPrefix(location, "s3://") {
+		storage, key, err := reportStorage(ctx, config, location)
+		if err != nil {
+			return nil, err
+		}
+		tmp, err := os.MkdirTemp("", "s3safe-report-*")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = os.RemoveAll(tmp)
+		}()
+		path = filepath.Join(tmp, "report.json")
+		if err := storage.Download(ctx, key, path, DownloadOptions{Force: true}); err != nil {
+			return nil, fmt.Errorf("failed to read failure report: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failure report: %w", err)
+	}
+	var report FailureReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid failure report: %w", err)
+	}
+	if report.Operation != operationBackup && report.Operation != operationRestore {
+		return nil, fmt.Errorf("invalid failure report: unknown operation %q", report.Operation)
+	}
+	return &report, nil
+}
+
+// reportStorage returns the storage and the key of an s3:// report location
+func reportStorage(ctx context.Context, config *Config, location string) (*S3Storage, string, error) {
+	remote, err := config.ParseRemote(location)
+	if err != nil {
+		return nil, "", err
+	}
+	if remote.Prefix == "" {
+		return nil, "", fmt.Errorf("invalid report location %q, expected s3://bucket/key", location)
+	}
+	storage, err := remote.Config.NewS3Storage(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	return storage, remote.Prefix, nil
+}
+
+// saveFailureReport writes the report of the failed files when --failure-report is set
+func saveFailureReport(ctx context.Context, config *Config, report *FailureReport) error {
+	if config.FailureReport == "" || len(report.Files) == 0 {
+		return nil
+	}
+	if err := writeFailureReport(ctx, config, config.FailureReport, report); err != nil {
+		return err
+	}
+	loggerOrDefault(config.logger).Info("Failure report written, run \"s3safe retry --report\" to retry the failed files", "report", config.FailureReport, "files", len(report.Files))
+	return nil
+}
+
+// Retry is the cobra command handler for retry
+func Retry(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if config.Report == "" {
+		return withExitCode(ExitConfig, errors.New("--report is required"))
+	}
+	report, err := readFailureReport(cmd.Context(), config, config.Report)
+	if err != nil {
+		return err
+	}
+	if len(report.Files) == 0 {
+		slog.Info("No failed files in the report", "report", config.Report)
+		return nil
+	}
+	// The failed files are retried with the source and destination of the original run
+	config.Path = report.Path
+	config.Dest = report.Dest
+	config.Decompress = report.Decompress
+	config.CompressFiles = report.CompressFiles
+	if report.Bucket != "" {
+		config.Bucket = report.Bucket
+	}
+	keys := make([]string, 0, len(report.Files))
+	for _, f := range report.Files {
+		keys = append(keys, f.Key)
+	}
+
+	intro()
+	switch report.Operation {
+	case operationBackup:
+		bm, err := newBackupManager(cmd.Context(), config, managerOptions{})
+		if err != nil {
+			return err
+		}
+		_, err = bm.Retry(cmd.Context(), keys)
+		return err
+	default:
+		rm, err := newRestoreManager(cmd.Context(), config, managerOptions{})
+		if err != nil {
+			return err
+		}
+		_, err = rm.Retry(cmd.Context(), keys)
+		return err
+	}
+}
+
+// Retry uploads again the files of a failure report, keys are relative to the backup path.
+// Files of a compressed backup are uploaded individually.
+func (bm *BackupManager) Retry(ctx context.Context, keys []string) (BackupResult, error) {
+	bm.result = BackupResult{}
+	bm.audit.reset()
+	start := time.Now()
+	bm.log().Info("Retrying failed files...", "files", len(keys))
+	err := bm.preScan(ctx)
+	if err == nil {
+		for _, key := range keys {
+			if err := bm.processFileForUpload(ctx, Item{Key: filepath.FromSlash(key)}); err != nil {
+				bm.log().Warn("Retry failed", "file", key, "error", err)
+				bm.result.Failed = append(bm.result.Failed, FileError{Key: key, Err: err})
+			}
+		}
+		err = errors.Join(bm.skippedError(), saveFailureReport(ctx, bm.config, newFailureReport(operationBackup, bm.config, bm.config.Path, bm.result.Failed)))
+	}
+	err = bm.writeAudit(ctx, operationBackup, start, err)
+	bm.result.Duration = time.Since(start)
+	bm.postRun(ctx, err)
+	return bm.result, err
+}
+
+// Retry downloads again the objects of a failure report
+func (rm *RestoreManager) Retry(ctx context.Context, keys []string) (RestoreResult, error) {
+	rm.result = RestoreResult{}
+	rm.audit.reset()
+	start := time.Now()
+	rm.log().Info("Retrying failed files...", "files", len(keys))
+	err := rm.ensureDestinationExists()
+	if err == nil {
+		for _, key := range keys {
+			if err := rm.processFileForDownload(ctx, Item{Key: key}); err != nil {
+				rm.log().Warn("Retry failed", "file", key, "error", err)
+				rm.result.Failed = append(rm.result.Failed, FileError{Key: key, Err: err})
+			}
+		}
+		err = errors.Join(rm.failedError(), saveFailureReport(ctx, rm.config, newFailureReport(operationRestore, rm.config, rm.location, rm.result.Failed)))
+	}
+	err = rm.writeAudit(ctx, operationRestore, start, err)
+	rm.result.Duration = time.Since(start)
+	return rm.result, err
+}
@@ -0,0 +1,107 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFailureReportRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures.json")
+	config := &Config{Bucket: "bucket", Path: "backups", Dest: "/restore", Decompress: true}
+	report := newFailureReport(operationRestore, config, "backups", []FileError{{Key: "backups/a.tar.gz", Err: errors.New("checksum mismatch")}})
+	if err := writeFailureReport(context.Background(), config, path, report); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readFailureReport(context.Background(), config, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Operation != operationRestore || read.Path != "backups" || !read.Decompress || len(read.Files) != 1 ||
+		read.Files[0] != (FailedFile{Key: "backups/a.tar.gz", Error: "checksum mismatch"}) {
+		t.Errorf("Unexpected report %+v", read)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"operation":"delete"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFailureReport(context.Background(), config, path); err == nil {
+		t.Error("Expected an error for an unknown operation")
+	}
+}
+
+func TestBackupFailureReportRetry(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, keys := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "target.txt")
+	if err := os.Symlink(target, filepath.Join(src, "broken")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.IgnoreErrors = true
+	cfg.FailureReport = filepath.Join(t.TempDir(), "failures.json")
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); ExitCode(err) != ExitPartial {
+		t.Fatalf("Expected a partial failure, got %v", err)
+	}
+	report, err := readFailureReport(context.Background(), bm.config, cfg.FailureReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Operation != operationBackup || report.Path != src || len(report.Files) != 1 || report.Files[0].Key != "broken" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+
+	// The file is readable once the symlink target exists
+	if err := os.WriteFile(target, []byte("fixed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Retry(context.Background(), []string{report.Files[0].Key})
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if result.Files != 1 || len(result.Failed) != 0 {
+		t.Errorf("Unexpected retry result %+v", result)
+	}
+	if got := keys(); !slices.Equal(got, []string{"backups/a.txt", "backups/broken"}) {
+		t.Errorf("uploaded keys = %v", got)
+	}
+}
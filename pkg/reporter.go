@@ -0,0 +1,83 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"log/slog"
+)
+
+// Reporter receives the progress of backups and restores, file by file
+type Reporter interface {
+	// FileStarted is called before a file is uploaded or downloaded, size is -1 when unknown
+	FileStarted(key string, size int64)
+	// FileCompleted is called once the transfer of a file ends, err is nil on success
+	FileCompleted(key string, size int64, err error)
+}
+
+// nopReporter ignores the progress
+type nopReporter struct{}
+
+func (nopReporter) FileStarted(string, int64)          {}
+func (nopReporter) FileCompleted(string, int64, error) {}
+
+// loggerOrDefault returns the logger, the slog default logger when nil
+func loggerOrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// reporterOrNop returns the reporter, a reporter ignoring the progress when nil
+func reporterOrNop(reporter Reporter) Reporter {
+	if reporter == nil {
+		return nopReporter{}
+	}
+	return reporter
+}
+
+func (bm *BackupManager) log() *slog.Logger {
+	return loggerOrDefault(bm.logger)
+}
+
+func (bm *BackupManager) report() Reporter {
+	return reporterOrNop(bm.reporter)
+}
+
+func (rm *RestoreManager) log() *slog.Logger {
+	return loggerOrDefault(rm.logger)
+}
+
+func (rm *RestoreManager) report() Reporter {
+	return reporterOrNop(rm.reporter)
+}
+
+func (s S3Storage) log() *slog.Logger {
+	return loggerOrDefault(s.logger)
+}
+
+func (a AzureStorage) log() *slog.Logger {
+	return loggerOrDefault(a.logger)
+}
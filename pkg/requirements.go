@@ -0,0 +1,117 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"strings"
+)
+
+// Bucket settings checked by --require
+const (
+	requireVersioning = "versioning"
+	requireObjectLock = "object-lock"
+	requireEncryption = "encryption"
+)
+
+var bucketRequirements = []string{requireVersioning, requireObjectLock, requireEncryption}
+
+// checkRequirements checks the bucket settings listed by --require before a backup.
+// Unmet requirements fail with ExitConfig, or are logged as warnings with --require-warn.
+func (c *Config) checkRequirements(ctx context.Context) error {
+	if len(c.Require) == 0 {
+		return nil
+	}
+	s3Storage, err := c.NewS3Storage(ctx)
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to create S3 storage: %w", err))
+	}
+
+	var unmet []string
+	for _, requirement := range c.Require {
+		if err := checkRequirement(ctx, s3Storage.client, c.Bucket, requirement); err != nil {
+			if c.RequireWarn {
+				loggerOrDefault(c.logger).Warn("Bucket requirement not met", "bucket", c.Bucket, "requirement", requirement, "error", err)
+				continue
+			}
+			unmet = append(unmet, err.Error())
+		}
+	}
+	if len(unmet) > 0 {
+		return withExitCode(ExitConfig, fmt.Errorf("bucket %s does not meet --require: %s", c.Bucket, strings.Join(unmet, "; ")))
+	}
+	return nil
+}
+
+// checkRequirement returns an error when the bucket doesn't have the setting, or when it could not be read
+func checkRequirement(ctx context.Context, client *s3.Client, bucket, requirement string) error {
+	switch requirement {
+	case requireVersioning:
+		out, err := client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+		if err != nil {
+			return fmt.Errorf("could not read the versioning status: %w", err)
+		}
+		if out.Status != types.BucketVersioningStatusEnabled {
+			return errors.New("versioning is not enabled")
+		}
+	case requireObjectLock:
+		out, err := client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{Bucket: aws.String(bucket)})
+		if apiErrorCode(err) == "ObjectLockConfigurationNotFoundError" {
+			return errors.New("object lock is not enabled")
+		}
+		if err != nil {
+			return fmt.Errorf("could not read the object lock configuration: %w", err)
+		}
+		if out.ObjectLockConfiguration == nil || out.ObjectLockConfiguration.ObjectLockEnabled != types.ObjectLockEnabledEnabled {
+			return errors.New("object lock is not enabled")
+		}
+	case requireEncryption:
+		out, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+		if apiErrorCode(err) == "ServerSideEncryptionConfigurationNotFoundError" {
+			return errors.New("default encryption is not enabled")
+		}
+		if err != nil {
+			return fmt.Errorf("could not read the default encryption: %w", err)
+		}
+		if out.ServerSideEncryptionConfiguration == nil || len(out.ServerSideEncryptionConfiguration.Rules) == 0 {
+			return errors.New("default encryption is not enabled")
+		}
+	}
+	return nil
+}
+
+// apiErrorCode returns the error code of an S3 API error, empty for other errors
+func apiErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bucketSettingsServer answers the bucket configuration requests of a bucket with versioning enabled,
+// no Object Lock configuration and default encryption
+func bucketSettingsServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Has("versioning"):
+			_, _ = w.Write([]byte(`<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`))
+		case query.Has("object-lock"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>ObjectLockConfigurationNotFoundError</Code><Message>none</Message></Error>`))
+		case query.Has("encryption"):
+			_, _ = w.Write([]byte(`<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault>` +
+				`<SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckRequirements(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := bucketSettingsServer(t)
+	cfg := testConfig(t.TempDir(), server.URL)
+
+	cfg.Require = []string{requireVersioning, requireEncryption}
+	if err := cfg.checkRequirements(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	cfg.Require = []string{requireVersioning, requireObjectLock}
+	err := cfg.checkRequirements(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "object lock is not enabled") || ExitCode(err) != ExitConfig {
+		t.Errorf("Expected an object lock error with exit code %d, got %v", ExitConfig, err)
+	}
+
+	cfg.RequireWarn = true
+	if err := cfg.checkRequirements(context.Background()); err != nil {
+		t.Errorf("Expected only a warning with --require-warn, got %v", err)
+	}
+}
+
+func TestValidateRequire(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Path: "/data", Dest: "backups", Require: []string{" Versioning", "object-lock"}})
+	if err := cfg.validateOptions(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Require[0] != requireVersioning {
+		t.Errorf("Require = %v, want normalized values", cfg.Require)
+	}
+
+	cfg = newManagerOptions(nil).config(Config{Path: "/data", Dest: "backups", Require: []string{"replication"}})
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected an error for an unknown requirement")
+	}
+}
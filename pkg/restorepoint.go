@@ -0,0 +1,98 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// asOfLayouts are the layouts accepted by --as-of, in the configured time zone unless an offset is given
+var asOfLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseAsOf parses a --as-of time, archive timestamp layouts are accepted too
+func (c *Config) parseAsOf(value string) (time.Time, error) {
+	return c.parseTime("as-of", value)
+}
+
+// parseTime parses the time given to a flag with the --as-of layouts
+func (c *Config) parseTime(flag, value string) (time.Time, error) {
+	location := c.now().Location()
+	for _, layout := range append(asOfLayouts, c.TimestampFormat) {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s time %q, use e.g. \"2025-06-01 03:00\" or RFC 3339", flag, value)
+}
+
+// archiveTime returns the time of the timestamp in an archive name, formatted with the timestamp format
+// in the configured time zone. The last timestamp of the name is used.
+func (c *Config) archiveTime(name string) (time.Time, bool) {
+	location := c.now().Location()
+	size := len(time.Date(2006, 1, 2, 15, 4, 5, 0, location).Format(c.TimestampFormat))
+	for i := len(name) - size; i >= 0; i-- {
+		if t, err := time.ParseInLocation(c.TimestampFormat, name[i:i+size], location); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// backupTime returns the time of a backup, the timestamp of its name or its modification time
+func (c *Config) backupTime(file Item) time.Time {
+	if t, ok := c.archiveTime(path.Base(file.Key)); ok {
+		return t
+	}
+	return file.LastModified
+}
+
+// restorePoint returns the newest backup made at or before asOf, the latest.json marker is ignored
+func (c *Config) restorePoint(files []Item, asOf time.Time) (Item, bool) {
+	var point Item
+	var pointTime time.Time
+	found := false
+	for _, file := range files {
+		if file.IsDir || path.Base(file.Key) == LatestFile {
+			continue
+		}
+		t := c.backupTime(file)
+		if t.After(asOf) {
+			continue
+		}
+		if !found || t.After(pointTime) {
+			point, pointTime, found = file, t, true
+		}
+	}
+	return point, found
+}
@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveTime(t *testing.T) {
+	c := &Config{TimestampFormat: "2006-01-02_15-04-05"}
+	tests := []struct {
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{"app-2025-06-01_03-00-00.tar.gz", time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC), true},
+		{"2024-12-31_23-59-59-app-2025-01-01_00-00-00.tar.gz", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"app.tar.gz", time.Time{}, false},
+		{"app-2025-13-01_03-00-00.tar.gz", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := c.archiveTime(tt.name)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("archiveTime(%q) = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	c = &Config{TimestampFormat: "20060102T150405", Timezone: "Europe/Paris"}
+	got, ok := c.archiveTime("db-20250601T050000.sql.gz")
+	if want := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("Expected %v in Europe/Paris, got %v, %v", want, got, ok)
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	c := &Config{TimestampFormat: "2006-01-02_15-04-05"}
+	want := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+	for _, value := range []string{"2025-06-01 03:00", "2025-06-01 03:00:00", "2025-06-01T03:00", "2025-06-01T05:00:00+02:00", "2025-06-01_03-00-00"} {
+		if got, err := c.parseAsOf(value); err != nil || !got.Equal(want) {
+			t.Errorf("parseAsOf(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := c.parseAsOf("yesterday"); err == nil {
+		t.Error("Expected an invalid time to be rejected")
+	}
+}
+
+func TestRestorePoint(t *testing.T) {
+	c := &Config{TimestampFormat: "2006-01-02_15-04-05"}
+	uploaded := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	files := []Item{
+		{Key: "backups/app-2025-05-31_03-00-00.tar.gz", LastModified: uploaded},
+		{Key: "backups/app-2025-06-01_03-00-00.tar.gz", LastModified: uploaded},
+		{Key: "backups/app-2025-06-02_03-00-00.tar.gz", LastModified: uploaded},
+		{Key: "backups/manual.tar.gz", LastModified: time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)},
+		{Key: "backups/" + LatestFile, LastModified: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "backups/old/", IsDir: true},
+	}
+	tests := []struct {
+		asOf time.Time
+		want string
+	}{
+		{time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), "backups/app-2025-06-01_03-00-00.tar.gz"},
+		{time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC), "backups/app-2025-06-01_03-00-00.tar.gz"},
+		{time.Date(2025, 6, 1, 2, 30, 0, 0, time.UTC), "backups/manual.tar.gz"},
+		{time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), "backups/app-2025-06-02_03-00-00.tar.gz"},
+	}
+	for _, tt := range tests {
+		if got, ok := c.restorePoint(files, tt.asOf); !ok || got.Key != tt.want {
+			t.Errorf("restorePoint(%v) = %q, %v, want %q", tt.asOf, got.Key, ok, tt.want)
+		}
+	}
+	if got, ok := c.restorePoint(files, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("Expected no backup before 2025, got %q", got.Key)
+	}
+}
+
+func TestValidateAsOf(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", AsOf: "2025-06-01 03:00"})
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	cfg.Newest = true
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected --as-of with --newest to be rejected")
+	}
+	cfg.Newest = false
+	cfg.AsOf = "tomorrow"
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected an invalid --as-of to be rejected")
+	}
+}
@@ -27,32 +27,68 @@ package pkg
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	goutils "github.com/jkaninda/go-utils"
 	"github.com/jkaninda/s3safe/utils"
 	"github.com/spf13/cobra"
 	"io"
+	"iter"
 	"log/slog"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // BackupManager handles backup operations
 type BackupManager struct {
-	config    *Config
-	s3Storage *S3Storage
+	config            *Config
+	destinations      []*destination
+	storageClassRules []StorageClassRule
+	result            BackupResult
+	maxErrors         errorThreshold
+	sample            *uploadSample
+	audit             *auditLog
+	plugins           *plugins
+	keys              *keyCipher
+	logger            *slog.Logger
+	reporter          Reporter
+	// run records the transfers of the run for --report-html
+	run *runRecorder
+	// source is the snapshot of the backed up directory during a --snapshot backup
+	source string
+	// started is the start of the run in the configured timezone, the Object Lock retention is derived from it
+	started time.Time
 }
 
 // RestoreManager handles restore operations
 type RestoreManager struct {
-	config    *Config
-	s3Storage *S3Storage
+	config  *Config
+	storage Storage
+	journal *restoreJournal
+	result  RestoreResult
+	// maxErrors aborts a restore with ignored errors once too many files failed
+	maxErrors errorThreshold
+	audit     *auditLog
+	keys      *keyCipher
+	// location is the restore path as configured, azblob:// URLs included
+	location string
+	logger   *slog.Logger
+	reporter Reporter
+	// run records the transfers of the run for --report-html
+	run *runRecorder
+	// flattened maps the file names restored with --flatten to their key
+	flattened map[string]string
 }
 
 // Backup is the cobra command handler for backup
@@ -61,7 +97,21 @@ func Backup(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	return bm.Backup()
+	introTo(bannerOutput(bm.config.Format))
+	result, err := bm.Backup(cmd.Context())
+	notifyStopping(fmt.Sprintf("Backup %s, %d files uploaded", outcome(err), result.Files))
+	summary := RunSummary{
+		Operation:  operationBackup,
+		Path:       bm.config.Path,
+		Dest:       bm.config.Dest,
+		Archive:    result.Archive,
+		Files:      result.Files,
+		Bytes:      result.Bytes,
+		Skipped:    result.Skipped,
+		Failed:     len(result.Failed),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	return errors.Join(err, writeRunSummary(os.Stdout, summary.withError(err), bm.config.Format))
 }
 
 // Restore is the cobra command handler for restore
@@ -70,123 +120,418 @@ func Restore(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
-	return rm.Restore()
+	introTo(bannerOutput(rm.config.Format))
+	result, err := rm.Restore(cmd.Context())
+	notifyStopping(fmt.Sprintf("Restore %s, %d files restored", outcome(err), result.Files))
+	// The listing of --list is the output
+	if rm.config.List {
+		return err
+	}
+	summary := RunSummary{
+		Operation:  operationRestore,
+		Path:       rm.location,
+		Dest:       rm.config.Dest,
+		Files:      result.Files,
+		Bytes:      result.Bytes,
+		Skipped:    result.Skipped,
+		Failed:     len(result.Failed),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	return errors.Join(err, writeRunSummary(os.Stdout, summary.withError(err), rm.config.Format))
 }
 
-// NewBackupManager creates a new BackupManager instance
+// NewBackupManager creates a new BackupManager instance from cobra command flags
 func NewBackupManager(cmd *cobra.Command) (*BackupManager, error) {
-	config := NewConfig(cmd)
-	if err := config.Validate(); err != nil {
+	return newBackupManager(cmd.Context(), NewConfig(cmd), managerOptions{reporter: newWatchdogReporter()})
+}
+
+func newBackupManager(ctx context.Context, config *Config, o managerOptions) (*BackupManager, error) {
+	config.logger = o.logger
+	if err := config.expandTemplates(); err != nil {
+		return nil, withExitCode(ExitConfig, err)
+	}
+	if err := config.validate(ctx, !o.skipConnectionCheck); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
+	if !o.skipConnectionCheck {
+		if err := config.checkRequirements(ctx); err != nil {
+			return nil, fmt.Errorf("config validation failed: %w", err)
+		}
+		config.ensureMPUCleanupRule(ctx)
+	}
 
-	s3Storage, err := config.NewS3Storage()
+	rules, err := ParseStorageClassRules(config.StorageClassRules)
+	if err != nil {
+		return nil, err
+	}
+	maxErrors, err := parseErrorThreshold(config.MaxErrors)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+		return nil, err
+	}
+	sample, err := parseUploadSample(config.VerifySample)
+	if err != nil {
+		return nil, err
 	}
 
+	destinations, err := newDestinations(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := newAuditLog(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := loadPlugins(config.PluginsDir)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := newKeyCipher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter, run := withRunRecorder(config, o.reporter)
 	return &BackupManager{
-		config:    config,
-		s3Storage: s3Storage,
+		config:            config,
+		destinations:      destinations,
+		storageClassRules: rules,
+		maxErrors:         maxErrors,
+		sample:            sample,
+		audit:             audit,
+		plugins:           plugins,
+		keys:              keys,
+		logger:            o.logger,
+		reporter:          reporter,
+		run:               run,
 	}, nil
 }
 
-// NewRestoreManager creates a new RestoreManager instance
+// NewRestoreManager creates a new RestoreManager instance from cobra command flags
 func NewRestoreManager(cmd *cobra.Command) (*RestoreManager, error) {
-	config := NewConfig(cmd)
-	if err := config.Validate(); err != nil {
+	return newRestoreManager(cmd.Context(), NewConfig(cmd), managerOptions{reporter: newWatchdogReporter()})
+}
+
+func newRestoreManager(ctx context.Context, config *Config, o managerOptions) (*RestoreManager, error) {
+	config.logger = o.logger
+	if err := config.expandTemplates(); err != nil {
+		return nil, withExitCode(ExitConfig, err)
+	}
+	if err := config.validate(ctx, !o.skipConnectionCheck); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
-	s3Storage, err := config.NewS3Storage()
+	maxErrors, err := parseErrorThreshold(config.MaxErrors)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := newAuditLog(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := newKeyCipher(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+		return nil, err
+	}
+	location := config.Path
+	var storage Storage
+	if isAzureRemote(config.Path) {
+		remote, err := parseAzureRemote(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		azureStorage, err := config.NewAzureStorage(remote)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure storage: %w", err)
+		}
+		storage = azureStorage
+		config.Path = remote.Prefix
+	} else {
+		s3Storage, err := config.NewS3Storage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+		}
+		storage = s3Storage
 	}
 
 	// Normalize path
-	if config.Path[0] == '/' {
-		config.Path = config.Path[1:]
-	}
+	config.Path = strings.TrimPrefix(filepath.ToSlash(config.Path), "/")
+	config.Path = normalizeUnicode(config.Path, config.NormalizeUnicode)
+	config.File = normalizeUnicode(config.File, config.NormalizeUnicode)
 
+	reporter, run := withRunRecorder(config, o.reporter)
 	return &RestoreManager{
 		config:    config,
-		s3Storage: s3Storage,
+		storage:   storage,
+		location:  location,
+		logger:    o.logger,
+		maxErrors: maxErrors,
+		audit:     audit,
+		keys:      keys,
+		reporter:  reporter,
+		run:       run,
 	}, nil
 }
 
-// Backup performs the backup operation
-func (bm *BackupManager) Backup() error {
-	intro()
-	slog.Info("Backing up data...")
+// Backup performs the backup operation, the result is returned with the error of a failed backup
+func (bm *BackupManager) Backup(ctx context.Context) (BackupResult, error) {
+	bm.result = BackupResult{}
+	bm.audit.reset()
+	bm.run.reset()
+	start := time.Now()
+	bm.started = bm.config.now()
+	if bm.config.ObjectLockMode != "" {
+		bm.log().Info("Uploads are locked with Object Lock", "mode", bm.config.ObjectLockMode, "until", bm.config.retainUntil(bm.started).Format(time.RFC3339))
+	}
+	err := bm.preScan(ctx)
+	if err == nil {
+		err = bm.backupSnapshot(ctx)
+	}
+	if reportErr := saveFailureReport(ctx, bm.config, newFailureReport(operationBackup, bm.config, bm.config.Path, bm.result.Failed)); reportErr != nil {
+		err = errors.Join(err, reportErr)
+	}
+	err = bm.writeAudit(ctx, operationBackup, start, err)
+	bm.result.Duration = time.Since(start)
+	err = saveHTMLReport(ctx, bm.config, htmlReport{
+		Operation: operationBackup,
+		Path:      bm.config.Path,
+		Archive:   bm.result.Archive,
+		Start:     start,
+		Duration:  bm.result.Duration,
+		Files:     bm.result.Files,
+		Bytes:     bm.result.Bytes,
+		Skipped:   bm.result.Skipped,
+		Failed:    bm.result.Failed,
+	}, bm.run, err)
+	bm.postRun(ctx, err)
+	return bm.result, err
+}
 
+func (bm *BackupManager) backup(ctx context.Context) error {
+	bm.log().Info("Backing up data...")
+
+	var err error
 	if bm.config.Compress {
-		return bm.backupWithCompression()
+		err = bm.backupWithCompression(ctx)
+	} else {
+		err = bm.backupWithoutCompression(ctx)
+		// Files uploaded by a backup with skipped files are verified too
+		if err == nil || ExitCode(err) == ExitPartial {
+			if sampleErr := bm.verifySample(ctx); sampleErr != nil {
+				err = errors.Join(sampleErr, err)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return withExitCode(ExitPartial, bm.destinationsError())
+}
+
+// Restore performs the restore operation, the result is returned with the error of a failed restore
+func (rm *RestoreManager) Restore(ctx context.Context) (RestoreResult, error) {
+	rm.result = RestoreResult{}
+	rm.audit.reset()
+	rm.run.reset()
+	start := time.Now()
+	err := rm.restore(ctx)
+	if err == nil {
+		err = rm.failedError()
+	}
+	if reportErr := saveFailureReport(ctx, rm.config, newFailureReport(operationRestore, rm.config, rm.location, rm.result.Failed)); reportErr != nil {
+		err = errors.Join(err, reportErr)
+	}
+	// Listing the files to restore is not an operation on the bucket
+	if !rm.config.List {
+		err = rm.writeAudit(ctx, operationRestore, start, err)
 	}
-	return bm.backupWithoutCompression()
+	rm.result.Duration = time.Since(start)
+	// A listing transfers nothing
+	if !rm.config.List {
+		err = saveHTMLReport(ctx, rm.config, htmlReport{
+			Operation: operationRestore,
+			Path:      rm.location,
+			Start:     start,
+			Duration:  rm.result.Duration,
+			Files:     rm.result.Files,
+			Bytes:     rm.result.Bytes,
+			Skipped:   rm.result.Skipped,
+			Failed:    rm.result.Failed,
+		}, rm.run, err)
+	}
+	return rm.result, err
 }
 
-// Restore performs the restore operation
-func (rm *RestoreManager) Restore() error {
-	intro()
-	slog.Info("Restoring data...")
+// checkErrors aborts the restore once the failed files exceed --max-errors
+func (rm *RestoreManager) checkErrors(final bool) error {
+	return rm.maxErrors.check(len(rm.result.Failed), rm.result.Files+len(rm.result.Failed), final)
+}
 
-	if err := rm.ensureDestinationExists(); err != nil {
-		return err
+// failedError reports the files whose errors were ignored
+func (rm *RestoreManager) failedError() error {
+	if len(rm.result.Failed) == 0 {
+		return nil
+	}
+	return withExitCode(ExitPartial, fmt.Errorf("%d files failed to restore, their errors were ignored", len(rm.result.Failed)))
+}
+
+func (rm *RestoreManager) restore(ctx context.Context) error {
+	rm.log().Info("Restoring data...")
+
+	if !rm.config.List {
+		if err := rm.ensureDestinationExists(); err != nil {
+			return err
+		}
+	}
+
+	if rm.config.Latest {
+		if err := rm.resolveLatest(ctx); err != nil {
+			return err
+		}
 	}
 
 	if rm.config.File != "" {
-		return rm.restoreSingleFile()
+		return rm.restoreSingleFile(ctx)
 	}
-	return rm.restoreMultipleFiles()
+	return rm.restoreMultipleFiles(ctx)
 }
 
-func (bm *BackupManager) backupWithCompression() error {
-	outputFile := bm.generateOutputFilename()
+func (bm *BackupManager) backupWithCompression(ctx context.Context) error {
+	outputFile, err := bm.generateOutputFilename()
+	if err != nil {
+		return err
+	}
 
-	if err := compressDirectory(bm.config.Path, outputFile); err != nil {
+	var skip skipFunc
+	if bm.config.OnUnreadable != unreadableFail {
+		skip = func(key string, err error, archived int) error {
+			_ = bm.unreadable(key, err)
+			return bm.maxErrors.check(len(bm.result.Failed), archived+len(bm.result.Failed), false)
+		}
+	}
+	archived, err := compressDirectory(bm.sourcePath(), outputFile, archiveOptions{
+		skip:           skip,
+		exclude:        bm.excluded(),
+		noRecompress:   bm.config.NoRecompress,
+		includeSpecial: bm.config.IncludeSpecial,
+		selinux:        bm.config.SELinux,
+	})
+	if err != nil {
 		return fmt.Errorf("compression failed: %w", err)
 	}
-	slog.Info("Compressed directory", "path", bm.config.Path, "dest", outputFile)
+	if err := bm.maxErrors.check(len(bm.result.Failed), archived+len(bm.result.Failed), true); err != nil {
+		return err
+	}
+	bm.log().Info("Compressed directory", "path", bm.sourcePath(), "dest", outputFile)
 
-	targetPath := filepath.Join(bm.config.Dest, filepath.Base(outputFile))
-	if err := bm.s3Storage.Upload(outputFile, targetPath); err != nil {
+	if err := bm.upload(ctx, outputFile, filepath.Base(outputFile)); err != nil {
 		return fmt.Errorf("upload failed: %w", err)
 	}
+	bm.result.Archive = filepath.Base(outputFile)
+	if err := bm.writeLatest(ctx, outputFile); err != nil {
+		return err
+	}
+	bm.pruneLocalArchives(outputFile)
 
-	slog.Info("Backup completed successfully", "path", bm.config.Path, "dest", bm.config.Dest)
-	return nil
+	bm.log().Info("Backup completed successfully", "path", bm.config.Path, "dest", bm.config.Dest)
+	return bm.skippedError()
 }
 
-func (bm *BackupManager) backupWithoutCompression() error {
+func (bm *BackupManager) backupWithoutCompression(ctx context.Context) error {
 	if bm.config.File != "" {
-		return bm.uploadSingleFile()
+		return bm.uploadSingleFile(ctx)
 	}
-	return bm.uploadMultipleFiles()
+	return bm.uploadMultipleFiles(ctx)
 }
 
-func (bm *BackupManager) uploadSingleFile() error {
-	sourcePath := filepath.Join(bm.config.Path, bm.config.File)
-	targetPath := filepath.Join(bm.config.Dest, bm.config.File)
-	return bm.s3Storage.Upload(sourcePath, targetPath)
+func (bm *BackupManager) uploadSingleFile(ctx context.Context) error {
+	sourcePath := filepath.Join(bm.sourcePath(), bm.config.File)
+	return bm.upload(ctx, sourcePath, bm.config.File)
 }
 
-func (bm *BackupManager) uploadMultipleFiles() error {
-	files, err := ListFiles(bm.config.Path, bm.config.Recursive)
+func (bm *BackupManager) uploadMultipleFiles(ctx context.Context) error {
+	var checkpoint *backupCheckpoint
+	if bm.config.Checkpoint != "" {
+		var err error
+		checkpoint, err = openBackupCheckpoint(bm.config.Checkpoint, bm.config, bm.config.Restart, bm.log())
+		if err != nil {
+			return err
+		}
+		checkpoint.restore(&bm.result)
+	}
+	err := bm.uploadFiles(ctx, checkpoint)
+	if closeErr := checkpoint.close(err == nil, bm.log()); closeErr != nil {
+		bm.log().Warn("Unable to close backup checkpoint", "error", closeErr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		return err
 	}
+	if len(bm.result.Rejected) > 0 {
+		rejected := make([]string, 0, len(bm.result.Rejected))
+		for _, key := range bm.result.Rejected {
+			rejected = append(rejected, strconv.Quote(key))
+		}
+		return withExitCode(ExitPartial, fmt.Errorf("%d files were not uploaded, their keys contain control characters, '#' or '?': %s, use --unsafe-keys encode to upload them",
+			len(rejected), strings.Join(rejected, ", ")))
+	}
+	return bm.skippedError()
+}
 
-	for _, file := range files {
-		if err := bm.processFileForUpload(file); err != nil {
+// uploadFiles uploads the files following the checkpoint while the directory is walked
+func (bm *BackupManager) uploadFiles(ctx context.Context, checkpoint *backupCheckpoint) error {
+	for file, err := range walkFilesAfter(bm.sourcePath(), bm.config.Recursive, checkpoint.after(), bm.excluded()) {
+		if err != nil && bm.config.OnUnreadable != unreadableFail {
+			_ = bm.unreadable(file.Key, err)
+			if err := bm.checkErrors(false); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		err = bm.processFileForUpload(ctx, file)
+		if errors.Is(err, errUnsafeKey) {
+			bm.log().Warn("Rejected file, its key contains unsafe characters", "file", file.Key)
+			bm.result.Rejected = append(bm.result.Rejected, file.Key)
+			err = nil
+		}
+		if err != nil {
 			return err
 		}
+		if err := bm.checkErrors(false); err != nil {
+			return err
+		}
+		// Directories are not recorded, the walk would skip their files when resuming
+		if !file.IsDir {
+			if err := checkpoint.record(file.Key, &bm.result); err != nil {
+				bm.log().Warn("Unable to write backup checkpoint", "error", err)
+			}
+		}
 	}
-	return nil
+	return bm.checkErrors(true)
+}
+
+// checkErrors aborts the backup once the skipped files exceed --max-errors
+func (bm *BackupManager) checkErrors(final bool) error {
+	return bm.maxErrors.check(len(bm.result.Failed), bm.result.Files+len(bm.result.Failed), final)
+}
+
+// skippedError reports the files skipped because they could not be read
+func (bm *BackupManager) skippedError() error {
+	if len(bm.result.Failed) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(bm.result.Failed))
+	for _, failed := range bm.result.Failed {
+		keys = append(keys, strconv.Quote(failed.Key))
+	}
+	return withExitCode(ExitPartial, fmt.Errorf("%d files were skipped because of errors: %s", len(keys), strings.Join(keys, ", ")))
 }
 
-func (bm *BackupManager) processFileForUpload(file Item) error {
+func (bm *BackupManager) processFileForUpload(ctx context.Context, file Item) error {
 	if slices.Contains(bm.config.Exclude, filepath.Base(file.Key)) {
-		slog.Warn("Ignoring file", "file", file.Key)
+		bm.log().Warn("Ignoring file", "file", file.Key)
+		bm.result.Skipped++
 		return nil
 	}
 
@@ -194,19 +539,112 @@ func (bm *BackupManager) processFileForUpload(file Item) error {
 		return nil
 	}
 
-	sourcePath := filepath.Join(bm.config.Path, file.Key)
-	targetPath := filepath.Join(bm.config.Dest, file.Key)
-	return bm.s3Storage.Upload(sourcePath, targetPath)
+	sourcePath := filepath.Join(bm.sourcePath(), file.Key)
+	// Special files have no content to upload, opening a FIFO would block
+	if info, err := os.Lstat(sourcePath); err == nil && isSpecialFile(info.Mode()) {
+		bm.log().Warn("Skipping special file", "file", file.Key, "type", specialFileType(info.Mode()))
+		bm.result.Skipped++
+		return nil
+	}
+	if bm.config.OnUnreadable != unreadableFail {
+		// An unreadable file would otherwise fail its destinations for the rest of the backup
+		if err := checkReadable(sourcePath); err != nil {
+			return bm.unreadable(file.Key, err)
+		}
+	}
+	return bm.upload(ctx, sourcePath, file.Key)
 }
 
-func (bm *BackupManager) generateOutputFilename() string {
+// excluded returns the filter of the files and directories left out by --max-depth, --one-file-system, --exclude-caches,
+// --exclude-common-caches and --respect-gitignore, nil when none is set. Excluded entries are counted as skipped,
+// except the directories below --max-depth.
+func (bm *BackupManager) excluded() excludeFunc {
+	if bm.config.MaxDepth == 0 && !bm.config.OneFileSystem && !bm.config.ExcludeCaches && !bm.config.ExcludeCommonCaches &&
+		!bm.config.RespectGitignore {
+		return nil
+	}
+	var ignore *ignoreMatcher
+	if bm.config.RespectGitignore {
+		ignore = newIgnoreMatcher(bm.sourcePath(), gitignoreFile)
+	}
+	otherDevice := func(os.FileInfo) bool { return false }
+	if bm.config.OneFileSystem {
+		otherDevice = bm.otherFileSystem()
+	}
+	root := bm.sourcePath()
+	return func(path string, info os.FileInfo) bool {
+		isDir := info.IsDir()
+		// Directories below --max-depth are left out like those of a walk that is not recursive
+		if isDir && bm.config.beyondMaxDepth(root, path) {
+			return true
+		}
+		switch {
+		case isDir && otherDevice(info):
+			bm.log().Info("Not crossing file system boundary", "dir", path)
+		case isDir && bm.config.isExcludedCacheDir(path):
+			bm.log().Info("Excluding cache directory", "dir", path)
+		case ignore != nil && ignore.match(path, isDir):
+			bm.log().Debug("Excluding ignored file", "path", path)
+		default:
+			return false
+		}
+		bm.result.Skipped++
+		return true
+	}
+}
+
+// uploadOptions returns the object settings applied to the uploaded file
+func (bm *BackupManager) uploadOptions(path string) UploadOptions {
+	opts := UploadOptions{
+		StorageClass: storageClassFor(bm.storageClassRules, bm.config.StorageClass, path),
+		ContentType:  bm.config.ContentType,
+		ACL:          bm.config.ACL,
+		LegalHold:    bm.config.LegalHold,
+		NoClobber:    bm.config.NoClobberRemote,
+		// Empty applies the default checksum of each storage
+		ChecksumAlgorithm: bm.config.Checksum,
+	}
+	if info, err := os.Stat(path); err == nil {
+		// The mode and owner of a temporary archive are meaningless
+		opts.Metadata = fileMetadata(info, !bm.config.Compress)
+	}
+	// Archives carry the context of each file in their entries
+	if bm.config.SELinux && !bm.config.Compress && opts.Metadata != nil {
+		if label, err := fileLabel(path); err != nil {
+			bm.log().Warn("Unable to read SELinux context", "file", path, "error", err)
+		} else if label != "" {
+			opts.Metadata[selinuxMetadataKey] = label
+		}
+	}
+	if bm.config.ObjectLockMode != "" {
+		opts.ObjectLockMode = bm.config.ObjectLockMode
+		started := bm.started
+		if started.IsZero() {
+			started = bm.config.now()
+		}
+		opts.RetainUntil = bm.config.retainUntil(started)
+	}
+	return opts
+}
+
+func (bm *BackupManager) generateOutputFilename() (string, error) {
 	baseName := filepath.Base(bm.config.Path)
-	if !bm.config.Timestamp {
-		return filepath.Join(bm.config.Path, fmt.Sprintf("%s.tar.gz", baseName))
+	now := bm.config.now()
+	timestamp := now.Format(bm.config.TimestampFormat)
+
+	if bm.config.NameTemplate != "" {
+		path := newPathTemplate(bm.config.Job, now)
+		name, err := NameTemplate{PathTemplate: path, Base: baseName, Host: path.Hostname, Timestamp: timestamp}.expand(bm.config.NameTemplate)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(bm.config.Path, name), nil
 	}
 
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	return filepath.Join(bm.config.Path, fmt.Sprintf("%s-%s.tar.gz", baseName, timestamp))
+	if !bm.config.Timestamp {
+		return filepath.Join(bm.config.Path, fmt.Sprintf("%s.tar.gz", baseName)), nil
+	}
+	return filepath.Join(bm.config.Path, fmt.Sprintf("%s-%s.tar.gz", baseName, timestamp)), nil
 }
 func (rm *RestoreManager) ensureDestinationExists() error {
 	if _, err := os.Stat(rm.config.Dest); os.IsNotExist(err) {
@@ -217,92 +655,283 @@ func (rm *RestoreManager) ensureDestinationExists() error {
 	return nil
 }
 
-func (rm *RestoreManager) restoreSingleFile() error {
-	sourcePath := filepath.Join(rm.config.Path, rm.config.File)
-	destPath := filepath.Join(rm.config.Dest, rm.config.File)
+func (rm *RestoreManager) restoreSingleFile(ctx context.Context) error {
+	sourcePath := objectKey(rm.config.Path, rm.objectName(rm.config.File))
+	if rm.config.List {
+		return rm.printRestoreList(os.Stdout, []Item{{Key: sourcePath}}, func(Item) (string, error) {
+			key, _ := rm.restoredKey(ctx, sourcePath)
+			return rm.localPath(key)
+		})
+	}
+	key, gzipped := rm.restoredKey(ctx, sourcePath)
+	destPath, err := rm.localPath(key)
+	if err != nil {
+		return err
+	}
+
+	if !rm.config.SkipSpaceCheck && rm.config.VersionID == "" {
+		if err := rm.checkFileSpace(ctx, sourcePath); err != nil {
+			return err
+		}
+	}
 
-	if err := rm.s3Storage.Download(sourcePath, destPath, rm.config.Force); err != nil {
+	opts := DownloadOptions{Force: rm.config.Force, VersionID: rm.config.VersionID, SkipVerify: rm.config.SkipVerify, PreservePermissions: rm.config.PreservePermissions,
+		SELinux: rm.config.SELinux}
+	rm.report().FileStarted(sourcePath, -1)
+	err = rm.download(ctx, sourcePath, destPath, opts, gzipped)
+	skipped := errors.Is(err, errFileExists)
+	if skipped {
+		err = nil
+	}
+	rm.report().FileCompleted(sourcePath, -1, err)
+	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
+	if skipped {
+		rm.skipExisting(destPath)
+		return nil
+	}
+	rm.recordDownload(sourcePath, destPath)
 
-	if rm.config.Decompress && isCompressed(destPath) {
-		if err := decompressDirectory(destPath, rm.config.Dest); err != nil {
+	if rm.config.Decompress && isArchive(destPath) {
+		if err := decompressDirectory(destPath, rm.config.Dest, rm.config.SELinux); err != nil {
 			return fmt.Errorf("decompression failed: %w", err)
 		}
-		slog.Info("Decompressed file", "file", rm.config.File)
+		rm.log().Info("Decompressed file", "file", rm.config.File)
 	}
 
-	slog.Info("Restore completed successfully", "file", rm.config.File)
+	rm.log().Info("Restore completed successfully", "file", rm.config.File)
 	return nil
 }
 
-func (rm *RestoreManager) restoreMultipleFiles() error {
-	files, err := rm.s3Storage.List(rm.config.Path, rm.config.Recursive)
+// checkFileSpace checks the free disk space for a single file, its size is read from the object properties
+func (rm *RestoreManager) checkFileSpace(ctx context.Context, key string) error {
+	sizer, ok := rm.storage.(ObjectSizer)
+	if !ok {
+		return nil
+	}
+	size, err := sizer.Size(ctx, key)
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		rm.log().Warn("Unable to check free disk space", "file", key, "error", err)
+		return nil
 	}
+	return checkDiskSpace(rm.config.Dest, []Item{{Key: key, Size: size}}, nil, rm.config.Decompress)
+}
 
-	for _, file := range files {
-		if err := rm.processFileForDownload(file); err != nil {
-			if rm.config.IgnoreErrors {
-				slog.Warn("Ignoring error", "error", err)
-				continue
+// checkTotalSpace lists the files once, keeping only their sizes, and checks the free disk space
+// for all of them before any download. Files already restored by an interrupted run are not counted.
+func (rm *RestoreManager) checkTotalSpace(ctx context.Context) error {
+	var total uint64
+	for file, err := range rm.files(ctx) {
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if rm.config.Match != "" && !matchItem(file, rm.config.Match) || rm.journal.completed(file) {
+			continue
+		}
+		total += fileSpace(file, rm.config.Exclude, rm.config.Decompress)
+	}
+	return checkRequiredSpace(rm.config.Dest, withHeadroom(total))
+}
+
+func (rm *RestoreManager) restoreMultipleFiles(ctx context.Context) error {
+	files := rm.files(ctx)
+	// Files are downloaded as they are listed, the free space for all of them is checked by a
+	// first listing pass, and again for each file in case the filesystem fills up meanwhile
+	checkEachFile := !rm.config.SkipSpaceCheck
+	// --newest, --as-of and --list need the complete listing
+	if rm.config.Newest || rm.config.AsOf != "" || rm.config.List {
+		items, err := collectItems(files)
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if rm.config.Match != "" {
+			items = matchItems(items, rm.config.Match)
+		}
+		if rm.config.Newest {
+			newest, ok := newestItem(items)
+			if !ok {
+				return fmt.Errorf("no backup found in %q matching %q", rm.config.Path, rm.config.Match)
 			}
+			rm.log().Info("Restoring newest backup", "file", newest.Key, "last_modified", newest.LastModified)
+			items = []Item{newest}
+		}
+		if rm.config.AsOf != "" {
+			asOf, _ := rm.config.parseAsOf(rm.config.AsOf)
+			point, ok := rm.config.restorePoint(items, asOf)
+			if !ok {
+				return fmt.Errorf("no backup found in %q at or before %s", rm.config.Path, asOf.Format(time.RFC3339))
+			}
+			rm.log().Info("Restoring backup as of", "as_of", asOf, "file", point.Key, "time", rm.config.backupTime(point))
+			items = []Item{point}
+		}
+		if rm.config.List {
+			return rm.printRestoreList(os.Stdout, items, func(file Item) (string, error) {
+				key, _ := rm.restoredKey(ctx, file.Key)
+				return rm.localPath(key)
+			})
+		}
+		if !rm.config.SkipSpaceCheck {
+			if err := checkDiskSpace(rm.config.Dest, items, rm.config.Exclude, rm.config.Decompress); err != nil {
+				return err
+			}
+		}
+		checkEachFile = false
+		files = itemSeq(items)
+	}
+
+	journal, err := openRestoreJournal(rm.config.Dest, rm.config.Restart)
+	if err != nil {
+		return err
+	}
+	rm.journal = journal
+
+	if checkEachFile {
+		if err := rm.checkTotalSpace(ctx); err != nil {
+			_ = journal.close(false)
 			return err
 		}
 	}
 
-	slog.Info("Restore completed successfully", "path", rm.config.Path, "dest", rm.config.Dest)
+	failed := false
+	for file, err := range files {
+		if err != nil {
+			_ = journal.close(false)
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if rm.config.Match != "" && !matchItem(file, rm.config.Match) {
+			continue
+		}
+		if checkEachFile && !journal.completed(file) {
+			if err := checkDiskSpace(rm.config.Dest, []Item{file}, rm.config.Exclude, rm.config.Decompress); err != nil {
+				_ = journal.close(false)
+				return err
+			}
+		}
+		if err := rm.processFileForDownload(ctx, file); err != nil {
+			if !rm.config.IgnoreErrors {
+				_ = journal.close(false)
+				return err
+			}
+			rm.log().Warn("Ignoring error", "error", err)
+			rm.result.Failed = append(rm.result.Failed, FileError{Key: file.Key, Err: err})
+			failed = true
+		}
+		if err := rm.checkErrors(false); err != nil {
+			_ = journal.close(false)
+			return err
+		}
+	}
+	if err := rm.checkErrors(true); err != nil {
+		_ = journal.close(false)
+		return err
+	}
+	if err := journal.close(!failed); err != nil {
+		rm.log().Warn("Unable to close restore journal", "error", err)
+	}
+
+	rm.log().Info("Restore completed successfully", "path", rm.config.Path, "dest", rm.config.Dest)
 	return nil
 }
 
-func (rm *RestoreManager) processFileForDownload(file Item) error {
+func (rm *RestoreManager) processFileForDownload(ctx context.Context, file Item) error {
 	if slices.Contains(rm.config.Exclude, filepath.Base(file.Key)) {
-		slog.Warn("Ignoring file", "file", file.Key)
+		rm.log().Warn("Ignoring file", "file", file.Key)
+		rm.result.Skipped++
 		return nil
 	}
 
 	if file.IsDir {
 		return nil
 	}
+	if rm.journal != nil && rm.journal.completed(file) {
+		rm.log().Debug("Already restored, skipping", "file", file.Key)
+		rm.result.Skipped++
+		return nil
+	}
 
-	destPath := filepath.Join(rm.config.Dest, removePrefix(file.Key, rm.config.Path))
-	if err := rm.s3Storage.Download(file.Key, destPath, rm.config.Force); err != nil {
+	key, gzipped := rm.restoredKey(ctx, file.Key)
+	destPath, err := rm.localPath(key)
+	if errors.Is(err, errSkippedName) {
+		rm.log().Warn("Skipping file", "file", file.Key, "error", err)
+		rm.result.Skipped++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	opts := DownloadOptions{Force: rm.config.Force, SkipVerify: rm.config.SkipVerify, PreservePermissions: rm.config.PreservePermissions,
+		SELinux: rm.config.SELinux}
+	rm.report().FileStarted(file.Key, file.Size)
+	err = rm.download(ctx, file.Key, destPath, opts, gzipped)
+	skipped := errors.Is(err, errFileExists)
+	if skipped {
+		err = nil
+	}
+	rm.report().FileCompleted(file.Key, file.Size, err)
+	if err != nil {
 		return fmt.Errorf("failed to download file %s: %w", file.Key, err)
 	}
+	if skipped {
+		rm.skipExisting(destPath)
+		return nil
+	}
+	rm.recordDownload(file.Key, destPath)
 
-	if rm.config.Decompress && isCompressed(destPath) {
-		if err := decompressDirectory(destPath, rm.config.Dest); err != nil {
+	if rm.config.Decompress && isArchive(destPath) {
+		if err := decompressDirectory(destPath, rm.config.Dest, rm.config.SELinux); err != nil {
 			if rm.config.IgnoreErrors {
-				slog.Warn("Ignoring decompression error", "error", err)
+				rm.log().Warn("Ignoring decompression error", "error", err)
+				rm.result.Failed = append(rm.result.Failed, FileError{Key: file.Key, Err: err})
 				return nil
 			}
 			return fmt.Errorf("failed to decompress file %s: %w", file.Key, err)
 		}
-		slog.Info("Decompressed file", "file", file.Key)
+		rm.log().Info("Decompressed file", "file", file.Key)
+	}
+
+	if rm.journal != nil {
+		if err := rm.journal.record(file); err != nil {
+			return err
+		}
 	}
 
-	slog.Info("Downloaded file", "file", file.Key)
+	rm.log().Info("Downloaded file", "file", file.Key)
 	return nil
 }
 
+// skipExisting counts a file left untouched because it already exists, it is neither restored nor audited
+func (rm *RestoreManager) skipExisting(path string) {
+	rm.log().Warn("File already exists, use --force to overwrite, skipping download", "file", path)
+	rm.result.Skipped++
+}
+
+// recordDownload adds a downloaded object to the restore result
+func (rm *RestoreManager) recordDownload(key, path string) {
+	rm.result.Files++
+	rm.audit.add(key)
+	if info, err := os.Stat(path); err == nil {
+		rm.result.Bytes += info.Size()
+	}
+}
+
 // Validate is the cobra command handler for config validation
 func Validate(cmd *cobra.Command) error {
 	config := NewConfig(cmd)
-	if err := config.Validate(); err != nil {
+	if err := config.Validate(cmd.Context()); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 	fmt.Println("Config validated successfully")
 	return nil
 }
-func (s S3Storage) Upload(path string, target string) error {
+func (s S3Storage) Upload(ctx context.Context, path string, target string, opts UploadOptions) error {
 
 	// Check if file exists
 	if !goutils.FileExists(path) {
 		return fmt.Errorf("file %s does not exist", path)
 
 	}
-	slog.Info("Uploading file", "file", path, "size", utils.FileSize(path), "target", target)
+	s.log().Info("Uploading file", "file", path, "size", utils.FileSize(path), "target", target, "storageClass", opts.StorageClass)
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("upload error: %w", err)
@@ -310,191 +939,466 @@ func (s S3Storage) Upload(path string, target string) error {
 	defer func(file *os.File) {
 		err := file.Close()
 		if err != nil {
-			slog.Error("error closing file,", "error", err)
+			s.log().Error("error closing file,", "error", err)
 		}
 	}(file)
 
-	uploader := s3manager.NewUploader(s.session)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(target),
-		Body:   file,
-	})
+	contentType := opts.ContentType
+	if contentType == "" {
+		if contentType, err = detectContentType(file); err != nil {
+			return fmt.Errorf("upload error: %w", err)
+		}
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(target),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = opts.Metadata
+	}
+	if opts.StorageClass != "" {
+		input.StorageClass = types.StorageClass(opts.StorageClass)
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	if opts.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(opts.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = aws.Time(opts.RetainUntil)
+	}
+	if opts.LegalHold {
+		input.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+	checksum := opts.ChecksumAlgorithm
+	if checksum == "" {
+		checksum = s.checksum
+	}
+	if checksum != "" && checksum != checksumNone {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(checksum)
+	} else if opts.ObjectLockMode != "" || opts.LegalHold {
+		// Object Lock requests must carry an integrity checksum
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+	}
+	if opts.NoClobber {
+		// Multipart uploads carry the condition on CompleteMultipartUpload
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("upload error: %w", err)
+	}
+	uploader := manager.NewUploader(s.client, s.uploaderOptions(info.Size()))
+	_, err = uploader.Upload(ctx, input)
 
+	if err != nil && opts.NoClobber && isConditionFailed(err) {
+		return fmt.Errorf("unable to upload %q to %q: %w", path, target, errObjectExists)
+	}
 	if err != nil {
 		return fmt.Errorf("unable to upload %q to %q: %w", path, s.bucket, err)
 	}
-	slog.Info("Upload completed successfully", "file", path, "target", target)
+	s.log().Info("Upload completed successfully", "file", path, "target", target)
 	return nil
 }
 
-func (s S3Storage) Download(path string, dest string, force bool) error {
-	// Check if the destination path exists
-	destPath := filepath.Dir(dest)
-	if _, err := os.Stat(destPath); os.IsNotExist(err) {
-		err := os.MkdirAll(destPath, os.ModePerm)
+func (s S3Storage) Download(ctx context.Context, path string, dest string, opts DownloadOptions) error {
+	file, err := createDownloadFile(dest, opts.Force)
+	if err != nil {
+		return err
+	}
+	// An incomplete download never replaces dest
+	defer file.discard()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	}
+	if opts.VersionID != "" {
+		input.VersionId = aws.String(opts.VersionID)
+	}
+
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       input.Bucket,
+		Key:          input.Key,
+		VersionId:    input.VersionId,
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to download %q from %q: %w", path, s.bucket, err)
+	}
+	digest := headDigest(head)
+	// Download the version that was inspected
+	input.IfMatch = head.ETag
+	downloader := manager.NewDownloader(s.client, s.downloaderOptions(aws.ToInt64(head.ContentLength)))
+
+	// A download that does not match the object digest is retried once
+	for attempt := 1; ; attempt++ {
+		_, err = downloader.Download(ctx, file, input)
 		if err != nil {
-			return fmt.Errorf("failed to create destination directory: %w", err)
+			var archived *types.InvalidObjectState
+			if errors.As(err, &archived) {
+				return fmt.Errorf("object %q is archived in %s, run \"s3safe thaw --file %s\" and wait for it to be restored: %w", path, archived.StorageClass, path, err)
+			}
+			return fmt.Errorf("unable to download %q from %q: %w", path, s.bucket, err)
 		}
-	}
-	// Check if the file already exists
-	if !force {
-		if _, err := os.Stat(dest); err == nil {
-			slog.Warn("File already exists, use --force to overwrite, skipping download", "file", dest)
+		if !opts.SkipVerify {
+			err = verifyFile(file.File, digest)
+		}
+		if opts.SkipVerify || err == nil {
+			if err := file.commit(); err != nil {
+				return fmt.Errorf("download error: %w", err)
+			}
+			applyMetadata(dest, head.Metadata, opts)
 			return nil
 		}
+		if attempt == 2 {
+			return fmt.Errorf("downloaded file %q is corrupted: %w", dest, err)
+		}
+		s.log().Warn("Downloaded file does not match the object, downloading again", "file", path, "error", err)
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("download error: %w", err)
+		}
 	}
-	file, err := os.Create(dest)
+}
+
+// Open streams the content of an object with the digest it is verified against
+func (s S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, objectDigest, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
 	if err != nil {
-		return fmt.Errorf("download error: %w", err)
+		return nil, objectDigest{}, fmt.Errorf("unable to read %q from %q: %w", key, s.bucket, err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			slog.Error("error closing file", "error", err)
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		IfMatch: head.ETag,
+	})
+	if err != nil {
+		var archived *types.InvalidObjectState
+		if errors.As(err, &archived) {
+			return nil, objectDigest{}, fmt.Errorf("object %q is archived in %s, run \"s3safe thaw --file %s\" and wait for it to be restored: %w", key, archived.StorageClass, key, err)
 		}
-	}(file)
+		return nil, objectDigest{}, fmt.Errorf("unable to read %q from %q: %w", key, s.bucket, err)
+	}
+	return output.Body, headDigest(head), nil
+}
 
-	downloader := s3manager.NewDownloader(s.session)
+// VerifyUpload compares the size and the checksums computed by S3 for the object with the local file,
+// the composite checksum of multipart uploads included
+func (s S3Storage) VerifyUpload(ctx context.Context, path string, target string) error {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(target),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to verify %q: %w", target, err)
+	}
+	digest := storedDigest(head)
+	if checksum, parts, ok := compositeChecksum(head); ok && digest.MD5 == "" && digest.SHA256 == "" {
+		err = verifyCompositeChecksum(path, digest.Size, checksum, parts, s.uploadSettings(digest.Size).partSize)
+	} else {
+		err = verifyLocalFile(path, digest)
+	}
+	if err != nil {
+		return fmt.Errorf("verification of %q failed: %w", target, err)
+	}
+	return nil
+}
 
-	_, err = downloader.Download(file, &s3.GetObjectInput{
+// Size returns the size of an object
+func (s S3Storage) Size(ctx context.Context, key string) (int64, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(path),
+		Key:    aws.String(key),
 	})
-
 	if err != nil {
-		return fmt.Errorf("unable to download %q from %q: %w", path, s.bucket, err)
+		return 0, err
 	}
+	return aws.ToInt64(head.ContentLength), nil
+}
 
-	return nil
+// Metadata returns the user metadata of an object
+func (s S3Storage) Metadata(ctx context.Context, key string) (map[string]string, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read metadata of %q: %w", key, err)
+	}
+	return head.Metadata, nil
 }
 
-func (s S3Storage) List(path string, recursive bool) ([]Item, error) {
-	svc := s3.New(s.session)
-	files := make([]Item, 0)
+func (s S3Storage) List(ctx context.Context, path string, recursive bool) ([]Item, error) {
+	return collectItems(s.Objects(ctx, path, recursive))
+}
 
+// Objects iterates over the objects under path one page at a time
+func (s S3Storage) Objects(ctx context.Context, path string, recursive bool) iter.Seq2[Item, error] {
 	// Ensure the path ends with a slash for proper folder listing
 	if path != "" && !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
 
-	var contToken *string
-	var delimiter *string
+	if recursive && s.listShards > 1 {
+		return s.shardedObjects(ctx, path)
+	}
 
-	// Only use delimiter for non-recursive listing
+	// Keys are URL encoded so control characters survive the XML response
+	input := &s3.ListObjectsV2Input{
+		Bucket:       aws.String(s.bucket),
+		Prefix:       aws.String(path),
+		EncodingType: types.EncodingTypeUrl,
+	}
+	// Without a delimiter a single pass returns every key under the prefix
 	if !recursive {
-		delimiter = aws.String("/")
+		input.Delimiter = aws.String("/")
 	}
+	return s.listPages(ctx, input, path)
+}
 
-	for {
-		input := &s3.ListObjectsV2Input{
-			Bucket:            aws.String(s.bucket),
-			Prefix:            aws.String(path),
-			ContinuationToken: contToken,
-		}
-
-		if delimiter != nil {
-			input.Delimiter = delimiter
-		}
-
-		resp, err := svc.ListObjectsV2(input)
-		if err != nil {
-			return files, fmt.Errorf("could not list items in S3 bucket %s: %w", s.bucket, err)
-		}
+// listedItem returns the item of a listed object
+func listedItem(encoding types.EncodingType, object types.Object) Item {
+	key := decodeListKey(encoding, aws.ToString(object.Key))
+	return Item{
+		Key:          key,
+		LastModified: aws.ToTime(object.LastModified),
+		IsDir:        aws.ToInt64(object.Size) == 0 && strings.HasSuffix(key, "/"),
+		StorageClass: string(object.StorageClass),
+		Size:         aws.ToInt64(object.Size),
+		ETag:         strings.Trim(aws.ToString(object.ETag), `"`),
+	}
+}
 
-		// Process actual files
-		for _, item := range resp.Contents {
-			// Skip the directory marker itself (the path with trailing slash)
-			if *item.Key == path {
-				continue
+// listPages iterates over the items of a listing, the directory marker of path is skipped
+func (s S3Storage) listPages(ctx context.Context, input *s3.ListObjectsV2Input, path string) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		paginator := s3.NewListObjectsV2Paginator(s.client, input)
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				yield(Item{}, fmt.Errorf("could not list items in S3 bucket %s: %w", s.bucket, err))
+				return
 			}
 
-			file := Item{
-				Key:          *item.Key,
-				LastModified: *item.LastModified,
-				IsDir:        *item.Size == 0 && strings.HasSuffix(*item.Key, "/"),
-			}
+			// Process actual files
+			for _, item := range resp.Contents {
+				file := listedItem(resp.EncodingType, item)
+				// Skip the directory marker itself (the path with trailing slash)
+				if file.Key == path {
+					continue
+				}
 
-			files = append(files, file)
-		}
+				if !yield(file, nil) {
+					return
+				}
+			}
 
-		// Only process common prefixes (folders) in non-recursive mode
-		if !recursive {
+			// Common prefixes (folders) are only returned in non-recursive mode
 			for _, prefix := range resp.CommonPrefixes {
-				files = append(files, Item{
-					Key:          *prefix.Prefix,
+				dir := Item{
+					Key:          decodeListKey(resp.EncodingType, aws.ToString(prefix.Prefix)),
 					LastModified: time.Time{},
 					IsDir:        true,
-				})
+				}
+				if !yield(dir, nil) {
+					return
+				}
 			}
 		}
+	}
+}
 
-		if !*resp.IsTruncated {
-			break
+// matchItems returns the files whose base name matches the pattern
+func matchItems(files []Item, pattern string) []Item {
+	var matched []Item
+	for _, file := range files {
+		if matchItem(file, pattern) {
+			matched = append(matched, file)
 		}
-
-		contToken = resp.NextContinuationToken
 	}
+	return matched
+}
 
-	// If recursive and we found folders (items ending with /), list them too
-	if recursive {
-		var subDirs []Item
-		for _, file := range files {
-			if file.IsDir {
-				subFiles, err := s.List(file.Key, true)
-				if err != nil {
-					return files, err
-				}
-				subDirs = append(subDirs, subFiles...)
-			}
+// matchItem reports whether the name of a file matches the pattern
+func matchItem(file Item, pattern string) bool {
+	ok, _ := filepath.Match(pattern, filepath.Base(file.Key))
+	return ok && !file.IsDir
+}
+
+// newestItem returns the most recently modified file, the latest.json marker is ignored
+func newestItem(files []Item) (Item, bool) {
+	var newest Item
+	found := false
+	for _, file := range files {
+		if file.IsDir || filepath.Base(file.Key) == LatestFile {
+			continue
+		}
+		if !found || file.LastModified.After(newest.LastModified) {
+			newest = file
+			found = true
 		}
-		files = append(files, subDirs...)
 	}
+	return newest, found
+}
+
+// errFileExists is returned by downloads skipped because the destination file exists and force is not set
+var errFileExists = errors.New("file already exists, use --force to overwrite")
 
-	return files, nil
+// partialSuffix is appended to the name of a file being downloaded
+const partialSuffix = ".s3safe-part"
+
+// downloadFile is a download written next to its destination and renamed to it once complete,
+// so an interrupted download does not leave a truncated file behind
+type downloadFile struct {
+	*os.File
+	dest      string
+	committed bool
+}
+
+// commit closes the complete download and renames it to its destination
+func (f *downloadFile) commit() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), f.dest); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// discard closes and removes an incomplete download, it does nothing once committed
+func (f *downloadFile) discard() {
+	if f.committed {
+		return
+	}
+	_ = f.File.Close()
+	_ = os.Remove(f.Name())
+}
+
+// createDownloadFile creates the partial file of a download to dest.
+// It returns errFileExists when dest already exists and force is not set.
+func createDownloadFile(dest string, force bool) (*downloadFile, error) {
+	// Check if the destination path exists
+	destPath := filepath.Dir(dest)
+	if _, err := os.Stat(destPath); os.IsNotExist(err) {
+		err := os.MkdirAll(destPath, os.ModePerm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create destination directory: %w", err)
+		}
+	}
+	// Check if the file already exists
+	if !force {
+		if _, err := os.Stat(dest); err == nil {
+			return nil, errFileExists
+		}
+	}
+	file, err := os.Create(dest + partialSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("download error: %w", err)
+	}
+	return &downloadFile{File: file, dest: dest}, nil
 }
 
 // ListFiles lists files in the local directory, optionally recursively.
 func ListFiles(path string, recursive bool) ([]Item, error) {
-	var files []Item
+	return collectItems(WalkFiles(path, recursive))
+}
 
-	err := walkDir(path, path, recursive, &files)
-	if err != nil {
-		return files, err
+// WalkFiles iterates over the files in the local directory, optionally recursively.
+// Directories are read one at a time, so items are produced before the whole tree is walked.
+func WalkFiles(path string, recursive bool) iter.Seq2[Item, error] {
+	return walkFilesAfter(path, recursive, "", nil)
+}
+
+// walkFilesAfter iterates over the files following the slash separated key in walk order,
+// the files before it are neither yielded nor read. Files and directories matched by exclude are left out.
+func walkFilesAfter(path string, recursive bool, after string, exclude excludeFunc) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		if err := walkDir(path, path, recursive, after, exclude, yield); err != nil && !errors.Is(err, errStopIteration) {
+			yield(Item{}, err)
+		}
 	}
+}
 
-	return files, nil
+// checkReadable reports an error when the file cannot be opened for reading
+func checkReadable(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
 }
 
-// walkDir is a recursive helper to collect items.
-func walkDir(root, current string, recursive bool, files *[]Item) error {
+// errStopIteration is returned by item producers when the consumer of the items is done
+var errStopIteration = errors.New("iteration stopped")
+
+// walkDir is a recursive helper to yield items, entries up to the after key are skipped.
+// Unreadable entries are yielded as errors with their relative path, the walk goes on when the consumer continues.
+func walkDir(root, current string, recursive bool, after string, exclude excludeFunc, yield func(Item, error) bool) error {
 	entries, err := os.ReadDir(current)
 	if err != nil {
-		return fmt.Errorf("could not read directory %q: %w", current, err)
+		relPath, _ := filepath.Rel(root, current)
+		if !yield(Item{Key: relPath, IsDir: true}, fmt.Errorf("could not read directory %q: %w", current, err)) {
+			return errStopIteration
+		}
+		return nil
 	}
 
 	for _, entry := range entries {
 		fullPath := filepath.Join(current, entry.Name())
+		relPath, err := filepath.Rel(root, fullPath)
+		if err != nil {
+			return fmt.Errorf("could not determine relative path: %w", err)
+		}
+
+		if after != "" {
+			key := filepath.ToSlash(relPath)
+			if compareWalkOrder(key, after) <= 0 {
+				// Only the directories leading to the after key are read again
+				if recursive && entry.IsDir() && strings.HasPrefix(after, key+"/") {
+					if err := walkDir(root, fullPath, recursive, after, exclude, yield); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
 
 		info, err := entry.Info()
 		if err != nil {
-			return fmt.Errorf("could not get file info for %q: %w", fullPath, err)
+			if !yield(Item{Key: relPath}, fmt.Errorf("could not get file info for %q: %w", fullPath, err)) {
+				return errStopIteration
+			}
+			continue
 		}
 
-		relPath, err := filepath.Rel(root, fullPath)
-		if err != nil {
-			return fmt.Errorf("could not determine relative path: %w", err)
+		if exclude != nil && exclude(fullPath, info) {
+			continue
 		}
 
-		*files = append(*files, Item{
+		item := Item{
 			Key:          relPath,
 			LastModified: info.ModTime(),
 			IsDir:        info.IsDir(),
-		})
+			Size:         info.Size(),
+		}
+		if !yield(item, nil) {
+			return errStopIteration
+		}
 
 		// If recursive and it's a directory, go deeper
 		if recursive && info.IsDir() {
-			if err := walkDir(root, fullPath, recursive, files); err != nil {
+			if err := walkDir(root, fullPath, recursive, after, exclude, yield); err != nil {
 				return err
 			}
 		}
@@ -503,17 +1407,32 @@ func walkDir(root, current string, recursive bool, files *[]Item) error {
 	return nil
 }
 
-// compressDirectory compresses a directory into a tar.gz file
-func compressDirectory(sourceDir, outputFile string) error {
+// archiveOptions are the settings of compressDirectory
+type archiveOptions struct {
+	// skip is passed the unreadable files and directories, they abort the compression when it is nil
+	skip skipFunc
+	// exclude leaves the matched files and directories out of the archive
+	exclude excludeFunc
+	// noRecompress stores the files that are already compressed without compression
+	noRecompress bool
+	// includeSpecial archives FIFOs and device files as tar entries, they are skipped with a warning otherwise
+	includeSpecial bool
+	// selinux stores the SELinux context of every file in its PAX records
+	selinux bool
+}
+
+// compressDirectory compresses a directory into a tar.gz file.
+// The number of archived files is returned.
+func compressDirectory(sourceDir, outputFile string, opts archiveOptions) (int, error) {
 	slog.Info("Compressing directory", "sourceDir", sourceDir, "outputFile", outputFile)
 	absOutputFile, err := filepath.Abs(outputFile)
 	if err != nil {
-		return fmt.Errorf("could not get absolute path of output file: %w", err)
+		return 0, fmt.Errorf("could not get absolute path of output file: %w", err)
 	}
 
 	outFile, err := os.Create(absOutputFile)
 	if err != nil {
-		return fmt.Errorf("could not create output file: %w", err)
+		return 0, fmt.Errorf("could not create output file: %w", err)
 	}
 	defer func(outFile *os.File) {
 		err := outFile.Close()
@@ -522,8 +1441,8 @@ func compressDirectory(sourceDir, outputFile string) error {
 		}
 	}(outFile)
 
-	gw := gzip.NewWriter(outFile)
-	defer func(gw *gzip.Writer) {
+	gw := newGzipMembers(outFile)
+	defer func(gw *gzipMembers) {
 		err := gw.Close()
 		if err != nil {
 			slog.Error("error closing gzip writer", "error", err)
@@ -538,10 +1457,20 @@ func compressDirectory(sourceDir, outputFile string) error {
 		}
 	}(tw)
 
-	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	archived := 0
+	// unreadable leaves a file out of the archive when it is skipped
+	unreadable := func(path string, err error) error {
+		if opts.skip == nil {
 			return err
 		}
+		relPath, _ := filepath.Rel(sourceDir, path)
+		return opts.skip(filepath.ToSlash(relPath), err, archived)
+	}
+
+	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return unreadable(path, err)
+		}
 
 		// Skip the output file
 		absPath, err := filepath.Abs(path)
@@ -551,6 +1480,17 @@ func compressDirectory(sourceDir, outputFile string) error {
 		if absPath == absOutputFile {
 			return nil
 		}
+		// Skip the archives of previous backups kept next to the output file, see --keep-local
+		if info.Mode().IsRegular() && filepath.Dir(absPath) == filepath.Dir(absOutputFile) && isLocalArchive(absPath) {
+			return nil
+		}
+
+		if path != sourceDir && opts.exclude != nil && opts.exclude(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		// Skip directories, tar only needs file headers
 		if info.IsDir() {
@@ -563,10 +1503,31 @@ func compressDirectory(sourceDir, outputFile string) error {
 			return err
 		}
 
+		// Opening a FIFO blocks, sockets cannot be stored in a tar archive
+		if isSpecialFile(info.Mode()) {
+			if !opts.includeSpecial || info.Mode()&os.ModeSocket != 0 {
+				slog.Warn("Skipping special file", "file", relPath, "type", specialFileType(info.Mode()))
+				return nil
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
+			if opts.selinux {
+				addArchiveLabel(header, path)
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			archived++
+			return nil
+		}
+
 		// Open the file
 		file, err := os.Open(path)
 		if err != nil {
-			return err
+			return unreadable(path, err)
 		}
 		defer func(file *os.File) {
 			err := file.Close()
@@ -580,7 +1541,36 @@ func compressDirectory(sourceDir, outputFile string) error {
 		if err != nil {
 			return err
 		}
-		header.Name = relPath
+		header.Name = filepath.ToSlash(relPath)
+		if opts.selinux {
+			addArchiveLabel(header, path)
+		}
+
+		level := gzip.DefaultCompression
+		if opts.noRecompress && isIncompressible(file, path) {
+			slog.Debug("Storing compressed file without recompression", "file", relPath)
+			level = gzip.NoCompression
+		}
+		if err := gw.setLevel(level); err != nil {
+			return err
+		}
+
+		// Store sparse files such as VM images without their holes
+		segments, sparse, err := dataSegments(file, info)
+		if err != nil {
+			return unreadable(path, fmt.Errorf("could not read holes of %s: %w", path, err))
+		}
+		if sparse && info.Mode().IsRegular() {
+			slog.Debug("Storing sparse file", "file", relPath, "segments", len(segments))
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			if err := writeSparseEntry(gw, header, file, segments); err != nil {
+				return err
+			}
+			archived++
+			return nil
+		}
 
 		// Write header
 		if err := tw.WriteHeader(header); err != nil {
@@ -591,14 +1581,27 @@ func compressDirectory(sourceDir, outputFile string) error {
 		if _, err := io.Copy(tw, file); err != nil {
 			return err
 		}
+		archived++
 
 		return nil
 	})
+	return archived, err
 }
 
-// decompressDirectory decompresses a tar.gz file into a directory
-func decompressDirectory(sourceFile, destDir string) error {
-	// Open the tar.gz file
+// skipFunc is called for a file that cannot be read, with the number of files archived so far.
+// The file is left out of the archive unless an error is returned.
+type skipFunc func(key string, err error, archived int) error
+
+// excludeFunc reports whether a file or a directory with its files is left out of a backup
+type excludeFunc func(path string, info os.FileInfo) bool
+
+// decompressDirectory decompresses a tar.gz, tar.zst or tar.xz file, or a zip archive, into a directory.
+// The SELinux contexts stored in tar archives are restored when selinux is set.
+func decompressDirectory(sourceFile, destDir string, selinux bool) error {
+	format := archiveFormat(sourceFile)
+	if format == formatZip {
+		return extractZip(sourceFile, destDir)
+	}
 	file, err := os.Open(sourceFile)
 	if err != nil {
 		return fmt.Errorf("could not open file: %w", err)
@@ -610,18 +1613,25 @@ func decompressDirectory(sourceFile, destDir string) error {
 		}
 	}(file)
 
-	gzr, err := gzip.NewReader(file)
+	stream, closeStream, err := tarStream(format, file)
 	if err != nil {
-		return fmt.Errorf("could not create gzip reader: %w", err)
+		return fmt.Errorf("could not create %s reader: %w", format, err)
 	}
-	defer func(gzr *gzip.Reader) {
-		err := gzr.Close()
-		if err != nil {
-			slog.Error("error closing gzip reader", "error", err)
-		}
-	}(gzr)
+	err = extractTar(stream, destDir, selinux)
+	if closeErr := closeStream(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	// Delete the original file
+	// err = os.Remove(sourceFile)
+	// if err != nil {
+	//	slog.Error("error removing file", "file", sourceFile, "error", err)
+	// }
+	return err
+}
 
-	tr := tar.NewReader(gzr)
+// extractTar extracts a tar stream into destDir, with the stored SELinux contexts when selinux is set
+func extractTar(r io.Reader, destDir string, selinux bool) error {
+	tr := tar.NewReader(r)
 
 	for {
 		header, err := tr.Next()
@@ -632,7 +1642,11 @@ func decompressDirectory(sourceFile, destDir string) error {
 			return fmt.Errorf("could not read tar header: %w", err)
 		}
 
-		target := filepath.Join(destDir, header.Name)
+		name := header.Name
+		if windowsPaths {
+			name, _ = sanitizeWindowsPath(name)
+		}
+		target := longPath(keyToLocal(destDir, name))
 
 		switch header.Typeflag {
 		case tar.TypeDir:
@@ -651,40 +1665,49 @@ func decompressDirectory(sourceFile, destDir string) error {
 				}
 			}(outFile)
 
-			if _, err := io.Copy(outFile, tr); err != nil {
+			if isSparseEntry(header) {
+				err = copySparse(outFile, tr, header.Size)
+			} else {
+				_, err = io.Copy(outFile, tr)
+			}
+			if err != nil {
 				return fmt.Errorf("could not write to file: %w", err)
 			}
+		case tar.TypeFifo, tar.TypeChar, tar.TypeBlock:
+			if err := makeSpecialFile(target, header); err != nil {
+				slog.Warn("Skipping special file", "file", header.Name, "error", err)
+			}
 		default:
 			return fmt.Errorf("unsupported type: %c in %s", header.Typeflag, header.Name)
 		}
+		if selinux {
+			restoreLabel(target, archiveLabel(header))
+		}
 	}
-	// Delete the original file
-	// err = os.Remove(sourceFile)
-	// if err != nil {
-	//	slog.Error("error removing file", "file", sourceFile, "error", err)
-	// }
 	return nil
 }
 
-// Check if the file is compressed
+// isCompressed reports whether the file is a gzip, zstd, xz or zip archive
 func isCompressed(filePath string) bool {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return false
+	return archiveFormat(filePath) != ""
+}
+
+// detectContentType returns the content type of a file from its extension, falling back to content sniffing.
+// The file offset is reset to the beginning.
+func detectContentType(file *os.File) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(file.Name())); contentType != "" {
+		return contentType, nil
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			slog.Error("error closing file", "error", err)
-		}
-	}(file)
 
 	buf := make([]byte, 512)
-	if _, err := file.Read(buf); err != nil {
-		return false
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("could not read file: %w", err)
 	}
-
-	return string(buf[:2]) == "\x1f\x8b"
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("could not rewind file: %w", err)
+	}
+	return http.DetectContentType(buf[:n]), nil
 }
 
 // // Check if file has relative path
@@ -697,19 +1720,22 @@ func IsAbsolutePath(path string) bool {
 	return filepath.IsAbs(path)
 }
 
-// removePrefix removes the prefix from the file path
-func removePrefix(filePath, prefix string) string {
-	if len(filePath) < len(prefix) {
-		return filePath
-	}
-	if filePath[:len(prefix)] == prefix {
-		return filePath[len(prefix):]
-	}
-	return filePath
+// intro prints the intro message once the operation is set up and notifies systemd it is ready,
+// in Kubernetes mode the version is logged and the job is marked ready
+func intro() {
+	introTo(os.Stdout)
 }
 
-// intro prints the intro message
-func intro() {
-	fmt.Printf("Version: %s\n", utils.Version)
-	fmt.Println("Copyright (c) 2025 Jonas Kaninda")
+// introTo prints the intro message to out
+func introTo(out io.Writer) {
+	if err := sdNotify("READY=1"); err != nil {
+		slog.Warn("Unable to notify systemd", "error", err)
+	}
+	if kubernetesMode {
+		slog.Info("Starting s3safe", "version", utils.Version)
+		markReady()
+		return
+	}
+	_, _ = fmt.Fprintf(out, "Version: %s\n", utils.Version)
+	_, _ = fmt.Fprintln(out, "Copyright (c) 2025 Jonas Kaninda")
 }
@@ -24,7 +24,21 @@ SOFTWARE.
 
 package pkg
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestIsRelativePath(t *testing.T) {
 	relativePath := "path/to/file.txt"
@@ -39,3 +53,215 @@ func TestIsRelativePath(t *testing.T) {
 	}
 
 }
+
+func TestDetectContentType(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"index.html": []byte("<html></html>"),
+		"data.json":  []byte("{}"),
+		"noext":      []byte("plain text content"),
+		"archive":    {0x1f, 0x8b, 0x08, 0x00},
+	}
+	expected := map[string]string{
+		"index.html": "text/html; charset=utf-8",
+		"data.json":  "application/json",
+		"noext":      "text/plain; charset=utf-8",
+		"archive":    "application/x-gzip",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, err := detectContentType(file)
+		_ = file.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", name, err)
+		}
+		if contentType != expected[name] {
+			t.Errorf("Expected %s for %s, got %s", expected[name], name, contentType)
+		}
+	}
+}
+
+func TestNewestMatchingItem(t *testing.T) {
+	now := time.Now()
+	files := []Item{
+		{Key: "backups/db-1.tar.gz", LastModified: now.Add(-2 * time.Hour)},
+		{Key: "backups/db-2.tar.gz", LastModified: now.Add(-time.Hour)},
+		{Key: "backups/web-1.tar.gz", LastModified: now},
+		{Key: "backups/latest.json", LastModified: now.Add(time.Minute)},
+		{Key: "backups/db-dir/", IsDir: true, LastModified: now},
+	}
+
+	matched := matchItems(files, "db-*.tar.gz")
+	if len(matched) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matched))
+	}
+	newest, ok := newestItem(matched)
+	if !ok || newest.Key != "backups/db-2.tar.gz" {
+		t.Errorf("Expected backups/db-2.tar.gz, got %q", newest.Key)
+	}
+
+	newest, ok = newestItem(files)
+	if !ok || newest.Key != "backups/web-1.tar.gz" {
+		t.Errorf("Expected the latest marker to be ignored, got %q", newest.Key)
+	}
+	if _, ok := newestItem(nil); ok {
+		t.Errorf("Expected no file")
+	}
+}
+
+// listServer serves two ListObjectsV2 pages, recursive listings return no common prefixes
+func listServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	pages := map[string]string{
+		"":      `<Contents><Key>backups/a.txt</Key><Size>1</Size><LastModified>2025-01-01T00:00:00.000Z</LastModified></Contents><Contents><Key>backups/sub/</Key><Size>0</Size><LastModified>2025-01-01T00:00:00.000Z</LastModified></Contents><IsTruncated>true</IsTruncated><NextContinuationToken>page2</NextContinuationToken>`,
+		"page2": `<Contents><Key>backups/sub/b%0A.txt</Key><Size>2</Size><LastModified>2025-01-02T00:00:00.000Z</LastModified></Contents><IsTruncated>false</IsTruncated>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		query := r.URL.Query()
+		if query.Get("delimiter") != "" {
+			t.Errorf("Recursive listing must not use a delimiter")
+		}
+		page, ok := pages[query.Get("continuation-token")]
+		if !ok {
+			http.Error(w, "unknown token", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>bucket</Name><Prefix>%s</Prefix><EncodingType>url</EncodingType>%s</ListBucketResult>`,
+			query.Get("prefix"), page)
+	}))
+}
+
+func TestS3StorageListRecursive(t *testing.T) {
+	var requests atomic.Int32
+	server := listServer(t, &requests)
+	defer server.Close()
+
+	storage := S3Storage{bucket: "bucket", client: s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})}
+	files, err := storage.List(context.Background(), "backups", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+	if want := "backups/a.txt,backups/sub/,backups/sub/b\n.txt"; strings.Join(keys, ",") != want {
+		t.Errorf("Expected %q, got %q", want, strings.Join(keys, ","))
+	}
+	if !files[1].IsDir {
+		t.Errorf("Expected the directory marker to be a directory")
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected 2 list requests, got %d", n)
+	}
+}
+
+func TestS3StorageObjectsStopsEarly(t *testing.T) {
+	var requests atomic.Int32
+	server := listServer(t, &requests)
+	defer server.Close()
+
+	storage := S3Storage{bucket: "bucket", client: s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})}
+	for file, err := range storage.Objects(context.Background(), "backups", true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Key != "backups/a.txt" {
+			t.Errorf("Expected backups/a.txt first, got %q", file.Key)
+		}
+		break
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected the next page not to be requested, got %d requests", n)
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", filepath.Join("sub", "b.txt")} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ListFiles(root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[2].Key != filepath.Join("sub", "b.txt") {
+		t.Errorf("Unexpected files %+v", files)
+	}
+
+	count := 0
+	for _, err := range WalkFiles(root, true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected the walk to stop after the first file, got %d", count)
+	}
+
+	if _, err := ListFiles(filepath.Join(root, "missing"), true); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestCompressDirectoryIgnoreErrors(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "good.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling symlink cannot be opened
+	if err := os.Symlink(filepath.Join(src, "missing"), filepath.Join(src, "broken")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	if _, err := compressDirectory(src, archive, archiveOptions{}); err == nil {
+		t.Fatal("Expected an error for the unreadable file")
+	}
+	var skipped []string
+	archived, err := compressDirectory(src, archive, archiveOptions{skip: func(key string, err error, archived int) error {
+		skipped = append(skipped, key)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != 1 || len(skipped) != 1 || skipped[0] != "broken" {
+		t.Fatalf("Unexpected archived %d and skipped files %v", archived, skipped)
+	}
+
+	dest := t.TempDir()
+	if err := decompressDirectory(archive, dest, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "good.txt")); err != nil {
+		t.Errorf("Expected the readable file in the archive: %v", err)
+	}
+}
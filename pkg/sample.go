@@ -0,0 +1,216 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errSourceChanged is returned for a sampled file modified after its upload
+var errSourceChanged = errors.New("source file changed after the upload")
+
+// sampledFile is an uploaded file picked for the verification of --verify-sample
+type sampledFile struct {
+	path string
+	// key is the object key on the main destination, gzipped with --compress-files
+	key     string
+	gzipped bool
+	size    int64
+	modTime time.Time
+}
+
+// uploadSample picks uploaded files at random, a percentage of them or a fixed number.
+// A nil sample picks nothing.
+type uploadSample struct {
+	count   int
+	percent float64
+	seen    int
+	files   []sampledFile
+}
+
+// parseUploadSample parses a number of files such as "20" or a percentage such as "5%"
+func parseUploadSample(value string) (*uploadSample, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if n, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid verify sample %q, use a number of files or a percentage between 0%% and 100%%", value)
+		}
+		return &uploadSample{percent: percent}, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid verify sample %q, use a number of files or a percentage such as 5%%", value)
+	}
+	return &uploadSample{count: count}, nil
+}
+
+// add offers an uploaded file to the sample, a fixed number of files is kept with reservoir sampling
+func (s *uploadSample) add(file sampledFile) {
+	if s == nil {
+		return
+	}
+	s.seen++
+	if s.percent > 0 {
+		if rand.Float64()*100 < s.percent {
+			s.files = append(s.files, file)
+		}
+		return
+	}
+	if len(s.files) < s.count {
+		s.files = append(s.files, file)
+		return
+	}
+	if i := rand.IntN(s.seen); i < s.count {
+		s.files[i] = file
+	}
+}
+
+// sampleUpload offers a file uploaded to the main destination to the sample
+func (bm *BackupManager) sampleUpload(sourcePath, key string, gzipped bool, info os.FileInfo) {
+	if bm.sample == nil || bm.destinations[0].err != nil {
+		return
+	}
+	bm.sample.add(sampledFile{
+		path:    sourcePath,
+		key:     objectKey(bm.destinations[0].prefix, key),
+		gzipped: gzipped,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	})
+}
+
+// verifySample downloads the sampled objects from the main destination and compares them with their source files
+func (bm *BackupManager) verifySample(ctx context.Context) error {
+	if bm.sample == nil || len(bm.sample.files) == 0 {
+		return nil
+	}
+	reader, ok := bm.destinations[0].uploader.(objectReader)
+	if !ok {
+		return fmt.Errorf("--verify-sample is not supported by destination %s", bm.destinations[0].name)
+	}
+	bm.log().Info("Verifying a sample of the uploaded files", "files", len(bm.sample.files), "uploaded", bm.sample.seen)
+
+	var mismatches []string
+	for _, file := range bm.sample.files {
+		err := compareObject(ctx, reader, file)
+		if errors.Is(err, errSourceChanged) {
+			bm.log().Warn("Skipping sampled file, it changed after the upload", "file", file.path)
+			continue
+		}
+		if err != nil {
+			bm.log().Error("Uploaded object does not match its source file", "file", file.path, "key", file.key, "error", err)
+			mismatches = append(mismatches, strconv.Quote(file.key))
+			continue
+		}
+		bm.result.Verified++
+	}
+	if len(mismatches) > 0 {
+		return withExitCode(ExitFailure, fmt.Errorf("sample verification failed for %d of %d files: %s",
+			len(mismatches), len(bm.sample.files), strings.Join(mismatches, ", ")))
+	}
+	bm.log().Info("Sample verification completed successfully", "files", bm.result.Verified)
+	return nil
+}
+
+// compareObject compares the content of an uploaded object with its source file
+func compareObject(ctx context.Context, reader objectReader, file sampledFile) error {
+	info, err := os.Stat(file.path)
+	if err != nil || info.Size() != file.size || !info.ModTime().Equal(file.modTime) {
+		return errSourceChanged
+	}
+	local, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer func(local *os.File) {
+		_ = local.Close()
+	}(local)
+
+	body, _, err := reader.Open(ctx, file.key)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(body)
+	var content io.Reader = body
+	if file.gzipped {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		content = gz
+	}
+	return compareContent(local, content)
+}
+
+// compareContent compares two streams, the offset of the first difference is reported
+func compareContent(expected, actual io.Reader) error {
+	bufExpected, bufActual := make([]byte, 64*1024), make([]byte, 64*1024)
+	var offset int64
+	for {
+		n, errExpected := io.ReadFull(expected, bufExpected)
+		m, errActual := io.ReadFull(actual, bufActual)
+		if !bytes.Equal(bufExpected[:n], bufActual[:m]) {
+			i := 0
+			for i < n && i < m && bufExpected[i] == bufActual[i] {
+				i++
+			}
+			if i == n || i == m {
+				return fmt.Errorf("size mismatch at byte %d", offset+int64(i))
+			}
+			return fmt.Errorf("content mismatch at byte %d", offset+int64(i))
+		}
+		offset += int64(n)
+		expectedDone := errors.Is(errExpected, io.EOF) || errors.Is(errExpected, io.ErrUnexpectedEOF)
+		actualDone := errors.Is(errActual, io.EOF) || errors.Is(errActual, io.ErrUnexpectedEOF)
+		if errExpected != nil && !expectedDone {
+			return errExpected
+		}
+		if errActual != nil && !actualDone {
+			return errActual
+		}
+		if expectedDone || actualDone {
+			if expectedDone != actualDone {
+				return fmt.Errorf("size mismatch at byte %d", offset)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseUploadSample(t *testing.T) {
+	for _, value := range []string{"1", "20", "5%", "0.5%", "100%"} {
+		if _, err := parseUploadSample(value); err != nil {
+			t.Errorf("parseUploadSample(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"0", "-1", "0%", "101%", "five"} {
+		if _, err := parseUploadSample(value); err == nil {
+			t.Errorf("parseUploadSample(%q) expected an error", value)
+		}
+	}
+	if sample, err := parseUploadSample(""); sample != nil || err != nil {
+		t.Errorf("Expected no sample, got %v %v", sample, err)
+	}
+}
+
+func TestUploadSampleAdd(t *testing.T) {
+	sample, _ := parseUploadSample("3")
+	for i := 0; i < 100; i++ {
+		sample.add(sampledFile{key: string(rune('a' + i%26))})
+	}
+	if len(sample.files) != 3 || sample.seen != 100 {
+		t.Errorf("Expected 3 sampled files of 100, got %d of %d", len(sample.files), sample.seen)
+	}
+	all, _ := parseUploadSample("100%")
+	for i := 0; i < 10; i++ {
+		all.add(sampledFile{})
+	}
+	if len(all.files) != 10 {
+		t.Errorf("Expected every file to be sampled, got %d", len(all.files))
+	}
+}
+
+func TestCompareContent(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 20000)
+	if err := compareContent(bytes.NewReader(data), bytes.NewReader(data)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	changed := bytes.Clone(data)
+	changed[150000] = 'x'
+	if err := compareContent(bytes.NewReader(data), bytes.NewReader(changed)); err == nil || !strings.Contains(err.Error(), "150000") {
+		t.Errorf("Expected a mismatch at byte 150000, got %v", err)
+	}
+	if err := compareContent(bytes.NewReader(data), bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("Expected a size mismatch")
+	}
+	if err := compareContent(bytes.NewReader(data[:65536]), bytes.NewReader(data[:65537])); err == nil {
+		t.Error("Expected a size mismatch on a buffer boundary")
+	}
+}
+
+func TestBackupVerifySample(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	corrupt := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			if key == corrupt {
+				body[0] ^= 0xff
+			}
+			objects[key] = body
+			w.Header().Set("ETag", `"etag"`)
+			return
+		}
+		body, ok := objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("content of "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.VerifySample = "100%"
+	for _, compressFiles := range []bool{false, true} {
+		cfg.CompressFiles = compressFiles
+		bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := bm.Backup(context.Background())
+		if err != nil {
+			t.Fatalf("compressFiles=%v: %v", compressFiles, err)
+		}
+		if result.Verified != 3 {
+			t.Errorf("compressFiles=%v: result.Verified = %d, want 3", compressFiles, result.Verified)
+		}
+	}
+
+	cfg.CompressFiles = false
+	mu.Lock()
+	corrupt = "backups/b.txt"
+	mu.Unlock()
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if ExitCode(err) != ExitFailure || !strings.Contains(err.Error(), "backups/b.txt") {
+		t.Errorf("Expected the sample verification to fail for b.txt, got %v", err)
+	}
+	if result.Verified != 2 {
+		t.Errorf("result.Verified = %d, want 2", result.Verified)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// notifySocketEnv is the datagram socket of the systemd notification protocol, set for Type=notify services
+	notifySocketEnv = "NOTIFY_SOCKET"
+	// watchdogUsecEnv is the WatchdogSec of the service in microseconds
+	watchdogUsecEnv = "WATCHDOG_USEC"
+	// watchdogPIDEnv is the process expected to send the watchdog keep-alive pings
+	watchdogPIDEnv = "WATCHDOG_PID"
+)
+
+// sdNotify sends a state such as READY=1 to systemd, nothing is sent outside of a Type=notify service
+func sdNotify(state string) error {
+	socket := os.Getenv(notifySocketEnv)
+	if socket == "" {
+		return nil
+	}
+	// Sockets starting with @ are in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("unable to notify systemd: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("unable to notify systemd: %w", err)
+	}
+	return nil
+}
+
+// watchdogTimeout returns the WatchdogSec of the service, 0 when the watchdog is disabled or expects another process
+func watchdogTimeout() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(watchdogUsecEnv), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv(watchdogPIDEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// watchdogReporter pings the systemd watchdog as files are transferred, a backup or restore making no progress
+// for WatchdogSec is restarted by systemd
+type watchdogReporter struct {
+	// interval is the minimum time between two pings, a tenth of the watchdog timeout
+	interval time.Duration
+	mu       sync.Mutex
+	lastPing time.Time
+}
+
+// newWatchdogReporter returns the reporter pinging the systemd watchdog, nil when the watchdog is disabled
+func newWatchdogReporter() Reporter {
+	timeout := watchdogTimeout()
+	if timeout == 0 {
+		return nil
+	}
+	return &watchdogReporter{interval: timeout / 10}
+}
+
+func (w *watchdogReporter) FileStarted(key string, _ int64) {
+	w.ping("STATUS=Transferring " + key)
+}
+
+func (w *watchdogReporter) FileCompleted(string, int64, error) {
+	w.ping("")
+}
+
+// ping sends a keep-alive ping with the status, unless the last one was sent less than the interval ago
+func (w *watchdogReporter) ping(status string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if time.Since(w.lastPing) < w.interval {
+		return
+	}
+	w.lastPing = time.Now()
+	state := "WATCHDOG=1"
+	if status != "" {
+		state += "\n" + status
+	}
+	_ = sdNotify(state)
+}
+
+// notifyStopping tells systemd the operation is done with its outcome as status
+func notifyStopping(status string) {
+	if err := sdNotify("STOPPING=1\nSTATUS=" + status); err != nil {
+		slog.Warn("Unable to notify systemd", "error", err)
+	}
+}
+
+// outcome describes the result of an operation in the status sent to systemd
+func outcome(err error) string {
+	switch ExitCode(err) {
+	case ExitOK:
+		return "completed"
+	case ExitPartial:
+		return "partially completed"
+	default:
+		return "failed"
+	}
+}
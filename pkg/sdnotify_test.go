@@ -0,0 +1,122 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// notifySocket listens on a systemd notification socket set in NOTIFY_SOCKET
+func notifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("datagram unix sockets are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv(notifySocketEnv, path)
+	return conn
+}
+
+// received returns the notifications sent to the socket
+func received(t *testing.T, conn *net.UnixConn) []string {
+	t.Helper()
+	var states []string
+	buf := make([]byte, 1024)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		n, err := conn.Read(buf)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return states
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, string(buf[:n]))
+	}
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv(notifySocketEnv, "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("sdNotify without systemd: %v", err)
+	}
+	conn := notifySocket(t)
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatalf("sdNotify: %v", err)
+	}
+	notifyStopping("Backup " + outcome(withExitCode(ExitPartial, errors.New("skipped"))))
+	got := received(t, conn)
+	if len(got) != 2 || got[0] != "READY=1" || got[1] != "STOPPING=1\nSTATUS=Backup partially completed" {
+		t.Errorf("notifications = %q", got)
+	}
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	t.Setenv(watchdogPIDEnv, "")
+	t.Setenv(watchdogUsecEnv, "")
+	if got := watchdogTimeout(); got != 0 {
+		t.Errorf("watchdog without WatchdogSec = %s", got)
+	}
+	if newWatchdogReporter() != nil {
+		t.Error("a reporter pings a disabled watchdog")
+	}
+	t.Setenv(watchdogUsecEnv, "30000000")
+	if got := watchdogTimeout(); got != 30*time.Second {
+		t.Errorf("watchdog timeout = %s, want 30s", got)
+	}
+	t.Setenv(watchdogPIDEnv, strconv.Itoa(os.Getpid()+1))
+	if got := watchdogTimeout(); got != 0 {
+		t.Errorf("watchdog of another process = %s", got)
+	}
+}
+
+func TestWatchdogReporter(t *testing.T) {
+	conn := notifySocket(t)
+	w := &watchdogReporter{interval: time.Hour}
+	w.FileStarted("a.txt", 5)
+	w.FileCompleted("a.txt", 5, nil)
+	w.FileStarted("b.txt", 6)
+	if got := received(t, conn); len(got) != 1 || got[0] != "WATCHDOG=1\nSTATUS=Transferring a.txt" {
+		t.Errorf("pings = %q, want one ping within the interval", got)
+	}
+	w.interval = 0
+	w.FileCompleted("b.txt", 6, nil)
+	if got := received(t, conn); len(got) != 1 || got[0] != "WATCHDOG=1" {
+		t.Errorf("pings = %q", got)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SearchMatch is a backed up copy of a file, in an archive or stored as an object
+type SearchMatch struct {
+	// Path is the path of the file in the archive, or the key of the object relative to the searched prefix
+	Path string `json:"path"`
+	// Object is the key of the archive or of the object holding the file
+	Object string    `json:"object"`
+	Time   time.Time `json:"time"`
+	Size   int64     `json:"size"`
+}
+
+// Search is the cobra command handler for search
+func Search(cmd *cobra.Command, pattern string) error {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	if config.Format == "" {
+		config.Format = formatText
+	}
+	if !slices.Contains(outputFormats, config.Format) {
+		return withExitCode(ExitConfig, fmt.Errorf("invalid format %q, supported values: %v", config.Format, outputFormats))
+	}
+	if _, err := path.Match(pattern, ""); err != nil || strings.Trim(pattern, "/") == "" {
+		return withExitCode(ExitConfig, fmt.Errorf("invalid search pattern %q", pattern))
+	}
+	var before time.Time
+	if config.Before != "" {
+		t, err := config.parseTime("before", config.Before)
+		if err != nil {
+			return withExitCode(ExitConfig, err)
+		}
+		before = t
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	root := strings.Trim(filepath.ToSlash(config.Path), "/")
+	if config.Job != "" {
+		root = strings.Trim(path.Join(root, config.Job), "/")
+	}
+
+	var objects []Item
+	for item, err := range s3Storage.Objects(cmd.Context(), root, true) {
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if !item.IsDir {
+			objects = append(objects, item)
+		}
+	}
+	matches, skipped := config.search(cmd.Context(), s3Storage, root, pattern, objects, before)
+	if skipped > 0 {
+		slog.Info("Archives not searched, set --archives to read their entries", "archives", skipped)
+	}
+	return writeSearchMatches(os.Stdout, matches, pattern, root, config.Format)
+}
+
+// search returns the copies of the files matching pattern under root, the newest first. Objects are matched
+// by key, the entries of compressed backups are read with Archives, otherwise their number is returned.
+// With before, only the backups made before the time are searched.
+func (c *Config) search(ctx context.Context, reader objectReader, root, pattern string, objects []Item, before time.Time) ([]SearchMatch, int) {
+	var matches []SearchMatch
+	skipped := 0
+	for _, object := range objects {
+		if path.Base(object.Key) == LatestFile {
+			continue
+		}
+		t := c.backupTime(object)
+		if !before.IsZero() && !t.Before(before) {
+			continue
+		}
+		if !isTarArchive(object.Key) {
+			rel := relativeKey(object.Key, root)
+			if matchesPath(pattern, rel) || matchesPath(pattern, strings.TrimSuffix(rel, gzipSuffix)) {
+				matches = append(matches, SearchMatch{Path: rel, Object: object.Key, Time: t, Size: object.Size})
+			}
+			continue
+		}
+		if !c.Archives {
+			skipped++
+			continue
+		}
+		found, err := searchArchive(ctx, reader, object.Key, pattern)
+		if err != nil {
+			slog.Warn("Unable to read archive", "file", object.Key, "error", err)
+		}
+		for _, match := range found {
+			match.Time = t
+			matches = append(matches, match)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b SearchMatch) int {
+		if c := b.Time.Compare(a.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return matches, skipped
+}
+
+// searchArchive streams a gzipped tar archive and returns its regular files matching pattern,
+// the whole archive is downloaded
+func searchArchive(ctx context.Context, reader objectReader, key, pattern string) ([]SearchMatch, error) {
+	body, _, err := reader.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(body)
+	stream, closeStream, err := tarStream(formatGzip, body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = closeStream()
+	}()
+
+	var matches []SearchMatch
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+		if header.Typeflag == tar.TypeReg && matchesPath(pattern, header.Name) {
+			matches = append(matches, SearchMatch{Path: header.Name, Object: key, Size: header.Size})
+		}
+	}
+}
+
+// matchesPath reports whether a glob pattern matches the file name, or the end of the path when the
+// pattern holds a slash, e.g. nginx.conf, *.conf or nginx/sites-enabled/*
+func matchesPath(pattern, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(name, "./"), "/"), "/")
+	n := min(strings.Count(pattern, "/")+1, len(parts))
+	ok, _ := path.Match(pattern, strings.Join(parts[len(parts)-n:], "/"))
+	return ok
+}
+
+// writeSearchMatches writes the matches as a text table, JSON lines or CSV
+func writeSearchMatches(out io.Writer, matches []SearchMatch, pattern, root, format string) error {
+	switch format {
+	case formatJSON:
+		return writeJSONLines(out, matches)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"time", "path", "object", "size"})
+		for _, match := range matches {
+			_ = w.Write([]string{match.Time.Format(time.RFC3339), match.Path, match.Object, strconv.FormatInt(match.Size, 10)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if len(matches) == 0 {
+		_, err := fmt.Fprintf(out, "No copies of %q found under %q\n", pattern, root)
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TIME\tSIZE\tPATH\tOBJECT")
+	for _, match := range matches {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", match.Time.Format(time.RFC3339),
+			goutils.ConvertBytes(uint64(match.Size)), match.Path, match.Object)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,129 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"github.com/jkaninda/s3safe/utils"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveReader streams in-memory objects
+type archiveReader map[string][]byte
+
+func (r archiveReader) Open(_ context.Context, key string) (io.ReadCloser, objectDigest, error) {
+	data, ok := r[key]
+	if !ok {
+		return nil, objectDigest{}, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), objectDigest{Size: int64(len(data))}, nil
+}
+
+func TestMatchesPath(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"nginx.conf", "etc/nginx/nginx.conf", true},
+		{"nginx.conf", "./etc/nginx/nginx.conf", true},
+		{"nginx.conf", "etc/nginx/nginx.conf.bak", false},
+		{"*.conf", "etc/nginx/nginx.conf", true},
+		{"nginx/*.conf", "etc/nginx/nginx.conf", true},
+		{"/etc/nginx/nginx.conf", "etc/nginx/nginx.conf", true},
+		{"apache/*.conf", "etc/nginx/nginx.conf", false},
+		{"etc/nginx/nginx.conf", "nginx.conf", false},
+	}
+	for _, tt := range tests {
+		if got := matchesPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchesPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigSearch(t *testing.T) {
+	c := Config{TimestampFormat: utils.DefaultTimestampFormat}
+	modified := time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)
+	objects := []Item{
+		{Key: "backups/web/2025-06-01/a.txt", Size: 5, LastModified: modified},
+		{Key: "backups/web/2025-06-02/a.txt.gz", Size: 4, LastModified: modified.Add(time.Hour)},
+		{Key: "backups/web/2025-06-02/b.txt", Size: 6, LastModified: modified.Add(time.Hour)},
+		{Key: "backups/db/db_2025-05-01_02-00-00.tar.gz", Size: 100, LastModified: modified},
+		{Key: "backups/db/" + LatestFile, Size: 10, LastModified: modified},
+	}
+	reader := archiveReader{"backups/db/db_2025-05-01_02-00-00.tar.gz": testArchive(t)}
+
+	matches, skipped := c.search(context.Background(), reader, "backups", "a.txt", objects, time.Time{})
+	if skipped != 1 || len(matches) != 2 {
+		t.Fatalf("matches = %+v, skipped = %d, want 2 matches and 1 archive", matches, skipped)
+	}
+	if matches[0].Path != "web/2025-06-02/a.txt.gz" || matches[1].Object != "backups/web/2025-06-01/a.txt" {
+		t.Errorf("Expected the newest copy first, got %+v", matches)
+	}
+
+	c.Archives = true
+	matches, skipped = c.search(context.Background(), reader, "backups", "a.txt", objects, time.Time{})
+	if skipped != 0 || len(matches) != 3 {
+		t.Fatalf("matches = %+v, skipped = %d, want 3 matches", matches, skipped)
+	}
+	archived := matches[2]
+	if archived.Object != "backups/db/db_2025-05-01_02-00-00.tar.gz" || !strings.HasSuffix(archived.Path, "a.txt") ||
+		archived.Size != 5000 || !archived.Time.Equal(time.Date(2025, 5, 1, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected archive match %+v", archived)
+	}
+
+	matches, _ = c.search(context.Background(), reader, "backups", "a.txt", objects, modified.Add(time.Minute))
+	if len(matches) != 2 || matches[0].Object != "backups/web/2025-06-01/a.txt" {
+		t.Errorf("Expected the copies made before the time, got %+v", matches)
+	}
+}
+
+func TestWriteSearchMatches(t *testing.T) {
+	matches := []SearchMatch{{Path: "etc/nginx.conf", Object: "backups/web.tar.gz", Time: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Size: 1024}}
+	var out bytes.Buffer
+	if err := writeSearchMatches(&out, matches, "nginx.conf", "backups", formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"path":"etc/nginx.conf","object":"backups/web.tar.gz","time":"2025-06-01T00:00:00Z","size":1024}` + "\n"; out.String() != want {
+		t.Errorf("JSON = %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := writeSearchMatches(&out, matches, "nginx.conf", "backups", formatCSV); err != nil {
+		t.Fatal(err)
+	}
+	if want := "time,path,object,size\n2025-06-01T00:00:00Z,etc/nginx.conf,backups/web.tar.gz,1024\n"; out.String() != want {
+		t.Errorf("CSV = %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := writeSearchMatches(&out, nil, "nginx.conf", "backups", formatText); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No copies") {
+		t.Errorf("Unexpected text output %q", out.String())
+	}
+}
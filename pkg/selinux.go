@@ -0,0 +1,77 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"archive/tar"
+	"log/slog"
+)
+
+// selinuxXattr is the extended attribute holding the SELinux context of a file
+const selinuxXattr = "security.selinux"
+
+// selinuxPAXRecord holds the SELinux context of a tar entry, as written by GNU tar --selinux
+const selinuxPAXRecord = "RHT.security.selinux"
+
+// selinuxXattrPAXRecord holds the SELinux context of a tar entry written by GNU tar --xattrs or star
+const selinuxXattrPAXRecord = "SCHILY.xattr." + selinuxXattr
+
+// selinuxMetadataKey holds the SELinux context of an uploaded file in the object metadata
+const selinuxMetadataKey = "selinux"
+
+// addArchiveLabel stores the SELinux context of a file in the PAX records of its tar entry,
+// nothing is stored when the file has no context
+func addArchiveLabel(header *tar.Header, path string) {
+	label, err := fileLabel(path)
+	if err != nil {
+		slog.Warn("Unable to read SELinux context", "file", path, "error", err)
+		return
+	}
+	if label == "" {
+		return
+	}
+	if header.PAXRecords == nil {
+		header.PAXRecords = make(map[string]string)
+	}
+	header.PAXRecords[selinuxPAXRecord] = label
+	header.Format = tar.FormatPAX
+}
+
+// archiveLabel returns the SELinux context stored in the PAX records of a tar entry
+func archiveLabel(header *tar.Header) string {
+	if label := header.PAXRecords[selinuxPAXRecord]; label != "" {
+		return label
+	}
+	return header.PAXRecords[selinuxXattrPAXRecord]
+}
+
+// restoreLabel sets the SELinux context of a restored file, failures are logged
+func restoreLabel(path, label string) {
+	if label == "" {
+		return
+	}
+	if err := setFileLabel(path, label); err != nil {
+		slog.Warn("Unable to restore SELinux context", "file", path, "context", label, "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"strings"
+)
+
+// fileLabel returns the SELinux context of a file, empty when it has none or the file system has no labels
+func fileLabel(path string) (string, error) {
+	size := 256
+	for {
+		buf := make([]byte, size)
+		n, err := unix.Lgetxattr(path, selinuxXattr, buf)
+		switch {
+		case errors.Is(err, unix.ENODATA), errors.Is(err, unix.ENOTSUP):
+			return "", nil
+		case errors.Is(err, unix.ERANGE):
+			size *= 4
+			continue
+		case err != nil:
+			return "", err
+		}
+		return strings.TrimRight(string(buf[:n]), "\x00"), nil
+	}
+}
+
+// setFileLabel sets the SELinux context of a file, relabeling requires the relabelfrom and relabelto permissions
+func setFileLabel(path, label string) error {
+	return unix.Lsetxattr(path, selinuxXattr, []byte(label), 0)
+}
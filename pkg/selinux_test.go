@@ -0,0 +1,117 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveLabel(t *testing.T) {
+	tests := []struct {
+		records map[string]string
+		want    string
+	}{
+		{nil, ""},
+		{map[string]string{selinuxPAXRecord: "system_u:object_r:etc_t:s0"}, "system_u:object_r:etc_t:s0"},
+		{map[string]string{selinuxXattrPAXRecord: "system_u:object_r:bin_t:s0"}, "system_u:object_r:bin_t:s0"},
+		{map[string]string{selinuxPAXRecord: "system_u:object_r:etc_t:s0", selinuxXattrPAXRecord: "system_u:object_r:bin_t:s0"}, "system_u:object_r:etc_t:s0"},
+	}
+	for _, tt := range tests {
+		if got := archiveLabel(&tar.Header{PAXRecords: tt.records}); got != tt.want {
+			t.Errorf("archiveLabel(%v) = %q, want %q", tt.records, got, tt.want)
+		}
+	}
+}
+
+func TestExtractTarSELinux(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("config")
+	header := &tar.Header{
+		Name:       "app.conf",
+		Mode:       0o644,
+		Size:       int64(len(content)),
+		Typeflag:   tar.TypeReg,
+		PAXRecords: map[string]string{selinuxPAXRecord: "system_u:object_r:etc_t:s0"},
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A context that cannot be set, without SELinux or privileges, does not fail the extraction
+	dest := t.TempDir()
+	if err := extractTar(bytes.NewReader(buf.Bytes()), dest, true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("content = %q, want %q", got, content)
+	}
+}
+
+func TestCompressDirectorySELinux(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	label, err := fileLabel(filepath.Join(src, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := compressDirectory(src, archive, archiveOptions{selinux: true}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, closeStream, err := tarStream(formatGzip, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStream()
+	header, err := tar.NewReader(stream).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files are only labeled on SELinux systems
+	if got := archiveLabel(header); got != label {
+		t.Errorf("archived context = %q, want %q", got, label)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"iter"
+	"slices"
+)
+
+const (
+	// maxListShards bounds --list-shards, S3 throttles the requests to a prefix above a few thousand per second
+	maxListShards = 64
+	// maxShardDescent is the number of levels descended into a lone common prefix to find prefixes to shard on
+	maxShardDescent = 4
+	// shardBuffer is the number of items a shard lists ahead of the merged stream, a page of ListObjectsV2
+	shardBuffer = 1000
+)
+
+// listUnit is an entry of the delimited listing a sharded listing starts from:
+// a file, or a common prefix listed by its own requests
+type listUnit struct {
+	item   Item
+	prefix string
+}
+
+func (u listUnit) key() string {
+	if u.prefix != "" {
+		return u.prefix
+	}
+	return u.item.Key
+}
+
+// listResult is an item or an error of a shard
+type listResult struct {
+	item Item
+	err  error
+}
+
+// shardedObjects lists the keys under path recursively, the common prefixes below path are listed in parallel
+// with up to listShards requests at once. Items are yielded in key order, as with a single listing:
+// the keys of a prefix sort between the files and prefixes around it.
+func (s S3Storage) shardedObjects(ctx context.Context, path string) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		units, err := s.shardUnits(ctx, path)
+		if err != nil {
+			yield(Item{}, err)
+			return
+		}
+
+		shards := make([]chan listResult, len(units))
+		for i, unit := range units {
+			if unit.prefix != "" {
+				shards[i] = make(chan listResult, shardBuffer)
+			}
+		}
+		// Shards start in key order, the shard read by the merged stream always holds a slot
+		go func() {
+			slots := make(chan struct{}, s.listShards)
+			for i, unit := range units {
+				if unit.prefix == "" {
+					continue
+				}
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				go func() {
+					defer func() {
+						close(shards[i])
+						<-slots
+					}()
+					input := &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket), Prefix: aws.String(unit.prefix), EncodingType: types.EncodingTypeUrl}
+					for item, err := range s.listPages(ctx, input, path) {
+						select {
+						case shards[i] <- listResult{item: item, err: err}:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+			}
+		}()
+
+		for i, unit := range units {
+			if unit.prefix == "" {
+				if !yield(unit.item, nil) {
+					return
+				}
+				continue
+			}
+			for {
+				var result listResult
+				var ok bool
+				select {
+				case result, ok = <-shards[i]:
+				case <-ctx.Done():
+					yield(Item{}, ctx.Err())
+					return
+				}
+				if !ok {
+					break
+				}
+				if !yield(result.item, result.err) || result.err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// shardUnits lists path with a delimiter and returns its files and common prefixes in key order.
+// A lone common prefix, such as the folder of a job, is descended into to find prefixes to shard on.
+func (s S3Storage) shardUnits(ctx context.Context, path string) ([]listUnit, error) {
+	prefix := path
+	var units []listUnit
+	for range maxShardDescent {
+		units = nil
+		paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+			Bucket:       aws.String(s.bucket),
+			Prefix:       aws.String(prefix),
+			Delimiter:    aws.String("/"),
+			EncodingType: types.EncodingTypeUrl,
+		})
+		for paginator.HasMorePages() {
+			resp, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("could not list items in S3 bucket %s: %w", s.bucket, err)
+			}
+			for _, object := range resp.Contents {
+				if item := listedItem(resp.EncodingType, object); item.Key != path {
+					units = append(units, listUnit{item: item})
+				}
+			}
+			for _, common := range resp.CommonPrefixes {
+				units = append(units, listUnit{prefix: decodeListKey(resp.EncodingType, aws.ToString(common.Prefix))})
+			}
+		}
+		if len(units) != 1 || units[0].prefix == "" {
+			break
+		}
+		prefix = units[0].prefix
+	}
+	slices.SortFunc(units, func(a, b listUnit) int { return cmp.Compare(a.key(), b.key()) })
+	return units, nil
+}
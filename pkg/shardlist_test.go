@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// bucketServer answers ListObjectsV2 requests over a fixed set of keys, with or without a delimiter
+func bucketServer(keys []string, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		query := r.URL.Query()
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		var body strings.Builder
+		var common []string
+		for _, key := range keys {
+			rest, ok := strings.CutPrefix(key, prefix)
+			if !ok {
+				continue
+			}
+			if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+				if p := prefix + rest[:i+1]; !slices.Contains(common, p) {
+					common = append(common, p)
+				}
+				continue
+			}
+			size := 1
+			if strings.HasSuffix(key, "/") {
+				size = 0
+			}
+			fmt.Fprintf(&body, `<Contents><Key>%s</Key><Size>%d</Size><LastModified>2025-01-01T00:00:00.000Z</LastModified></Contents>`, key, size)
+		}
+		for _, p := range common {
+			fmt.Fprintf(&body, `<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>`, p)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>bucket</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>%s</ListBucketResult>`,
+			prefix, body.String())
+	}))
+}
+
+func TestShardedObjects(t *testing.T) {
+	keys := []string{
+		"backups/", "backups/job/", "backups/job/2025-01/a.txt", "backups/job/2025-01/b.txt",
+		"backups/job/2025-02/", "backups/job/2025-02/c.txt", "backups/job/2025-02/deep/d.txt",
+		"backups/job/2025-03/e.txt", "backups/job/readme.txt", "backups/job/z/f.txt",
+	}
+	var requests atomic.Int32
+	server := bucketServer(keys, &requests)
+	defer server.Close()
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+
+	sequential, err := S3Storage{bucket: "bucket", client: client}.List(context.Background(), "backups", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests.Store(0)
+	sharded, err := S3Storage{bucket: "bucket", client: client, listShards: 3}.List(context.Background(), "backups", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sharded, sequential) {
+		t.Errorf("Sharded listing = %v, want %v", sharded, sequential)
+	}
+	// backups/ and backups/job/ with a delimiter, then one request per month and for z/
+	if got := requests.Load(); got != 6 {
+		t.Errorf("Expected 6 list requests, got %d", got)
+	}
+
+	// The consumer can stop early without waiting for the other shards
+	for item, err := range (S3Storage{bucket: "bucket", client: client, listShards: 2}).Objects(context.Background(), "backups", true) {
+		if err != nil || item.Key != "backups/job/" {
+			t.Errorf("First item = %v, %v", item.Key, err)
+		}
+		break
+	}
+}
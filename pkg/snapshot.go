@@ -0,0 +1,331 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+const (
+	snapshotLVM   = "lvm"
+	snapshotZFS   = "zfs"
+	snapshotBtrfs = "btrfs"
+	snapshotVSS   = "vss"
+	// snapshotPrefix names the snapshots taken by s3safe, followed by the time of the backup
+	snapshotPrefix = "s3safe-"
+	// lvmSnapshotSize is the copy-on-write space of LVM snapshots, relative to the size of the origin volume
+	lvmSnapshotSize = "10%ORIGIN"
+)
+
+var snapshotKinds = []string{snapshotLVM, snapshotZFS, snapshotBtrfs, snapshotVSS}
+
+// runSnapshotCommand runs a volume manager command and returns its trimmed output, replaced by tests
+var runSnapshotCommand = func(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// vssCreateScript creates a shadow copy of a volume and prints its ID and device path,
+// vssadmin only creates shadow copies on Windows Server
+const vssCreateScript = `$r = (Get-WmiObject -List Win32_ShadowCopy).Create('%s', 'ClientAccessible')
+if ($r.ReturnValue -ne 0) { throw "Win32_ShadowCopy.Create returned $($r.ReturnValue)" }
+$s = Get-WmiObject Win32_ShadowCopy -Filter "ID='$($r.ShadowID)'"
+$s.ID
+$s.DeviceObject`
+
+// snapshotSpec is a --snapshot value, kind:target such as lvm:vg/lv, zfs:pool/data, btrfs:/srv or vss:C:
+type snapshotSpec struct {
+	kind string
+	// target is the LVM volume group/logical volume, the ZFS dataset, the Btrfs subvolume or the Windows volume,
+	// the volume of the backed up directory when a VSS target is omitted
+	target string
+}
+
+// parseSnapshotSpec parses a --snapshot value
+func parseSnapshotSpec(value string) (snapshotSpec, error) {
+	kind, target, ok := strings.Cut(value, ":")
+	spec := snapshotSpec{kind: strings.ToLower(kind), target: strings.TrimSuffix(target, "/")}
+	if spec.kind == snapshotVSS {
+		if runtime.GOOS != "windows" {
+			return spec, errors.New("vss snapshots are only supported on Windows")
+		}
+		spec.target = strings.TrimRight(target, `\`)
+		return spec, nil
+	}
+	if !ok || spec.target == "" {
+		return spec, fmt.Errorf("invalid snapshot %q, expected kind:target such as lvm:vg/lv, zfs:pool/data, btrfs:/srv or vss", value)
+	}
+	if !slices.Contains(snapshotKinds, spec.kind) {
+		return spec, fmt.Errorf("invalid snapshot kind %q, supported values: %v", kind, snapshotKinds)
+	}
+	if spec.kind == snapshotLVM && strings.Count(spec.target, "/") != 1 {
+		return spec, fmt.Errorf("invalid LVM snapshot target %q, expected volume group/logical volume", spec.target)
+	}
+	if spec.kind == snapshotBtrfs && !filepath.IsAbs(spec.target) {
+		return spec, fmt.Errorf("invalid Btrfs snapshot target %q, expected the absolute path of a subvolume", spec.target)
+	}
+	return spec, nil
+}
+
+// snapshot is a snapshot taken for a backup
+type snapshot struct {
+	// path is the backed up directory in the snapshot
+	path string
+	// cleanup removes the snapshot, the steps are run in reverse order
+	cleanup []func(ctx context.Context) error
+}
+
+// remove runs the cleanup steps of the snapshot, a failed step doesn't stop the next ones
+func (s *snapshot) remove(ctx context.Context) error {
+	var errs []error
+	for _, step := range slices.Backward(s.cleanup) {
+		errs = append(errs, step(ctx))
+	}
+	s.cleanup = nil
+	return errors.Join(errs...)
+}
+
+// command adds a cleanup step running a command
+func (s *snapshot) command(name string, args ...string) {
+	s.cleanup = append(s.cleanup, func(ctx context.Context) error {
+		_, err := runSnapshotCommand(ctx, name, args...)
+		return err
+	})
+}
+
+// takeSnapshot takes a snapshot of the volume holding dir, named after the backup time.
+// The steps taken before a failure are undone.
+func takeSnapshot(ctx context.Context, spec snapshotSpec, dir, name string) (*snapshot, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{}
+	switch spec.kind {
+	case snapshotLVM:
+		err = snap.takeLVM(ctx, spec.target, dir, name)
+	case snapshotZFS:
+		err = snap.takeZFS(ctx, spec.target, dir, name)
+	case snapshotBtrfs:
+		err = snap.takeBtrfs(ctx, spec.target, dir, name)
+	case snapshotVSS:
+		err = snap.takeVSS(ctx, spec.target, dir)
+	}
+	if err != nil {
+		// The context may be canceled, the snapshot is removed anyway
+		return nil, errors.Join(fmt.Errorf("unable to take %s snapshot of %s: %w", spec.kind, spec.target, err), snap.remove(context.WithoutCancel(ctx)))
+	}
+	return snap, nil
+}
+
+// takeLVM creates a snapshot of the logical volume and mounts it read-only in a temporary directory
+func (s *snapshot) takeLVM(ctx context.Context, volume, dir, name string) error {
+	device := "/dev/" + volume
+	mountPoint, err := firstLine(runSnapshotCommand(ctx, "findmnt", "--noheadings", "--output", "TARGET", device))
+	if err != nil {
+		return err
+	}
+	rel, err := relativeTo(mountPoint, dir, volume)
+	if err != nil {
+		return err
+	}
+	fsType, err := firstLine(runSnapshotCommand(ctx, "findmnt", "--noheadings", "--output", "FSTYPE", device))
+	if err != nil {
+		return err
+	}
+	snapshotVolume := filepath.Dir(volume) + "/" + name
+	if _, err := runSnapshotCommand(ctx, "lvcreate", "--snapshot", "--extents", lvmSnapshotSize, "--name", name, volume); err != nil {
+		return err
+	}
+	s.command("lvremove", "--force", snapshotVolume)
+
+	tmp, err := os.MkdirTemp("", snapshotPrefix)
+	if err != nil {
+		return err
+	}
+	s.cleanup = append(s.cleanup, func(context.Context) error { return os.Remove(tmp) })
+	options := "ro"
+	// XFS refuses to mount a snapshot with the UUID of the mounted origin
+	if fsType == "xfs" {
+		options += ",nouuid"
+	}
+	if _, err := runSnapshotCommand(ctx, "mount", "-o", options, "/dev/"+snapshotVolume, tmp); err != nil {
+		return err
+	}
+	s.command("umount", tmp)
+	s.path = filepath.Join(tmp, rel)
+	return nil
+}
+
+// takeZFS creates a snapshot of the dataset, read through the .zfs/snapshot directory of its mount point
+func (s *snapshot) takeZFS(ctx context.Context, dataset, dir, name string) error {
+	mountPoint, err := runSnapshotCommand(ctx, "zfs", "get", "-H", "-o", "value", "mountpoint", dataset)
+	if err != nil {
+		return err
+	}
+	rel, err := relativeTo(mountPoint, dir, dataset)
+	if err != nil {
+		return err
+	}
+	if _, err := runSnapshotCommand(ctx, "zfs", "snapshot", dataset+"@"+name); err != nil {
+		return err
+	}
+	s.command("zfs", "destroy", dataset+"@"+name)
+	s.path = filepath.Join(mountPoint, ".zfs", "snapshot", name, rel)
+	return nil
+}
+
+// takeBtrfs creates a read-only snapshot of the subvolume next to it
+func (s *snapshot) takeBtrfs(ctx context.Context, subvolume, dir, name string) error {
+	rel, err := relativeTo(subvolume, dir, subvolume)
+	if err != nil {
+		return err
+	}
+	snapshotDir := filepath.Join(filepath.Dir(subvolume), "."+filepath.Base(subvolume)+"-"+name)
+	if _, err := runSnapshotCommand(ctx, "btrfs", "subvolume", "snapshot", "-r", subvolume, snapshotDir); err != nil {
+		return err
+	}
+	s.command("btrfs", "subvolume", "delete", snapshotDir)
+	s.path = filepath.Join(snapshotDir, rel)
+	return nil
+}
+
+// takeVSS creates a Volume Shadow Copy of the volume, read through its device path so locked files can be backed up.
+// Shadow copies are unnamed, they are identified by the ID returned on creation.
+func (s *snapshot) takeVSS(ctx context.Context, volume, dir string) error {
+	if volume == "" {
+		volume = vssVolume(dir)
+	}
+	rel, ok := vssRelative(volume, dir)
+	if !ok {
+		return fmt.Errorf("%s is not on volume %s", dir, volume)
+	}
+	output, err := runSnapshotCommand(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command",
+		fmt.Sprintf(vssCreateScript, volume+`\`))
+	if err != nil {
+		return err
+	}
+	id, device, _ := strings.Cut(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	if id == "" {
+		return errors.New("no shadow copy was created")
+	}
+	s.command("vssadmin", "delete", "shadows", "/shadow="+id, "/quiet")
+	device = strings.TrimSpace(device)
+	if device == "" {
+		return fmt.Errorf("shadow copy %s has no device path", id)
+	}
+	s.path = strings.TrimRight(device+`\`+rel, `\`)
+	return nil
+}
+
+// vssVolume returns the drive letter of a Windows path, such as C:
+func vssVolume(dir string) string {
+	if len(dir) >= 2 && dir[1] == ':' {
+		return strings.ToUpper(dir[:2])
+	}
+	return ""
+}
+
+// vssRelative returns the path of a Windows directory relative to its volume, such as Users\alice for C:\Users\alice
+func vssRelative(volume, dir string) (string, bool) {
+	if volume == "" || len(dir) < len(volume) || !strings.EqualFold(dir[:len(volume)], volume) {
+		return "", false
+	}
+	rest := dir[len(volume):]
+	if rest != "" && rest[0] != '\\' && rest[0] != '/' {
+		return "", false
+	}
+	return strings.Trim(strings.ReplaceAll(rest, "/", `\`), `\`), true
+}
+
+// relativeTo returns the path of dir relative to the mount point of the snapshotted volume
+func relativeTo(mountPoint, dir, volume string) (string, error) {
+	if mountPoint == "" || !filepath.IsAbs(mountPoint) {
+		return "", fmt.Errorf("%s is not mounted", volume)
+	}
+	rel, err := filepath.Rel(mountPoint, dir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is not on %s mounted on %s", dir, volume, mountPoint)
+	}
+	return rel, nil
+}
+
+// firstLine returns the first line of a command output
+func firstLine(output string, err error) (string, error) {
+	line, _, _ := strings.Cut(output, "\n")
+	return line, err
+}
+
+// backupSnapshot runs the backup from a snapshot of the volume given by --snapshot, removed once the backup is done
+func (bm *BackupManager) backupSnapshot(ctx context.Context) error {
+	if bm.config.Snapshot == "" {
+		return bm.backup(ctx)
+	}
+	spec, err := parseSnapshotSpec(bm.config.Snapshot)
+	if err != nil {
+		return err
+	}
+	name := snapshotPrefix + bm.config.now().Format("20060102-150405")
+	snap, err := takeSnapshot(ctx, spec, bm.config.Path, name)
+	if err != nil {
+		return err
+	}
+	bm.log().Info("Created snapshot", "kind", spec.kind, "target", spec.target, "path", snap.path)
+	bm.source = snap.path
+	defer func() { bm.source = "" }()
+
+	err = bm.backup(ctx)
+	if removeErr := snap.remove(context.WithoutCancel(ctx)); removeErr != nil {
+		return errors.Join(err, fmt.Errorf("unable to remove %s snapshot %s: %w", spec.kind, name, removeErr))
+	}
+	bm.log().Info("Removed snapshot", "kind", spec.kind, "target", spec.target)
+	return err
+}
+
+// sourcePath returns the directory the backed up files are read from, the snapshot of --path with --snapshot
+func (bm *BackupManager) sourcePath() string {
+	if bm.source != "" {
+		return bm.source
+	}
+	return bm.config.Path
+}
@@ -0,0 +1,261 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeSnapshotCommands replaces the volume manager commands, outputs maps a command line to its output
+func fakeSnapshotCommands(t *testing.T, outputs map[string]string, failing string) *[]string {
+	t.Helper()
+	var commands []string
+	previous := runSnapshotCommand
+	runSnapshotCommand = func(_ context.Context, name string, args ...string) (string, error) {
+		line := strings.Join(append([]string{name}, args...), " ")
+		commands = append(commands, line)
+		if name == failing {
+			return "", errors.New("command failed")
+		}
+		return outputs[line], nil
+	}
+	t.Cleanup(func() { runSnapshotCommand = previous })
+	return &commands
+}
+
+func TestParseSnapshotSpec(t *testing.T) {
+	for value, want := range map[string]snapshotSpec{
+		"lvm:vg/data":    {kind: snapshotLVM, target: "vg/data"},
+		"ZFS:tank/home/": {kind: snapshotZFS, target: "tank/home"},
+		"btrfs:/srv":     {kind: snapshotBtrfs, target: "/srv"},
+	} {
+		got, err := parseSnapshotSpec(value)
+		if err != nil || got != want {
+			t.Errorf("parseSnapshotSpec(%q) = %+v, %v, want %+v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "lvm", "lvm:", "lvm:data", "lvm:vg/data/extra", "btrfs:srv", "lvm2:vg/data"} {
+		if _, err := parseSnapshotSpec(value); err == nil {
+			t.Errorf("parseSnapshotSpec(%q) is valid", value)
+		}
+	}
+	spec, err := parseSnapshotSpec(`vss:D:\`)
+	if runtime.GOOS == "windows" {
+		if err != nil || spec != (snapshotSpec{kind: snapshotVSS, target: "D:"}) {
+			t.Errorf("parseSnapshotSpec(vss:D:) = %+v, %v", spec, err)
+		}
+	} else if err == nil {
+		t.Error("vss snapshots are accepted on " + runtime.GOOS)
+	}
+}
+
+func TestVSSRelative(t *testing.T) {
+	for _, test := range []struct {
+		volume, dir, rel string
+		ok               bool
+	}{
+		{"C:", `C:\Users\alice`, `Users\alice`, true},
+		{"C:", `c:/Users/alice/`, `Users\alice`, true},
+		{"C:", `C:\`, "", true},
+		{"C:", `D:\Users`, "", false},
+		{"", `C:\Users`, "", false},
+	} {
+		rel, ok := vssRelative(test.volume, test.dir)
+		if rel != test.rel || ok != test.ok {
+			t.Errorf("vssRelative(%q, %q) = %q, %v, want %q, %v", test.volume, test.dir, rel, ok, test.rel, test.ok)
+		}
+	}
+	if got := vssVolume(`d:\data`); got != "D:" {
+		t.Errorf("vssVolume = %q, want D:", got)
+	}
+}
+
+func TestTakeVSSSnapshot(t *testing.T) {
+	commands := fakeSnapshotCommands(t, nil, "")
+	previous := runSnapshotCommand
+	runSnapshotCommand = func(ctx context.Context, name string, args ...string) (string, error) {
+		output, err := previous(ctx, name, args...)
+		if name == "powershell" {
+			if !strings.Contains(args[len(args)-1], `Create('C:\', 'ClientAccessible')`) {
+				t.Errorf("script = %q", args[len(args)-1])
+			}
+			return "{8A3B}\r\n\\\\?\\GLOBALROOT\\Device\\HarddiskVolumeShadowCopy3", err
+		}
+		return output, err
+	}
+	snap := &snapshot{}
+	if err := snap.takeVSS(context.Background(), "", `C:\Users\alice`); err != nil {
+		t.Fatalf("takeVSS: %v", err)
+	}
+	if want := `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3\Users\alice`; snap.path != want {
+		t.Errorf("snapshot path = %q, want %q", snap.path, want)
+	}
+	if err := snap.remove(context.Background()); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := (*commands)[len(*commands)-1]; got != "vssadmin delete shadows /shadow={8A3B} /quiet" {
+		t.Errorf("cleanup = %q", got)
+	}
+}
+
+func TestTakeZFSSnapshot(t *testing.T) {
+	commands := fakeSnapshotCommands(t, map[string]string{
+		"zfs get -H -o value mountpoint tank/home": "/home",
+	}, "")
+	snap, err := takeSnapshot(context.Background(), snapshotSpec{kind: snapshotZFS, target: "tank/home"}, "/home/alice", "s3safe-20250102-030405")
+	if err != nil {
+		t.Fatalf("takeSnapshot: %v", err)
+	}
+	if want := filepath.Join("/home", ".zfs", "snapshot", "s3safe-20250102-030405", "alice"); snap.path != want {
+		t.Errorf("snapshot path = %q, want %q", snap.path, want)
+	}
+	if err := snap.remove(context.Background()); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	want := []string{
+		"zfs get -H -o value mountpoint tank/home",
+		"zfs snapshot tank/home@s3safe-20250102-030405",
+		"zfs destroy tank/home@s3safe-20250102-030405",
+	}
+	if !slices.Equal(*commands, want) {
+		t.Errorf("commands = %q, want %q", *commands, want)
+	}
+}
+
+func TestTakeSnapshotOutsideVolume(t *testing.T) {
+	commands := fakeSnapshotCommands(t, map[string]string{
+		"zfs get -H -o value mountpoint tank/home": "/home",
+	}, "")
+	if _, err := takeSnapshot(context.Background(), snapshotSpec{kind: snapshotZFS, target: "tank/home"}, "/srv", "s3safe-1"); err == nil {
+		t.Fatal("a directory outside the dataset is snapshotted")
+	}
+	if len(*commands) != 1 {
+		t.Errorf("commands = %q, no snapshot should be taken", *commands)
+	}
+}
+
+func TestTakeLVMSnapshotFailureCleanup(t *testing.T) {
+	commands := fakeSnapshotCommands(t, map[string]string{
+		"findmnt --noheadings --output TARGET /dev/vg/data": "/data",
+		"findmnt --noheadings --output FSTYPE /dev/vg/data": "xfs",
+	}, "mount")
+	if _, err := takeSnapshot(context.Background(), snapshotSpec{kind: snapshotLVM, target: "vg/data"}, "/data/www", "s3safe-1"); err == nil {
+		t.Fatal("a failed mount is ignored")
+	}
+	got := *commands
+	if len(got) != 5 {
+		t.Fatalf("commands = %q", got)
+	}
+	if got[2] != "lvcreate --snapshot --extents 10%ORIGIN --name s3safe-1 vg/data" {
+		t.Errorf("lvcreate = %q", got[2])
+	}
+	if !strings.HasPrefix(got[3], "mount -o ro,nouuid /dev/vg/s3safe-1 ") {
+		t.Errorf("mount = %q", got[3])
+	}
+	if got[4] != "lvremove --force vg/s3safe-1" {
+		t.Errorf("the snapshot is not removed after a failed mount: %q", got[4])
+	}
+	mountPoint := strings.TrimPrefix(got[3], "mount -o ro,nouuid /dev/vg/s3safe-1 ")
+	if _, err := os.Stat(mountPoint); !os.IsNotExist(err) {
+		t.Errorf("the mount point %s is not removed: %v", mountPoint, err)
+	}
+}
+
+func TestBackupFromSnapshot(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, keys := uploadServer(t)
+	defer server.Close()
+
+	root := t.TempDir()
+	subvolume := filepath.Join(root, "srv")
+	src := filepath.Join(subvolume, "data")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "created-after-snapshot.txt"), []byte("live"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commands := fakeSnapshotCommands(t, nil, "")
+	previous := runSnapshotCommand
+	runSnapshotCommand = func(ctx context.Context, name string, args ...string) (string, error) {
+		// The snapshot holds the files at the time it is taken
+		if len(args) > 2 && args[1] == "snapshot" {
+			dir := filepath.Join(args[len(args)-1], "data")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return "", err
+			}
+			if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("snapshot"), 0o644); err != nil {
+				return "", err
+			}
+		}
+		return previous(ctx, name, args...)
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.Snapshot = "btrfs:" + subvolume
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatalf("NewBackupManagerFromConfig: %v", err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if result.Files != 1 || result.Bytes != 8 {
+		t.Errorf("result = %+v, want the file of the snapshot", result)
+	}
+	if got := keys(); !slices.Equal(got, []string{"backups/a.txt"}) {
+		t.Errorf("uploaded keys = %v", got)
+	}
+	if len(*commands) != 2 || !strings.HasPrefix((*commands)[1], "btrfs subvolume delete "+filepath.Join(root, ".srv-s3safe-")) {
+		t.Errorf("commands = %q, want the snapshot taken and deleted", *commands)
+	}
+	if bm.sourcePath() != src {
+		t.Errorf("source path = %q after the backup, want %q", bm.sourcePath(), src)
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", Snapshot: "zfs:tank/data"})
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	cfg.DeleteSource, cfg.Verify = true, true
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected --delete-source to be rejected with --snapshot")
+	}
+	cfg.DeleteSource = false
+	cfg.Snapshot = "lvm:data"
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected an invalid snapshot to be rejected")
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Sparse files are stored as PAX format 1.0 GNU sparse entries, archive/tar reads them but cannot write them
+const (
+	tarBlockSize = 512
+	// sparseMajorRecord is present in the PAX records of sparse entries
+	sparseMajorRecord = "GNU.sparse.major"
+	// maxSparseMap is the largest sparse map readers accept
+	maxSparseMap = 1 << 20
+)
+
+// sparseSegment is a data region of a sparse file
+type sparseSegment struct {
+	Offset int64
+	Length int64
+}
+
+// sparseMap encodes the data regions of a file of size bytes, padded to the tar block size
+func sparseMap(segments []sparseSegment, size int64) []byte {
+	// A trailing hole is recorded as an empty region at the end of the file
+	if n := len(segments); n == 0 || segments[n-1].Offset+segments[n-1].Length < size {
+		segments = append(segments[:n:n], sparseSegment{Offset: size})
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\n", len(segments))
+	for _, s := range segments {
+		fmt.Fprintf(&buf, "%d\n%d\n", s.Offset, s.Length)
+	}
+	buf.Write(make([]byte, blockPadding(int64(buf.Len()))))
+	return buf.Bytes()
+}
+
+// writeSparseEntry writes a file as a GNU sparse entry, w must be positioned at a block boundary of the archive
+func writeSparseEntry(w io.Writer, header *tar.Header, file *os.File, segments []sparseSegment) error {
+	spMap := sparseMap(segments, header.Size)
+	if len(spMap) > maxSparseMap {
+		return fmt.Errorf("sparse map of %s is too large", header.Name)
+	}
+	size := int64(len(spMap))
+	for _, s := range segments {
+		size += s.Length
+	}
+	dir, base := path.Split(header.Name)
+	records := map[string]string{
+		sparseMajorRecord:     "1",
+		"GNU.sparse.minor":    "0",
+		"GNU.sparse.name":     header.Name,
+		"GNU.sparse.realsize": strconv.FormatInt(header.Size, 10),
+		"size":                strconv.FormatInt(size, 10),
+		"mtime":               strconv.FormatInt(header.ModTime.Unix(), 10),
+		"uid":                 strconv.Itoa(header.Uid),
+		"gid":                 strconv.Itoa(header.Gid),
+	}
+	if header.Uname != "" {
+		records["uname"] = header.Uname
+	}
+	if header.Gname != "" {
+		records["gname"] = header.Gname
+	}
+	// Records of the entry such as its SELinux context
+	for key, value := range header.PAXRecords {
+		records[key] = value
+	}
+	pax := paxRecords(records)
+	if _, err := w.Write(tarHeader(path.Join(dir, "PaxHeaders.0", base), tar.TypeXHeader, int64(len(pax)), header)); err != nil {
+		return err
+	}
+	if _, err := w.Write(pax); err != nil {
+		return err
+	}
+	if _, err := w.Write(make([]byte, blockPadding(int64(len(pax))))); err != nil {
+		return err
+	}
+	if _, err := w.Write(tarHeader(path.Join(dir, "GNUSparseFile.0", base), tar.TypeReg, size, header)); err != nil {
+		return err
+	}
+	if _, err := w.Write(spMap); err != nil {
+		return err
+	}
+	var written int64
+	for _, s := range segments {
+		n, err := io.Copy(w, io.NewSectionReader(file, s.Offset, s.Length))
+		if err != nil {
+			return err
+		}
+		if n != s.Length {
+			return fmt.Errorf("%s changed while archiving", header.Name)
+		}
+		written += n
+	}
+	_, err := w.Write(make([]byte, blockPadding(written)))
+	return err
+}
+
+// paxRecords formats PAX extended header records, sorted by key
+func paxRecords(records map[string]string) []byte {
+	keys := make([]string, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		// The record length includes its own decimal digits
+		line := " " + k + "=" + records[k] + "\n"
+		n := len(line) + len(strconv.Itoa(len(line)))
+		if len(strconv.Itoa(n)) != len(strconv.Itoa(len(line))) {
+			n++
+		}
+		buf.WriteString(strconv.Itoa(n) + line)
+	}
+	return buf.Bytes()
+}
+
+// tarHeader builds a ustar header block, fields that do not fit are carried by the PAX records
+func tarHeader(name string, typeflag byte, size int64, header *tar.Header) []byte {
+	blk := make([]byte, tarBlockSize)
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	copy(blk[0:100], name)
+	formatOctal(blk[100:108], header.Mode&0o7777)
+	formatOctal(blk[108:116], int64(header.Uid))
+	formatOctal(blk[116:124], int64(header.Gid))
+	formatOctal(blk[124:136], size)
+	formatOctal(blk[136:148], header.ModTime.Unix())
+	blk[156] = typeflag
+	copy(blk[257:265], "ustar\x0000")
+	copy(blk[265:297], truncate(header.Uname, 32))
+	copy(blk[297:329], truncate(header.Gname, 32))
+
+	// The checksum is computed with the checksum field filled with spaces
+	copy(blk[148:156], "        ")
+	var sum int64
+	for _, c := range blk {
+		sum += int64(c)
+	}
+	copy(blk[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	return blk
+}
+
+// formatOctal writes v as a NUL terminated octal number, leaving the field zeroed when it does not fit
+func formatOctal(field []byte, v int64) {
+	s := strconv.FormatInt(v, 8)
+	if v < 0 || len(s) > len(field)-1 {
+		return
+	}
+	copy(field, fmt.Sprintf("%0*s", len(field)-1, s))
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// blockPadding returns the bytes needed to pad n to the tar block size
+func blockPadding(n int64) int64 {
+	return -n & (tarBlockSize - 1)
+}
+
+// isSparseEntry reports whether a tar entry was stored as a sparse file
+func isSparseEntry(header *tar.Header) bool {
+	_, ok := header.PAXRecords[sparseMajorRecord]
+	return ok
+}
+
+// copySparse writes size bytes from r to file, seeking over zero blocks to recreate holes
+func copySparse(file *os.File, r io.Reader, size int64) error {
+	buf := make([]byte, 64*1024)
+	zero := make([]byte, len(buf))
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zero[:n]) {
+				if _, err := file.Seek(int64(n), io.SeekCurrent); err != nil {
+					return err
+				}
+			} else if _, err := file.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Truncate sets the size of a file ending in a hole
+	return file.Truncate(size)
+}
@@ -0,0 +1,185 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteSparseEntry(t *testing.T) {
+	const size = 3 << 20
+	file, err := os.Create(filepath.Join(t.TempDir(), "disk.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data := bytes.Repeat([]byte("s3safe"), 100)
+	if _, err := file.WriteAt(data, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	header := &tar.Header{Name: "images/disk.img", Mode: 0o640, Size: size, ModTime: time.Unix(1700000000, 0), Uid: 1000, Gid: 1000}
+	segments := []sparseSegment{{Offset: 1 << 20, Length: int64(len(data))}}
+	if err := writeSparseEntry(&buf, header, file, segments); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len()%tarBlockSize != 0 {
+		t.Fatalf("entry of %d bytes is not block aligned", buf.Len())
+	}
+	if buf.Len() > 6*tarBlockSize {
+		t.Errorf("sparse entry takes %d bytes", buf.Len())
+	}
+
+	tr := tar.NewReader(&buf)
+	got, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != header.Name || got.Size != size || got.Mode != 0o640 || !got.ModTime.Equal(header.ModTime) || got.Uid != 1000 {
+		t.Errorf("unexpected header %+v", got)
+	}
+	if !isSparseEntry(got) {
+		t.Error("entry is not sparse")
+	}
+	content, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, size)
+	copy(want[1<<20:], data)
+	if !bytes.Equal(content, want) {
+		t.Error("sparse content does not match")
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("expected end of archive, got %v", err)
+	}
+}
+
+func TestSparseRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	path := filepath.Join(src, "disk.img")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt([]byte("header"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt([]byte("footer"), 8<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Truncate(16 << 20); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := os.WriteFile(filepath.Join(src, "notes.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := compressDirectory(src, archive, archiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		f, _ := os.Open(path)
+		_, sparse, _ := dataSegments(f, info)
+		f.Close()
+		if sparse && !archiveHasSparseEntry(t, archive) {
+			t.Error("sparse file was not stored as a sparse entry")
+		}
+	}
+	dest := t.TempDir()
+	if err := decompressDirectory(archive, dest, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := os.ReadFile(path)
+	got, err := os.ReadFile(filepath.Join(dest, "disk.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("restored sparse file does not match")
+	}
+	if notes, _ := os.ReadFile(filepath.Join(dest, "notes.txt")); string(notes) != "hello" {
+		t.Errorf("unexpected notes %q", notes)
+	}
+}
+
+func archiveHasSparseEntry(t *testing.T, archive string) bool {
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isSparseEntry(header) {
+			return true
+		}
+	}
+}
+
+func TestCopySparse(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content := make([]byte, 300<<10)
+	copy(content[100<<10:], "data")
+	if err := copySparse(file, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("copied content does not match")
+	}
+}
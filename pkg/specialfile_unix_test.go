@@ -0,0 +1,119 @@
+//go:build linux || darwin
+
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"archive/tar"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// specialTree creates a directory with a regular file, a FIFO and a socket
+func specialTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "data.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	// Socket paths are limited to about 100 bytes, the directory is changed to keep the path short
+	t.Chdir(root)
+	listener, err := net.Listen("unix", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	return root
+}
+
+func TestCompressDirectorySkipsSpecialFiles(t *testing.T) {
+	root := specialTree(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	archived, err := compressDirectory(root, archive, archiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := archiveEntries(t, archive)
+	if archived != 1 || len(entries) != 1 || entries["data.txt"] != tar.TypeReg {
+		t.Errorf("archived %d entries %v, want only data.txt", archived, entries)
+	}
+}
+
+func TestCompressDirectoryIncludeSpecial(t *testing.T) {
+	root := specialTree(t)
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	archived, err := compressDirectory(root, archive, archiveOptions{includeSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sockets cannot be archived
+	entries := archiveEntries(t, archive)
+	if archived != 2 || len(entries) != 2 || entries["pipe"] != tar.TypeFifo {
+		t.Fatalf("archived %d entries %v, want data.txt and pipe", archived, entries)
+	}
+
+	dest := t.TempDir()
+	if err := decompressDirectory(archive, dest, false); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(filepath.Join(dest, "pipe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 || info.Mode().Perm() != 0o640 {
+		t.Errorf("pipe mode = %v, want a FIFO with mode 0640", info.Mode())
+	}
+}
+
+func TestProcessFileForUploadSkipsSpecialFiles(t *testing.T) {
+	root := specialTree(t)
+	bm := &BackupManager{config: &Config{Path: root}}
+	for _, name := range []string{"pipe", "sock"} {
+		if err := bm.processFileForUpload(context.Background(), Item{Key: name}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if bm.result.Skipped != 2 {
+		t.Errorf("skipped = %d, want 2", bm.result.Skipped)
+	}
+}
+
+func TestValidateIncludeSpecial(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", IncludeSpecial: true})
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected --include-special without --compress to be rejected")
+	}
+	cfg.Compress = true
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/jkaninda/s3safe/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StorageClassRule assigns a storage class to files matching a condition.
+// Conditions are a size comparison (size>1GB), an age comparison (age>30d) or a file name pattern (*.json).
+type StorageClassRule struct {
+	Field        string
+	Operator     byte
+	Size         int64
+	Age          time.Duration
+	Pattern      string
+	StorageClass string
+}
+
+// ParseStorageClassRules parses comma-separated rules such as "size>1GB:GLACIER,*.json:STANDARD"
+func ParseStorageClassRules(rules string) ([]StorageClassRule, error) {
+	var parsed []StorageClassRule
+	for _, raw := range strings.Split(rules, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		rule, err := parseStorageClassRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, rule)
+	}
+	return parsed, nil
+}
+
+func parseStorageClassRule(raw string) (StorageClassRule, error) {
+	idx := strings.LastIndex(raw, ":")
+	if idx <= 0 || idx == len(raw)-1 {
+		return StorageClassRule{}, fmt.Errorf("invalid storage class rule %q, expected condition:CLASS", raw)
+	}
+	condition := strings.TrimSpace(raw[:idx])
+	rule := StorageClassRule{StorageClass: strings.ToUpper(strings.TrimSpace(raw[idx+1:]))}
+	if !isValidStorageClass(rule.StorageClass) {
+		return StorageClassRule{}, fmt.Errorf("invalid storage class %q in rule %q", rule.StorageClass, raw)
+	}
+
+	opIdx := strings.IndexAny(condition, "<>")
+	field := ""
+	if opIdx > 0 {
+		field = strings.ToLower(condition[:opIdx])
+	}
+	switch field {
+	case "size":
+		size, err := goutils.ConvertToBytes(condition[opIdx+1:])
+		if err != nil {
+			return StorageClassRule{}, fmt.Errorf("invalid size in rule %q: %w", raw, err)
+		}
+		rule.Field, rule.Operator, rule.Size = field, condition[opIdx], size
+	case "age":
+		age, err := utils.ParseDuration(condition[opIdx+1:])
+		if err != nil {
+			return StorageClassRule{}, fmt.Errorf("invalid age in rule %q: %w", raw, err)
+		}
+		rule.Field, rule.Operator, rule.Age = field, condition[opIdx], age
+	default:
+		if _, err := filepath.Match(condition, ""); err != nil {
+			return StorageClassRule{}, fmt.Errorf("invalid pattern in rule %q: %w", raw, err)
+		}
+		rule.Field, rule.Pattern = "pattern", condition
+	}
+	return rule, nil
+}
+
+// Match reports whether the file matches the rule condition
+func (r StorageClassRule) Match(name string, info os.FileInfo) bool {
+	switch r.Field {
+	case "size":
+		return compare(r.Operator, info.Size(), r.Size)
+	case "age":
+		return compare(r.Operator, int64(time.Since(info.ModTime())), int64(r.Age))
+	default:
+		matched, _ := filepath.Match(r.Pattern, filepath.Base(name))
+		return matched
+	}
+}
+
+func compare(operator byte, value, limit int64) bool {
+	if operator == '<' {
+		return value < limit
+	}
+	return value > limit
+}
+
+// storageClassFor returns the storage class of the first matching rule, or the default class
+func storageClassFor(rules []StorageClassRule, defaultClass, path string) string {
+	if len(rules) == 0 {
+		return defaultClass
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return defaultClass
+	}
+	for _, rule := range rules {
+		if rule.Match(path, info) {
+			return rule.StorageClass
+		}
+	}
+	return defaultClass
+}
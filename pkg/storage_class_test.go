@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseStorageClassRules(t *testing.T) {
+	rules, err := ParseStorageClassRules("size>1GB:GLACIER, *.json:standard,age>30d:STANDARD_IA")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(rules))
+	}
+	if rules[0].Field != "size" || rules[0].Size != 1000*1000*1000 || rules[0].StorageClass != "GLACIER" {
+		t.Errorf("Unexpected size rule: %+v", rules[0])
+	}
+	if rules[1].Field != "pattern" || rules[1].Pattern != "*.json" || rules[1].StorageClass != "STANDARD" {
+		t.Errorf("Unexpected pattern rule: %+v", rules[1])
+	}
+
+	for _, invalid := range []string{"size>1GB", "size>big:GLACIER", "*.json:COLD"} {
+		if _, err := ParseStorageClassRules(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestStorageClassFor(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.json")
+	archive := filepath.Join(dir, "data.tar.gz")
+	if err := os.WriteFile(manifest, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := ParseStorageClassRules("*.json:STANDARD,size>1KB:GLACIER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class := storageClassFor(rules, "STANDARD_IA", manifest); class != "STANDARD" {
+		t.Errorf("Expected STANDARD for manifest, got %s", class)
+	}
+	if class := storageClassFor(rules, "STANDARD_IA", archive); class != "GLACIER" {
+		t.Errorf("Expected GLACIER for archive, got %s", class)
+	}
+	if class := storageClassFor(nil, "STANDARD_IA", archive); class != "STANDARD_IA" {
+		t.Errorf("Expected default class without rules, got %s", class)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+)
+
+// RunSummary is the result of a backup, restore or check, printed on stdout with --format json or csv
+type RunSummary struct {
+	Operation string `json:"operation"`
+	// Path and Dest are the source and destination of the run, as configured
+	Path    string `json:"path"`
+	Dest    string `json:"dest,omitempty"`
+	Archive string `json:"archive,omitempty"`
+	// Files is the number of transferred files, or the number of entries of a checked archive
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	Skipped    int    `json:"skipped"`
+	Failed     int    `json:"failed"`
+	DurationMs int64  `json:"duration_ms"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// withError completes the summary with the outcome of the run
+func (s RunSummary) withError(err error) RunSummary {
+	s.ExitCode = ExitCode(err)
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// writeRunSummary writes the summary as JSON or CSV, the text format is left to the logs
+func writeRunSummary(out io.Writer, summary RunSummary, format string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"operation", "path", "dest", "archive", "files", "bytes", "skipped", "failed", "duration_ms", "exit_code", "error"})
+		_ = w.Write([]string{summary.Operation, summary.Path, summary.Dest, summary.Archive, strconv.Itoa(summary.Files),
+			strconv.FormatInt(summary.Bytes, 10), strconv.Itoa(summary.Skipped), strconv.Itoa(summary.Failed),
+			strconv.FormatInt(summary.DurationMs, 10), strconv.Itoa(summary.ExitCode), summary.Error})
+		w.Flush()
+		return w.Error()
+	}
+	return nil
+}
+
+// writeJSONLines writes one JSON object per line, so listings can be piped into jq or read line by line
+func writeJSONLines[T any](out io.Writer, entries []T) error {
+	encoder := json.NewEncoder(out)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bannerOutput returns where the intro message is printed, stderr when stdout is read by another program
+func bannerOutput(format string) io.Writer {
+	if format == formatJSON || format == formatCSV {
+		return os.Stderr
+	}
+	return os.Stdout
+}
@@ -0,0 +1,84 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWriteRunSummary(t *testing.T) {
+	summary := RunSummary{Operation: operationBackup, Path: "/data", Dest: "backups", Files: 2, Bytes: 11, Skipped: 1, DurationMs: 1500}
+
+	var out bytes.Buffer
+	if err := writeRunSummary(&out, summary.withError(nil), formatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "operation,path,dest,archive,files,bytes,skipped,failed,duration_ms,exit_code,error\n" +
+		"backup,/data,backups,,2,11,1,0,1500,0,\n"
+	if out.String() != want {
+		t.Errorf("CSV summary = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeRunSummary(&out, summary.withError(withExitCode(ExitPartial, errors.New("1 file failed"))), formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded RunSummary
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ExitCode != ExitPartial || decoded.Error != "1 file failed" || decoded.Files != 2 {
+		t.Errorf("JSON summary = %+v", decoded)
+	}
+
+	out.Reset()
+	if err := writeRunSummary(&out, summary, formatText); err != nil || out.Len() != 0 {
+		t.Errorf("the text summary is printed: %q, %v", out.String(), err)
+	}
+}
+
+func TestBannerOutput(t *testing.T) {
+	if bannerOutput(formatCSV) != os.Stderr || bannerOutput(formatJSON) != os.Stderr {
+		t.Error("the intro message is printed with the CSV or JSON output")
+	}
+	if bannerOutput("") != os.Stdout || bannerOutput(formatText) != os.Stdout {
+		t.Error("the intro message is not printed on stdout")
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", Format: formatCSV})
+	if err := cfg.validateOptions(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	cfg.Format = "xlsx"
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
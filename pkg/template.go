@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PathTemplate holds the variables available in path templates,
+// e.g. --dest 'backups/{{ .Hostname }}/{{ .Date "2006/01/02" }}'
+type PathTemplate struct {
+	Hostname string
+	Job      string
+	Year     string
+	Month    string
+	Day      string
+	Now      time.Time
+}
+
+// Date formats the current time with a Go time layout
+func (p PathTemplate) Date(layout string) string {
+	return p.Now.Format(layout)
+}
+
+// Env returns the value of an environment variable
+func (p PathTemplate) Env(key string) string {
+	return os.Getenv(key)
+}
+
+// NameTemplate holds the variables available in archive name templates,
+// e.g. --name-template '{{ .Base }}-{{ .Host }}-{{ .Timestamp }}.tar.gz'
+type NameTemplate struct {
+	PathTemplate
+	// Base is the base name of the backed up directory
+	Base string
+	// Host is an alias of Hostname
+	Host string
+	// Timestamp is the current time formatted with the timestamp format
+	Timestamp string
+}
+
+func newPathTemplate(job string, now time.Time) PathTemplate {
+	hostname, _ := os.Hostname()
+	return PathTemplate{
+		Hostname: hostname,
+		Job:      job,
+		Year:     now.Format("2006"),
+		Month:    now.Format("01"),
+		Day:      now.Format("02"),
+		Now:      now,
+	}
+}
+
+// expand renders a path template, values without template actions are returned as is
+func (p PathTemplate) expand(value string) (string, error) {
+	return renderTemplate(value, p)
+}
+
+// expand renders an archive name template, the result must be a file name
+func (n NameTemplate) expand(value string) (string, error) {
+	name, err := renderTemplate(value, n)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid name template %q, it must render a file name, got %q", value, name)
+	}
+	return name, nil
+}
+
+func renderTemplate(value string, data any) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	tmpl, err := template.New("path").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", value, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", value, err)
+	}
+	return b.String(), nil
+}
+
+// expandTemplates renders the path, destination and mirror templates
+func (c *Config) expandTemplates() error {
+	p := newPathTemplate(c.Job, c.now())
+	var err error
+	if c.Path, err = p.expand(c.Path); err != nil {
+		return err
+	}
+	if c.Dest, err = p.expand(c.Dest); err != nil {
+		return err
+	}
+	for i, mirror := range c.Mirrors {
+		if c.Mirrors[i], err = p.expand(mirror); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPathTemplateExpand(t *testing.T) {
+	t.Setenv("S3SAFE_TEST_ENV", "prod")
+	p := PathTemplate{Hostname: "web-1", Job: "db", Year: "2025", Month: "06", Day: "01", Now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	tests := map[string]string{
+		"backups/static": "backups/static",
+		`backups/{{ .Hostname }}/{{ .Date "2006/01/02" }}`:                              "backups/web-1/2025/06/01",
+		"{{ .Env \"S3SAFE_TEST_ENV\" }}/{{ .Job }}/{{ .Year }}-{{ .Month }}-{{ .Day }}": "prod/db/2025-06-01",
+	}
+	for value, expected := range tests {
+		got, err := p.expand(value)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", value, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+
+	for _, invalid := range []string{"{{ .Hostname", "{{ .Unknown }}"} {
+		if _, err := p.expand(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestNameTemplateExpand(t *testing.T) {
+	n := NameTemplate{PathTemplate: PathTemplate{Hostname: "web-1"}, Base: "data", Host: "web-1", Timestamp: "2025-06-01_12-00-00"}
+	name, err := n.expand("{{ .Base }}-{{ .Host }}-{{ .Timestamp }}.tar.gz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name != "data-web-1-2025-06-01_12-00-00.tar.gz" {
+		t.Errorf("Unexpected name %q", name)
+	}
+
+	if _, err := n.expand("{{ .Hostname }}/{{ .Base }}.tar.gz"); err == nil {
+		t.Errorf("Expected a name with a path separator to be rejected")
+	}
+}
@@ -0,0 +1,301 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/cobra"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultThawDays     = 7
+	defaultPollInterval = 15 * time.Minute
+)
+
+// archiveStorageClasses are the storage classes that require a restore request before download
+var archiveStorageClasses = []string{string(types.StorageClassGlacier), string(types.StorageClassDeepArchive)}
+
+// ThawManager handles restore requests for objects archived in Glacier and Deep Archive
+type ThawManager struct {
+	config    *Config
+	s3Storage *S3Storage
+}
+
+// ArchiveStatus describes the retrieval state of an object
+type ArchiveStatus struct {
+	Key          string
+	StorageClass string
+	Requested    bool
+	Ready        bool
+	Expiry       string
+}
+
+// Thaw is the cobra command handler for thaw
+func Thaw(cmd *cobra.Command) error {
+	tm, err := NewThawManager(cmd)
+	if err != nil {
+		return err
+	}
+	return tm.Thaw(cmd.Context())
+}
+
+// ThawStatus is the cobra command handler for thaw-status
+func ThawStatus(cmd *cobra.Command) error {
+	tm, err := NewThawManager(cmd)
+	if err != nil {
+		return err
+	}
+	return tm.Status(cmd.Context())
+}
+
+// NewThawManager creates a new ThawManager instance
+func NewThawManager(cmd *cobra.Command) (*ThawManager, error) {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+
+	config.Path = strings.TrimPrefix(filepath.ToSlash(config.Path), "/")
+	if config.Days <= 0 {
+		config.Days = defaultThawDays
+	}
+	if config.Tier == "" {
+		config.Tier = string(types.TierStandard)
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+
+	return &ThawManager{
+		config:    config,
+		s3Storage: s3Storage,
+	}, nil
+}
+
+// Thaw issues restore requests for archived objects, optionally waiting until they are retrievable
+func (tm *ThawManager) Thaw(ctx context.Context) error {
+	intro()
+	keys, err := tm.archivedKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		slog.Info("No archived objects found", "path", tm.config.Path)
+		return nil
+	}
+
+	// Only the objects whose restore was requested are waited for
+	requested := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if err := tm.s3Storage.RestoreArchived(ctx, key, int32(tm.config.Days), tm.config.Tier); err != nil {
+			if tm.config.IgnoreErrors {
+				slog.Warn("Ignoring error", "error", err)
+				continue
+			}
+			return err
+		}
+		requested = append(requested, key)
+		slog.Info("Restore requested", "key", key, "tier", tm.config.Tier, "days", tm.config.Days)
+	}
+
+	if tm.config.Wait {
+		if err := tm.wait(ctx, requested); err != nil {
+			return err
+		}
+	} else {
+		slog.Info("Restore requests submitted, use thaw-status to follow progress", "objects", len(requested))
+	}
+	if failed := len(keys) - len(requested); failed > 0 {
+		return withExitCode(ExitPartial, fmt.Errorf("%d restore requests failed, their errors were ignored", failed))
+	}
+	return nil
+}
+
+// Status prints the retrieval state of archived objects, optionally waiting until they are retrievable
+func (tm *ThawManager) Status(ctx context.Context) error {
+	intro()
+	keys, err := tm.archivedKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if tm.config.Wait {
+		return tm.wait(ctx, keys)
+	}
+
+	for _, key := range keys {
+		status, err := tm.s3Storage.ArchiveStatus(ctx, key)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %-14s %s\n", status.state(), status.StorageClass, status.Key)
+	}
+	return nil
+}
+
+// wait polls the archived objects until all of them are retrievable
+func (tm *ThawManager) wait(ctx context.Context, keys []string) error {
+	pending := slices.Clone(keys)
+	for {
+		var stillPending []string
+		for _, key := range pending {
+			status, err := tm.s3Storage.ArchiveStatus(ctx, key)
+			if err != nil {
+				return err
+			}
+			if !status.Requested && !status.Ready {
+				return fmt.Errorf("no restore request found for %q, run thaw first", key)
+			}
+			if !status.Ready {
+				stillPending = append(stillPending, key)
+			}
+		}
+		if len(stillPending) == 0 {
+			slog.Info("All objects are retrievable", "objects", len(keys))
+			return nil
+		}
+
+		slog.Info("Waiting for objects to be restored", "pending", len(stillPending), "total", len(keys), "next check", tm.config.PollInterval)
+		pending = stillPending
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tm.config.PollInterval):
+		}
+	}
+}
+
+// archivedKeys returns the keys of the objects stored in an archive storage class
+func (tm *ThawManager) archivedKeys(ctx context.Context) ([]string, error) {
+	if tm.config.File != "" {
+		key := objectKey(tm.config.Path, tm.config.File)
+		status, err := tm.s3Storage.ArchiveStatus(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(archiveStorageClasses, status.StorageClass) {
+			return nil, nil
+		}
+		return []string{key}, nil
+	}
+
+	files, err := tm.s3Storage.List(ctx, tm.config.Path, tm.config.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var keys []string
+	for _, file := range files {
+		if file.IsDir || slices.Contains(tm.config.Exclude, filepath.Base(file.Key)) {
+			continue
+		}
+		if slices.Contains(archiveStorageClasses, file.StorageClass) {
+			keys = append(keys, file.Key)
+		}
+	}
+	return keys, nil
+}
+
+func (a ArchiveStatus) state() string {
+	switch {
+	case !slices.Contains(archiveStorageClasses, a.StorageClass):
+		return "available"
+	case a.Ready:
+		return "restored"
+	case a.Requested:
+		return "in-progress"
+	default:
+		return "archived"
+	}
+}
+
+// RestoreArchived requests a temporary copy of an archived object, existing requests are left untouched
+func (s S3Storage) RestoreArchived(ctx context.Context, key string, days int32, tier string) error {
+	_, err := s.client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 aws.Int32(days),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: types.Tier(tier)},
+		},
+	})
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+		s.log().Info("Restore already in progress", "key", key)
+		return nil
+	}
+	return fmt.Errorf("unable to request restore of %q: %w", key, err)
+}
+
+// ArchiveStatus returns the storage class and restore state of an object
+func (s S3Storage) ArchiveStatus(ctx context.Context, key string) (ArchiveStatus, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return ArchiveStatus{}, fmt.Errorf("unable to get status of %q: %w", key, err)
+	}
+	return parseArchiveStatus(key, string(head.StorageClass), aws.ToString(head.Restore)), nil
+}
+
+// parseArchiveStatus parses the x-amz-restore header, e.g. ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+func parseArchiveStatus(key, storageClass, restore string) ArchiveStatus {
+	status := ArchiveStatus{
+		Key:          key,
+		StorageClass: storageClass,
+		Requested:    restore != "",
+	}
+	if !slices.Contains(archiveStorageClasses, storageClass) {
+		status.Ready = true
+		return status
+	}
+	if strings.Contains(restore, `ongoing-request="false"`) {
+		status.Ready = true
+		if _, expiry, ok := strings.Cut(restore, `expiry-date="`); ok {
+			status.Expiry = strings.TrimSuffix(expiry, `"`)
+		}
+	}
+	return status
+}
@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArchiveStatus(t *testing.T) {
+	status := parseArchiveStatus("backup.tar.gz", "GLACIER", "")
+	if status.Requested || status.Ready || status.state() != "archived" {
+		t.Errorf("Expected archived object without request, got %+v", status)
+	}
+
+	status = parseArchiveStatus("backup.tar.gz", "DEEP_ARCHIVE", `ongoing-request="true"`)
+	if !status.Requested || status.Ready || status.state() != "in-progress" {
+		t.Errorf("Expected in-progress restore, got %+v", status)
+	}
+
+	status = parseArchiveStatus("backup.tar.gz", "GLACIER", `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`)
+	if !status.Ready || status.Expiry != "Fri, 21 Dec 2012 00:00:00 GMT" || status.state() != "restored" {
+		t.Errorf("Expected restored object, got %+v", status)
+	}
+
+	status = parseArchiveStatus("backup.tar.gz", "STANDARD", "")
+	if !status.Ready || status.state() != "available" {
+		t.Errorf("Expected standard object to be available, got %+v", status)
+	}
+}
+
+// thawServer serves two GLACIER objects, the restore request of b.bin is denied
+func thawServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.URL.Query().Has("list-type"):
+			_, _ = w.Write([]byte(`<ListBucketResult><Name>bucket</Name><KeyCount>2</KeyCount><IsTruncated>false</IsTruncated>` +
+				`<Contents><Key>backups/a.bin</Key><Size>1</Size><StorageClass>GLACIER</StorageClass></Contents>` +
+				`<Contents><Key>backups/b.bin</Key><Size>1</Size><StorageClass>GLACIER</StorageClass></Contents></ListBucketResult>`))
+		case r.URL.Query().Has("restore") && key == "backups/b.bin":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		case r.URL.Query().Has("restore"):
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodHead:
+			w.Header().Set("X-Amz-Storage-Class", "GLACIER")
+			if key == "backups/a.bin" {
+				w.Header().Set("X-Amz-Restore", `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestThawWaitIgnoreErrors(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := thawServer(t)
+	cfg := testConfig("backups", server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.IgnoreErrors = true
+	cfg.Wait = true
+	cfg.Days = defaultThawDays
+	cfg.Tier = "Standard"
+	cfg.PollInterval = time.Millisecond
+	tm := &ThawManager{config: &cfg, s3Storage: storage}
+	// The failed request of b.bin is not waited for, it fails the run as a partial failure
+	if err := tm.Thaw(context.Background()); ExitCode(err) != ExitPartial {
+		t.Errorf("Expected a partial failure, got %v", err)
+	}
+}
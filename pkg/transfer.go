@@ -0,0 +1,137 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	goutils "github.com/jkaninda/go-utils"
+)
+
+// minMaxMemory holds two minimum-size parts, the smallest budget a multipart upload fits in
+const minMaxMemory = 2 * manager.MinUploadPartSize
+
+// transferLimits parses the part size and memory limit options, zero when unset
+func (c *Config) transferLimits() (partSize int64, maxMemory int64, err error) {
+	if c.PartSize != "" {
+		if partSize, err = goutils.ConvertToBytes(c.PartSize); err != nil {
+			return 0, 0, fmt.Errorf("invalid part size %q: %w", c.PartSize, err)
+		}
+		if partSize < manager.MinUploadPartSize {
+			return 0, 0, fmt.Errorf("invalid part size %q, the minimum is %s", c.PartSize, goutils.ConvertBytes(uint64(manager.MinUploadPartSize)))
+		}
+	}
+	if c.MaxMemory != "" {
+		if maxMemory, err = goutils.ConvertToBytes(c.MaxMemory); err != nil {
+			return 0, 0, fmt.Errorf("invalid max memory %q: %w", c.MaxMemory, err)
+		}
+		if maxMemory < minMaxMemory {
+			return 0, 0, fmt.Errorf("invalid max memory %q, the minimum is %s", c.MaxMemory, goutils.ConvertBytes(uint64(minMaxMemory)))
+		}
+	}
+	if c.Concurrency < 0 {
+		return 0, 0, fmt.Errorf("invalid concurrency %d, it must be positive", c.Concurrency)
+	}
+	return partSize, maxMemory, nil
+}
+
+// transferSettings holds the multipart settings of a single transfer
+type transferSettings struct {
+	partSize    int64
+	concurrency int
+}
+
+// memory is the size of the part buffers held by a transfer with extra spare buffers
+func (t transferSettings) memory(extra int) int64 {
+	return t.partSize * int64(t.concurrency+extra)
+}
+
+// uploadSettings returns the multipart settings of an upload of size bytes, negative when unknown.
+// The part size grows to keep the upload within the 10,000 parts limit, then the concurrency and
+// the part size are lowered until the buffered parts, concurrency plus one spare, fit in maxMemory.
+func (s S3Storage) uploadSettings(size int64) transferSettings {
+	t := transferSettings{partSize: s.partSize, concurrency: s.concurrency}
+	if t.partSize == 0 {
+		t.partSize = manager.DefaultUploadPartSize
+	}
+	if t.concurrency == 0 {
+		t.concurrency = manager.DefaultUploadConcurrency
+	}
+	minPartSize := manager.MinUploadPartSize
+	if size > 0 {
+		minPartSize = max(minPartSize, (size+int64(manager.MaxUploadParts)-1)/int64(manager.MaxUploadParts))
+	}
+	t.partSize = max(t.partSize, minPartSize)
+	return s.limitMemory(t, minPartSize, 1, size)
+}
+
+// downloadSettings returns the multipart settings of a download, ranges of a part size are
+// written by concurrency workers.
+func (s S3Storage) downloadSettings(size int64) transferSettings {
+	t := transferSettings{partSize: s.partSize, concurrency: s.concurrency}
+	if t.partSize == 0 {
+		t.partSize = manager.DefaultDownloadPartSize
+	}
+	if t.concurrency == 0 {
+		t.concurrency = manager.DefaultDownloadConcurrency
+	}
+	return s.limitMemory(t, manager.MinUploadPartSize, 0, size)
+}
+
+// limitMemory lowers the concurrency, then the part size down to minPartSize, to fit in maxMemory
+func (s S3Storage) limitMemory(t transferSettings, minPartSize int64, extra int, size int64) transferSettings {
+	if s.maxMemory == 0 || t.memory(extra) <= s.maxMemory {
+		return t
+	}
+	t.concurrency = max(1, int(s.maxMemory/t.partSize)-extra)
+	if t.memory(extra) > s.maxMemory {
+		t.partSize = max(minPartSize, s.maxMemory/int64(1+extra))
+	}
+	if t.memory(extra) > s.maxMemory {
+		s.log().Warn("Transfer exceeds the memory limit, the part size is required by the object size",
+			"size", goutils.ConvertBytes(uint64(max(size, 0))),
+			"partSize", goutils.ConvertBytes(uint64(t.partSize)),
+			"maxMemory", goutils.ConvertBytes(uint64(s.maxMemory)))
+	}
+	return t
+}
+
+// uploaderOptions applies the multipart settings of an upload of size bytes
+func (s S3Storage) uploaderOptions(size int64) func(*manager.Uploader) {
+	t := s.uploadSettings(size)
+	return func(u *manager.Uploader) {
+		u.PartSize = t.partSize
+		u.Concurrency = t.concurrency
+	}
+}
+
+// downloaderOptions applies the multipart settings of a download of size bytes
+func (s S3Storage) downloaderOptions(size int64) func(*manager.Downloader) {
+	t := s.downloadSettings(size)
+	return func(d *manager.Downloader) {
+		d.PartSize = t.partSize
+		d.Concurrency = t.concurrency
+	}
+}
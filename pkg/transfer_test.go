@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"testing"
+)
+
+const mib = 1024 * 1024
+
+func TestUploadSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		storage     S3Storage
+		size        int64
+		partSize    int64
+		concurrency int
+	}{
+		{"defaults", S3Storage{}, 100 * mib, 5 * mib, 5},
+		{"overrides", S3Storage{partSize: 16 * mib, concurrency: 8}, 100 * mib, 16 * mib, 8},
+		{"parts limit", S3Storage{}, 100_000 * mib, 10 * mib, 5},
+		{"lower concurrency", S3Storage{partSize: 16 * mib, concurrency: 8, maxMemory: 64 * mib}, 100 * mib, 16 * mib, 3},
+		{"lower part size", S3Storage{partSize: 64 * mib, maxMemory: 64 * mib}, 100 * mib, 32 * mib, 1},
+		{"required part size", S3Storage{maxMemory: 16 * mib}, 100_000 * mib, 10 * mib, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.storage.uploadSettings(tt.size)
+			if got.partSize != tt.partSize || got.concurrency != tt.concurrency {
+				t.Errorf("uploadSettings(%d) = %d x %d, want %d x %d", tt.size, got.partSize, got.concurrency, tt.partSize, tt.concurrency)
+			}
+		})
+	}
+}
+
+func TestDownloadSettings(t *testing.T) {
+	got := S3Storage{partSize: 16 * mib, concurrency: 10, maxMemory: 100 * mib}.downloadSettings(1024 * mib)
+	if got.partSize != 16*mib || got.concurrency != 6 {
+		t.Errorf("downloadSettings = %d x %d, want %d x 6", got.partSize, got.concurrency, 16*mib)
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	c := &Config{PartSize: "16MiB", MaxMemory: "256MiB"}
+	partSize, maxMemory, err := c.transferLimits()
+	if err != nil {
+		t.Fatalf("transferLimits: %v", err)
+	}
+	if partSize != 16*mib || maxMemory != 256*mib {
+		t.Errorf("transferLimits = %d, %d", partSize, maxMemory)
+	}
+	for _, invalid := range []*Config{{PartSize: "1MiB"}, {PartSize: "abc"}, {MaxMemory: "8MiB"}, {Concurrency: -1}} {
+		if _, _, err := invalid.transferLimits(); err == nil {
+			t.Errorf("transferLimits(%+v) succeeded, want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+// Unicode normalization forms of keys, macOS file names are usually NFD while Linux and Windows use NFC
+const (
+	normalizeNFC  = "nfc"
+	normalizeNFD  = "nfd"
+	normalizeNone = "none"
+)
+
+var normalizeForms = []string{normalizeNFC, normalizeNFD, normalizeNone}
+
+// normalizeUnicode returns s in the given normalization form, unchanged for none
+func normalizeUnicode(s, form string) string {
+	switch strings.ToLower(form) {
+	case normalizeNFC:
+		return norm.NFC.String(s)
+	case normalizeNFD:
+		return norm.NFD.String(s)
+	default:
+		return s
+	}
+}
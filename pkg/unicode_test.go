@@ -0,0 +1,62 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	nfc := "backups/café.txt"
+	nfd := "backups/café.txt"
+	tests := []struct {
+		in, form, want string
+	}{
+		{nfd, normalizeNFC, nfc},
+		{nfc, normalizeNFC, nfc},
+		{nfc, normalizeNFD, nfd},
+		{nfd, "NFD", nfd},
+		{nfd, normalizeNone, nfd},
+		{nfc, "", nfc},
+	}
+	for _, tt := range tests {
+		if got := normalizeUnicode(tt.in, tt.form); got != tt.want {
+			t.Errorf("normalizeUnicode(%q, %q) = %q, want %q", tt.in, tt.form, got, tt.want)
+		}
+	}
+}
+
+func TestLocalPathNormalizeUnicode(t *testing.T) {
+	dest := t.TempDir()
+	rm := &RestoreManager{config: &Config{Path: "backups", Dest: dest, NormalizeUnicode: normalizeNFC, SanitizeNames: sanitizeReplace}}
+	got, err := rm.localPath("backups/café.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dest, "café.txt"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
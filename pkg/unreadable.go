@@ -0,0 +1,55 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import "path/filepath"
+
+// Policies of --on-unreadable for the files and directories of a backup that cannot be read
+const (
+	// unreadableSkip leaves the file out of the backup, it is counted as skipped
+	unreadableSkip = "skip"
+	// unreadableWarn leaves the file out of the backup with a warning, it is reported as failed
+	unreadableWarn = "warn"
+	// unreadableFail aborts the backup
+	unreadableFail = "fail"
+)
+
+var unreadablePolicies = []string{unreadableSkip, unreadableWarn, unreadableFail}
+
+// unreadable applies --on-unreadable to a file or directory of the backup that cannot be read,
+// the error is returned when the backup fails
+func (bm *BackupManager) unreadable(key string, err error) error {
+	key = filepath.ToSlash(key)
+	switch bm.config.OnUnreadable {
+	case unreadableSkip:
+		bm.log().Info("Skipping unreadable file", "file", key, "error", err)
+		bm.result.Skipped++
+		return nil
+	case unreadableWarn:
+		bm.log().Warn("Skipping unreadable file", "file", key, "error", err)
+		bm.result.Failed = append(bm.result.Failed, FileError{Key: key, Err: err})
+		return nil
+	}
+	return err
+}
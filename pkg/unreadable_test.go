@@ -0,0 +1,87 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBackupOnUnreadable(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server, keys := uploadServer(t)
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(src, "missing"), filepath.Join(src, "broken")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	cfg := testConfig(src, server.URL)
+	cfg.OnUnreadable = unreadableSkip
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Expected skipped unreadable files to succeed, got %v", err)
+	}
+	if len(result.Failed) != 0 || result.Skipped != 1 || result.Files != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if got := keys(); !slices.Contains(got, "backups/a.txt") {
+		t.Errorf("uploaded keys = %v", got)
+	}
+
+	// fail takes precedence over --ignore-errors
+	cfg.OnUnreadable = unreadableFail
+	cfg.IgnoreErrors = true
+	bm, err = NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bm.Backup(context.Background()); err == nil {
+		t.Error("Expected the unreadable file to abort the backup")
+	}
+}
+
+func TestOnUnreadableDefault(t *testing.T) {
+	if got := newManagerOptions(nil).config(Config{}).OnUnreadable; got != unreadableFail {
+		t.Errorf("default = %q, want %q", got, unreadableFail)
+	}
+	if got := newManagerOptions(nil).config(Config{IgnoreErrors: true}).OnUnreadable; got != unreadableWarn {
+		t.Errorf("default with IgnoreErrors = %q, want %q", got, unreadableWarn)
+	}
+	cfg := newManagerOptions(nil).config(Config{Bucket: "bucket", OnUnreadable: "ignore"})
+	if err := cfg.validateOptions(); err == nil {
+		t.Error("Expected an unknown --on-unreadable policy to be rejected")
+	}
+}
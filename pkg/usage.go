@@ -0,0 +1,240 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultUsageRuns is the number of runs reported per job
+const defaultUsageRuns = 10
+
+// Output formats of reports, listings and run summaries
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatCSV}
+
+// UsageReport is the size of the last runs of every job under a prefix
+type UsageReport struct {
+	Path string     `json:"path"`
+	Jobs []JobUsage `json:"jobs"`
+}
+
+// JobUsage lists the runs of a job from the oldest to the newest
+type JobUsage struct {
+	Job  string     `json:"job"`
+	Runs []RunUsage `json:"runs"`
+	// Growth is the size change from the first to the last listed run
+	Growth int64 `json:"growth"`
+}
+
+// RunUsage is the size of a backup run, an archive or a run prefix
+type RunUsage struct {
+	Run     string    `json:"run"`
+	Time    time.Time `json:"time"`
+	Objects int       `json:"objects"`
+	Size    int64     `json:"size"`
+	// Change is the size change from the previous run
+	Change int64 `json:"change"`
+}
+
+// Usage is the cobra command handler for usage
+func Usage(cmd *cobra.Command) error {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	if config.Format == "" {
+		config.Format = formatText
+	}
+	if !slices.Contains(outputFormats, config.Format) {
+		return withExitCode(ExitConfig, fmt.Errorf("invalid format %q, supported values: %v", config.Format, outputFormats))
+	}
+	if config.Runs <= 0 {
+		config.Runs = defaultUsageRuns
+	}
+	if config.Depth < 0 {
+		return withExitCode(ExitConfig, fmt.Errorf("invalid depth %d", config.Depth))
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+	config.Path = strings.Trim(filepath.ToSlash(config.Path), "/")
+
+	var objects []Item
+	for item, err := range s3Storage.Objects(cmd.Context(), config.Path, true) {
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		if !item.IsDir {
+			objects = append(objects, item)
+		}
+	}
+	report, ignored := usageReport(config.Path, objects, config.Depth, config.Runs)
+	if ignored > 0 {
+		slog.Info("Objects outside of archives and run prefixes ignored, set --depth for folder backups", "objects", ignored)
+	}
+	return writeUsageReport(os.Stdout, report, config.Format)
+}
+
+// isTarArchive reports whether a key is a compressed backup archive
+func isTarArchive(key string) bool {
+	return strings.HasSuffix(key, ".tar.gz") || strings.HasSuffix(key, ".tgz")
+}
+
+// usageReport groups the objects under root into runs of jobs and keeps the last runs of each job.
+// Every archive is a run of the job named by its directory. With depth, the first depth directories
+// below root name the job and every directory below it is a run, such as the dated prefix of a folder backup.
+// The number of objects belonging to no run is returned.
+func usageReport(root string, objects []Item, depth, runs int) (UsageReport, int) {
+	jobs := make(map[string]map[string]*RunUsage)
+	ignored := 0
+	add := func(job, run string, object Item) {
+		if jobs[job] == nil {
+			jobs[job] = make(map[string]*RunUsage)
+		}
+		r, ok := jobs[job][run]
+		if !ok {
+			r = &RunUsage{Run: run}
+			jobs[job][run] = r
+		}
+		r.Objects++
+		r.Size += object.Size
+		if object.LastModified.After(r.Time) {
+			r.Time = object.LastModified
+		}
+	}
+
+	for _, object := range objects {
+		if path.Base(object.Key) == LatestFile {
+			continue
+		}
+		rel := relativeKey(object.Key, root)
+		dirs := strings.Split(path.Dir(rel), "/")
+		if dirs[0] == "." {
+			dirs = nil
+		}
+		switch {
+		case depth > 0 && len(dirs) > depth:
+			add(strings.Join(dirs[:depth], "/"), dirs[depth], object)
+		case isTarArchive(object.Key):
+			add(path.Dir(rel), path.Base(rel), object)
+		default:
+			ignored++
+		}
+	}
+
+	report := UsageReport{Path: root}
+	for _, job := range slices.Sorted(maps.Keys(jobs)) {
+		all := slices.Collect(maps.Values(jobs[job]))
+		slices.SortFunc(all, func(a, b *RunUsage) int {
+			if c := a.Time.Compare(b.Time); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Run, b.Run)
+		})
+		// The change of the oldest listed run is relative to the run before it, when there is one
+		start := max(len(all)-runs, 0)
+		usage := JobUsage{Job: job}
+		for i := start; i < len(all); i++ {
+			run := *all[i]
+			if i > 0 {
+				run.Change = run.Size - all[i-1].Size
+			}
+			usage.Runs = append(usage.Runs, run)
+		}
+		usage.Growth = usage.Runs[len(usage.Runs)-1].Size - usage.Runs[0].Size
+		report.Jobs = append(report.Jobs, usage)
+	}
+	return report, ignored
+}
+
+// writeUsageReport writes the report as a text table, JSON or CSV
+func writeUsageReport(out io.Writer, report UsageReport, format string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"job", "run", "time", "objects", "size", "change"})
+		for _, job := range report.Jobs {
+			for _, run := range job.Runs {
+				_ = w.Write([]string{job.Job, run.Run, run.Time.UTC().Format(time.RFC3339), strconv.Itoa(run.Objects),
+					strconv.FormatInt(run.Size, 10), strconv.FormatInt(run.Change, 10)})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if len(report.Jobs) == 0 {
+		_, err := fmt.Fprintf(out, "No backup runs found under %q\n", report.Path)
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, job := range report.Jobs {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "Job %s: %d runs, growth %s\n", job.Job, len(job.Runs), signedBytes(job.Growth))
+		_, _ = fmt.Fprintln(w, "RUN\tTIME\tOBJECTS\tSIZE\tCHANGE")
+		for _, run := range job.Runs {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", run.Run, run.Time.Format(time.RFC3339), run.Objects,
+				goutils.ConvertBytes(uint64(run.Size)), signedBytes(run.Change))
+		}
+	}
+	return w.Flush()
+}
+
+// signedBytes formats a size change with its sign
+func signedBytes(n int64) string {
+	if n < 0 {
+		return "-" + goutils.ConvertBytes(uint64(-n))
+	}
+	return "+" + goutils.ConvertBytes(uint64(n))
+}
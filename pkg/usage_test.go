@@ -0,0 +1,120 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageReportArchives(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 6, d, 2, 0, 0, 0, time.UTC)
+	}
+	objects := []Item{
+		{Key: "backups/db/db-3.tar.gz", Size: 300, LastModified: day(3)},
+		{Key: "backups/db/db-1.tar.gz", Size: 100, LastModified: day(1)},
+		{Key: "backups/db/db-2.tar.gz", Size: 250, LastModified: day(2)},
+		{Key: "backups/db/latest.json", Size: 80, LastModified: day(3)},
+		{Key: "backups/web.tgz", Size: 50, LastModified: day(1)},
+		{Key: "backups/notes.txt", Size: 10, LastModified: day(1)},
+	}
+	report, ignored := usageReport("backups", objects, 0, 2)
+	if ignored != 1 {
+		t.Errorf("ignored = %d, want 1", ignored)
+	}
+	if len(report.Jobs) != 2 || report.Jobs[0].Job != "." || report.Jobs[1].Job != "db" {
+		t.Fatalf("jobs = %+v", report.Jobs)
+	}
+	db := report.Jobs[1]
+	if len(db.Runs) != 2 || db.Runs[0].Run != "db-2.tar.gz" || db.Runs[1].Run != "db-3.tar.gz" {
+		t.Fatalf("runs = %+v", db.Runs)
+	}
+	// The oldest listed run is compared with the run before it
+	if db.Runs[0].Change != 150 || db.Runs[1].Change != 50 || db.Growth != 50 {
+		t.Errorf("db = %+v", db)
+	}
+}
+
+func TestUsageReportDepth(t *testing.T) {
+	at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	objects := []Item{
+		{Key: "backups/host-a/2025-06-01/etc/hosts", Size: 10, LastModified: at},
+		{Key: "backups/host-a/2025-06-01/etc/passwd", Size: 20, LastModified: at.Add(time.Minute)},
+		{Key: "backups/host-a/2025-06-02/etc/hosts", Size: 15, LastModified: at.Add(24 * time.Hour)},
+		{Key: "backups/host-a/full.tar.gz", Size: 500, LastModified: at.Add(48 * time.Hour)},
+		{Key: "backups/host-b/readme.txt", Size: 5, LastModified: at},
+	}
+	report, ignored := usageReport("backups", objects, 1, 10)
+	if ignored != 1 || len(report.Jobs) != 1 {
+		t.Fatalf("ignored = %d, jobs = %+v", ignored, report.Jobs)
+	}
+	runs := report.Jobs[0].Runs
+	if len(runs) != 3 || runs[0].Run != "2025-06-01" || runs[0].Objects != 2 || runs[0].Size != 30 || !runs[0].Time.Equal(at.Add(time.Minute)) {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if runs[1].Change != -15 || runs[2].Run != "full.tar.gz" || report.Jobs[0].Growth != 470 {
+		t.Errorf("runs = %+v, growth = %d", runs, report.Jobs[0].Growth)
+	}
+}
+
+func TestWriteUsageReport(t *testing.T) {
+	at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	report, _ := usageReport("backups", []Item{
+		{Key: "backups/db/db-1.tar.gz", Size: 2048, LastModified: at},
+		{Key: "backups/db/db-2.tar.gz", Size: 1024, LastModified: at.Add(time.Hour)},
+	}, 0, 10)
+
+	var out bytes.Buffer
+	if err := writeUsageReport(&out, report, formatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "job,run,time,objects,size,change\ndb,db-1.tar.gz,2025-06-01T00:00:00Z,1,2048,0\ndb,db-2.tar.gz,2025-06-01T01:00:00Z,1,1024,-1024\n"
+	if out.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeUsageReport(&out, report, formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded UsageReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded.Jobs) != 1 || decoded.Jobs[0].Growth != -1024 {
+		t.Errorf("json = %s (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	if err := writeUsageReport(&out, report, formatText); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Job db: 2 runs, growth -1.00 KB", "db-2.tar.gz", "-1.00 KB"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("text output does not contain %q:\n%s", s, out.String())
+		}
+	}
+}
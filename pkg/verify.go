@@ -0,0 +1,232 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sha256MetadataKey is the user metadata holding the hex SHA-256 of an object, x-amz-meta-sha256
+const sha256MetadataKey = "sha256"
+
+const (
+	// defaultChecksum is the S3 additional checksum computed on uploads to AWS unless disabled,
+	// S3-compatible providers only compute the one requested with --checksum
+	defaultChecksum = string(types.ChecksumAlgorithmSha256)
+	checksumNone    = "NONE"
+)
+
+// errUnverifiable is returned by VerifyUpload when the object has no MD5 or SHA-256 to compare with the
+// local file, only its size
+var errUnverifiable = errors.New("no MD5 or SHA-256 checksum to compare, only the size matches")
+
+// objectDigest holds the values a downloaded file is verified against, empty values are not checked
+type objectDigest struct {
+	Size   int64
+	MD5    string
+	SHA256 string
+}
+
+// UploadVerifier is implemented by uploaders that can check an uploaded object against the local file
+type UploadVerifier interface {
+	VerifyUpload(ctx context.Context, path string, target string) error
+}
+
+// headDigest returns the digest of an object, from the x-amz-meta-sha256 metadata or the
+// x-amz-checksum-sha256 full object checksum
+func headDigest(head *s3.HeadObjectOutput) objectDigest {
+	digest := storedDigest(head)
+	if sum := head.Metadata[sha256MetadataKey]; sum != "" {
+		digest.SHA256 = strings.ToLower(sum)
+	}
+	return digest
+}
+
+// storedDigest returns the digest computed by S3 for an object, user metadata is ignored.
+// The ETag is the MD5 of the content only for single part uploads without SSE-KMS or SSE-C encryption.
+func storedDigest(head *s3.HeadObjectOutput) objectDigest {
+	digest := objectDigest{
+		Size: aws.ToInt64(head.ContentLength),
+	}
+	// Composite checksums of multipart uploads end with -<parts> and do not cover the whole content
+	if checksum := aws.ToString(head.ChecksumSHA256); checksum != "" && !strings.Contains(checksum, "-") {
+		if sum, err := base64.StdEncoding.DecodeString(checksum); err == nil {
+			digest.SHA256 = hex.EncodeToString(sum)
+		}
+	}
+	if etag := strings.Trim(aws.ToString(head.ETag), `"`); !encryptedObject(head) && isMD5(etag) && md5Allowed() {
+		digest.MD5 = strings.ToLower(etag)
+	}
+	return digest
+}
+
+// encryptedObject reports whether an object is encrypted with SSE-KMS or SSE-C
+func encryptedObject(head *s3.HeadObjectOutput) bool {
+	return head.SSECustomerAlgorithm != nil ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKms ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKmsDsse
+}
+
+func isMD5(value string) bool {
+	if len(value) != 2*md5.Size {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// verifyLocalFile compares a local file with the digest of its uploaded copy, errUnverifiable is returned
+// when the sizes match and the digest has no checksum
+func verifyLocalFile(path string, digest objectDigest) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	if err := verifyFile(file, digest); err != nil {
+		return err
+	}
+	if digest.MD5 == "" && digest.SHA256 == "" {
+		return errUnverifiable
+	}
+	return nil
+}
+
+// compositeChecksum returns the SHA-256 checksum of an object uploaded in parts, the SHA-256 of the
+// concatenated SHA-256 of each part followed by -<parts>
+func compositeChecksum(head *s3.HeadObjectOutput) (string, int, bool) {
+	checksum := aws.ToString(head.ChecksumSHA256)
+	sum, count, found := strings.Cut(checksum, "-")
+	if !found || head.ChecksumType == types.ChecksumTypeFullObject {
+		return "", 0, false
+	}
+	parts, err := strconv.Atoi(count)
+	if err != nil || parts < 1 {
+		return "", 0, false
+	}
+	return sum, parts, true
+}
+
+// verifyCompositeChecksum compares a local file with the composite SHA-256 checksum of its copy uploaded
+// in parts of partSize bytes
+func verifyCompositeChecksum(path string, size int64, checksum string, parts int, partSize int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", size, info.Size())
+	}
+	if int64(parts) != (size+partSize-1)/partSize {
+		return fmt.Errorf("%w, the object was uploaded in %d parts", errUnverifiable, parts)
+	}
+	sums := sha256.New()
+	for range parts {
+		hash := sha256.New()
+		if _, err := io.CopyN(hash, file, partSize); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		sums.Write(hash.Sum(nil))
+	}
+	if sum := base64.StdEncoding.EncodeToString(sums.Sum(nil)); sum != checksum {
+		return fmt.Errorf("sha256 mismatch: expected %s-%d, got %s-%d", checksum, parts, sum, parts)
+	}
+	return nil
+}
+
+// verifyFile compares the size and hashes of a downloaded file with the object digest
+func verifyFile(file *os.File, digest objectDigest) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w := newDigestWriter()
+	if _, err := io.Copy(w, file); err != nil {
+		return err
+	}
+	return w.check(digest)
+}
+
+// digestWriter hashes the content written to it
+type digestWriter struct {
+	size   int64
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+func newDigestWriter() *digestWriter {
+	w := &digestWriter{sha256: sha256.New()}
+	if md5Allowed() {
+		w.md5 = md5.New()
+	}
+	return w
+}
+
+func (w *digestWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	if w.md5 != nil {
+		w.md5.Write(p)
+	}
+	w.sha256.Write(p)
+	return len(p), nil
+}
+
+// check compares the size and hashes of the written content with the object digest
+func (w *digestWriter) check(digest objectDigest) error {
+	if w.size != digest.Size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", digest.Size, w.size)
+	}
+	if w.md5 != nil && digest.MD5 != "" {
+		if sum := hex.EncodeToString(w.md5.Sum(nil)); sum != digest.MD5 {
+			return fmt.Errorf("md5 mismatch: expected %s, got %s", digest.MD5, sum)
+		}
+	}
+	if sum := hex.EncodeToString(w.sha256.Sum(nil)); digest.SHA256 != "" && sum != digest.SHA256 {
+		return fmt.Errorf("sha256 mismatch: expected %s, got %s", digest.SHA256, sum)
+	}
+	return nil
+}
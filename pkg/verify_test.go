@@ -0,0 +1,311 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jkaninda/s3safe/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHeadDigest(t *testing.T) {
+	digest := headDigest(&s3.HeadObjectOutput{ContentLength: aws.Int64(6), ETag: aws.String(`"B3A8E0E1F9AB1BFE3A36F231F676F78B"`)})
+	if digest.Size != 6 || digest.MD5 != "b3a8e0e1f9ab1bfe3a36f231f676f78b" {
+		t.Errorf("Unexpected digest %+v", digest)
+	}
+	if digest := headDigest(&s3.HeadObjectOutput{ETag: aws.String(`"9b2cf535f27731c974343645a3985328-2"`)}); digest.MD5 != "" {
+		t.Errorf("Expected multipart ETag to be ignored, got %q", digest.MD5)
+	}
+	kms := &s3.HeadObjectOutput{ETag: aws.String(`"b3a8e0e1f9ab1bfe3a36f231f676f78b"`), ServerSideEncryption: types.ServerSideEncryptionAwsKms}
+	if digest := headDigest(kms); digest.MD5 != "" {
+		t.Errorf("Expected SSE-KMS ETag to be ignored, got %q", digest.MD5)
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("backup"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	valid := objectDigest{
+		Size:   6,
+		MD5:    "402051f4be0cc3aad33bcf3ac3d6532b",
+		SHA256: "54d00d867758cef816bc4685f58e327b949712b07ebd17c3485f3ffc9e9f5133",
+	}
+	if err := verifyFile(file, valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, invalid := range []objectDigest{
+		{Size: 7},
+		{Size: 6, MD5: "00000000000000000000000000000000"},
+		{Size: 6, SHA256: "0000"},
+	} {
+		if err := verifyFile(file, invalid); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestHeadDigestChecksum(t *testing.T) {
+	digest := headDigest(&s3.HeadObjectOutput{ChecksumSHA256: aws.String("n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=")})
+	if digest.SHA256 != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Errorf("Unexpected sha256 %q", digest.SHA256)
+	}
+	if digest := headDigest(&s3.HeadObjectOutput{ChecksumSHA256: aws.String("n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=-3")}); digest.SHA256 != "" {
+		t.Errorf("Expected composite checksum to be ignored, got %q", digest.SHA256)
+	}
+}
+
+func TestStoredDigestIgnoresMetadata(t *testing.T) {
+	head := &s3.HeadObjectOutput{
+		ContentLength:  aws.Int64(4),
+		ChecksumSHA256: aws.String("n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg="),
+		Metadata:       map[string]string{sha256MetadataKey: "0000"},
+	}
+	if digest := storedDigest(head); digest.SHA256 != "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" {
+		t.Errorf("Unexpected sha256 %q", digest.SHA256)
+	}
+	if digest := headDigest(head); digest.SHA256 != "0000" {
+		t.Errorf("Expected the metadata sha256 to take precedence, got %q", digest.SHA256)
+	}
+}
+
+func TestLocalStorageVerifyUpload(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "copy", "src.txt")
+	if err := (LocalStorage{}).Upload(context.Background(), src, target, UploadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := (LocalStorage{}).VerifyUpload(context.Background(), src, target); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("hellO"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (LocalStorage{}).VerifyUpload(context.Background(), src, target); err == nil {
+		t.Error("Expected a modified copy to fail the verification")
+	}
+}
+
+func TestBackupDeleteSource(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			// The object of b.txt is stored corrupted
+			if key == "backups/b.txt" {
+				body = append(body, '!')
+			}
+			objects[key] = body
+			w.Header().Set("ETag", `"etag"`)
+		case http.MethodHead:
+			body, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			sum := md5.Sum(body)
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.DeleteSource = true
+	if _, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck()); ExitCode(err) != ExitConfig {
+		t.Fatalf("Expected --delete-source without --verify to be rejected, got %v", err)
+	}
+
+	cfg.Verify = true
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "verification") {
+		t.Errorf("Expected the verification of b.txt to fail, got %v", err)
+	}
+	if result.Deleted != 1 {
+		t.Errorf("result.Deleted = %d, want 1", result.Deleted)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the verified file to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(src, "b.txt")); err != nil {
+		t.Errorf("Expected the corrupted upload to keep its source: %v", err)
+	}
+}
+
+func TestBackupDeleteSourceUnverifiable(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			w.Header().Set("ETag", `"etag"`)
+		case http.MethodHead:
+			// An encrypted object has no MD5 ETag and no checksum, only its size can be compared
+			w.Header().Set("Content-Length", "5")
+			w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+			w.Header().Set("x-amz-server-side-encryption", "aws:kms")
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(src, server.URL)
+	cfg.DeleteSource = true
+	cfg.Verify = true
+	bm, err := NewBackupManagerFromConfig(context.Background(), cfg, WithoutConnectionCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := bm.Backup(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the upload verified by size to succeed, got %v", err)
+	}
+	if result.Files != 1 || result.Deleted != 0 {
+		t.Errorf("Expected 1 file uploaded and none deleted, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.txt")); err != nil {
+		t.Errorf("Expected the source verified by size only to be kept: %v", err)
+	}
+}
+
+func TestVerifyCompositeChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	content := []byte(strings.Repeat("a", 10) + strings.Repeat("b", 10) + "c")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sums := sha256.New()
+	for _, part := range [][]byte{content[:10], content[10:20], content[20:]} {
+		sum := sha256.Sum256(part)
+		sums.Write(sum[:])
+	}
+	checksum := base64.StdEncoding.EncodeToString(sums.Sum(nil))
+	head := &s3.HeadObjectOutput{ChecksumSHA256: aws.String(checksum + "-3"), ChecksumType: types.ChecksumTypeComposite}
+	sum, parts, ok := compositeChecksum(head)
+	if !ok || sum != checksum || parts != 3 {
+		t.Fatalf("Unexpected composite checksum %q %d %v", sum, parts, ok)
+	}
+	if err := verifyCompositeChecksum(path, 21, sum, parts, 10); err != nil {
+		t.Errorf("Expected the composite checksum to match, got %v", err)
+	}
+	if err := verifyCompositeChecksum(path, 21, base64.StdEncoding.EncodeToString(make([]byte, 32)), parts, 10); err == nil {
+		t.Error("Expected a wrong composite checksum to fail")
+	}
+	if err := verifyCompositeChecksum(path, 21, sum, parts, 5); !errors.Is(err, errUnverifiable) {
+		t.Errorf("Expected another part size to be unverifiable, got %v", err)
+	}
+	if err := verifyLocalFile(path, objectDigest{Size: 21}); !errors.Is(err, errUnverifiable) {
+		t.Errorf("Expected a size-only digest to be unverifiable, got %v", err)
+	}
+}
+
+func TestUploadDefaultChecksum(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	var mu sync.Mutex
+	var checksums []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		checksums = append(checksums, r.Header.Get("X-Amz-Checksum-Sha256")+r.Header.Get("X-Amz-Trailer"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(path, server.URL)
+	storage, err := cfg.NewS3Storage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, algorithm := range []string{"", checksumNone, defaultChecksum} {
+		if err := storage.Upload(context.Background(), path, "backups/a.txt", UploadOptions{ChecksumAlgorithm: algorithm}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(checksums) != 3 || checksums[0] != "" || checksums[1] != "" || checksums[2] == "" {
+		t.Errorf("Expected a checksum on the explicit SHA256 upload only to a custom endpoint, got %q", checksums)
+	}
+
+	cfg.EndPoint = utils.AwsS3Url
+	if storage, err = cfg.NewS3Storage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if storage.checksum != defaultChecksum {
+		t.Errorf("Expected uploads to AWS to default to %s, got %q", defaultChecksum, storage.checksum)
+	}
+}
@@ -0,0 +1,376 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	goutils "github.com/jkaninda/go-utils"
+	"github.com/jkaninda/s3safe/utils"
+	"github.com/spf13/cobra"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// VersionManager handles operations on object versions of versioned buckets
+type VersionManager struct {
+	config    *Config
+	s3Storage *S3Storage
+	audit     *auditLog
+}
+
+// ObjectVersion describes an object version or a delete marker
+type ObjectVersion struct {
+	Key          string
+	VersionID    string
+	IsLatest     bool
+	DeleteMarker bool
+	Size         int64
+	LastModified time.Time
+	StorageClass string
+	ETag         string
+}
+
+// Versions is the cobra command handler for versions
+func Versions(cmd *cobra.Command) error {
+	vm, err := NewVersionManager(cmd)
+	if err != nil {
+		return err
+	}
+	return vm.Versions(cmd.Context())
+}
+
+// Undelete is the cobra command handler for undelete
+func Undelete(cmd *cobra.Command) error {
+	vm, err := NewVersionManager(cmd)
+	if err != nil {
+		return err
+	}
+	return vm.Undelete(cmd.Context())
+}
+
+// PurgeVersions is the cobra command handler for purge-versions
+func PurgeVersions(cmd *cobra.Command) error {
+	vm, err := NewVersionManager(cmd)
+	if err != nil {
+		return err
+	}
+	return vm.PurgeVersions(cmd.Context())
+}
+
+// NewVersionManager creates a new VersionManager instance
+func NewVersionManager(cmd *cobra.Command) (*VersionManager, error) {
+	config := NewConfig(cmd)
+	if err := config.Validate(cmd.Context()); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	s3Storage, err := config.NewS3Storage(cmd.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 storage: %w", err)
+	}
+
+	audit, err := newAuditLog(cmd.Context(), config)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Path = strings.TrimPrefix(filepath.ToSlash(config.Path), "/")
+
+	return &VersionManager{
+		config:    config,
+		s3Storage: s3Storage,
+		audit:     audit,
+	}, nil
+}
+
+// Versions prints all versions and delete markers under the configured path
+func (vm *VersionManager) Versions(ctx context.Context) error {
+	versions, err := vm.s3Storage.ListVersions(ctx, vm.config.Path)
+	if err != nil {
+		return err
+	}
+	return writeVersions(os.Stdout, versions, vm.config.Format)
+}
+
+// writeVersions writes the versions as a text table, JSON lines or CSV
+func writeVersions(out io.Writer, versions []ObjectVersion, format string) error {
+	switch format {
+	case formatJSON:
+		entries := make([]VersionEntry, 0, len(versions))
+		for _, v := range versions {
+			entries = append(entries, newVersionEntry(v))
+		}
+		return writeJSONLines(out, entries)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"key", "version_id", "latest", "delete_marker", "size", "last_modified"})
+		for _, v := range versions {
+			_ = w.Write([]string{v.Key, v.VersionID, strconv.FormatBool(v.IsLatest), strconv.FormatBool(v.DeleteMarker),
+				strconv.FormatInt(v.Size, 10), v.LastModified.UTC().Format(time.RFC3339)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVERSION ID\tLATEST\tDELETE MARKER\tSIZE\tLAST MODIFIED")
+	for _, v := range versions {
+		size := "-"
+		if !v.DeleteMarker {
+			size = goutils.ConvertBytes(uint64(v.Size))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\n", v.Key, v.VersionID, v.IsLatest, v.DeleteMarker, size, v.LastModified.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// Undelete removes the delete markers hiding objects under the configured path,
+// making the latest real version current again. With --since only markers created after that time are removed.
+func (vm *VersionManager) Undelete(ctx context.Context) (err error) {
+	intro()
+	start := time.Now()
+	restored := 0
+	vm.audit.reset()
+	defer func() {
+		err = vm.writeAudit(ctx, operationUndelete, start, restored, 0, err)
+	}()
+	var since time.Time
+	if vm.config.Since != "" {
+		since, _ = utils.ParseTime(vm.config.Since)
+	}
+
+	versions, err := vm.s3Storage.ListVersions(ctx, vm.config.Path)
+	if err != nil {
+		return err
+	}
+
+	markers := latestDeleteMarkers(versions, since)
+	if len(markers) == 0 {
+		slog.Info("No deleted objects found", "path", vm.config.Path)
+		return nil
+	}
+
+	if vm.config.DryRun {
+		for _, marker := range markers {
+			slog.Info("Would undelete object", "key", marker.Key, "deleted", marker.LastModified)
+		}
+		slog.Info("Undelete completed", "path", vm.config.Path, "objects", restored, "dryRun", vm.config.DryRun)
+		return nil
+	}
+
+	removed, failed := vm.s3Storage.DeleteVersions(ctx, markers)
+	for _, marker := range removed {
+		restored++
+		vm.audit.add(versionKey(marker))
+		slog.Info("Undeleted object", "key", marker.Key, "deleted", marker.LastModified)
+	}
+	if err := vm.deleteErrors(failed); err != nil {
+		return err
+	}
+
+	slog.Info("Undelete completed", "path", vm.config.Path, "objects", restored, "dryRun", vm.config.DryRun)
+	return nil
+}
+
+// PurgeVersions permanently deletes noncurrent versions under the configured path
+// that have been noncurrent for longer than --older-than. Current versions are never deleted.
+func (vm *VersionManager) PurgeVersions(ctx context.Context) (err error) {
+	intro()
+	start := time.Now()
+	var deleted int
+	var reclaimed int64
+	var versions []ObjectVersion
+	report := vm.newPruneReport()
+	vm.audit.reset()
+	defer func() {
+		err = vm.savePruneReport(ctx, report, versions, err)
+		err = vm.writeAudit(ctx, operationPurgeVersions, start, deleted, reclaimed, err)
+	}()
+	if vm.config.OlderThan == "" {
+		return errors.New("older-than is required, e.g. --older-than 90d")
+	}
+	olderThan, err := utils.ParseDuration(vm.config.OlderThan)
+	if err != nil {
+		return err
+	}
+
+	versions, err = vm.s3Storage.ListVersions(ctx, vm.config.Path)
+	if err != nil {
+		return err
+	}
+
+	expired := expiredVersions(versions, time.Now().Add(-olderThan))
+	if len(expired) == 0 {
+		slog.Info("No expired versions found", "path", vm.config.Path, "olderThan", vm.config.OlderThan)
+		return nil
+	}
+
+	if vm.config.DryRun {
+		for _, v := range expired {
+			slog.Info("Would delete version", "key", v.Key, "versionId", v.VersionID, "size", goutils.ConvertBytes(uint64(v.Size)))
+			report.deleted(v)
+		}
+		slog.Info("Purge completed", "path", vm.config.Path, "versions", deleted, "reclaimed", goutils.ConvertBytes(uint64(reclaimed)), "dryRun", vm.config.DryRun)
+		return nil
+	}
+
+	removed, failed := vm.s3Storage.DeleteVersions(ctx, expired)
+	for _, v := range removed {
+		report.deleted(v)
+		deleted++
+		reclaimed += v.Size
+		vm.audit.add(versionKey(v))
+		slog.Info("Deleted version", "key", v.Key, "versionId", v.VersionID)
+	}
+	for _, f := range failed {
+		report.Failed = append(report.Failed, FailedFile{Key: versionKey(f.version), Error: f.err.Error()})
+	}
+	if err := vm.deleteErrors(failed); err != nil {
+		return err
+	}
+
+	slog.Info("Purge completed", "path", vm.config.Path, "versions", deleted, "reclaimed", goutils.ConvertBytes(uint64(reclaimed)), "dryRun", vm.config.DryRun)
+	return nil
+}
+
+// expiredVersions returns the noncurrent versions that became noncurrent before cutoff.
+// A version becomes noncurrent when the next newer version of the same key is created,
+// versions must be sorted with sortVersions.
+func expiredVersions(versions []ObjectVersion, cutoff time.Time) []ObjectVersion {
+	var expired []ObjectVersion
+	for i, v := range versions {
+		if v.IsLatest || i == 0 || versions[i-1].Key != v.Key {
+			continue
+		}
+		if versions[i-1].LastModified.Before(cutoff) {
+			expired = append(expired, v)
+		}
+	}
+	return expired
+}
+
+// latestDeleteMarkers returns the delete markers that are the current version of their key, created after since
+func latestDeleteMarkers(versions []ObjectVersion, since time.Time) []ObjectVersion {
+	var markers []ObjectVersion
+	for _, v := range versions {
+		if v.DeleteMarker && v.IsLatest && v.LastModified.After(since) {
+			markers = append(markers, v)
+		}
+	}
+	return markers
+}
+
+// deleteErrors returns an error counting the versions that could not be deleted. With --ignore-errors
+// they are logged and the error has the partial failure exit code.
+func (vm *VersionManager) deleteErrors(failed []versionError) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	if vm.config.IgnoreErrors {
+		for _, f := range failed {
+			slog.Warn("Ignoring error", "error", f.err)
+		}
+		return withExitCode(ExitPartial, fmt.Errorf("%d versions could not be deleted, their errors were ignored", len(failed)))
+	}
+	return fmt.Errorf("%d versions could not be deleted: %w", len(failed), failed[0].err)
+}
+
+// versionKey identifies a deleted version in the audit log, in the key?versionId=id form of S3 URLs
+func versionKey(v ObjectVersion) string {
+	return v.Key + "?versionId=" + v.VersionID
+}
+
+// ListVersions returns all object versions and delete markers under the prefix,
+// sorted by key and from newest to oldest.
+func (s S3Storage) ListVersions(ctx context.Context, prefix string) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	var keyMarker, versionIDMarker *string
+
+	for {
+		resp, err := s.client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(s.bucket),
+			Prefix:          aws.String(prefix),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+			EncodingType:    types.EncodingTypeUrl,
+		})
+		if err != nil {
+			return versions, fmt.Errorf("could not list object versions in S3 bucket %s: %w", s.bucket, err)
+		}
+
+		for _, v := range resp.Versions {
+			versions = append(versions, ObjectVersion{
+				Key:          decodeListKey(resp.EncodingType, aws.ToString(v.Key)),
+				VersionID:    aws.ToString(v.VersionId),
+				IsLatest:     aws.ToBool(v.IsLatest),
+				Size:         aws.ToInt64(v.Size),
+				LastModified: aws.ToTime(v.LastModified),
+				StorageClass: string(v.StorageClass),
+				ETag:         strings.Trim(aws.ToString(v.ETag), `"`),
+			})
+		}
+		for _, m := range resp.DeleteMarkers {
+			versions = append(versions, ObjectVersion{
+				Key:          decodeListKey(resp.EncodingType, aws.ToString(m.Key)),
+				VersionID:    aws.ToString(m.VersionId),
+				IsLatest:     aws.ToBool(m.IsLatest),
+				DeleteMarker: true,
+				LastModified: aws.ToTime(m.LastModified),
+			})
+		}
+
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		keyMarker = resp.NextKeyMarker
+		versionIDMarker = resp.NextVersionIdMarker
+	}
+
+	sortVersions(versions)
+	return versions, nil
+}
+
+// sortVersions sorts versions by key, newest first
+func sortVersions(versions []ObjectVersion) {
+	slices.SortStableFunc(versions, func(a, b ObjectVersion) int {
+		if c := cmp.Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return b.LastModified.Compare(a.LastModified)
+	})
+}
@@ -0,0 +1,133 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortVersions(t *testing.T) {
+	now := time.Now()
+	versions := []ObjectVersion{
+		{Key: "b", VersionID: "b1", LastModified: now.Add(-time.Hour)},
+		{Key: "a", VersionID: "a1", LastModified: now.Add(-2 * time.Hour)},
+		{Key: "a", VersionID: "a2", LastModified: now, DeleteMarker: true},
+	}
+	sortVersions(versions)
+
+	expected := []string{"a2", "a1", "b1"}
+	for i, id := range expected {
+		if versions[i].VersionID != id {
+			t.Errorf("Expected version %s at position %d, got %s", id, i, versions[i].VersionID)
+		}
+	}
+}
+
+func TestLatestDeleteMarkers(t *testing.T) {
+	now := time.Now()
+	versions := []ObjectVersion{
+		{Key: "a", VersionID: "a2", IsLatest: true, DeleteMarker: true, LastModified: now},
+		{Key: "a", VersionID: "a1", LastModified: now.Add(-time.Hour)},
+		{Key: "b", VersionID: "b2", IsLatest: true, DeleteMarker: true, LastModified: now.Add(-48 * time.Hour)},
+		{Key: "c", VersionID: "c1", DeleteMarker: true, LastModified: now},
+	}
+
+	if markers := latestDeleteMarkers(versions, time.Time{}); len(markers) != 2 {
+		t.Errorf("Expected 2 delete markers, got %d", len(markers))
+	}
+	markers := latestDeleteMarkers(versions, now.Add(-24*time.Hour))
+	if len(markers) != 1 || markers[0].VersionID != "a2" {
+		t.Errorf("Expected only the recent delete marker, got %+v", markers)
+	}
+}
+
+func TestExpiredVersions(t *testing.T) {
+	now := time.Now()
+	versions := []ObjectVersion{
+		{Key: "a", VersionID: "a3", IsLatest: true, LastModified: now.Add(-24 * time.Hour)},
+		{Key: "a", VersionID: "a2", LastModified: now.Add(-100 * 24 * time.Hour)},
+		{Key: "a", VersionID: "a1", LastModified: now.Add(-200 * 24 * time.Hour)},
+		{Key: "b", VersionID: "b1", IsLatest: true, LastModified: now.Add(-300 * 24 * time.Hour)},
+	}
+
+	expired := expiredVersions(versions, now.Add(-90*24*time.Hour))
+	if len(expired) != 1 || expired[0].VersionID != "a1" {
+		t.Errorf("Expected only a1 to be expired, got %+v", expired)
+	}
+}
+
+func TestWriteVersions(t *testing.T) {
+	modified := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	versions := []ObjectVersion{
+		{Key: "db.tar.gz", VersionID: "v2", IsLatest: true, Size: 2048, LastModified: modified, StorageClass: "STANDARD", ETag: "abc"},
+		{Key: "old.txt", VersionID: "v1", DeleteMarker: true, LastModified: modified},
+	}
+	var out bytes.Buffer
+	if err := writeVersions(&out, versions, formatCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "key,version_id,latest,delete_marker,size,last_modified\n" +
+		"db.tar.gz,v2,true,false,2048,2025-06-01T10:00:00Z\n" +
+		"old.txt,v1,false,true,0,2025-06-01T10:00:00Z\n"
+	if out.String() != want {
+		t.Errorf("CSV versions = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeVersions(&out, versions, formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one JSON object per version, got %q", out.String())
+	}
+	var first, second VersionEntry
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.StorageClass != "STANDARD" || first.ETag != "abc" {
+		t.Errorf("JSON version = %s, %v", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil || !second.DeleteMarker {
+		t.Errorf("JSON version = %s, %v", lines[1], err)
+	}
+}
+
+func TestDeleteErrors(t *testing.T) {
+	failed := []versionError{{version: ObjectVersion{Key: "backups/a.txt", VersionID: "v1"}, err: errors.New("access denied")}}
+	vm := &VersionManager{config: &Config{}}
+	if err := vm.deleteErrors(nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := vm.deleteErrors(failed); err == nil || ExitCode(err) == ExitPartial {
+		t.Errorf("Expected a failure, got %v", err)
+	}
+	vm.config.IgnoreErrors = true
+	if err := vm.deleteErrors(failed); ExitCode(err) != ExitPartial {
+		t.Errorf("Expected ignored errors to be a partial failure, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Strategies applied to keys that are not valid Windows file names
+const (
+	sanitizeReplace = "replace"
+	sanitizeSkip    = "skip"
+	sanitizeFail    = "fail"
+)
+
+var sanitizeStrategies = []string{sanitizeReplace, sanitizeSkip, sanitizeFail}
+
+// errSkippedName is returned for keys skipped by the skip strategy
+var errSkippedName = errors.New("not a valid Windows file name")
+
+// windowsReservedNames are device names Windows reserves regardless of the extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sanitizeWindowsName returns a name valid on Windows: invalid and control characters, trailing dots and
+// spaces are replaced with underscores and reserved device names get an underscore suffix
+func sanitizeWindowsName(name string) (string, bool) {
+	if name == "" || name == "." || name == ".." {
+		return name, false
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*\`, r) {
+			b.WriteRune('_')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	sanitized := b.String()
+	if trimmed := strings.TrimRight(sanitized, ". "); trimmed != sanitized {
+		sanitized = trimmed + strings.Repeat("_", len(sanitized)-len(trimmed))
+	}
+	stem, ext, _ := strings.Cut(sanitized, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		sanitized = stem + "_"
+		if ext != "" {
+			sanitized += "." + ext
+		}
+	}
+	return sanitized, sanitized != name
+}
+
+// sanitizeWindowsPath sanitizes every element of a slash separated key
+func sanitizeWindowsPath(key string) (string, bool) {
+	elems := strings.Split(key, "/")
+	changed := false
+	for i, elem := range elems {
+		sanitized, ok := sanitizeWindowsName(elem)
+		elems[i] = sanitized
+		changed = changed || ok
+	}
+	return strings.Join(elems, "/"), changed
+}
+
+// windowsName applies the sanitize strategy to a key relative to the restore destination
+func windowsName(key, strategy string) (string, error) {
+	sanitized, changed := sanitizeWindowsPath(key)
+	if !changed {
+		return key, nil
+	}
+	switch strategy {
+	case sanitizeSkip:
+		return "", fmt.Errorf("%q: %w", key, errSkippedName)
+	case sanitizeFail:
+		return "", fmt.Errorf("%q is not a valid Windows file name, use --sanitize-names replace or skip", key)
+	default:
+		slog.Warn("Renaming file invalid on Windows", "key", key, "name", sanitized)
+		return sanitized, nil
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2025 Jonas Kaninda
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeWindowsName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		changed bool
+	}{
+		{"app.conf", "app.conf", false},
+		{"CON", "CON_", true},
+		{"nul.txt", "nul_.txt", true},
+		{"com1.tar.gz", "com1_.tar.gz", true},
+		{"CONSOLE.log", "CONSOLE.log", false},
+		{"report.", "report_", true},
+		{"notes  ", "notes__", true},
+		{"a:b?c*.txt", "a_b_c_.txt", true},
+		{"line\nbreak", "line_break", true},
+		{"..", "..", false},
+	}
+	for _, tt := range tests {
+		got, changed := sanitizeWindowsName(tt.name)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("sanitizeWindowsName(%q) = %q, %v, want %q, %v", tt.name, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestWindowsName(t *testing.T) {
+	if got, err := windowsName("/logs/aux/app.log", sanitizeReplace); err != nil || got != "/logs/aux_/app.log" {
+		t.Errorf("Expected /logs/aux_/app.log, got %q (%v)", got, err)
+	}
+	if got, err := windowsName("/logs/app.log", sanitizeFail); err != nil || got != "/logs/app.log" {
+		t.Errorf("Expected valid names to be kept, got %q (%v)", got, err)
+	}
+	if _, err := windowsName("/logs/PRN", sanitizeSkip); !errors.Is(err, errSkippedName) {
+		t.Errorf("Expected errSkippedName, got %v", err)
+	}
+	if _, err := windowsName("/logs/PRN", sanitizeFail); err == nil || errors.Is(err, errSkippedName) {
+		t.Errorf("Expected a failure, got %v", err)
+	}
+}
@@ -25,8 +25,12 @@
 package utils
 
 import (
+	"fmt"
 	goutils "github.com/jkaninda/go-utils"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -35,7 +39,7 @@ var (
 )
 
 const (
-	appName    = "s3safe"
+	AppName    = "s3safe"
 	AppExample = `
 		Backup: s3safe backup --path /path/to/backup --dest /s3path/backups,
 		Restore: s3safe restore --path /s3path/backups --dest /path/to/dest"`
@@ -45,10 +49,60 @@ const (
 		Backup: "s3safe backup --path /path/to/backup --dest /s3path/backups",
 		Backup a single file: "s3safe backup --file /path/to/data/tar.gz --dest /path/to/dest",
 		Backup with compression: "s3safe backup --path /path/to/backup --dest /path/to/dest --compress",
-		Backup with timestamp: "s3safe backup --path /path/to/backup --dest /path/to/dest --compress --timestamp"`
+		Backup with timestamp: "s3safe backup --path /path/to/backup --dest /path/to/dest --compress --timestamp",
+		Backup to an archive storage class: "s3safe backup --path /path/to/backup --dest /path/to/dest --compress --storage-class GLACIER",
+		Backup to additional destinations: "s3safe backup --path /path/to/backup --dest /path/to/dest --mirror 's3://dr-bucket/backups?endpoint=https://minio.local:9000&credentials=MINIO' --parallel",
+		Backup to a per-host daily prefix: "s3safe backup --path /path/to/backup --dest 'backups/{{ .Hostname }}/{{ .Date \"2006/01/02\" }}' --compress",
+		Backup with Object Lock retention: "s3safe backup --path /path/to/backup --dest /path/to/dest --compress --object-lock-mode COMPLIANCE --object-lock-days 90"`
 	RestoreExample = `
 		Restore: "s3safe restore --path /s3path --file backup.tar.gz --dest /path/to/dest",
-		Restore a single file with decompression: "s3safe restore --path /s3path/backups --file backup.tar.gz --dest /path/to/dest --decompress",`
+		Restore a single file with decompression: "s3safe restore --path /s3path/backups --file backup.tar.gz --dest /path/to/dest --decompress",
+		Restore the newest compressed backup: "s3safe restore --path /s3path/backups --dest /path/to/dest --latest --decompress",
+		Restore the newest matching file: "s3safe restore --path /s3path/backups --dest /path/to/dest --match 'db-*.tar.gz' --newest --decompress",
+		Restore a previous version: "s3safe restore --path /s3path/backups --file backup.tar.gz --dest /path/to/dest --version-id <id>",`
+	ThawExample = `
+		Thaw archived backups: "s3safe thaw --path /s3path/backups --recursive --tier Bulk",
+		Thaw and wait: "s3safe thaw --path /s3path/backups --file backup.tar.gz --wait",
+		Check status: "s3safe thaw-status --path /s3path/backups --recursive"`
+	RetryExample = `
+		Retry failed files: "s3safe retry --report failures.json",
+		Retry from S3 and keep the remaining failures: "s3safe retry --report s3://bucket/reports/failures.json --failure-report failures.json"`
+	CheckExample = `
+		Check an archive: "s3safe check --path /s3path/backups --file backup.tar.gz",
+		Check the newest compressed backup: "s3safe check --path /s3path/backups --latest"`
+	CostExample = `
+		Compare every storage class: "s3safe cost --path /s3path/backups",
+		Estimate a storage class: "s3safe cost --path /s3path/backups --provider aws --class STANDARD_IA",
+		Self-hosted storage: "s3safe cost --path /s3path/backups --provider minio --price-per-gb 0.005"`
+	UsageExample = `
+		Archive sizes of the last runs: "s3safe usage --path /s3path/backups",
+		Dated folder backups per job: "s3safe usage --path backups --depth 1 --runs 30",
+		CSV export: "s3safe usage --path /s3path/backups --format csv > usage.csv"`
+	BackupsExample = `
+		Restore points of a destination: "s3safe backups --dest /s3path/backups",
+		Archive timestamps in local time: "s3safe backups --dest backups --timezone Europe/Paris",
+		JSON export: "s3safe backups --dest /s3path/backups --format json"`
+	SearchExample = `
+		Last copies of a file: "s3safe search nginx.conf --path /s3path/backups --archives",
+		Copies of a job before a date: "s3safe search 'nginx/sites-enabled/*' --path backups --job web --before 2025-05-01",
+		CSV export: "s3safe search '*.sql' --path /s3path/backups --format csv > copies.csv"`
+	LifecycleExample = `
+		Tier and expire backups: "s3safe lifecycle apply --dest /s3path/backups --transition-glacier 30d --expire 365d",
+		Preview the rule: "s3safe lifecycle apply --dest backups --transition-deep-archive 90d --dry-run"`
+	CompletionExample = `
+		Bash, current shell: "source <(s3safe completion bash)",
+		Zsh: "s3safe completion zsh > ${fpath[1]}/_s3safe",
+		Fish: "s3safe completion fish > ~/.config/fish/completions/s3safe.fish",
+		PowerShell: "s3safe completion powershell | Out-String | Invoke-Expression"`
+	CompletionDescription = `Generate the completion script of s3safe for bash, zsh, fish or powershell.
+
+Commands, flags and the values of flags such as --provider, --storage-class or --format are completed,
+--job completes the job names set by S3SAFE_JOB in the environment, the --env-file file and the .env files
+of the working directory. Bash completion requires the bash-completion package.`
+	ReplicateExample = `
+		Same provider: "s3safe replicate --from s3://primary/backups --to s3://dr-bucket/backups",
+		Other region: "s3safe replicate --from s3://primary/backups --to 's3://dr-bucket/backups?region=eu-west-1'",
+		Other provider: "s3safe replicate --from s3://primary/backups --to 's3://dr/backups?endpoint=https://minio.local:9000&force-path=true&credentials=MINIO'"`
 
 	AwsS3Url         = "https://s3.amazonaws.com"
 	RegionEnv        = "AWS_REGION"
@@ -59,6 +113,113 @@ const (
 	ForcePathEnv     = "AWS_FORCE_PATH"
 	DisableSSLEnv    = "AWS_DISABLE_SSL"
 	RetentionDaysEnv = "AWS_RETENTION_DAYS"
+	ProxyEnv         = "S3SAFE_PROXY"
+	ProviderEnv      = "S3SAFE_PROVIDER"
+	JobEnv           = "S3SAFE_JOB"
+	StorageClassEnv  = "AWS_STORAGE_CLASS"
+	// StorageClassRulesEnv holds storage class rules, e.g. "size>1GB:GLACIER,*.json:STANDARD"
+	StorageClassRulesEnv = "AWS_STORAGE_CLASS_RULES"
+	ACLEnv               = "AWS_ACL"
+	AccelerateEnv        = "AWS_ACCELERATE"
+	ObjectLockModeEnv    = "AWS_OBJECT_LOCK_MODE"
+	ObjectLockDaysEnv    = "AWS_OBJECT_LOCK_DAYS"
+	ObjectLockPolicyEnv  = "AWS_OBJECT_LOCK_POLICY"
+	// MirrorsEnv holds comma-separated additional backup destinations, e.g. "s3://dr-bucket/backups?region=eu-west-1"
+	MirrorsEnv = "S3SAFE_MIRRORS"
+	// AzureSASTokenEnv and AzureAccountKeyEnv authenticate azblob:// locations, the default Azure credential chain is used otherwise
+	AzureSASTokenEnv   = "AZURE_STORAGE_SAS_TOKEN"
+	AzureAccountKeyEnv = "AZURE_STORAGE_KEY"
+	// TimestampFormatEnv holds the Go time layout of archive timestamps, DefaultTimestampFormat by default
+	TimestampFormatEnv = "S3SAFE_TIMESTAMP_FORMAT"
+	// TimezoneEnv holds the IANA time zone of timestamps and path templates, e.g. UTC or Europe/Paris
+	TimezoneEnv            = "S3SAFE_TIMEZONE"
+	DefaultTimestampFormat = "2006-01-02_15-04-05"
+	// NameTemplateEnv holds the compressed archive name template, e.g. "{{ .Base }}-{{ .Host }}-{{ .Timestamp }}.tar.gz"
+	NameTemplateEnv = "S3SAFE_NAME_TEMPLATE"
+	// ChecksumEnv holds the S3 additional checksum algorithm of uploads, SHA256 by default or NONE
+	ChecksumEnv = "S3SAFE_CHECKSUM"
+	// SanitizeNamesEnv holds the strategy for keys that are not valid Windows file names: replace, skip or fail
+	SanitizeNamesEnv = "S3SAFE_SANITIZE_NAMES"
+	// NormalizeUnicodeEnv holds the Unicode normalization form of keys: nfc, nfd or none
+	NormalizeUnicodeEnv = "S3SAFE_NORMALIZE_UNICODE"
+	// UnsafeKeysEnv holds the strategy for keys containing control characters, '#' or '?': keep, encode or reject
+	UnsafeKeysEnv = "S3SAFE_UNSAFE_KEYS"
+	// InventoryEnv holds the s3:// URL of an S3 Inventory manifest.json listing the objects to restore
+	InventoryEnv = "S3SAFE_INVENTORY"
+	// PartSizeEnv, ConcurrencyEnv and MaxMemoryEnv tune S3 multipart transfers, e.g. "16MiB", 4 and "256MiB"
+	PartSizeEnv    = "S3SAFE_PART_SIZE"
+	ConcurrencyEnv = "S3SAFE_CONCURRENCY"
+	MaxMemoryEnv   = "S3SAFE_MAX_MEMORY"
+	// ListShardsEnv holds the number of prefixes listed at once by recursive listings
+	ListShardsEnv = "S3SAFE_LIST_SHARDS"
+	// ConnectTimeoutEnv, ResponseHeaderTimeoutEnv, MaxIdleConnsEnv, DisableKeepAlivesEnv and DisableHTTP2Env
+	// tune the HTTP connections to the endpoint, e.g. "5s", "30s", 64, true and true
+	ConnectTimeoutEnv        = "S3SAFE_CONNECT_TIMEOUT"
+	ResponseHeaderTimeoutEnv = "S3SAFE_RESPONSE_HEADER_TIMEOUT"
+	MaxIdleConnsEnv          = "S3SAFE_MAX_IDLE_CONNS"
+	DisableKeepAlivesEnv     = "S3SAFE_DISABLE_KEEP_ALIVES"
+	DisableHTTP2Env          = "S3SAFE_DISABLE_HTTP2"
+	// DebugRequestsEnv logs every S3 API call at debug level
+	DebugRequestsEnv = "S3SAFE_DEBUG_REQUESTS"
+	// BandwidthScheduleEnv holds the transfer rates by time of day, e.g. "00:00-06:00=unlimited,*=5MB"
+	BandwidthScheduleEnv = "S3SAFE_BANDWIDTH_SCHEDULE"
+	// FIPSEnv enables the FIPS endpoints and FIPS-approved crypto only
+	FIPSEnv = "S3SAFE_FIPS"
+	// FailureReportEnv holds the local path or s3:// URL of the report of files that failed with --ignore-errors
+	FailureReportEnv = "S3SAFE_FAILURE_REPORT"
+	// ReportHTMLEnv holds the local path or s3:// URL of the HTML report of a run
+	ReportHTMLEnv = "S3SAFE_REPORT_HTML"
+	// MaxErrorsEnv holds the number or percentage of failed files aborting a run with --ignore-errors, e.g. 10 or 5%
+	MaxErrorsEnv = "S3SAFE_MAX_ERRORS"
+	// CheckpointEnv holds the local file the progress of folder backups is written to, e.g. /var/lib/s3safe/backup.checkpoint
+	CheckpointEnv = "S3SAFE_CHECKPOINT"
+	// VerifySampleEnv holds the number or percentage of uploaded files compared with their objects after a backup, e.g. 20 or 5%
+	VerifySampleEnv = "S3SAFE_VERIFY_SAMPLE"
+	// SkipIdenticalEnv skips the upload of files whose content is already stored under the same key
+	SkipIdenticalEnv = "S3SAFE_SKIP_IDENTICAL"
+	// NoClobberRemoteEnv makes uploads fail instead of overwriting an existing object
+	NoClobberRemoteEnv = "S3SAFE_NO_CLOBBER_REMOTE"
+	// AuditLogEnv holds the prefix or s3:// URL of the monthly audit log objects, e.g. s3://audit-bucket/s3safe
+	AuditLogEnv = "S3SAFE_AUDIT_LOG"
+	// PruneReportEnv holds the local path, s3:// URL or file name under the pruned path of the purge-versions report
+	PruneReportEnv = "S3SAFE_PRUNE_REPORT"
+	// PluginsDirEnv holds the directory of the backup plugins
+	PluginsDirEnv = "S3SAFE_PLUGINS_DIR"
+	// ObfuscateKeysEnv enables the obfuscation of object keys, KeySecretEnv holds the secret encrypting the keys
+	ObfuscateKeysEnv = "S3SAFE_OBFUSCATE_KEYS"
+	KeySecretEnv     = "S3SAFE_KEY_SECRET"
+	// KeepLocalEnv holds the number of compressed archives kept locally
+	KeepLocalEnv = "S3SAFE_KEEP_LOCAL"
+	// LayoutEnv holds the layout of the uploads under the destination prefix, flat or date
+	LayoutEnv = "S3SAFE_LAYOUT"
+	// OneFileSystemEnv keeps the walk on the file system of the backed up directory
+	OneFileSystemEnv = "S3SAFE_ONE_FILE_SYSTEM"
+	// ExcludeCachesEnv excludes the directories tagged with CACHEDIR.TAG, ExcludeCommonCachesEnv the common cache directories
+	ExcludeCachesEnv       = "S3SAFE_EXCLUDE_CACHES"
+	ExcludeCommonCachesEnv = "S3SAFE_EXCLUDE_COMMON_CACHES"
+	// RespectGitignoreEnv excludes the files matched by .gitignore files
+	RespectGitignoreEnv = "S3SAFE_RESPECT_GITIGNORE"
+	// OnUnreadableEnv holds the policy for unreadable files of a backup: skip, warn or fail
+	OnUnreadableEnv = "S3SAFE_ON_UNREADABLE"
+	// SELinuxEnv enables storing and restoring SELinux contexts
+	SELinuxEnv = "S3SAFE_SELINUX"
+	// SnapshotEnv holds the volume backed up from a snapshot, kind:target or vss
+	SnapshotEnv = "S3SAFE_SNAPSHOT"
+	// RequireEnv holds the comma-separated bucket settings required for a backup, RequireWarnEnv only warns when unmet
+	RequireEnv     = "S3SAFE_REQUIRE"
+	RequireWarnEnv = "S3SAFE_REQUIRE_WARN"
+	// MPUCleanupDaysEnv holds the days after which incomplete multipart uploads under the destination are aborted
+	MPUCleanupDaysEnv = "S3SAFE_ENSURE_MPU_CLEANUP_RULE"
+	// K8sEnv enables the Kubernetes mode
+	K8sEnv = "S3SAFE_K8S"
+	// K8sConfigDirEnv holds the directory of the mounted ConfigMap and Secret files read in Kubernetes mode
+	K8sConfigDirEnv = "S3SAFE_CONFIG_DIR"
+	// K8sAnnotationsFileEnv holds the pod annotations file of the Downward API
+	K8sAnnotationsFileEnv = "S3SAFE_ANNOTATIONS_FILE"
+	// K8sReadyFileEnv holds the file created once the job is set up, for readiness probes
+	K8sReadyFileEnv = "S3SAFE_READY_FILE"
+	// K8sTerminationLogEnv holds the termination message path of the container
+	K8sTerminationLogEnv = "S3SAFE_TERMINATION_LOG"
 )
 
 func Env(key string) string {
@@ -77,6 +238,42 @@ func BoolEnv(key string) bool {
 	}
 	return false
 }
+
+// ParseDuration parses a duration, in addition to time.ParseDuration units it accepts days (d) and weeks (w)
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if n, ok := strings.CutSuffix(value, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if n, ok := strings.CutSuffix(value, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(weeks) * 7 * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// ParseTime parses an absolute time (RFC3339, "2006-01-02 15:04", "2006-01-02") in local time,
+// or a duration relative to now such as "24h" or "7d".
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if d, err := ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339, \"2006-01-02 15:04\" or a duration such as 24h or 7d", value)
+}
+
 func FileSize(path string) string {
 	file, err := os.Stat(path)
 	if err != nil {